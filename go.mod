@@ -1,6 +1,6 @@
 module github.com/nrexception/mockapi
 
-go 1.21.3
+go 1.24
 
 require (
 	github.com/google/uuid v1.3.1
@@ -1,12 +1,19 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"log/slog"
 	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"syscall"
 	"text/tabwriter"
+	"time"
 
+	"github.com/nrexception/mockapi/pkg/certs"
 	co "github.com/nrexception/mockapi/pkg/common"
 	ser "github.com/nrexception/mockapi/pkg/server"
 	se "github.com/nrexception/mockapi/pkg/settings"
@@ -30,7 +37,16 @@ func printHelp() {
 	_, _ = fmt.Fprintln(w, "")
 	_, _ = fmt.Fprintln(w, "Command\tPurpose\tExample")
 	_, _ = fmt.Fprintln(w, "-f\tConfiguration input file location\t./mockapi -f <filepath>")
-	_, _ = fmt.Fprintln(w, "-v\tVerbose logging flag\t./mockapi -f <filepath> -v")
+	_, _ = fmt.Fprintln(w, "-v\tVerbose logging flag, shorthand for -loglevel debug\t./mockapi -f <filepath> -v")
+	_, _ = fmt.Fprintln(w, "-loglevel\tMinimum level to log, one of debug, info, warn or error (default info)\t./mockapi -f <filepath> -loglevel warn")
+	_, _ = fmt.Fprintln(w, "-logformat\tLog output format, one of text or json (default text)\t./mockapi -f <filepath> -logformat json")
+	_, _ = fmt.Fprintln(w, "-l\tCopy log output to a file, it's reopened on SIGHUP\t./mockapi -f <filepath> -l <logfile>")
+	_, _ = fmt.Fprintln(w, "-logmaxsize\tRotate the -l log file once it reaches this many megabytes\t./mockapi -f <filepath> -l <logfile> -logmaxsize 100")
+	_, _ = fmt.Fprintln(w, "-logmaxage\tRotate the -l log file once it's been open this long\t./mockapi -f <filepath> -l <logfile> -logmaxage 24h")
+	_, _ = fmt.Fprintln(w, "-logmaxfiles\tNumber of rotated log files to keep, oldest are removed first\t./mockapi -f <filepath> -l <logfile> -logmaxfiles 7")
+	_, _ = fmt.Fprintln(w, "-logcompress\tGzip rotated log files\t./mockapi -f <filepath> -l <logfile> -logcompress")
+	_, _ = fmt.Fprintln(w, "-waitready\tRetry listeners that fail to bind for up to this long, then report readiness or exit non-zero\t./mockapi -f <filepath> -waitready 30s")
+	_, _ = fmt.Fprintln(w, "-exportca\tWrite the local CA certificate from the settings file's localca dir, creating the CA if needed, then exit\t./mockapi -f <filepath> -exportca ca.pem")
 	_, _ = fmt.Fprintln(w, "-w\tWatch config file(s) provided by -f, re-apply their configuration if they are changed\t./mockapi -f <filepath> -w")
 
 	_ = w.Flush()
@@ -38,22 +54,29 @@ func printHelp() {
 	os.Exit(0)
 }
 
-func handleConfigFileRefresh(fileEventChannel chan co.FileChangedEvent, listenerCommandChannel chan ser.ListenerCommandPacket, listenerResponseChannel chan ser.ListenerResponse, filePath string) error {
+// Re-applies the config file whenever it changes. A config that fails to load or validate is logged and skipped, the
+// listeners keep serving whatever was last applied. One that applies with listeners failing to start is logged too.
+func handleConfigFileRefresh(fileEventChannel chan co.FileChangedEvent, manager *ser.ListenerManager, filePath string) {
 	for l := range fileEventChannel {
-		co.LogVerbose(fmt.Sprintf("Config file \"%s\" was changed. Was: %s is: %s", l.FileName, l.FileHashBeforeChange, l.FileHashAfterChange), co.MSGTYPE_WARN)
+		co.LogVerbose(fmt.Sprintf("Config file was %s", l.Type), co.MSGTYPE_WARN, co.LOGKEY_FILE, l.FileName)
 
-		ser.ClearAllListeners(listenerCommandChannel)
+		// Keep serving the current config until the file comes back...
+		if l.Type == co.FILEEVENT_REMOVED {
+			continue
+		}
 
-		err := handleListenersFromFile(listenerCommandChannel, listenerResponseChannel, filePath)
-		if err != nil {
-			return fmt.Errorf("error handling listeners from file: %w", err)
+		err := handleListenersFromFile(manager, filePath)
+		manager.RecordConfigReload(err)
+		switch {
+		case errors.Is(err, ser.ErrListenersNotStarted):
+			co.LogNonVerbose("Config file applied, but some listeners could not be started", co.MSGTYPE_WARN, co.LOGKEY_FILE, filePath, co.LOGKEY_ERROR, err)
+		case err != nil:
+			co.LogNonVerbose("Config file rejected, keeping last good config", co.MSGTYPE_WARN, co.LOGKEY_FILE, filePath, co.LOGKEY_ERROR, err)
 		}
 	}
-
-	return nil
 }
 
-func handleListenersFromFile(listenerCommandChannel chan ser.ListenerCommandPacket, listenerResponseChannel chan ser.ListenerResponse, filePath string) error {
+func handleListenersFromFile(manager *ser.ListenerManager, filePath string) error {
 	// Init...
 	co.LogVerbose("Reading settings file", co.MSGTYPE_INFO)
 
@@ -71,21 +94,128 @@ func handleListenersFromFile(listenerCommandChannel chan ser.ListenerCommandPack
 		return fmt.Errorf("handleListenersFromFile: %w", err)
 	}
 
-	// Stand up web listeners and listen
-	for _, listener := range u.WebListeners {
-		listener := listener
+	// Stand up, update or tear down web listeners to match
+	err = manager.Apply(u)
+	if err != nil {
+		return fmt.Errorf("handleListenersFromFile: %w", err)
+	}
+
+	return nil
+}
 
-		go func() {
-			err := ser.EstablishListener(listenerCommandChannel, listenerResponseChannel, listener)
-			if err != nil {
-				log.Printf("error establishing listener: %s", err)
-			}
-		}()
+// How often -waitready retries listeners that couldn't bind.
+const readyRetryInterval = 250 * time.Millisecond
+
+// Re-applies the config file until every listener in it is bound or timeout runs out. Only bind failures are retried, a
+// config that can't be loaded fails straight away.
+func waitUntilReady(manager *ser.ListenerManager, filePath string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+
+	err := handleListenersFromFile(manager, filePath)
+	for err != nil {
+		if !errors.Is(err, ser.ErrListenersNotStarted) || time.Now().After(deadline) {
+			return fmt.Errorf("waitUntilReady: %w", err)
+		}
+
+		co.LogVerbose("Listeners not ready, retrying...", co.MSGTYPE_WARN, co.LOGKEY_ERROR, err)
+		time.Sleep(readyRetryInterval)
+		err = handleListenersFromFile(manager, filePath)
+	}
+
+	status := manager.Status()
+	co.LogNonVerbose("All listeners ready", co.MSGTYPE_INFO, co.LOGKEY_CONFIGID, status.ConfigId, co.LOGKEY_CONFIGHASH, status.ConfigHash)
+
+	return nil
+}
+
+// Writes the CA persisted in the settings file's localca dir to outPath, so clients can be set up to trust it before
+// (or without) mockapi running.
+func exportLocalCA(settingsPath string, outPath string) error {
+	u, err := se.UnmarshalSettingsFile(settingsPath)
+	if err != nil {
+		return fmt.Errorf("exportLocalCA: %w", err)
+	}
+
+	if u.LocalCA == nil {
+		return errors.New("exportLocalCA: settings file has no localca dir, without one the CA only lives in memory (fetch it from the admin listener's /ca.pem instead)")
+	}
+
+	ca, err := certs.LoadOrCreateCA(u.LocalCA.Dir)
+	if err != nil {
+		return fmt.Errorf("exportLocalCA: %w", err)
+	}
+
+	err = os.WriteFile(outPath, ca.CertPEM(), 0644)
+	if err != nil {
+		return fmt.Errorf("exportLocalCA: %w", err)
 	}
 
 	return nil
 }
 
+func configureLogging() error {
+	level := slog.LevelInfo
+	if co.ArgSliceContains(os.Args, "-v") {
+		level = slog.LevelDebug
+	}
+
+	m, params := co.ArgSliceSwitchParameters(os.Args, "-loglevel")
+	if m {
+		l, err := co.ParseLogLevel(params[0])
+		if err != nil {
+			return fmt.Errorf("configureLogging: %w", err)
+		}
+		level = l
+	}
+
+	format := co.LOGFORMAT_TEXT
+	m, params = co.ArgSliceSwitchParameters(os.Args, "-logformat")
+	if m {
+		f, err := co.ParseLogFormat(params[0])
+		if err != nil {
+			return fmt.Errorf("configureLogging: %w", err)
+		}
+		format = f
+	}
+
+	co.ConfigureLogger(level, format)
+
+	return nil
+}
+
+func parseLogRotation() (co.LogRotationSettings, error) {
+	rotation := co.LogRotationSettings{Compress: co.ArgSliceContains(os.Args, "-logcompress")}
+
+	m, params := co.ArgSliceSwitchParameters(os.Args, "-logmaxsize")
+	if m {
+		megabytes, err := strconv.ParseInt(params[0], 10, 64)
+		if err != nil || megabytes <= 0 {
+			return rotation, fmt.Errorf("parseLogRotation: -logmaxsize must be a positive number of megabytes: %s", params[0])
+		}
+		rotation.MaxSizeBytes = megabytes * 1024 * 1024
+	}
+
+	m, params = co.ArgSliceSwitchParameters(os.Args, "-logmaxage")
+	if m {
+		maxAge, err := time.ParseDuration(params[0])
+		if err != nil || maxAge <= 0 {
+			return rotation, fmt.Errorf("parseLogRotation: -logmaxage must be a positive duration (eg. 24h): %s", params[0])
+		}
+		rotation.MaxAge = maxAge
+	}
+
+	m, params = co.ArgSliceSwitchParameters(os.Args, "-logmaxfiles")
+	if m {
+		maxFiles, err := strconv.Atoi(params[0])
+		if err != nil || maxFiles <= 0 {
+			return rotation, fmt.Errorf("parseLogRotation: -logmaxfiles must be a positive number: %s", params[0])
+		}
+		rotation.MaxFiles = maxFiles
+	}
+
+	return rotation, nil
+}
+
 func run() error {
 	fmt.Print(banner)
 
@@ -104,49 +234,110 @@ func run() error {
 		return nil
 	}
 
+	// Handle -loglevel and -logformat
+	err := configureLogging()
+	if err != nil {
+		return fmt.Errorf("error configuring logging: %w", err)
+	}
+
 	// Handle -l log file location
 	m, params := co.ArgSliceSwitchParameters(os.Args, "-l")
 	if len(params) > 1 {
 		return fmt.Errorf("Only one log file location is supported at the moment")
 	}
 	if m {
-		co.SetLogFileActive(params[0])
+		rotation, err := parseLogRotation()
+		if err != nil {
+			return fmt.Errorf("error configuring log rotation: %w", err)
+		}
+
+		logFile, err := co.SetLogFileActive(params[0], rotation)
+		if err != nil {
+			return fmt.Errorf("error opening log file: %w", err)
+		}
+		defer logFile.Close()
+
+		// Cooperate with external logrotate, which moves the file and sends SIGHUP
+		go func() {
+			hangups := make(chan os.Signal, 1)
+			signal.Notify(hangups, syscall.SIGHUP)
+			for range hangups {
+				err := logFile.Reopen()
+				if err != nil {
+					co.LogNonVerbose("error reopening log file", co.MSGTYPE_ERROR, co.LOGKEY_FILE, params[0], co.LOGKEY_ERROR, err)
+				}
+			}
+		}()
 	}
 
 	// Handle -f file inputs
 	m, params = co.ArgSliceSwitchParameters(os.Args, "-f")
+
+	// Handle -exportca, which only needs the settings file
+	exportCA, exportParams := co.ArgSliceSwitchParameters(os.Args, "-exportca")
+	if m && exportCA {
+		err = exportLocalCA(params[0], exportParams[0])
+		if err != nil {
+			return fmt.Errorf("error exporting local CA: %w", err)
+		}
+		co.LogNonVerbose("Local CA exported", co.MSGTYPE_INFO, co.LOGKEY_FILE, exportParams[0])
+		return nil
+	}
+
 	if m {
 		fileWatcherChannel := make(chan co.FileChangedEvent)
 		listenerCommandChannel := make(chan ser.ListenerCommandPacket)
 		listenerResponseChannel := make(chan ser.ListenerResponse)
 
+		manager := ser.NewListenerManager(listenerCommandChannel, listenerResponseChannel)
+		defer manager.Close()
+
+		// And output out listeners channel!
+		go func() {
+			for listenResponse := range listenerResponseChannel {
+				co.LogNonVerbose(string(listenResponse), co.MSGTYPE_INFO)
+			}
+		}()
+
+		// Takes first member of slice for now... Will change this when adding multiple file support...
+		waitReady, waitParams := co.ArgSliceSwitchParameters(os.Args, "-waitready")
+		if waitReady {
+			timeout, err := time.ParseDuration(waitParams[0])
+			if err != nil || timeout <= 0 {
+				return fmt.Errorf("-waitready must be a positive duration (eg. 30s): %s", waitParams[0])
+			}
+
+			err = waitUntilReady(manager, params[0], timeout)
+			if err != nil {
+				return fmt.Errorf("listeners not ready: %w", err)
+			}
+		} else {
+			// Listeners that can't bind are logged, the rest keep serving
+			err = handleListenersFromFile(manager, params[0])
+			if errors.Is(err, ser.ErrListenersNotStarted) {
+				co.LogNonVerbose("Some listeners could not be started", co.MSGTYPE_ERROR, co.LOGKEY_FILE, params[0], co.LOGKEY_ERROR, err)
+			} else if err != nil {
+				return fmt.Errorf("error handling listeners from file: %w", err)
+			}
+		}
+
 		// If specified, watch our config file(s), reload them if needed...
 		if watchConfigFile {
 			go func() {
 				err := co.WatchFile(params[0], fileWatcherChannel, false)
 				if err != nil {
-					log.Printf("error watching file: %s\n", err)
+					co.LogNonVerbose("error watching file", co.MSGTYPE_ERROR, co.LOGKEY_FILE, params[0], co.LOGKEY_ERROR, err)
 				}
 			}()
-		}
 
-		go func() {
-			err := handleConfigFileRefresh(fileWatcherChannel, listenerCommandChannel, listenerResponseChannel, params[0])
-			if err != nil {
-				log.Printf("error handling config file refresh: %s\n", err)
-			}
-		}()
-
-		// Takes first member of slice for now... Will change this when adding multiple file support...
-		err := handleListenersFromFile(listenerCommandChannel, listenerResponseChannel, params[0])
-		if err != nil {
-			return fmt.Errorf("error handling listeners from file: %w", err)
+			go handleConfigFileRefresh(fileWatcherChannel, manager, params[0])
 		}
 
-		// And output out listeners channel!
-		for listenResponse := range listenerResponseChannel {
-			log.Println(listenResponse)
-		}
+		// Serve until we're asked to stop, then let in-flight requests finish
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		<-signals
+		co.LogNonVerbose("Shutting down...", co.MSGTYPE_INFO)
 	}
 
 	return nil
@@ -0,0 +1,273 @@
+// Package certs generates the certificates behind auto tls listeners, either self-signed or issued by a local CA that
+// can be trusted once and reused across runs.
+package certs
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	CAFileName    = "ca.pem"
+	CAKeyFileName = "ca-key.pem"
+
+	caValidity   = 10 * 365 * 24 * time.Hour
+	leafValidity = 365 * 24 * time.Hour
+)
+
+// A certificate authority for signing leaf certificates.
+type CA struct {
+	Cert    *x509.Certificate
+	key     crypto.Signer
+	certPEM []byte
+}
+
+func newKey() (*ecdsa.PrivateKey, error) {
+	return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+}
+
+func newSerial() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
+// Creates a new CA that only lives in memory.
+func NewCA() (*CA, error) {
+	key, err := newKey()
+	if err != nil {
+		return nil, fmt.Errorf("NewCA: %w", err)
+	}
+
+	serial, err := newSerial()
+	if err != nil {
+		return nil, fmt.Errorf("NewCA: %w", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"MockAPI"}, CommonName: "MockAPI Local CA"},
+		NotBefore:             time.Now().Add(-time.Hour), // Some slack for clocks that are slightly behind
+		NotAfter:              time.Now().Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, fmt.Errorf("NewCA: %w", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("NewCA: %w", err)
+	}
+
+	return &CA{Cert: cert, key: key, certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}, nil
+}
+
+// Loads the CA persisted in dir, creating and saving a new one if there isn't one yet. The key is written readable by
+// the current user only.
+func LoadOrCreateCA(dir string) (*CA, error) {
+	certPath, keyPath := filepath.Join(dir, CAFileName), filepath.Join(dir, CAKeyFileName)
+
+	certPEM, err := os.ReadFile(certPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		ca, err := NewCA()
+		if err != nil {
+			return nil, fmt.Errorf("LoadOrCreateCA: %w", err)
+		}
+
+		err = ca.save(certPath, keyPath)
+		if err != nil {
+			return nil, fmt.Errorf("LoadOrCreateCA: %w", err)
+		}
+
+		return ca, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("LoadOrCreateCA: %w", err)
+	}
+
+	keyPEM, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("LoadOrCreateCA: %w", err)
+	}
+
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("LoadOrCreateCA: %w", err)
+	}
+
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("LoadOrCreateCA: %w", err)
+	}
+
+	key, ok := pair.PrivateKey.(crypto.Signer)
+	if !ok || !cert.IsCA {
+		return nil, fmt.Errorf("LoadOrCreateCA: %s is not a usable CA", certPath)
+	}
+
+	return &CA{Cert: cert, key: key, certPEM: certPEM}, nil
+}
+
+func (ca *CA) save(certPath string, keyPath string) error {
+	keyDER, err := x509.MarshalPKCS8PrivateKey(ca.key)
+	if err != nil {
+		return fmt.Errorf("CA.save: %w", err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(certPath), 0755)
+	if err != nil {
+		return fmt.Errorf("CA.save: %w", err)
+	}
+
+	err = os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600)
+	if err != nil {
+		return fmt.Errorf("CA.save: %w", err)
+	}
+
+	err = os.WriteFile(certPath, ca.certPEM, 0644)
+	if err != nil {
+		return fmt.Errorf("CA.save: %w", err)
+	}
+
+	return nil
+}
+
+// The CA certificate, PEM encoded, for clients to trust.
+func (ca *CA) CertPEM() []byte {
+	return ca.certPEM
+}
+
+func leafTemplate(commonName string, hostnames []string, ips []net.IP, usage x509.ExtKeyUsage) (*x509.Certificate, error) {
+	serial, err := newSerial()
+	if err != nil {
+		return nil, err
+	}
+
+	return &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{Organization: []string{"MockAPI"}, CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(leafValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		DNSNames:     hostnames,
+		IPAddresses:  ips,
+	}, nil
+}
+
+func (ca *CA) issue(template *x509.Certificate) (tls.Certificate, error) {
+	key, err := newKey()
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.Cert, key.Public(), ca.key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der, ca.Cert.Raw}, PrivateKey: key}, nil
+}
+
+// Issues a server certificate for hostnames and ips, signed by the CA and chained to it.
+func (ca *CA) Issue(hostnames []string, ips []net.IP) (tls.Certificate, error) {
+	commonName := "localhost"
+	if len(hostnames) > 0 {
+		commonName = hostnames[0]
+	}
+
+	template, err := leafTemplate(commonName, hostnames, ips, x509.ExtKeyUsageServerAuth)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("CA.Issue: %w", err)
+	}
+
+	cert, err := ca.issue(template)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("CA.Issue: %w", err)
+	}
+
+	return cert, nil
+}
+
+// Issues a client certificate, for testing listeners that ask for one (see clientauth).
+func (ca *CA) IssueClient(commonName string, sans []string) (tls.Certificate, error) {
+	template, err := leafTemplate(commonName, sans, nil, x509.ExtKeyUsageClientAuth)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("CA.IssueClient: %w", err)
+	}
+
+	cert, err := ca.issue(template)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("CA.IssueClient: %w", err)
+	}
+
+	return cert, nil
+}
+
+// Writes cert (and its chain) and key as PEM files, eg. for a listener's certdetails.
+func SaveKeyPair(cert tls.Certificate, certPath string, keyPath string) error {
+	keyDER, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	if err != nil {
+		return fmt.Errorf("SaveKeyPair: %w", err)
+	}
+
+	certPEM := []byte{}
+	for _, der := range cert.Certificate {
+		certPEM = append(certPEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	}
+
+	err = os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600)
+	if err != nil {
+		return fmt.Errorf("SaveKeyPair: %w", err)
+	}
+
+	err = os.WriteFile(certPath, certPEM, 0644)
+	if err != nil {
+		return fmt.Errorf("SaveKeyPair: %w", err)
+	}
+
+	return nil
+}
+
+// Creates a server certificate for hostnames and ips that signs itself.
+func SelfSigned(hostnames []string, ips []net.IP) (tls.Certificate, error) {
+	key, err := newKey()
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("SelfSigned: %w", err)
+	}
+
+	commonName := "localhost"
+	if len(hostnames) > 0 {
+		commonName = hostnames[0]
+	}
+
+	template, err := leafTemplate(commonName, hostnames, ips, x509.ExtKeyUsageServerAuth)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("SelfSigned: %w", err)
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("SelfSigned: %w", err)
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
@@ -0,0 +1,65 @@
+package certs
+
+import (
+	"bytes"
+	"crypto/x509"
+	"net"
+	"testing"
+)
+
+func TestLoadOrCreateCA(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	created, err := LoadOrCreateCA(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadOrCreateCA(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(created.CertPEM(), loaded.CertPEM()) {
+		t.Fatal("expected the persisted CA to be loaded again")
+	}
+
+	// Leaves issued by the reloaded CA verify against the original
+	leaf, err := loaded.Issue([]string{"localhost"}, []net.IP{net.ParseIP("127.0.0.1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(leaf.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(created.Cert)
+	for _, name := range []string{"localhost", "127.0.0.1"} {
+		_, err = cert.Verify(x509.VerifyOptions{Roots: pool, DNSName: name})
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+		}
+	}
+}
+
+func TestSelfSigned(t *testing.T) {
+	t.Parallel()
+
+	leaf, err := SelfSigned([]string{"api.local"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(leaf.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	_, err = cert.Verify(x509.VerifyOptions{Roots: pool, DNSName: "api.local"})
+	if err != nil {
+		t.Error(err)
+	}
+}
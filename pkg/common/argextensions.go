@@ -27,10 +27,12 @@ func ArgSliceSwitchParameters(args []string, switchTerm string) (switchTermMatch
 	params := []string{}
 	for i, arg := range args {
 		if arg == switchTerm {
+			// Parameters run up to the next switch, so "-f a.yaml -loglevel warn" doesn't hand "warn" to -f...
 			for p := i + 1; p < len(args); p++ {
-				if !strings.Contains(args[p], "-") {
-					params = append(params, args[p])
+				if strings.HasPrefix(args[p], "-") {
+					break
 				}
+				params = append(params, args[p])
 			}
 			break
 		}
@@ -0,0 +1,39 @@
+package common
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestArgSliceSwitchParameters(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name            string
+		args            []string
+		switchTerm      string
+		expectedMatched bool
+		expectedParams  []string
+	}{
+		{name: "one parameter", args: []string{"mockapi", "-f", "a.yaml"}, switchTerm: "-f", expectedMatched: true, expectedParams: []string{"a.yaml"}},
+		{name: "several parameters", args: []string{"mockapi", "-f", "a.yaml", "b.yaml"}, switchTerm: "-f", expectedMatched: true, expectedParams: []string{"a.yaml", "b.yaml"}},
+		{name: "stops at the next switch", args: []string{"mockapi", "-f", "a.yaml", "-loglevel", "warn"}, switchTerm: "-f", expectedMatched: true, expectedParams: []string{"a.yaml"}},
+		{name: "later switch", args: []string{"mockapi", "-f", "a.yaml", "-loglevel", "warn"}, switchTerm: "-loglevel", expectedMatched: true, expectedParams: []string{"warn"}},
+		{name: "no parameters", args: []string{"mockapi", "-f", "-v"}, switchTerm: "-f"},
+		{name: "last argument", args: []string{"mockapi", "-f"}, switchTerm: "-f"},
+		{name: "missing switch", args: []string{"mockapi", "-v", "a.yaml"}, switchTerm: "-f"},
+		{name: "no arguments", switchTerm: "-f"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			matched, params := ArgSliceSwitchParameters(tc.args, tc.switchTerm)
+			if matched != tc.expectedMatched || !slices.Equal(params, tc.expectedParams) {
+				t.Errorf("unexpected result:\ngot: %t %q\nwant:%t %q", matched, params, tc.expectedMatched, tc.expectedParams)
+			}
+		})
+	}
+}
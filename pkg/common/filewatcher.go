@@ -1,74 +1,283 @@
 package common
 
 import (
-	"crypto/md5"
-	"encoding/hex"
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sync"
 	"time"
 )
 
+type FileEventType string
+
+func (re FileEventType) String() string { return string(re) }
+
+const (
+	FILEEVENT_CREATED  FileEventType = "created"
+	FILEEVENT_MODIFIED FileEventType = "modified"
+	FILEEVENT_REMOVED  FileEventType = "removed"
+)
+
+// Default quiet period a path must see before its event is emitted, editors tend to write in bursts...
+const DefaultWatchDebounce = 100 * time.Millisecond
+
 type FileChangedEvent struct {
-	FileName             string
-	FileHashBeforeChange string
-	FileHashAfterChange  string
+	FileName string
+	Type     FileEventType
 }
 
-func generateHash(filePath string) (hash string, err error) {
-	b, err := os.ReadFile(filePath)
-	if err != nil {
-		return "", fmt.Errorf("generateHash(): %w", err)
+// Backends only ever watch directories, files are watched through their parent so rename-and-replace saves are seen.
+// They report any path that may have changed with notify, FileWatcher works out what actually happened.
+type watchBackend interface {
+	addDir(dir string) error
+	removeDir(dir string) error
+	close() error
+}
+
+// A debounce timer waiting to flush its path.
+type pendingFlush struct {
+	timer *time.Timer
+}
+
+// Watches any number of files and directories, emitting debounced, typed events on Events. Events is closed by Close,
+// once nothing can send on it anymore.
+type FileWatcher struct {
+	Events chan FileChangedEvent
+
+	mu       sync.Mutex
+	debounce time.Duration
+	backend  watchBackend
+	files    map[string]bool          // Explicitly watched files
+	dirs     map[string]bool          // Explicitly watched directories, their direct children are reported
+	dirRefs  map[string]int           // Backend directory watches, shared between files and directories
+	known    map[string]bool          // Whether a path existed when it was last reported
+	pending  map[string]*pendingFlush // Debounce timers, keyed by path
+	flushing sync.WaitGroup           // Timers that have been started and not stopped, each may still send an event
+	closed   chan struct{}
+}
+
+func NewFileWatcher(debounce time.Duration) (*FileWatcher, error) {
+	return newFileWatcher(debounce, func(notify func(path string)) watchBackend {
+		backend, err := newWatchBackend(notify)
+		if err != nil {
+			LogVerbose(fmt.Sprintf("NewFileWatcher(): falling back to polling: %s", err), MSGTYPE_WARN)
+			return newPollingBackend(notify, time.Second)
+		}
+
+		return backend
+	}), nil
+}
+
+func newFileWatcher(debounce time.Duration, newBackend func(notify func(path string)) watchBackend) *FileWatcher {
+	w := &FileWatcher{
+		Events:   make(chan FileChangedEvent, 64),
+		debounce: debounce,
+		files:    map[string]bool{},
+		dirs:     map[string]bool{},
+		dirRefs:  map[string]int{},
+		known:    map[string]bool{},
+		pending:  map[string]*pendingFlush{},
+		closed:   make(chan struct{}),
 	}
-	if len(b) <= 0 {
-		return "", errors.New("generateHash(): file contains 0 bytes %s")
+	w.backend = newBackend(w.notify)
+
+	return w
+}
+
+func (w *FileWatcher) addDirRef(dir string) error {
+	if w.dirRefs[dir] == 0 {
+		err := w.backend.addDir(dir)
+		if err != nil {
+			return err
+		}
+	}
+	w.dirRefs[dir]++
+
+	return nil
+}
+
+func (w *FileWatcher) removeDirRef(dir string) {
+	w.dirRefs[dir]--
+	if w.dirRefs[dir] <= 0 {
+		delete(w.dirRefs, dir)
+		_ = w.backend.removeDir(dir)
+	}
+}
+
+// Adds a file or directory to the watcher. Files don't need to exist yet but their parent directory does.
+func (w *FileWatcher) Add(path string) error {
+	path = filepath.Clean(path)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.files[path] || w.dirs[path] {
+		return nil
+	}
+
+	stat, err := os.Stat(path)
+	if err == nil && stat.IsDir() {
+		err = w.addDirRef(path)
+		if err != nil {
+			return fmt.Errorf("FileWatcher.Add(): %w", err)
+		}
+		w.dirs[path] = true
+
+		entries, _ := os.ReadDir(path)
+		for _, e := range entries {
+			w.known[filepath.Join(path, e.Name())] = true
+		}
+
+		LogVerbose(fmt.Sprintf("Watching directory \"%s\"...", path), MSGTYPE_INFO)
+		return nil
 	}
 
-	h := md5.New()
-	_, err = h.Write(b)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("FileWatcher.Add(): %w", err)
+	}
+
+	err = w.addDirRef(filepath.Dir(path))
 	if err != nil {
-		return "", fmt.Errorf("generateHash(): %w", err)
+		return fmt.Errorf("FileWatcher.Add(): %w", err)
 	}
+	w.files[path] = true
+	w.known[path] = stat != nil
 
-	return hex.EncodeToString(h.Sum(nil)), nil
+	LogVerbose(fmt.Sprintf("Watching file \"%s\"...", path), MSGTYPE_INFO)
+	return nil
 }
 
-func WatchFile(filePath string, eventChannel chan FileChangedEvent, quitOnDetect bool) (err error) {
-	LogVerbose(fmt.Sprintf("Watching file \"%s\"...", filePath), MSGTYPE_INFO)
-	defer func() { LogVerbose(fmt.Sprintf("Closing file watcher for \"%s\"...", filePath), MSGTYPE_INFO) }()
+func (w *FileWatcher) Remove(path string) error {
+	path = filepath.Clean(path)
 
-	stat, err := os.Stat(filePath)
-	if err != nil {
-		return fmt.Errorf("WatchFile(): %w", err)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	switch {
+	case w.files[path]:
+		delete(w.files, path)
+		delete(w.known, path)
+		w.removeDirRef(filepath.Dir(path))
+	case w.dirs[path]:
+		delete(w.dirs, path)
+		w.removeDirRef(path)
+	default:
+		return fmt.Errorf("FileWatcher.Remove(): \"%s\" is not being watched", path)
+	}
+
+	LogVerbose(fmt.Sprintf("Closing file watcher for \"%s\"...", path), MSGTYPE_INFO)
+	return nil
+}
+
+// Stops watching and closes Events, so ranging over it ends.
+func (w *FileWatcher) Close() error {
+	w.mu.Lock()
+	select {
+	case <-w.closed:
+		w.mu.Unlock()
+		return nil
+	default:
+	}
+
+	close(w.closed)
+	for _, p := range w.pending {
+		if p.timer.Stop() {
+			w.flushing.Done()
+		}
+	}
+	err := w.backend.close()
+	w.mu.Unlock()
+
+	// Flushes already running see closed and drop their event, once they're done nothing can send on Events
+	w.flushing.Wait()
+	close(w.Events)
+
+	return err
+}
+
+// Called by backends for every path that might have changed, (re)starts that paths debounce timer.
+func (w *FileWatcher) notify(path string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	select {
+	case <-w.closed:
+		return // The backend can report a last path while it's being closed
+	default:
 	}
 
-	if stat.Size() <= 0 {
-		return errors.New("WatchFile(): file is 0 bytes")
+	if !w.files[path] && !w.dirs[filepath.Dir(path)] {
+		return
 	}
 
-	sleepInterval := time.Second
+	// A timer that has already fired is flushing, the change gets a timer of its own
+	if p, ok := w.pending[path]; ok && p.timer.Stop() {
+		p.timer.Reset(w.debounce)
+		return
+	}
+
+	p := &pendingFlush{}
+	w.flushing.Add(1)
+	p.timer = time.AfterFunc(w.debounce, func() { w.flush(path, p) })
+	w.pending[path] = p
+}
+
+// Compares what we knew about path with what's on disk now, so a burst of raw events collapses into a single one.
+func (w *FileWatcher) flush(path string, p *pendingFlush) {
+	defer w.flushing.Done()
+
+	_, err := os.Stat(path)
+	exists := err == nil
+
+	w.mu.Lock()
+	if w.pending[path] == p {
+		delete(w.pending, path)
+	}
+	existed := w.known[path]
+	w.known[path] = exists
+	if !exists && !w.files[path] {
+		delete(w.known, path)
+	}
+	w.mu.Unlock()
+
+	var eventType FileEventType
+	switch {
+	case exists && existed:
+		eventType = FILEEVENT_MODIFIED
+	case exists:
+		eventType = FILEEVENT_CREATED
+	case existed:
+		eventType = FILEEVENT_REMOVED
+	default:
+		return // Created and removed again within the debounce window...
+	}
 
-	lastFileHash, err := generateHash(filePath)
+	select {
+	case w.Events <- FileChangedEvent{FileName: path, Type: eventType}:
+	case <-w.closed:
+	}
+}
+
+// Watches a single file, sending its events to eventChannel until quitOnDetect is satisfied.
+func WatchFile(filePath string, eventChannel chan FileChangedEvent, quitOnDetect bool) (err error) {
+	w, err := NewFileWatcher(DefaultWatchDebounce)
 	if err != nil {
 		return fmt.Errorf("WatchFile(): %w", err)
 	}
+	defer w.Close()
 
-	for {
-		currentFileHash, err := generateHash(filePath)
-		if err != nil {
-			return fmt.Errorf("WatchFile(): %w", err)
-		}
+	err = w.Add(filePath)
+	if err != nil {
+		return fmt.Errorf("WatchFile(): %w", err)
+	}
 
-		if currentFileHash != lastFileHash {
-			eventChannel <- FileChangedEvent{FileName: filePath, FileHashBeforeChange: lastFileHash, FileHashAfterChange: currentFileHash}
-			lastFileHash = currentFileHash
+	for e := range w.Events {
+		eventChannel <- e
 
-			if quitOnDetect == true {
-				break
-			}
+		if quitOnDetect {
+			break
 		}
-
-		time.Sleep(sleepInterval)
 	}
 
 	return nil
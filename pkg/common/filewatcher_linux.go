@@ -0,0 +1,112 @@
+//go:build linux
+
+package common
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+const inotifyMask = syscall.IN_CREATE | syscall.IN_MODIFY | syscall.IN_CLOSE_WRITE | syscall.IN_ATTRIB |
+	syscall.IN_DELETE | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO
+
+// inotify backend, one watch descriptor per directory.
+type inotifyBackend struct {
+	mu     sync.Mutex
+	notify func(path string)
+	file   *os.File
+	fd     int
+	dirs   map[int]string
+	wds    map[string]int
+}
+
+func newWatchBackend(notify func(path string)) (watchBackend, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("newWatchBackend(): %w", err)
+	}
+
+	// Non-blocking fd wrapped in an os.File goes through the runtime poller, so close() can interrupt a pending read.
+	b := &inotifyBackend{
+		notify: notify,
+		file:   os.NewFile(uintptr(fd), "inotify"),
+		fd:     fd,
+		dirs:   map[int]string{},
+		wds:    map[string]int{},
+	}
+	go b.run()
+
+	return b, nil
+}
+
+func (b *inotifyBackend) addDir(dir string) error {
+	wd, err := syscall.InotifyAddWatch(b.fd, dir, inotifyMask)
+	if err != nil {
+		return fmt.Errorf("inotifyBackend.addDir(): %s: %w", dir, err)
+	}
+
+	b.mu.Lock()
+	b.dirs[wd] = dir
+	b.wds[dir] = wd
+	b.mu.Unlock()
+
+	return nil
+}
+
+func (b *inotifyBackend) removeDir(dir string) error {
+	b.mu.Lock()
+	wd, ok := b.wds[dir]
+	delete(b.wds, dir)
+	delete(b.dirs, wd)
+	b.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	_, err := syscall.InotifyRmWatch(b.fd, uint32(wd))
+	if err != nil {
+		return fmt.Errorf("inotifyBackend.removeDir(): %w", err)
+	}
+
+	return nil
+}
+
+func (b *inotifyBackend) close() error {
+	return b.file.Close()
+}
+
+func (b *inotifyBackend) run() {
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+
+	for {
+		n, err := b.file.Read(buf)
+		if err != nil {
+			if !errors.Is(err, os.ErrClosed) {
+				LogNonVerbose(fmt.Sprintf("inotify watcher stopped: %s", err), MSGTYPE_WARN)
+			}
+			return
+		}
+
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameStart := offset + syscall.SizeofInotifyEvent
+			name := strings.TrimRight(string(buf[nameStart:nameStart+int(event.Len)]), "\x00")
+			offset = nameStart + int(event.Len)
+
+			b.mu.Lock()
+			dir, ok := b.dirs[int(event.Wd)]
+			b.mu.Unlock()
+
+			if ok && name != "" {
+				b.notify(filepath.Join(dir, name))
+			}
+		}
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package common
+
+import "time"
+
+func newWatchBackend(notify func(path string)) (watchBackend, error) {
+	return newPollingBackend(notify, time.Second), nil
+}
@@ -0,0 +1,118 @@
+package common
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+type fileSnapshot struct {
+	size    int64
+	modTime time.Time
+}
+
+// Portable backend, compares the size and modification time of every entry in the watched directories each interval.
+type pollingBackend struct {
+	mu     sync.Mutex
+	notify func(path string)
+	dirs   map[string]map[string]fileSnapshot
+	done   chan struct{}
+}
+
+func newPollingBackend(notify func(path string), interval time.Duration) *pollingBackend {
+	b := &pollingBackend{notify: notify, dirs: map[string]map[string]fileSnapshot{}, done: make(chan struct{})}
+	go b.run(interval)
+
+	return b
+}
+
+func snapshotDir(dir string) (map[string]fileSnapshot, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("snapshotDir(): %w", err)
+	}
+
+	snapshot := make(map[string]fileSnapshot, len(entries))
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil {
+			continue // Removed between ReadDir and Info
+		}
+		snapshot[e.Name()] = fileSnapshot{size: info.Size(), modTime: info.ModTime()}
+	}
+
+	return snapshot, nil
+}
+
+func (b *pollingBackend) addDir(dir string) error {
+	snapshot, err := snapshotDir(dir)
+	if err != nil {
+		return fmt.Errorf("pollingBackend.addDir(): %w", err)
+	}
+
+	b.mu.Lock()
+	b.dirs[dir] = snapshot
+	b.mu.Unlock()
+
+	return nil
+}
+
+func (b *pollingBackend) removeDir(dir string) error {
+	b.mu.Lock()
+	delete(b.dirs, dir)
+	b.mu.Unlock()
+
+	return nil
+}
+
+func (b *pollingBackend) close() error {
+	close(b.done)
+	return nil
+}
+
+func (b *pollingBackend) poll() {
+	changed := []string{}
+
+	b.mu.Lock()
+	for dir, last := range b.dirs {
+		current, err := snapshotDir(dir)
+		if err != nil {
+			current = map[string]fileSnapshot{} // Directory went away, everything in it is gone too
+		}
+
+		for name, s := range current {
+			if l, ok := last[name]; !ok || l != s {
+				changed = append(changed, filepath.Join(dir, name))
+			}
+		}
+		for name := range last {
+			if _, ok := current[name]; !ok {
+				changed = append(changed, filepath.Join(dir, name))
+			}
+		}
+
+		b.dirs[dir] = current
+	}
+	b.mu.Unlock()
+
+	// Notified outside of our lock, FileWatcher may be calling addDir/removeDir while holding its own...
+	for _, path := range changed {
+		b.notify(path)
+	}
+}
+
+func (b *pollingBackend) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.done:
+			return
+		case <-ticker.C:
+			b.poll()
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func expectFileEvent(t *testing.T, w *FileWatcher, fileName string, eventType FileEventType) {
+	t.Helper()
+
+	select {
+	case e := <-w.Events:
+		if e.FileName != fileName || e.Type != eventType {
+			t.Fatalf("unexpected event:\ngot: %s %s\nwant:%s %s", e.FileName, e.Type, fileName, eventType)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for %s %s", fileName, eventType)
+	}
+}
+
+func testFileWatcher(t *testing.T, w *FileWatcher) {
+	t.Helper()
+
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "config.yaml")
+
+	err := w.Add(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile(filePath, []byte("a"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectFileEvent(t, w, filePath, FILEEVENT_CREATED)
+
+	// Burst of writes should collapse into a single event...
+	for i := 0; i < 5; i++ {
+		err = os.WriteFile(filePath, []byte("content that changes size "+string(rune('a'+i))), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	expectFileEvent(t, w, filePath, FILEEVENT_MODIFIED)
+
+	// Editors saving atomically write a temp file and rename it over the original
+	tmpPath := filepath.Join(dir, ".config.yaml.swp")
+	err = os.WriteFile(tmpPath, []byte("replaced"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Rename(tmpPath, filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectFileEvent(t, w, filePath, FILEEVENT_MODIFIED)
+
+	err = os.Remove(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectFileEvent(t, w, filePath, FILEEVENT_REMOVED)
+
+	select {
+	case e := <-w.Events:
+		t.Errorf("unexpected trailing event: %s %s", e.FileName, e.Type)
+	case <-time.After(300 * time.Millisecond):
+	}
+}
+
+func TestFileWatcher(t *testing.T) {
+	t.Parallel()
+
+	w, err := NewFileWatcher(50 * time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	testFileWatcher(t, w)
+}
+
+func TestFileWatcher_Polling(t *testing.T) {
+	t.Parallel()
+
+	w := newFileWatcher(50*time.Millisecond, func(notify func(path string)) watchBackend {
+		return newPollingBackend(notify, 20*time.Millisecond)
+	})
+	defer w.Close()
+
+	testFileWatcher(t, w)
+}
+
+func TestFileWatcher_Directory(t *testing.T) {
+	t.Parallel()
+
+	w, err := NewFileWatcher(50 * time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	dir := t.TempDir()
+	err = w.Add(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filePath := filepath.Join(dir, "body.json")
+	err = os.WriteFile(filePath, []byte("{}"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectFileEvent(t, w, filePath, FILEEVENT_CREATED)
+}
+
+func TestFileWatcher_CloseEndsEvents(t *testing.T) {
+	t.Parallel()
+
+	w, err := NewFileWatcher(time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	err = w.Add(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ranged := make(chan struct{})
+	go func() {
+		for range w.Events {
+		}
+		close(ranged)
+	}()
+
+	// Changes still being debounced or flushed while closing mustn't send on the closed channel
+	for i := 0; i < 20; i++ {
+		err = os.WriteFile(filepath.Join(dir, "body.json"), []byte{byte('a' + i)}, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-ranged:
+	case <-time.After(5 * time.Second):
+		t.Fatal("ranging over Events didn't end after Close")
+	}
+
+	// Closing again is harmless
+	err = w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
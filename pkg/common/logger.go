@@ -0,0 +1,93 @@
+package common
+
+import (
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+type LogFormat string
+
+func (re LogFormat) String() string { return string(re) }
+
+const (
+	LOGFORMAT_TEXT LogFormat = "text"
+	LOGFORMAT_JSON LogFormat = "json"
+)
+
+// Attribute keys shared by every structured log line, so aggregators see the same field names everywhere.
+const (
+	LOGKEY_LISTENER   = "listener"
+	LOGKEY_THREAD     = "thread"
+	LOGKEY_BINDING    = "binding"
+	LOGKEY_METHOD     = "method"
+	LOGKEY_PATH       = "path"
+	LOGKEY_STATUS     = "status"
+	LOGKEY_BYTES      = "bytes"
+	LOGKEY_LATENCY    = "latency"
+	LOGKEY_REMOTEADDR = "remote_addr"
+	LOGKEY_FILE       = "file"
+	LOGKEY_ERROR      = "error"
+	LOGKEY_TYPE       = "type"
+	LOGKEY_CONFIGID   = "config_id"
+	LOGKEY_CONFIGHASH = "config_hash"
+	LOGKEY_URL        = "url"
+	LOGKEY_ATTEMPT    = "attempt"
+)
+
+// Writes wherever the standard logger currently does, so log.SetOutput (and the -l log file) applies to slog as well.
+type stdLogWriter struct{}
+
+func (stdLogWriter) Write(p []byte) (int, error) { return log.Writer().Write(p) }
+
+var logLevel = new(slog.LevelVar)
+var logger atomic.Pointer[slog.Logger]
+
+func init() {
+	if ArgSliceContains(os.Args, "-v") {
+		logLevel.Set(slog.LevelDebug)
+	}
+	ConfigureLogger(logLevel.Level(), LOGFORMAT_TEXT)
+}
+
+// Parses debug, info, warn or error (case insensitive).
+func ParseLogLevel(level string) (slog.Level, error) {
+	var l slog.Level
+	err := l.UnmarshalText([]byte(level))
+	if err != nil {
+		return l, fmt.Errorf("ParseLogLevel: %w", err)
+	}
+
+	return l, nil
+}
+
+func ParseLogFormat(format string) (LogFormat, error) {
+	switch LogFormat(strings.ToLower(format)) {
+	case LOGFORMAT_TEXT:
+		return LOGFORMAT_TEXT, nil
+	case LOGFORMAT_JSON:
+		return LOGFORMAT_JSON, nil
+	}
+
+	return "", fmt.Errorf("ParseLogFormat: unknown log format \"%s\", expected text or json", format)
+}
+
+// Replaces the process wide logger, messages below level are dropped.
+func ConfigureLogger(level slog.Level, format LogFormat) {
+	logLevel.Set(level)
+
+	opts := &slog.HandlerOptions{Level: logLevel}
+	var handler slog.Handler = slog.NewTextHandler(stdLogWriter{}, opts)
+	if format == LOGFORMAT_JSON {
+		handler = slog.NewJSONHandler(stdLogWriter{}, opts)
+	}
+
+	logger.Store(slog.New(handler))
+}
+
+func Logger() *slog.Logger {
+	return logger.Load()
+}
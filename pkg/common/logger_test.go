@@ -0,0 +1,123 @@
+package common
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+)
+
+func TestParseLogLevel(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		level       string
+		expected    slog.Level
+		expectedErr bool
+	}{
+		{level: "debug", expected: slog.LevelDebug},
+		{level: "info", expected: slog.LevelInfo},
+		{level: "WARN", expected: slog.LevelWarn},
+		{level: "Error", expected: slog.LevelError},
+		{level: "verbose", expectedErr: true},
+		{level: "", expectedErr: true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.level, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseLogLevel(tc.level)
+			if tc.expectedErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseLogFormat(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		format      string
+		expected    LogFormat
+		expectedErr bool
+	}{
+		{format: "text", expected: LOGFORMAT_TEXT},
+		{format: "json", expected: LOGFORMAT_JSON},
+		{format: "JSON", expected: LOGFORMAT_JSON},
+		{format: "logfmt", expectedErr: true},
+		{format: "", expectedErr: true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.format, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseLogFormat(tc.format)
+			if tc.expectedErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestConfigureLogger(t *testing.T) {
+	// The logger is process wide, so the cases run one after another and the original is put back after
+	previous, previousLevel := Logger(), logLevel.Level()
+	t.Cleanup(func() {
+		logLevel.Set(previousLevel)
+		logger.Store(previous)
+	})
+
+	testCases := []struct {
+		name     string
+		level    slog.Level
+		format   LogFormat
+		json     bool
+		disabled slog.Level // Dropped at this level
+		enabled  slog.Level // Logged at this one
+	}{
+		{name: "text debug", level: slog.LevelDebug, format: LOGFORMAT_TEXT, disabled: slog.LevelDebug - 1, enabled: slog.LevelDebug},
+		{name: "text warn", level: slog.LevelWarn, format: LOGFORMAT_TEXT, disabled: slog.LevelInfo, enabled: slog.LevelWarn},
+		{name: "json info", level: slog.LevelInfo, format: LOGFORMAT_JSON, json: true, disabled: slog.LevelDebug, enabled: slog.LevelInfo},
+		{name: "json error", level: slog.LevelError, format: LOGFORMAT_JSON, json: true, disabled: slog.LevelWarn, enabled: slog.LevelError},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ConfigureLogger(tc.level, tc.format)
+
+			_, isJSON := Logger().Handler().(*slog.JSONHandler)
+			if isJSON != tc.json {
+				t.Errorf("unexpected handler: %T", Logger().Handler())
+			}
+			if Logger().Enabled(context.Background(), tc.disabled) {
+				t.Errorf("expected %v to be dropped", tc.disabled)
+			}
+			if !Logger().Enabled(context.Background(), tc.enabled) {
+				t.Errorf("expected %v to be logged", tc.enabled)
+			}
+		})
+	}
+}
@@ -0,0 +1,260 @@
+package common
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rotated files are named <file>.<timestamp>[-<n>][.gz], the timestamp sorts the same way as it reads. n counts
+// rotations within the same millisecond.
+const rotatedFileTimeFormat = "2006-01-02T15-04-05.000"
+
+type LogRotationSettings struct {
+	MaxSizeBytes int64         // Rotate once the file would grow past this, 0 disables
+	MaxAge       time.Duration // Rotate once the file has been open this long, 0 disables
+	MaxFiles     int           // Rotated files to keep, oldest are removed first, 0 keeps everything
+	Compress     bool          // Gzip rotated files
+}
+
+// An append-only log file that rotates itself by size and age, and can be reopened after something else moved it.
+type RotatingFile struct {
+	mu       sync.Mutex
+	path     string
+	settings LogRotationSettings
+	file     *os.File
+	size     int64
+	openedAt time.Time
+	wg       sync.WaitGroup // Background compression and pruning
+}
+
+func OpenRotatingFile(path string, settings LogRotationSettings) (*RotatingFile, error) {
+	f := &RotatingFile{path: path, settings: settings}
+
+	err := f.open()
+	if err != nil {
+		return nil, fmt.Errorf("OpenRotatingFile: %w", err)
+	}
+
+	return f, nil
+}
+
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	f.file = file
+	f.size = stat.Size()
+	f.openedAt = time.Now()
+
+	return nil
+}
+
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	tooBig := f.settings.MaxSizeBytes > 0 && f.size > 0 && f.size+int64(len(p)) > f.settings.MaxSizeBytes
+	tooOld := f.settings.MaxAge > 0 && time.Since(f.openedAt) >= f.settings.MaxAge
+	if tooBig || tooOld {
+		err := f.rotate()
+		if err != nil {
+			return 0, fmt.Errorf("RotatingFile.Write: %w", err)
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+
+	return n, err
+}
+
+// Closes and reopens the file at the same path, for use after an external tool (logrotate...) has moved it.
+func (f *RotatingFile) Reopen() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file != nil {
+		_ = f.file.Close()
+	}
+
+	err := f.open()
+	if err != nil {
+		f.file = nil
+		return fmt.Errorf("RotatingFile.Reopen: %w", err)
+	}
+
+	return nil
+}
+
+// Closes the file, waiting for any background compression to finish.
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	var err error
+	if f.file != nil {
+		err = f.file.Close()
+		f.file = nil
+	}
+	f.mu.Unlock()
+
+	f.wg.Wait()
+
+	return err
+}
+
+// A name no rotated file has yet.
+func (f *RotatingFile) rotatedPath() string {
+	base := f.path + "." + time.Now().Format(rotatedFileTimeFormat)
+	path := base
+	for n := 1; ; n++ {
+		_, err := os.Lstat(path)
+		_, gzErr := os.Lstat(path + ".gz")
+		if os.IsNotExist(err) && os.IsNotExist(gzErr) {
+			return path
+		}
+		path = fmt.Sprintf("%s-%03d", base, n)
+	}
+}
+
+// Whether what follows "<file>." in a name is a rotated file's timestamp, counter and extension.
+func isRotatedSuffix(suffix string) bool {
+	suffix = strings.TrimSuffix(suffix, ".gz")
+	if len(suffix) < len(rotatedFileTimeFormat) {
+		return false
+	}
+
+	_, err := time.Parse(rotatedFileTimeFormat, suffix[:len(rotatedFileTimeFormat)])
+	if err != nil {
+		return false
+	}
+
+	n, ok := strings.CutPrefix(suffix[len(rotatedFileTimeFormat):], "-")
+	if !ok {
+		return n == ""
+	}
+	_, err = strconv.Atoi(n)
+	return err == nil
+}
+
+func (f *RotatingFile) rotate() error {
+	err := f.file.Close()
+	if err != nil {
+		return err
+	}
+
+	// Windows won't rename an open file, so it's closed first and reopened where it was if the rename fails
+	rotatedPath := f.rotatedPath()
+	err = os.Rename(f.path, rotatedPath)
+	if err != nil {
+		if openErr := f.open(); openErr != nil {
+			f.file = nil
+		}
+		return err
+	}
+
+	err = f.open()
+	if err != nil {
+		f.file = nil
+		return err
+	}
+
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+
+		if f.settings.Compress {
+			err := compressFile(rotatedPath)
+			if err != nil {
+				LogNonVerbose("failed to compress rotated log file", MSGTYPE_WARN, LOGKEY_FILE, rotatedPath, LOGKEY_ERROR, err)
+			}
+		}
+
+		f.prune()
+	}()
+
+	return nil
+}
+
+func compressFile(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("compressFile: %w", err)
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(path+".gz", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("compressFile: %w", err)
+	}
+
+	gz := gzip.NewWriter(out)
+	_, err = io.Copy(gz, in)
+	if err == nil {
+		err = gz.Close()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(path + ".gz")
+		return fmt.Errorf("compressFile: %w", err)
+	}
+
+	return os.Remove(path)
+}
+
+// Removes the oldest rotated files beyond MaxFiles.
+func (f *RotatingFile) prune() {
+	if f.settings.MaxFiles <= 0 {
+		return
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(f.path))
+	if err != nil {
+		return
+	}
+
+	prefix := filepath.Base(f.path) + "."
+	rotated := []string{}
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+
+		if isRotatedSuffix(strings.TrimPrefix(name, prefix)) {
+			rotated = append(rotated, name)
+		}
+	}
+
+	if len(rotated) <= f.settings.MaxFiles {
+		return
+	}
+
+	// Without .gz, which would sort <timestamp>.gz after <timestamp>-001
+	slices.SortFunc(rotated, func(a string, b string) int {
+		return strings.Compare(strings.TrimSuffix(a, ".gz"), strings.TrimSuffix(b, ".gz"))
+	})
+	for _, name := range rotated[:len(rotated)-f.settings.MaxFiles] {
+		_ = os.Remove(filepath.Join(filepath.Dir(f.path), name))
+	}
+}
@@ -0,0 +1,190 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRotatingFile(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name          string
+		settings      LogRotationSettings
+		writes        int
+		expectedFiles int
+		expectedGzip  bool
+	}{
+		{
+			name:          "no rotation",
+			settings:      LogRotationSettings{},
+			writes:        10,
+			expectedFiles: 1,
+		},
+		{
+			name:          "size",
+			settings:      LogRotationSettings{MaxSizeBytes: 20},
+			writes:        4,
+			expectedFiles: 4,
+		},
+		{
+			name:          "size capped",
+			settings:      LogRotationSettings{MaxSizeBytes: 20, MaxFiles: 2},
+			writes:        6,
+			expectedFiles: 3,
+		},
+		{
+			name:          "size compressed",
+			settings:      LogRotationSettings{MaxSizeBytes: 20, MaxFiles: 1, Compress: true},
+			writes:        3,
+			expectedFiles: 2,
+			expectedGzip:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			f, err := OpenRotatingFile(filepath.Join(dir, "mockapi.log"), tc.settings)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i := 0; i < tc.writes; i++ {
+				_, err = f.Write([]byte("a sixteen b line\n"))
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err = f.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != tc.expectedFiles {
+				t.Fatalf("unexpected file count:\ngot: %d\nwant:%d", len(entries), tc.expectedFiles)
+			}
+
+			gzipped := false
+			for _, e := range entries {
+				gzipped = gzipped || strings.HasSuffix(e.Name(), ".gz")
+			}
+			if gzipped != tc.expectedGzip {
+				t.Errorf("unexpected compression:\ngot: %t\nwant:%t", gzipped, tc.expectedGzip)
+			}
+		})
+	}
+}
+
+func TestRotatingFile_Reopen(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "mockapi.log")
+	f, err := OpenRotatingFile(path, LogRotationSettings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// What logrotate does before sending SIGHUP...
+	err = os.Rename(path, path+".1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = f.Reopen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = f.Write([]byte("after reopen\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "after reopen\n" {
+		t.Errorf("unexpected content: %s", b)
+	}
+}
+
+func TestRotatingFile_RenameFails(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "mockapi.log")
+	f, err := OpenRotatingFile(path, LogRotationSettings{MaxSizeBytes: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	_, err = f.Write([]byte("a sixteen b line\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// With the file gone there's nothing to rename, the write fails but the file has to stay usable
+	err = os.Remove(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = f.Write([]byte("a sixteen b line\n"))
+	if err == nil {
+		t.Fatal("expected the rotation to fail")
+	}
+
+	_, err = f.Write([]byte("after the failure\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "after the failure\n" {
+		t.Errorf("unexpected content: %s", b)
+	}
+}
+
+func TestIsRotatedSuffix(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		suffix   string
+		expected bool
+	}{
+		{suffix: "2024-05-01T10-20-30.123", expected: true},
+		{suffix: "2024-05-01T10-20-30.123.gz", expected: true},
+		{suffix: "2024-05-01T10-20-30.123-002", expected: true},
+		{suffix: "2024-05-01T10-20-30.123-002.gz", expected: true},
+		{suffix: "2024-05-01T10-20-30.123-", expected: false},
+		{suffix: "2024-05-01T10-20-30.123x", expected: false},
+		{suffix: "1", expected: false},
+		{suffix: "bak", expected: false},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.suffix, func(t *testing.T) {
+			t.Parallel()
+
+			if got := isRotatedSuffix(tc.suffix); got != tc.expected {
+				t.Errorf("expected %t, got %t", tc.expected, got)
+			}
+		})
+	}
+}
@@ -1,9 +1,11 @@
 package common
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"log"
+	"log/slog"
 	"os"
 
 	"github.com/google/uuid"
@@ -14,35 +16,47 @@ type LogMessageType string
 func (re LogMessageType) String() string { return string(re) }
 
 const (
-	MSGTYPE_INFO LogMessageType = "INFO"
-	MSGTYPE_WARN LogMessageType = "WARNING"
+	MSGTYPE_INFO  LogMessageType = "INFO"
+	MSGTYPE_WARN  LogMessageType = "WARNING"
+	MSGTYPE_ERROR LogMessageType = "ERROR"
 )
 
-func SetLogFileActive(filePath string) error {
-	f, err := os.OpenFile(filePath, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+func (re LogMessageType) level() slog.Level {
+	switch re {
+	case MSGTYPE_WARN:
+		return slog.LevelWarn
+	case MSGTYPE_ERROR:
+		return slog.LevelError
+	}
+
+	return slog.LevelInfo
+}
+
+// Copies all log output to filePath, rotating it as configured. The caller owns the returned file and should close it
+// once logging is done.
+func SetLogFileActive(filePath string, rotation LogRotationSettings) (*RotatingFile, error) {
+	f, err := OpenRotatingFile(filePath, rotation)
 	if err != nil {
-		return fmt.Errorf("SetLogFileActive: %w", err)
+		return nil, fmt.Errorf("SetLogFileActive: %w", err)
 	}
-	defer f.Close()
 	wrt := io.MultiWriter(os.Stdout, f) // Copy io streams
 	log.SetOutput(wrt)
-	return nil
+	return f, nil
 }
 
-func LogVerbose(msg string, msgType LogMessageType) {
-	if ArgSliceContains(os.Args, "-v") {
-		log.Println("[" + msgType.String() + "] - " + msg)
-	}
+// Verbose messages are logged at debug level, they're only shown with -v or -loglevel debug.
+func LogVerbose(msg string, msgType LogMessageType, attrs ...any) {
+	Logger().Debug(msg, append(attrs, LOGKEY_TYPE, msgType.String())...)
 }
 
-func LogNonVerbose(msg string, msgType LogMessageType) {
-	log.Println("[" + msgType.String() + "] - " + msg)
+func LogNonVerbose(msg string, msgType LogMessageType, attrs ...any) {
+	Logger().Log(context.Background(), msgType.level(), msg, attrs...)
 }
 
-func LogVerboseOnThread(uuid uuid.UUID, msgType LogMessageType, msg string) {
-	LogVerbose("["+uuid.String()+"] - "+msg, msgType)
+func LogVerboseOnThread(uuid uuid.UUID, msgType LogMessageType, msg string, attrs ...any) {
+	LogVerbose(msg, msgType, append(attrs, LOGKEY_THREAD, uuid.String())...)
 }
 
-func LogNonVerboseOnThread(uuid uuid.UUID, msgType LogMessageType, msg string) {
-	LogNonVerbose("["+uuid.String()+"] - "+msg, msgType)
+func LogNonVerboseOnThread(uuid uuid.UUID, msgType LogMessageType, msg string, attrs ...any) {
+	LogNonVerbose(msg, msgType, append(attrs, LOGKEY_THREAD, uuid.String())...)
 }
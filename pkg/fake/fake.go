@@ -0,0 +1,243 @@
+// Package fake makes up plausible looking data (names, emails, addresses, dates, lorem text...) for response bodies.
+// Everything is drawn from one seedable source, so a seeded Generator makes the same data every run.
+package fake
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Dates are made up between these, rather than around now, so seeded output doesn't drift from day to day.
+var (
+	minDate = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	maxDate = time.Date(2030, 12, 31, 23, 59, 59, 0, time.UTC)
+)
+
+const DateLayout = "2006-01-02"
+
+// Not safe for concurrent use, callers sharing one need to take turns.
+type Generator struct {
+	r *rand.Rand
+}
+
+// A generator seeded with seed, or randomly if seed is nil.
+func New(seed *int64) *Generator {
+	if seed == nil {
+		return &Generator{r: rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))}
+	}
+
+	return &Generator{r: rand.New(rand.NewPCG(uint64(*seed), 0))}
+}
+
+func pick[T any](g *Generator, from []T) T {
+	return from[g.r.IntN(len(from))]
+}
+
+// Between lo and hi, inclusive. They're swapped if given the wrong way round.
+func (g *Generator) Int(lo int64, hi int64) int64 {
+	if lo > hi {
+		lo, hi = hi, lo
+	}
+
+	// hi-lo+1 overflows for ranges wider than an int64, so the offset is drawn unsigned. It wraps back into range.
+	span := uint64(hi-lo) + 1
+	if span == 0 {
+		return int64(g.r.Uint64())
+	}
+
+	return lo + int64(g.r.Uint64N(span))
+}
+
+// Between lo and hi, rounded to two decimal places.
+func (g *Generator) Float(lo float64, hi float64) float64 {
+	if lo > hi {
+		lo, hi = hi, lo
+	}
+
+	v := lo + g.r.Float64()*(hi-lo)
+	rounded, _ := strconv.ParseFloat(strconv.FormatFloat(v, 'f', 2, 64), 64)
+	return max(lo, min(hi, rounded))
+}
+
+func (g *Generator) Bool() bool {
+	return g.r.IntN(2) == 1
+}
+
+// A version 4 uuid, from the generator's source rather than crypto/rand so it can be seeded.
+func (g *Generator) UUID() string {
+	var b uuid.UUID
+	for i := 0; i < len(b); i += 8 {
+		n := g.r.Uint64()
+		for j := 0; j < 8; j++ {
+			b[i+j] = byte(n >> (8 * j))
+		}
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return b.String()
+}
+
+func (g *Generator) FirstName() string {
+	return pick(g, firstNames)
+}
+
+func (g *Generator) LastName() string {
+	return pick(g, lastNames)
+}
+
+func (g *Generator) Name() string {
+	return g.FirstName() + " " + g.LastName()
+}
+
+func (g *Generator) Username() string {
+	return strings.ToLower(g.FirstName()) + strconv.FormatInt(g.Int(1, 999), 10)
+}
+
+func (g *Generator) Email() string {
+	return strings.ToLower(g.FirstName()+"."+g.LastName()) + "@" + pick(g, domains)
+}
+
+func (g *Generator) Phone() string {
+	return fmt.Sprintf("+1-%03d-%03d-%04d", g.Int(201, 989), g.Int(200, 999), g.Int(0, 9999))
+}
+
+func (g *Generator) Company() string {
+	return g.LastName() + " " + pick(g, companySuffixes)
+}
+
+func (g *Generator) Street() string {
+	return fmt.Sprintf("%d %s %s", g.Int(1, 999), pick(g, streetNames), pick(g, streetSuffixes))
+}
+
+func (g *Generator) City() string {
+	return pick(g, cities)
+}
+
+func (g *Generator) Country() string {
+	return pick(g, countries)
+}
+
+func (g *Generator) Postcode() string {
+	return fmt.Sprintf("%05d", g.Int(501, 99950))
+}
+
+// A one line postal address.
+func (g *Generator) Address() string {
+	return g.Street() + ", " + g.City() + " " + g.Postcode() + ", " + g.Country()
+}
+
+// The parts of an address, for json bodies.
+func (g *Generator) AddressObject() map[string]any {
+	return map[string]any{"street": g.Street(), "city": g.City(), "postcode": g.Postcode(), "country": g.Country()}
+}
+
+func (g *Generator) URL() string {
+	return "https://" + pick(g, domains) + "/" + g.Word()
+}
+
+func (g *Generator) IPv4() string {
+	return fmt.Sprintf("%d.%d.%d.%d", g.Int(1, 223), g.Int(0, 255), g.Int(0, 255), g.Int(1, 254))
+}
+
+// A time between from and to, to the second. Zero times fall back to the defaults of 2000 to 2030.
+func (g *Generator) Time(from time.Time, to time.Time) time.Time {
+	if from.IsZero() {
+		from = minDate
+	}
+	if to.IsZero() {
+		to = maxDate
+	}
+
+	return time.Unix(g.Int(from.Unix(), to.Unix()), 0).UTC()
+}
+
+// A date as 2006-01-02.
+func (g *Generator) Date() string {
+	return g.Time(time.Time{}, time.Time{}).Format(DateLayout)
+}
+
+// A date and time in RFC 3339.
+func (g *Generator) DateTime() string {
+	return g.Time(time.Time{}, time.Time{}).Format(time.RFC3339)
+}
+
+func (g *Generator) Word() string {
+	return pick(g, lorem)
+}
+
+// n lorem ipsum words.
+func (g *Generator) Words(n int) string {
+	words := make([]string, max(n, 0))
+	for i := range words {
+		words[i] = g.Word()
+	}
+
+	return strings.Join(words, " ")
+}
+
+// A capitalised sentence of four to twelve words.
+func (g *Generator) Sentence() string {
+	s := g.Words(int(g.Int(4, 12)))
+	return strings.ToUpper(s[:1]) + s[1:] + "."
+}
+
+// Three to six sentences.
+func (g *Generator) Paragraph() string {
+	sentences := make([]string, g.Int(3, 6))
+	for i := range sentences {
+		sentences[i] = g.Sentence()
+	}
+
+	return strings.Join(sentences, " ")
+}
+
+// One of the given values.
+func (g *Generator) Pick(from ...any) any {
+	if len(from) == 0 {
+		return nil
+	}
+
+	return pick(g, from)
+}
+
+var firstNames = []string{
+	"Ada", "Alan", "Barbara", "Claude", "Dennis", "Donald", "Edsger", "Frances", "Grace", "Hedy", "Ivan", "Jean",
+	"John", "Katherine", "Ken", "Leslie", "Linus", "Margaret", "Niklaus", "Radia", "Robin", "Sophie", "Tim", "Yukihiro",
+}
+
+var lastNames = []string{
+	"Allen", "Berners-Lee", "Dijkstra", "Hamilton", "Hopper", "Johnson", "Kay", "Knuth", "Lamarr", "Lamport",
+	"Liskov", "Lovelace", "Matsumoto", "Milner", "Perlman", "Ritchie", "Shannon", "Sutherland", "Thompson",
+	"Torvalds", "Turing", "Wilson", "Wirth",
+}
+
+var domains = []string{"example.com", "example.net", "example.org", "mail.test", "corp.test"}
+
+var companySuffixes = []string{"Inc", "Ltd", "LLC", "Group", "Labs", "Systems", "& Co"}
+
+var streetNames = []string{"Acacia", "Bridge", "Church", "Elm", "High", "Kings", "Main", "Maple", "Mill", "Oak", "Park", "Station", "Victoria"}
+
+var streetSuffixes = []string{"Street", "Road", "Avenue", "Lane", "Way", "Drive", "Close"}
+
+var cities = []string{
+	"Amsterdam", "Auckland", "Berlin", "Boston", "Cape Town", "Dublin", "Edinburgh", "Lisbon", "London", "Madrid",
+	"Melbourne", "Montreal", "Nairobi", "Oslo", "Paris", "Seoul", "Singapore", "Tokyo", "Toronto", "Vienna",
+}
+
+var countries = []string{
+	"Australia", "Austria", "Canada", "France", "Germany", "Ireland", "Japan", "Kenya", "Netherlands", "New Zealand",
+	"Norway", "Portugal", "Singapore", "South Africa", "South Korea", "Spain", "United Kingdom", "United States",
+}
+
+var lorem = []string{
+	"lorem", "ipsum", "dolor", "sit", "amet", "consectetur", "adipiscing", "elit", "sed", "do", "eiusmod", "tempor",
+	"incididunt", "ut", "labore", "et", "dolore", "magna", "aliqua", "enim", "ad", "minim", "veniam", "quis",
+	"nostrud", "exercitation", "ullamco", "laboris", "nisi", "aliquip", "ex", "ea", "commodo", "consequat", "duis",
+	"aute", "irure", "in", "reprehenderit", "voluptate", "velit", "esse", "cillum", "fugiat", "nulla", "pariatur",
+}
@@ -0,0 +1,283 @@
+package fake
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func seeded(seed int64) *Generator {
+	return New(&seed)
+}
+
+func TestGenerator_Seed(t *testing.T) {
+	t.Parallel()
+
+	draw := func(g *Generator) []any {
+		return []any{g.Name(), g.Email(), g.Address(), g.UUID(), g.Date(), g.DateTime(), g.Paragraph(), g.Int(1, 1000), g.Float(0, 1)}
+	}
+
+	a, b := draw(seeded(42)), draw(seeded(42))
+	if !reflect.DeepEqual(a, b) {
+		t.Errorf("the same seed made different data:\n%v\n%v", a, b)
+	}
+
+	if c := draw(seeded(43)); reflect.DeepEqual(a, c) {
+		t.Errorf("different seeds made the same data: %v", c)
+	}
+}
+
+func TestGenerator_Values(t *testing.T) {
+	t.Parallel()
+
+	g := New(nil)
+	for i := 0; i < 100; i++ {
+		if n := g.Int(10, 5); n < 5 || n > 10 {
+			t.Fatalf("int out of range: %d", n)
+		}
+		if f := g.Float(1.5, 2.5); f < 1.5 || f > 2.5 {
+			t.Fatalf("float out of range: %v", f)
+		}
+
+		u, err := uuid.Parse(g.UUID())
+		if err != nil || u.Version() != 4 || u.Variant() != uuid.RFC4122 {
+			t.Fatalf("not a version 4 uuid: %v (%v)", u, err)
+		}
+
+		d, err := time.Parse(DateLayout, g.Date())
+		if err != nil || d.Before(minDate) || d.After(maxDate) {
+			t.Fatalf("date out of range: %v (%v)", d, err)
+		}
+	}
+
+	if email := g.Email(); !strings.Contains(email, "@") || strings.ToLower(email) != email {
+		t.Errorf("unexpected email: %s", email)
+	}
+	if s := g.Sentence(); !strings.HasSuffix(s, ".") || strings.ToUpper(s[:1]) != s[:1] {
+		t.Errorf("unexpected sentence: %s", s)
+	}
+	if words := strings.Fields(g.Words(7)); len(words) != 7 {
+		t.Errorf("expected 7 words, got %v", words)
+	}
+}
+
+func TestGenerator_FromSchema(t *testing.T) {
+	t.Parallel()
+
+	var schema map[string]any
+	err := json.Unmarshal([]byte(`{
+		"type": "object",
+		"properties": {
+			"id": {"type": "string", "format": "uuid"},
+			"email": {"type": "string"},
+			"age": {"type": "integer", "minimum": 18, "exclusiveMaximum": 21},
+			"score": {"type": ["number", "null"], "minimum": 0.5, "maximum": 1},
+			"role": {"enum": ["admin", "member"]},
+			"kind": {"const": "user"},
+			"code": {"type": "string", "minLength": 20, "maxLength": 30},
+			"tags": {"type": "array", "items": {"type": "string"}, "minItems": 2, "maxItems": 2},
+			"manager": {"$ref": "#/$defs/person"}
+		},
+		"$defs": {"person": {"type": "object", "properties": {"name": {"type": "string"}, "manager": {"$ref": "#/$defs/person"}}}}
+	}`), &schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 20; i++ {
+		v, err := New(nil).FromSchema(schema)
+		if err != nil {
+			t.Fatal(err)
+		}
+		object := v.(map[string]any)
+
+		if _, err := uuid.Parse(object["id"].(string)); err != nil {
+			t.Errorf("id isn't a uuid: %v", object["id"])
+		}
+		if !strings.Contains(object["email"].(string), "@") {
+			t.Errorf("email wasn't guessed from its name: %v", object["email"])
+		}
+		if age := object["age"].(int64); age < 18 || age > 20 {
+			t.Errorf("age out of range: %d", age)
+		}
+		if score := object["score"].(float64); score < 0.5 || score > 1 {
+			t.Errorf("score out of range: %v", score)
+		}
+		if role := object["role"]; role != "admin" && role != "member" {
+			t.Errorf("role not from the enum: %v", role)
+		}
+		if object["kind"] != "user" {
+			t.Errorf("const not kept: %v", object["kind"])
+		}
+		if code := object["code"].(string); len(code) < 20 {
+			t.Errorf("code too short: %q", code)
+		}
+		if tags := object["tags"].([]any); len(tags) != 2 {
+			t.Errorf("expected 2 tags, got %v", tags)
+		}
+		if manager, ok := object["manager"].(map[string]any); !ok || manager["name"] == "" {
+			t.Errorf("ref not followed: %v", object["manager"])
+		}
+	}
+
+	_, err = New(nil).FromSchema(map[string]any{"$ref": "https://example.com/schema.json"})
+	if err == nil {
+		t.Error("expected a remote ref to be rejected")
+	}
+	_, err = New(nil).FromSchema(map[string]any{"type": "tuple"})
+	if err == nil {
+		t.Error("expected an unknown type to be rejected")
+	}
+	for _, invalid := range []map[string]any{
+		{"type": "array", "maxItems": -1},
+		{"type": "array", "minItems": -2},
+		{"type": "array", "minItems": 1e9},
+		{"type": "string", "maxLength": -1},
+	} {
+		_, err = New(nil).FromSchema(invalid)
+		if err == nil {
+			t.Errorf("expected %v to be rejected", invalid)
+		}
+	}
+
+	v, err := New(nil).FromSchema(map[string]any{"type": "array", "maxItems": 1e12})
+	if err != nil || len(v.([]any)) > maxSchemaItems {
+		t.Errorf("expected at most %d items: %v", maxSchemaItems, err)
+	}
+}
+
+func TestGenerator_Int_Extremes(t *testing.T) {
+	t.Parallel()
+
+	g := New(nil)
+	for _, r := range [][2]int64{{math.MinInt64, math.MaxInt64}, {-1, math.MaxInt64}, {math.MinInt64, 0}, {math.MaxInt64, math.MaxInt64}} {
+		for i := 0; i < 100; i++ {
+			if n := g.Int(r[0], r[1]); n < r[0] || n > r[1] {
+				t.Fatalf("int out of range %v: %d", r, n)
+			}
+		}
+	}
+
+	for _, schema := range []map[string]any{
+		{"type": "integer", "minimum": -9.3e18, "maximum": 9.3e18},
+		{"type": "integer", "minimum": 1e300},
+		{"type": "integer", "exclusiveMaximum": -1e300},
+	} {
+		_, err := g.FromSchema(schema)
+		if err != nil {
+			t.Errorf("%v: %v", schema, err)
+		}
+	}
+}
+
+func TestGenerator_FromExample(t *testing.T) {
+	t.Parallel()
+
+	example := map[string]any{
+		"id":        "9b2b0c51-6d7f-4d0e-9d59-5f4b4b1b7a10",
+		"firstName": "Ada",
+		"createdAt": "2024-01-02T03:04:05Z",
+		"website":   "https://example.com",
+		"note":      "three little words",
+		"age":       36,
+		"balance":   json.Number("10.5"),
+		"active":    true,
+		"parent":    nil,
+		"tags":      []any{"a", "b", "c"},
+	}
+
+	g := seeded(7)
+	v := g.FromExample(example).(map[string]any)
+
+	if _, err := uuid.Parse(v["id"].(string)); err != nil {
+		t.Errorf("id isn't a uuid: %v", v["id"])
+	}
+	if _, err := time.Parse(time.RFC3339, v["createdAt"].(string)); err != nil {
+		t.Errorf("createdAt isn't a timestamp: %v", v["createdAt"])
+	}
+	if !strings.HasPrefix(v["website"].(string), "https://") {
+		t.Errorf("website isn't a url: %v", v["website"])
+	}
+	if words := strings.Fields(v["note"].(string)); len(words) != 3 {
+		t.Errorf("expected 3 words in note, got %v", words)
+	}
+	if age := v["age"].(int64); age < 18 || age > 72 {
+		t.Errorf("age not around the example's: %d", age)
+	}
+	if _, ok := v["balance"].(float64); !ok {
+		t.Errorf("balance isn't a float: %v", v["balance"])
+	}
+	if _, ok := v["active"].(bool); !ok || v["parent"] != nil || len(v["tags"].([]any)) != 3 {
+		t.Errorf("unexpected shape: %v", v)
+	}
+
+	if again := seeded(7).FromExample(example); !reflect.DeepEqual(v, again) {
+		t.Errorf("the same seed made a different value:\n%v\n%v", v, again)
+	}
+}
+
+func TestGenerator_Template(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name          string
+		template      string
+		check         func(s string) bool
+		expectedError bool
+	}{
+		{
+			name:     "json",
+			template: `[{{range $i, $_ := seq 3}}{{if $i}},{{end}}{"name":{{name | json}},"n":{{int 1 9}}}{{end}}]`,
+			check: func(s string) bool {
+				var v []map[string]any
+				return json.Unmarshal([]byte(s), &v) == nil && len(v) == 3
+			},
+		},
+		{
+			name:     "date between",
+			template: `{{dateBetween "2024-02-01" "2024-02-02"}}`,
+			check:    func(s string) bool { return s == "2024-02-01" || s == "2024-02-02" },
+		},
+		{
+			name:     "pick",
+			template: `{{pick "red" "green"}}`,
+			check:    func(s string) bool { return s == "red" || s == "green" },
+		},
+		{
+			name:          "unknown function",
+			template:      `{{shoeSize}}`,
+			expectedError: true,
+		},
+		{
+			name:          "bad date",
+			template:      `{{dateBetween "yesterday" "2024-02-02"}}`,
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tmpl, err := New(nil).Template(tc.name, tc.template)
+			var b bytes.Buffer
+			if err == nil {
+				err = tmpl.Execute(&b, nil)
+			}
+			if (err != nil) != tc.expectedError {
+				t.Fatalf("unexpected error response: %v", err)
+			}
+			if !tc.expectedError && !tc.check(b.String()) {
+				t.Errorf("unexpected output: %s", b.String())
+			}
+		})
+	}
+}
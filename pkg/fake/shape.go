@@ -0,0 +1,474 @@
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/mail"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxRefDepth    = 8    // How deep $refs are followed, so recursive schemas (a tree of nodes, say) end
+	maxSchemaItems = 1000 // Most items made for an array, whatever its maxItems says
+)
+
+// Makes up a value fitting a JSON Schema: type, properties, items, minItems and maxItems, enum, const, minimum and
+// maximum, minLength and maxLength, format (email, uuid, date, date-time, time, uri, hostname, ipv4), oneOf, anyOf,
+// allOf and local $refs. Strings without a format are guessed from their property's name (email, city, createdAt...).
+func (g *Generator) FromSchema(schema map[string]any) (any, error) {
+	return g.fromSchema(schema, schema, "", 0)
+}
+
+func (g *Generator) fromSchema(root map[string]any, schema map[string]any, name string, depth int) (any, error) {
+	if ref, ok := schema["$ref"].(string); ok {
+		if depth >= maxRefDepth {
+			return nil, nil
+		}
+
+		resolved, err := resolveRef(root, ref)
+		if err != nil {
+			return nil, fmt.Errorf("FromSchema: %w", err)
+		}
+
+		return g.fromSchema(root, resolved, name, depth+1)
+	}
+
+	if v, ok := schema["const"]; ok {
+		return v, nil
+	}
+
+	if enum, ok := schema["enum"].([]any); ok {
+		if len(enum) == 0 {
+			return nil, fmt.Errorf("FromSchema: \"%s\" has an empty enum", name)
+		}
+		return pick(g, enum), nil
+	}
+
+	for _, keyword := range []string{"oneOf", "anyOf"} {
+		if branches, ok := schema[keyword].([]any); ok && len(branches) > 0 {
+			branch, ok := pick(g, branches).(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("FromSchema: \"%s\" has a %s that isn't a schema", name, keyword)
+			}
+			return g.fromSchema(root, branch, name, depth)
+		}
+	}
+
+	if branches, ok := schema["allOf"].([]any); ok {
+		merged := map[string]any{}
+		for _, b := range branches {
+			branch, ok := b.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("FromSchema: \"%s\" has an allOf that isn't a schema", name)
+			}
+
+			v, err := g.fromSchema(root, branch, name, depth)
+			if err != nil {
+				return nil, err
+			}
+			object, ok := v.(map[string]any)
+			if !ok {
+				return v, nil // Only objects merge, otherwise the branches had better agree
+			}
+			for k, field := range object {
+				merged[k] = field
+			}
+		}
+		return merged, nil
+	}
+
+	switch schemaType(schema) {
+	case "object":
+		properties, _ := schema["properties"].(map[string]any)
+		object := map[string]any{}
+
+		// Sorted, so a seeded generator draws for them in the same order every time
+		keys := make([]string, 0, len(properties))
+		for k := range properties {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+
+		for _, k := range keys {
+			property, ok := properties[k].(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("FromSchema: property \"%s\" isn't a schema", k)
+			}
+
+			v, err := g.fromSchema(root, property, k, depth)
+			if err != nil {
+				return nil, err
+			}
+			object[k] = v
+		}
+		return object, nil
+
+	case "array":
+		items, _ := schema["items"].(map[string]any)
+		lo, _ := number(schema["minItems"])
+		hi, ok := number(schema["maxItems"])
+		if lo < 0 || hi < 0 {
+			return nil, fmt.Errorf("FromSchema: \"%s\" has a negative minItems or maxItems", name)
+		}
+		if lo > maxSchemaItems {
+			return nil, fmt.Errorf("FromSchema: \"%s\" has a minItems over %d", name, maxSchemaItems)
+		}
+		if !ok {
+			hi = max(lo, 3)
+		}
+		if _, ok = schema["minItems"]; !ok {
+			lo = min(1, hi)
+		}
+
+		array := make([]any, g.Int(int64(lo), int64(min(hi, maxSchemaItems))))
+		for i := range array {
+			v, err := g.fromSchema(root, items, name, depth)
+			if err != nil {
+				return nil, err
+			}
+			array[i] = v
+		}
+		return array, nil
+
+	case "string":
+		lo, _ := number(schema["minLength"])
+		hi, _ := number(schema["maxLength"])
+		if lo < 0 || hi < 0 {
+			return nil, fmt.Errorf("FromSchema: \"%s\" has a negative minLength or maxLength", name)
+		}
+		return g.stringFromSchema(schema, name), nil
+
+	case "integer":
+		lo, hi := numberRange(schema, 0, 1000, 1)
+		return g.Int(toInt64(math.Ceil(lo)), toInt64(math.Floor(hi))), nil
+
+	case "number":
+		lo, hi := numberRange(schema, 0, 1000, 0.01)
+		return g.Float(lo, hi), nil
+
+	case "boolean":
+		return g.Bool(), nil
+
+	case "null":
+		return nil, nil
+
+	case "":
+		return g.byName(name), nil
+	}
+
+	return nil, fmt.Errorf("FromSchema: \"%s\" has an unknown type: %v", name, schema["type"])
+}
+
+// The schema's type, inferred from its keywords if it doesn't say. A list of types gives the first that isn't null.
+func schemaType(schema map[string]any) string {
+	switch t := schema["type"].(type) {
+	case string:
+		return t
+	case []any:
+		for _, v := range t {
+			if s, ok := v.(string); ok && s != "null" {
+				return s
+			}
+		}
+		return "null"
+	}
+
+	switch {
+	case schema["properties"] != nil:
+		return "object"
+	case schema["items"] != nil:
+		return "array"
+	}
+
+	return ""
+}
+
+// Only refs within the schema itself are followed, eg. #/$defs/user.
+func resolveRef(root map[string]any, ref string) (map[string]any, error) {
+	path, ok := strings.CutPrefix(ref, "#")
+	if !ok {
+		return nil, fmt.Errorf("resolveRef: only local refs are supported: \"%s\"", ref)
+	}
+
+	current := root
+	for _, part := range strings.Split(strings.TrimPrefix(path, "/"), "/") {
+		if part == "" {
+			continue
+		}
+		part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+
+		next, ok := current[part].(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("resolveRef: \"%s\" does not resolve to a schema", ref)
+		}
+		current = next
+	}
+
+	return current, nil
+}
+
+// Numbers arrive as whatever yaml or json decoded them as.
+func number(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+
+	return 0, false
+}
+
+// The range a number has to fall in, step being how far inside an exclusive bound the closest allowed value is.
+func numberRange(schema map[string]any, lo float64, hi float64, step float64) (float64, float64) {
+	if n, ok := number(schema["minimum"]); ok {
+		lo = n
+	}
+	if n, ok := number(schema["exclusiveMinimum"]); ok {
+		lo = n + step
+	}
+	if n, ok := number(schema["maximum"]); ok {
+		hi = n
+	}
+	if n, ok := number(schema["exclusiveMaximum"]); ok {
+		hi = n - step
+	}
+	if _, ok := schema["maximum"]; !ok && lo > hi {
+		hi = lo + 1000
+	}
+
+	return lo, max(lo, hi)
+}
+
+func (g *Generator) stringFromSchema(schema map[string]any, name string) string {
+	var s string
+	switch format, _ := schema["format"].(string); format {
+	case "email":
+		s = g.Email()
+	case "uuid":
+		s = g.UUID()
+	case "date":
+		s = g.Date()
+	case "date-time":
+		s = g.DateTime()
+	case "time":
+		s = g.Time(time.Time{}, time.Time{}).Format(time.TimeOnly)
+	case "uri", "url":
+		s = g.URL()
+	case "hostname":
+		s = pick(g, domains)
+	case "ipv4":
+		s = g.IPv4()
+	default:
+		v, _ := g.byName(name).(string)
+		s = v
+	}
+
+	if n, ok := number(schema["maxLength"]); ok && len(s) > int(n) {
+		s = strings.TrimSpace(s[:int(n)])
+	}
+	if n, ok := number(schema["minLength"]); ok {
+		for len(s) < int(n) {
+			s = strings.TrimSpace(s + " " + g.Word())
+		}
+	}
+
+	return s
+}
+
+// Makes up a value of the same shape as example: objects with the same keys, arrays of as many items, numbers around
+// the example's and strings of the same kind, guessed from their key (email, city, createdAt...) or what the example
+// looks like (a uuid, date, email, url...), or lorem words otherwise.
+func (g *Generator) FromExample(example any) any {
+	return g.fromExample(example, "")
+}
+
+func (g *Generator) fromExample(example any, name string) any {
+	switch v := example.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+
+		object := map[string]any{}
+		for _, k := range keys {
+			object[k] = g.fromExample(v[k], k)
+		}
+		return object
+
+	case []any:
+		array := make([]any, len(v))
+		for i, item := range v {
+			array[i] = g.fromExample(item, name)
+		}
+		return array
+
+	case string:
+		return g.stringLike(v, name)
+
+	case bool:
+		return g.Bool()
+
+	case nil:
+		return nil
+	}
+
+	n, ok := number(example)
+	if !ok {
+		return example
+	}
+
+	lo, hi := n/2, n*2
+	if n == 0 {
+		lo, hi = 0, 100
+	}
+	if n == math.Trunc(n) && !isFloat(example) {
+		return g.Int(toInt64(lo), toInt64(hi))
+	}
+	return g.Float(lo, hi)
+}
+
+// f clamped to what an int64 holds, converting out of range floats being implementation specific.
+func toInt64(f float64) int64 {
+	switch {
+	case f >= math.MaxInt64:
+		return math.MaxInt64
+	case f <= math.MinInt64:
+		return math.MinInt64
+	}
+
+	return int64(f)
+}
+
+func isFloat(v any) bool {
+	switch n := v.(type) {
+	case float64:
+		return true
+	case json.Number:
+		return strings.ContainsAny(string(n), ".eE")
+	}
+
+	return false
+}
+
+func (g *Generator) stringLike(example string, name string) string {
+	if name != "" && !isLorem(name) {
+		v, _ := g.byName(name).(string)
+		return v
+	}
+
+	if _, err := uuid.Parse(example); err == nil {
+		return g.UUID()
+	}
+	if _, err := time.Parse(time.RFC3339, example); err == nil {
+		return g.DateTime()
+	}
+	if _, err := time.Parse(DateLayout, example); err == nil {
+		return g.Date()
+	}
+	if _, err := mail.ParseAddress(example); err == nil && strings.Contains(example, "@") {
+		return g.Email()
+	}
+	if strings.HasPrefix(example, "http://") || strings.HasPrefix(example, "https://") {
+		return g.URL()
+	}
+	if example == "" {
+		return ""
+	}
+
+	return g.Words(max(1, len(strings.Fields(example))))
+}
+
+// Whether a name has no better guess than lorem words.
+func isLorem(name string) bool {
+	_, ok := nameHints[normaliseName(name)]
+	return !ok && !isTimestampName(normaliseName(name))
+}
+
+// createdAt, created_at and Created-At all look like createdat.
+func normaliseName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r == '-' || unicode.IsSpace(r) {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, name)
+}
+
+func isTimestampName(name string) bool {
+	return strings.HasSuffix(name, "edat") || strings.Contains(name, "timestamp") || strings.Contains(name, "datetime")
+}
+
+var nameHints = map[string]func(g *Generator) any{
+	"firstname":    func(g *Generator) any { return g.FirstName() },
+	"givenname":    func(g *Generator) any { return g.FirstName() },
+	"lastname":     func(g *Generator) any { return g.LastName() },
+	"surname":      func(g *Generator) any { return g.LastName() },
+	"familyname":   func(g *Generator) any { return g.LastName() },
+	"name":         func(g *Generator) any { return g.Name() },
+	"fullname":     func(g *Generator) any { return g.Name() },
+	"displayname":  func(g *Generator) any { return g.Name() },
+	"username":     func(g *Generator) any { return g.Username() },
+	"login":        func(g *Generator) any { return g.Username() },
+	"email":        func(g *Generator) any { return g.Email() },
+	"emailaddress": func(g *Generator) any { return g.Email() },
+	"phone":        func(g *Generator) any { return g.Phone() },
+	"phonenumber":  func(g *Generator) any { return g.Phone() },
+	"mobile":       func(g *Generator) any { return g.Phone() },
+	"company":      func(g *Generator) any { return g.Company() },
+	"organisation": func(g *Generator) any { return g.Company() },
+	"organization": func(g *Generator) any { return g.Company() },
+	"street":       func(g *Generator) any { return g.Street() },
+	"city":         func(g *Generator) any { return g.City() },
+	"town":         func(g *Generator) any { return g.City() },
+	"country":      func(g *Generator) any { return g.Country() },
+	"zip":          func(g *Generator) any { return g.Postcode() },
+	"zipcode":      func(g *Generator) any { return g.Postcode() },
+	"postcode":     func(g *Generator) any { return g.Postcode() },
+	"postalcode":   func(g *Generator) any { return g.Postcode() },
+	"address":      func(g *Generator) any { return g.Address() },
+	"url":          func(g *Generator) any { return g.URL() },
+	"website":      func(g *Generator) any { return g.URL() },
+	"ip":           func(g *Generator) any { return g.IPv4() },
+	"ipaddress":    func(g *Generator) any { return g.IPv4() },
+	"id":           func(g *Generator) any { return g.UUID() },
+	"uuid":         func(g *Generator) any { return g.UUID() },
+	"guid":         func(g *Generator) any { return g.UUID() },
+	"date":         func(g *Generator) any { return g.Date() },
+	"birthdate":    func(g *Generator) any { return g.Date() },
+	"dateofbirth":  func(g *Generator) any { return g.Date() },
+	"title":        func(g *Generator) any { return g.Words(int(g.Int(2, 5))) },
+	"description":  func(g *Generator) any { return g.Sentence() },
+	"summary":      func(g *Generator) any { return g.Sentence() },
+	"bio":          func(g *Generator) any { return g.Paragraph() },
+	"body":         func(g *Generator) any { return g.Paragraph() },
+	"content":      func(g *Generator) any { return g.Paragraph() },
+}
+
+// A string guessed from a field's name, lorem words if nothing fits.
+func (g *Generator) byName(name string) any {
+	normalised := normaliseName(name)
+	if hint, ok := nameHints[normalised]; ok {
+		return hint(g)
+	}
+	if isTimestampName(normalised) {
+		return g.DateTime()
+	}
+
+	return g.Words(int(g.Int(1, 3)))
+}
@@ -0,0 +1,89 @@
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"text/template"
+	"time"
+)
+
+// The generator as text/template functions, eg. {{name}}, {{int 1 10}}, {{words 3}}, {{range seq 5}}...{{end}}.
+// json quotes a value for a json body: {"email": {{email | json}}}.
+func (g *Generator) Funcs() template.FuncMap {
+	return template.FuncMap{
+		"name":      g.Name,
+		"firstName": g.FirstName,
+		"lastName":  g.LastName,
+		"username":  g.Username,
+		"email":     g.Email,
+		"phone":     g.Phone,
+		"company":   g.Company,
+		"street":    g.Street,
+		"city":      g.City,
+		"country":   g.Country,
+		"postcode":  g.Postcode,
+		"address":   g.Address,
+		"url":       g.URL,
+		"ipv4":      g.IPv4,
+		"uuid":      g.UUID,
+		"date":      g.Date,
+		"datetime":  g.DateTime,
+		"word":      g.Word,
+		"words":     g.Words,
+		"sentence":  g.Sentence,
+		"paragraph": g.Paragraph,
+		"int":       g.Int,
+		"float":     g.Float,
+		"bool":      g.Bool,
+		"pick":      g.Pick,
+		"dateBetween": func(from string, to string) (string, error) {
+			start, end, err := parseDates(from, to)
+			if err != nil {
+				return "", err
+			}
+			return g.Time(start, end).Format(DateLayout), nil
+		},
+		"datetimeBetween": func(from string, to string) (string, error) {
+			start, end, err := parseDates(from, to)
+			if err != nil {
+				return "", err
+			}
+			return g.Time(start, end).Format(time.RFC3339), nil
+		},
+		"seq": func(n int) []int {
+			s := make([]int, max(n, 0))
+			for i := range s {
+				s[i] = i
+			}
+			return s
+		},
+		"json": func(v any) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}
+}
+
+func parseDates(from string, to string) (time.Time, time.Time, error) {
+	start, err := time.Parse(DateLayout, from)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("parseDates: %w", err)
+	}
+
+	end, err := time.Parse(DateLayout, to)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("parseDates: %w", err)
+	}
+
+	return start, end, nil
+}
+
+// Parses text with the generator's functions, bound to g.
+func (g *Generator) Template(name string, text string) (*template.Template, error) {
+	t, err := template.New(name).Funcs(g.Funcs()).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("Template: %w", err)
+	}
+
+	return t, nil
+}
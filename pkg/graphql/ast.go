@@ -0,0 +1,226 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// A GraphQL error as a response carries it.
+type Error struct {
+	Message   string     `json:"message"`
+	Locations []Location `json:"locations,omitempty"`
+}
+
+func (e *Error) Error() string {
+	if len(e.Locations) == 0 {
+		return e.Message
+	}
+
+	return fmt.Sprintf("%s (%d:%d)", e.Message, e.Locations[0].Line, e.Locations[0].Column)
+}
+
+// A type as written in a field, argument or variable: Name, [Name], Name! and so on.
+type TypeRef struct {
+	Name    string   // Set unless this is a list
+	Elem    *TypeRef // Set if this is a list
+	NonNull bool
+}
+
+func (t *TypeRef) String() string {
+	s := t.Name
+	if t.Elem != nil {
+		s = "[" + t.Elem.String() + "]"
+	}
+	if t.NonNull {
+		s += "!"
+	}
+
+	return s
+}
+
+// The named type at the bottom of any lists.
+func (t *TypeRef) NamedType() string {
+	for t.Elem != nil {
+		t = t.Elem
+	}
+
+	return t.Name
+}
+
+type ValueKind int
+
+const (
+	VariableValue ValueKind = iota
+	IntValue
+	FloatValue
+	StringValue
+	BooleanValue
+	NullValue
+	EnumValue
+	ListValue
+	ObjectValue
+)
+
+// A literal in a document, or a reference to a variable.
+type Value struct {
+	Kind   ValueKind
+	Raw    string // The variable name, or the literal as written (unquoted for strings)
+	List   []*Value
+	Fields []*ObjectField
+	Loc    Location
+}
+
+type ObjectField struct {
+	Name  string
+	Value *Value
+}
+
+// The value as decoded json would hold it, with variables filled in from vars.
+func (v *Value) Resolve(vars map[string]any) any {
+	switch v.Kind {
+	case VariableValue:
+		return vars[v.Raw]
+	case IntValue, FloatValue:
+		f, _ := strconv.ParseFloat(v.Raw, 64)
+		return f
+	case StringValue, EnumValue:
+		return v.Raw
+	case BooleanValue:
+		return v.Raw == "true"
+	case ListValue:
+		list := make([]any, 0, len(v.List))
+		for _, item := range v.List {
+			list = append(list, item.Resolve(vars))
+		}
+		return list
+	case ObjectValue:
+		object := make(map[string]any, len(v.Fields))
+		for _, f := range v.Fields {
+			object[f.Name] = f.Value.Resolve(vars)
+		}
+		return object
+	}
+
+	return nil
+}
+
+type Argument struct {
+	Name  string
+	Value *Value
+	Loc   Location
+}
+
+type Directive struct {
+	Name      string
+	Arguments []*Argument
+	Loc       Location
+}
+
+type SelectionKind int
+
+const (
+	FieldSelection SelectionKind = iota
+	FragmentSpread
+	InlineFragment
+)
+
+// One entry in a selection set: a field, a ...Fragment spread or an inline ... on Type { } fragment.
+type Selection struct {
+	Kind          SelectionKind
+	Alias         string // Fields only, empty if not aliased
+	Name          string // The field, or the spread fragment
+	Arguments     []*Argument
+	Directives    []*Directive
+	TypeCondition string // Inline fragments only, empty if there's no condition
+	SelectionSet  []*Selection
+	Loc           Location
+}
+
+// The key the field goes under in the response.
+func (s *Selection) ResponseKey() string {
+	if s.Alias != "" {
+		return s.Alias
+	}
+
+	return s.Name
+}
+
+type VariableDefinition struct {
+	Name    string
+	Type    *TypeRef
+	Default *Value
+	Loc     Location
+}
+
+type Operation struct {
+	Type         string // query, mutation or subscription
+	Name         string // Empty for anonymous operations
+	Variables    []*VariableDefinition
+	Directives   []*Directive
+	SelectionSet []*Selection
+	Loc          Location
+}
+
+type Fragment struct {
+	Name          string
+	TypeCondition string
+	Directives    []*Directive
+	SelectionSet  []*Selection
+	Loc           Location
+}
+
+// An executable document, the query a client sends.
+type Document struct {
+	Operations []*Operation
+	Fragments  []*Fragment
+}
+
+func (d *Document) Fragment(name string) *Fragment {
+	for _, f := range d.Fragments {
+		if f.Name == name {
+			return f
+		}
+	}
+
+	return nil
+}
+
+// Picks the operation to run, by name or the only one there is.
+func (d *Document) Operation(name string) (*Operation, error) {
+	if name == "" {
+		if len(d.Operations) != 1 {
+			return nil, &Error{Message: "Must provide operation name if query contains multiple operations."}
+		}
+		return d.Operations[0], nil
+	}
+
+	for _, op := range d.Operations {
+		if op.Name == name {
+			return op, nil
+		}
+	}
+
+	return nil, &Error{Message: fmt.Sprintf("Unknown operation named \"%s\".", name)}
+}
+
+// Whether @skip or @include on the selection leave it out.
+func Skipped(directives []*Directive, vars map[string]any) bool {
+	for _, d := range directives {
+		if d.Name != "skip" && d.Name != "include" {
+			continue
+		}
+
+		var condition any
+		for _, a := range d.Arguments {
+			if a.Name == "if" {
+				condition = a.Value.Resolve(vars)
+			}
+		}
+
+		if b, ok := condition.(bool); ok && b == (d.Name == "skip") {
+			return true
+		}
+	}
+
+	return false
+}
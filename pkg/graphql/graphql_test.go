@@ -0,0 +1,220 @@
+package graphql
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const testSchema = `
+"""
+The things we sell.
+"""
+type Query {
+  product(id: ID!): Product
+  search(term: String!, first: Int = 10): [SearchResult!]!
+  node(id: ID!): Node
+}
+
+type Mutation { addReview(productId: ID!, input: ReviewInput!): Review! }
+
+interface Node { id: ID! }
+
+type Product implements Node @key(fields: "id") {
+  id: ID!
+  "Shown in listings"
+  name: String!
+  price: Float
+  status: Status!
+  reviews: [Review!]!
+}
+
+type Review implements Node { id: ID!, stars: Int!, body: String }
+
+union SearchResult = | Product | Review
+
+enum Status { ACTIVE DISCONTINUED }
+
+input ReviewInput { stars: Int!, body: String }
+
+directive @key(fields: String!) repeatable on OBJECT | INTERFACE
+
+extend type Query { me: String }
+`
+
+func TestParseSchema(t *testing.T) {
+	t.Parallel()
+
+	s, err := ParseSchema(testSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Query != "Query" || s.Mutation != "Mutation" || s.Subscription != "" {
+		t.Errorf("unexpected root types: %s %s %s", s.Query, s.Mutation, s.Subscription)
+	}
+	if s.Types["Query"].Field("me") == nil {
+		t.Error("extension fields were not added")
+	}
+	if got := s.Types["Query"].Field("search").Type.String(); got != "[SearchResult!]!" {
+		t.Errorf("unexpected type: %s", got)
+	}
+	if got := strings.Join(s.PossibleTypes("Node"), ","); got != "Product,Review" {
+		t.Errorf("unexpected possible types: %s", got)
+	}
+
+	testCases := []struct {
+		name   string
+		schema string
+	}{
+		{name: "unknown field type", schema: "type Query { a: Missing }"},
+		{name: "no query type", schema: "type Other { a: Int }"},
+		{name: "duplicate type", schema: "type Query { a: Int } type Query { b: Int }"},
+		{name: "syntax", schema: "type Query { a: }"},
+		{name: "input as output", schema: "type Query { a: In } input In { b: Int }"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := ParseSchema(tc.schema)
+			if err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestSchema_Validate(t *testing.T) {
+	t.Parallel()
+
+	s, err := ParseSchema(testSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name     string
+		query    string
+		expected string // The first error, empty for a valid query
+	}{
+		{name: "valid", query: `query P($id: ID!, $withReviews: Boolean = false) { product(id: $id) { ...Fields reviews @include(if: $withReviews) { stars } } } fragment Fields on Product { id, n: name }`},
+		{name: "union", query: `{ search(term: "x") { __typename ... on Review { stars } } }`},
+		{name: "syntax", query: `{ product(id: "1") { id }`, expected: `Syntax Error: Expected Name, found <EOF>. (1:26)`},
+		{name: "unknown field", query: `{ product(id: "1") { colour } }`, expected: `Cannot query field "colour" on type "Product". (1:22)`},
+		{name: "missing selection", query: `{ product(id: "1") }`, expected: `Field "product" of type "Product" must have a selection of subfields. Did you mean "product { ... }"? (1:3)`},
+		{name: "selection on leaf", query: `{ me { id } }`, expected: `Field "me" must not have a selection since type "String" has no subfields. (1:3)`},
+		{name: "missing argument", query: `{ product { id } }`, expected: `Field "product" argument "id" of type "ID!" is required, but it was not provided. (1:3)`},
+		{name: "unknown argument", query: `{ product(id: "1", sku: "2") { id } }`, expected: `Unknown argument "sku" on field "Query.product". (1:20)`},
+		{name: "undefined variable", query: `query Q { product(id: $id) { id } }`, expected: `Variable "$id" is not defined by operation "Q". (1:23)`},
+		{name: "unknown fragment", query: `{ product(id: "1") { ...Nope } }`, expected: `Unknown fragment "Nope". (1:22)`},
+		{name: "fragment cycle", query: `{ node(id: "1") { ...A } } fragment A on Node { ...A }`, expected: `Cannot spread fragment "A" within itself. (1:49)`},
+		{name: "no subscriptions", query: `subscription { me }`, expected: `Schema is not configured to execute subscription operation. (1:1)`},
+		{name: "unknown directive", query: `{ me @cached }`, expected: `Unknown directive "@cached". (1:6)`},
+		{name: "literals", query: `mutation { addReview(productId: 7, input: {stars: 5, body: null}) { id } }`},
+		{name: "valid input object", query: `mutation($body: String) { addReview(productId: "7", input: {stars: 5, body: $body}) { id } }`},
+		{name: "wrong scalar", query: `{ search(term: "x", first: "str") { __typename } }`, expected: `Expected value of type "Int", found "str"; Int cannot represent non-integer value: "str" (1:28)`},
+		{name: "int out of range", query: `{ search(term: "x", first: 3000000000) { __typename } }`, expected: `Expected value of type "Int", found 3000000000; Int cannot represent non 32-bit signed integer value: 3000000000 (1:28)`},
+		{name: "boolean id", query: `{ product(id: true) { id } }`, expected: `Expected value of type "ID!", found true; ID cannot represent a non-string and non-integer value: true (1:15)`},
+		{name: "null for non-null", query: `{ search(term: null) { __typename } }`, expected: `Expected value of type "String!", found null. (1:16)`},
+		{name: "unknown input field", query: `mutation { addReview(productId: "7", input: {stars: 5, colour: "red"}) { id } }`, expected: `Field "colour" is not defined by type "ReviewInput". (1:64)`},
+		{name: "missing input field", query: `mutation { addReview(productId: "7", input: {body: "ok"}) { id } }`, expected: `Field "ReviewInput.stars" of required type "Int!" was not provided. (1:45)`},
+		{name: "scalar for input object", query: `mutation { addReview(productId: "7", input: 5) { id } }`, expected: `Expected value of type "ReviewInput!", found 5. (1:45)`},
+		{name: "unknown variable type", query: `query($v: Nope) { me }`, expected: `Unknown type "Nope". (1:7)`},
+		{name: "output variable type", query: `query($v: Product) { me }`, expected: `Variable "$v" cannot be non-input type "Product". (1:7)`},
+		{name: "duplicate variable", query: `query($v: Int, $v: Int) { me }`, expected: `There can be only one variable named "$v". (1:16)`},
+		{name: "bad default", query: `query($v: Int = "ten") { search(term: "x", first: $v) { __typename } }`, expected: `Expected value of type "Int", found "ten"; Int cannot represent non-integer value: "ten" (1:17)`},
+		{name: "variable of the wrong type", query: `query($v: String) { search(term: "x", first: $v) { __typename } }`, expected: `Variable "$v" of type "String" used in position expecting type "Int". (1:46)`},
+		{name: "nullable variable for non-null", query: `query($v: String) { search(term: $v) { __typename } }`, expected: `Variable "$v" of type "String" used in position expecting type "String!". (1:34)`},
+		{name: "non-null variable for nullable", query: `query($v: Int!) { search(term: "x", first: $v) { __typename } }`},
+		{name: "directive if", query: `{ me @skip(if: "yes") }`, expected: `Expected value of type "Boolean!", found "yes"; Boolean cannot represent a non boolean value: "yes" (1:16)`},
+		{name: "aliased different fields", query: `{ a: product(id: "1") { id } a: node(id: "1") { id } }`, expected: `Fields "a" conflict because "product" and "node" are different fields. Use different aliases on the fields to fetch both if this was intentional. (1:3)`},
+		{name: "differing arguments", query: `{ product(id: "1") { id } product(id: "2") { id } }`, expected: `Fields "product" conflict because they have differing arguments. Use different aliases on the fields to fetch both if this was intentional. (1:3)`},
+		{name: "conflicting subfields", query: `{ product(id: "1") { x: id } product(id: "1") { x: name } }`, expected: `Fields "product" conflict because subfields "x" conflict because "id" and "name" are different fields. Use different aliases on the fields to fetch both if this was intentional. (1:3)`},
+		{name: "conflict through a fragment", query: `{ product(id: "1") { ...F name: id } } fragment F on Product { name }`, expected: `Fields "name" conflict because "name" and "id" are different fields. Use different aliases on the fields to fetch both if this was intentional. (1:64)`},
+		{name: "conflicting types", query: `{ search(term: "x") { ... on Product { v: price } ... on Review { v: stars } } }`, expected: `Fields "v" conflict because they return conflicting types "Float" and "Int!". Use different aliases on the fields to fetch both if this was intentional. (1:40)`},
+		{name: "mergeable fields", query: `{ product(id: "1") { id } product(id: "1") { name } ... on Query { product(id: "1") { id } } }`},
+		{name: "different fields on different types", query: `{ search(term: "x") { ... on Product { v: name } ... on Review { v: body } } }`, expected: `Fields "v" conflict because they return conflicting types "String!" and "String". Use different aliases on the fields to fetch both if this was intentional. (1:40)`},
+		{name: "same shape on different types", query: `{ search(term: "x") { ... on Product { v: id } ... on Review { v: id } } }`},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := ""
+			doc, err := ParseQuery(tc.query)
+			if err != nil {
+				got = err.Error()
+			} else if errs := s.Validate(doc); len(errs) > 0 {
+				got = errs[0].Error()
+			}
+
+			if got != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestSchema_CoerceVariables(t *testing.T) {
+	t.Parallel()
+
+	s, err := ParseSchema(testSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := ParseQuery(`query($id: ID!, $first: Int = 10, $status: Status, $input: ReviewInput, $tags: [String!]) { me }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	op := doc.Operations[0]
+
+	testCases := []struct {
+		name      string
+		variables string
+		expected  string // The first error, empty if the variables are fine
+	}{
+		{name: "valid", variables: `{"id": 7, "status": "ACTIVE", "input": {"stars": 5}, "tags": ["a", "b"]}`},
+		{name: "single item for a list", variables: `{"id": "7", "tags": "a"}`},
+		{name: "missing required", variables: `{}`, expected: `Variable "$id" of required type "ID!" was not provided. (1:7)`},
+		{name: "null for non-null", variables: `{"id": null}`, expected: `Variable "$id" of non-null type "ID!" must not be null. (1:7)`},
+		{name: "wrong scalar", variables: `{"id": "7", "first": "ten"}`, expected: `Variable "$first" got invalid value "ten"; Int cannot represent non-integer value: "ten" (1:17)`},
+		{name: "fraction for int", variables: `{"id": "7", "first": 1.5}`, expected: `Variable "$first" got invalid value 1.5; Int cannot represent non-integer value: 1.5 (1:17)`},
+		{name: "unknown enum value", variables: `{"id": "7", "status": "GONE"}`, expected: `Variable "$status" got invalid value "GONE"; Value "GONE" does not exist in "Status" enum. (1:35)`},
+		{name: "input field", variables: `{"id": "7", "input": {"stars": "five"}}`, expected: `Variable "$input" got invalid value "five" at "input.stars"; Int cannot represent non-integer value: "five" (1:52)`},
+		{name: "missing input field", variables: `{"id": "7", "input": {}}`, expected: `Variable "$input" got invalid value {}; Field "stars" of required type "Int!" was not provided. (1:52)`},
+		{name: "list item", variables: `{"id": "7", "tags": ["a", null]}`, expected: `Variable "$tags" got invalid value null at "tags[1]"; Expected non-nullable type "String!" not to be null. (1:73)`},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var variables map[string]any
+			err := json.Unmarshal([]byte(tc.variables), &variables)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := ""
+			coerced, errs := s.CoerceVariables(op, variables)
+			if len(errs) > 0 {
+				got = errs[0].Error()
+			} else if coerced["first"] == nil {
+				t.Error("the default was not filled in")
+			}
+
+			if got != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
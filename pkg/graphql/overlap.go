@@ -0,0 +1,165 @@
+package graphql
+
+import (
+	"fmt"
+	"strings"
+)
+
+var typenameType = &TypeRef{Name: "String", NonNull: true}
+
+// A field as the response sees it, with the type it was selected on. def is nil for __typename and unknown fields.
+type collectedField struct {
+	parent *TypeDef
+	sel    *Selection
+	def    *FieldDef
+}
+
+func (f collectedField) typeRef() *TypeRef {
+	if f.sel.Name == "__typename" {
+		return typenameType
+	}
+	if f.def == nil {
+		return nil
+	}
+
+	return f.def.Type
+}
+
+// The fields selections put in the response, through any fragments, by response key in the order the keys appear.
+func (v *validator) collectFields(parent *TypeDef, selections []*Selection, fields map[string][]collectedField, keys []string, spread map[string]bool) []string {
+	for _, sel := range selections {
+		switch sel.Kind {
+		case FieldSelection:
+			key := sel.ResponseKey()
+			if _, ok := fields[key]; !ok {
+				keys = append(keys, key)
+			}
+			f := collectedField{parent: parent, sel: sel}
+			if parent != nil {
+				f.def = parent.Field(sel.Name)
+			}
+			fields[key] = append(fields[key], f)
+		case InlineFragment:
+			t := parent
+			if sel.TypeCondition != "" {
+				t = v.schema.Types[sel.TypeCondition]
+			}
+			keys = v.collectFields(t, sel.SelectionSet, fields, keys, spread)
+		case FragmentSpread:
+			f := v.doc.Fragment(sel.Name)
+			if f == nil || spread[f.Name] {
+				continue // Unknown and cyclic spreads have errors of their own
+			}
+			spread[f.Name] = true
+			keys = v.collectFields(v.schema.Types[f.TypeCondition], f.SelectionSet, fields, keys, spread)
+		}
+	}
+
+	return keys
+}
+
+// Fields sharing a response key have to be the same field with the same arguments, or there'd be no telling which one
+// the value is for. Fields on different object types are exempt, only one of them can apply.
+func (v *validator) overlappingFields(parent *TypeDef, selections []*Selection) {
+	fields := map[string][]collectedField{}
+	keys := v.collectFields(parent, selections, fields, nil, map[string]bool{})
+
+	for _, key := range keys {
+		same := fields[key]
+		for i := 0; i < len(same); i++ {
+			for j := i + 1; j < len(same); j++ {
+				if reason := v.fieldConflict(same[i], same[j], false); reason != "" {
+					v.add(&Error{
+						Message:   fmt.Sprintf("Fields \"%s\" conflict because %s. Use different aliases on the fields to fetch both if this was intentional.", key, reason),
+						Locations: []Location{same[i].sel.Loc, same[j].sel.Loc},
+					})
+				}
+			}
+		}
+	}
+}
+
+// Why a and b can't share a response key, empty if they can.
+func (v *validator) fieldConflict(a collectedField, b collectedField, exclusive bool) string {
+	exclusive = exclusive || (a.parent != b.parent && a.parent != nil && b.parent != nil && a.parent.Kind == ObjectKind && b.parent.Kind == ObjectKind)
+	if !exclusive {
+		if a.sel.Name != b.sel.Name {
+			return fmt.Sprintf("\"%s\" and \"%s\" are different fields", a.sel.Name, b.sel.Name)
+		}
+		if !sameArguments(a.sel.Arguments, b.sel.Arguments) {
+			return "they have differing arguments"
+		}
+	}
+
+	if ta, tb := a.typeRef(), b.typeRef(); ta != nil && tb != nil && v.typesConflict(ta, tb) {
+		return fmt.Sprintf("they return conflicting types \"%s\" and \"%s\"", ta, tb)
+	}
+
+	if len(a.sel.SelectionSet) == 0 || len(b.sel.SelectionSet) == 0 {
+		return ""
+	}
+
+	// Both sets end up in the same object, so a field from one can't clash with a field from the other
+	fieldsA, fieldsB := map[string][]collectedField{}, map[string][]collectedField{}
+	keys := v.collectFields(v.fieldType(a), a.sel.SelectionSet, fieldsA, nil, map[string]bool{})
+	v.collectFields(v.fieldType(b), b.sel.SelectionSet, fieldsB, nil, map[string]bool{})
+
+	reasons := []string{}
+	for _, key := range keys {
+		for _, subA := range fieldsA[key] {
+			for _, subB := range fieldsB[key] {
+				if reason := v.fieldConflict(subA, subB, exclusive); reason != "" {
+					reasons = append(reasons, fmt.Sprintf("subfields \"%s\" conflict because %s", key, reason))
+				}
+			}
+		}
+	}
+
+	return strings.Join(reasons, " and ")
+}
+
+func (v *validator) fieldType(f collectedField) *TypeDef {
+	if t := f.typeRef(); t != nil {
+		return v.schema.Types[t.NamedType()]
+	}
+
+	return nil
+}
+
+// Whether values of a and b couldn't be told apart by shape: a list and not, nullable and not, or different leaves.
+func (v *validator) typesConflict(a *TypeRef, b *TypeRef) bool {
+	if a.NonNull != b.NonNull || (a.Elem == nil) != (b.Elem == nil) {
+		return true
+	}
+	if a.Elem != nil {
+		return v.typesConflict(a.Elem, b.Elem)
+	}
+
+	leafA, leafB := v.schema.Types[a.Name], v.schema.Types[b.Name]
+	if (leafA != nil && !leafA.IsComposite()) || (leafB != nil && !leafB.IsComposite()) {
+		return a.Name != b.Name
+	}
+
+	return false
+}
+
+func sameArguments(a []*Argument, b []*Argument) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for _, argA := range a {
+		found := false
+		for _, argB := range b {
+			if argA.Name == argB.Name && printValue(argA.Value) == printValue(argB.Value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
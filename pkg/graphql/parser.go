@@ -0,0 +1,479 @@
+package graphql
+
+import "fmt"
+
+type parser struct {
+	lexer *lexer
+	tok   token
+}
+
+func newParser(src string) (*parser, error) {
+	p := &parser{lexer: newLexer(src)}
+	err := p.advance()
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (p *parser) advance() error {
+	tok, err := p.lexer.next()
+	if err != nil {
+		return err
+	}
+
+	p.tok = tok
+	return nil
+}
+
+func (p *parser) unexpected() error {
+	return p.lexer.errorf(p.tok.loc, "Unexpected %s.", p.tok)
+}
+
+func (p *parser) peek(punctuator string) bool {
+	return p.tok.kind == tokenPunctuator && p.tok.value == punctuator
+}
+
+func (p *parser) peekName(name string) bool {
+	return p.tok.kind == tokenName && p.tok.value == name
+}
+
+// Consumes the punctuator if it's next.
+func (p *parser) skip(punctuator string) (bool, error) {
+	if !p.peek(punctuator) {
+		return false, nil
+	}
+
+	return true, p.advance()
+}
+
+func (p *parser) expect(punctuator string) error {
+	if !p.peek(punctuator) {
+		return p.lexer.errorf(p.tok.loc, "Expected \"%s\", found %s.", punctuator, p.tok)
+	}
+
+	return p.advance()
+}
+
+func (p *parser) expectKeyword(keyword string) error {
+	if !p.peekName(keyword) {
+		return p.lexer.errorf(p.tok.loc, "Expected \"%s\", found %s.", keyword, p.tok)
+	}
+
+	return p.advance()
+}
+
+func (p *parser) name() (string, error) {
+	if p.tok.kind != tokenName {
+		return "", p.lexer.errorf(p.tok.loc, "Expected Name, found %s.", p.tok)
+	}
+
+	name := p.tok.value
+	return name, p.advance()
+}
+
+// Parses open, items until close, and close, as in { ... } or ( ... ). At least one item is needed.
+func (p *parser) many(open string, item func() error, close string) error {
+	err := p.expect(open)
+	if err != nil {
+		return err
+	}
+
+	for {
+		err = item()
+		if err != nil {
+			return err
+		}
+
+		done, err := p.skip(close)
+		if err != nil || done {
+			return err
+		}
+	}
+}
+
+// Like many, but the brackets are optional and may be empty.
+func (p *parser) optionalMany(open string, item func() error, close string) error {
+	if !p.peek(open) {
+		return nil
+	}
+
+	err := p.advance()
+	if err != nil {
+		return err
+	}
+
+	for {
+		done, err := p.skip(close)
+		if err != nil || done {
+			return err
+		}
+
+		err = item()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (p *parser) typeRef() (*TypeRef, error) {
+	t := &TypeRef{}
+
+	if p.peek("[") {
+		err := p.advance()
+		if err != nil {
+			return nil, err
+		}
+		t.Elem, err = p.typeRef()
+		if err != nil {
+			return nil, err
+		}
+		err = p.expect("]")
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		t.Name = name
+	}
+
+	nonNull, err := p.skip("!")
+	t.NonNull = nonNull
+	return t, err
+}
+
+func (p *parser) value(constant bool) (*Value, error) {
+	v := &Value{Loc: p.tok.loc}
+
+	switch p.tok.kind {
+	case tokenPunctuator:
+		switch p.tok.value {
+		case "$":
+			if constant {
+				return nil, p.unexpected()
+			}
+			err := p.advance()
+			if err != nil {
+				return nil, err
+			}
+			v.Kind = VariableValue
+			v.Raw, err = p.name()
+			return v, err
+		case "[":
+			v.Kind = ListValue
+			return v, p.optionalMany("[", func() error {
+				item, err := p.value(constant)
+				v.List = append(v.List, item)
+				return err
+			}, "]")
+		case "{":
+			v.Kind = ObjectValue
+			return v, p.optionalMany("{", func() error {
+				name, err := p.name()
+				if err != nil {
+					return err
+				}
+				err = p.expect(":")
+				if err != nil {
+					return err
+				}
+				fieldValue, err := p.value(constant)
+				v.Fields = append(v.Fields, &ObjectField{Name: name, Value: fieldValue})
+				return err
+			}, "}")
+		}
+		return nil, p.unexpected()
+	case tokenInt:
+		v.Kind = IntValue
+	case tokenFloat:
+		v.Kind = FloatValue
+	case tokenString, tokenBlockString:
+		v.Kind = StringValue
+	case tokenName:
+		switch p.tok.value {
+		case "true", "false":
+			v.Kind = BooleanValue
+		case "null":
+			v.Kind = NullValue
+		default:
+			v.Kind = EnumValue
+		}
+	default:
+		return nil, p.unexpected()
+	}
+
+	v.Raw = p.tok.value
+	return v, p.advance()
+}
+
+func (p *parser) arguments(constant bool) ([]*Argument, error) {
+	var args []*Argument
+
+	if !p.peek("(") {
+		return nil, nil
+	}
+
+	err := p.many("(", func() error {
+		a := &Argument{Loc: p.tok.loc}
+		name, err := p.name()
+		if err != nil {
+			return err
+		}
+		a.Name = name
+		err = p.expect(":")
+		if err != nil {
+			return err
+		}
+		a.Value, err = p.value(constant)
+		args = append(args, a)
+		return err
+	}, ")")
+
+	return args, err
+}
+
+func (p *parser) directives(constant bool) ([]*Directive, error) {
+	var directives []*Directive
+
+	for p.peek("@") {
+		d := &Directive{Loc: p.tok.loc}
+		err := p.advance()
+		if err != nil {
+			return nil, err
+		}
+		d.Name, err = p.name()
+		if err != nil {
+			return nil, err
+		}
+		d.Arguments, err = p.arguments(constant)
+		if err != nil {
+			return nil, err
+		}
+		directives = append(directives, d)
+	}
+
+	return directives, nil
+}
+
+func (p *parser) selectionSet() ([]*Selection, error) {
+	var selections []*Selection
+
+	err := p.many("{", func() error {
+		s, err := p.selection()
+		selections = append(selections, s)
+		return err
+	}, "}")
+
+	return selections, err
+}
+
+func (p *parser) selection() (*Selection, error) {
+	s := &Selection{Loc: p.tok.loc}
+
+	if p.peek("...") {
+		err := p.advance()
+		if err != nil {
+			return nil, err
+		}
+
+		if p.tok.kind == tokenName && p.tok.value != "on" {
+			s.Kind = FragmentSpread
+			s.Name, err = p.name()
+			if err != nil {
+				return nil, err
+			}
+			s.Directives, err = p.directives(false)
+			return s, err
+		}
+
+		s.Kind = InlineFragment
+		if p.peekName("on") {
+			err = p.advance()
+			if err != nil {
+				return nil, err
+			}
+			s.TypeCondition, err = p.name()
+			if err != nil {
+				return nil, err
+			}
+		}
+		s.Directives, err = p.directives(false)
+		if err != nil {
+			return nil, err
+		}
+		s.SelectionSet, err = p.selectionSet()
+		return s, err
+	}
+
+	s.Kind = FieldSelection
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	aliased, err := p.skip(":")
+	if err != nil {
+		return nil, err
+	}
+	if aliased {
+		s.Alias = name
+		name, err = p.name()
+		if err != nil {
+			return nil, err
+		}
+	}
+	s.Name = name
+
+	s.Arguments, err = p.arguments(false)
+	if err != nil {
+		return nil, err
+	}
+	s.Directives, err = p.directives(false)
+	if err != nil {
+		return nil, err
+	}
+	if p.peek("{") {
+		s.SelectionSet, err = p.selectionSet()
+	}
+
+	return s, err
+}
+
+func (p *parser) operation() (*Operation, error) {
+	op := &Operation{Type: "query", Loc: p.tok.loc}
+
+	// The { ... } shorthand, an anonymous query
+	if p.peek("{") {
+		var err error
+		op.SelectionSet, err = p.selectionSet()
+		return op, err
+	}
+
+	opType, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	if opType != "query" && opType != "mutation" && opType != "subscription" {
+		return nil, p.lexer.errorf(op.Loc, "Unexpected Name \"%s\".", opType)
+	}
+	op.Type = opType
+
+	if p.tok.kind == tokenName {
+		op.Name, err = p.name()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = p.optionalMany("(", func() error {
+		v := &VariableDefinition{Loc: p.tok.loc}
+		err := p.expect("$")
+		if err != nil {
+			return err
+		}
+		v.Name, err = p.name()
+		if err != nil {
+			return err
+		}
+		err = p.expect(":")
+		if err != nil {
+			return err
+		}
+		v.Type, err = p.typeRef()
+		if err != nil {
+			return err
+		}
+		hasDefault, err := p.skip("=")
+		if err != nil {
+			return err
+		}
+		if hasDefault {
+			v.Default, err = p.value(true)
+			if err != nil {
+				return err
+			}
+		}
+		_, err = p.directives(true)
+		op.Variables = append(op.Variables, v)
+		return err
+	}, ")")
+	if err != nil {
+		return nil, err
+	}
+
+	op.Directives, err = p.directives(false)
+	if err != nil {
+		return nil, err
+	}
+	op.SelectionSet, err = p.selectionSet()
+	return op, err
+}
+
+func (p *parser) fragment() (*Fragment, error) {
+	f := &Fragment{Loc: p.tok.loc}
+
+	err := p.expectKeyword("fragment")
+	if err != nil {
+		return nil, err
+	}
+	if p.peekName("on") {
+		return nil, p.unexpected()
+	}
+	f.Name, err = p.name()
+	if err != nil {
+		return nil, err
+	}
+	err = p.expectKeyword("on")
+	if err != nil {
+		return nil, err
+	}
+	f.TypeCondition, err = p.name()
+	if err != nil {
+		return nil, err
+	}
+	f.Directives, err = p.directives(false)
+	if err != nil {
+		return nil, err
+	}
+	f.SelectionSet, err = p.selectionSet()
+	return f, err
+}
+
+// Parses an executable document, a query as a client sends it. Errors are *Error.
+func ParseQuery(src string) (*Document, error) {
+	p, err := newParser(src)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &Document{}
+	for p.tok.kind != tokenEOF {
+		switch {
+		case p.peek("{") || p.peekName("query") || p.peekName("mutation") || p.peekName("subscription"):
+			op, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.Operations = append(doc.Operations, op)
+		case p.peekName("fragment"):
+			f, err := p.fragment()
+			if err != nil {
+				return nil, err
+			}
+			doc.Fragments = append(doc.Fragments, f)
+		case p.tok.kind == tokenName:
+			return nil, &Error{Message: fmt.Sprintf("The \"%s\" definition is not executable.", p.tok.value), Locations: []Location{p.tok.loc}}
+		default:
+			return nil, p.unexpected()
+		}
+	}
+
+	if len(doc.Operations) == 0 {
+		return nil, &Error{Message: "Syntax Error: Document has no operations.", Locations: []Location{p.tok.loc}}
+	}
+
+	return doc, nil
+}
@@ -0,0 +1,571 @@
+package graphql
+
+import (
+	"fmt"
+	"os"
+	"slices"
+)
+
+type TypeKind int
+
+const (
+	ScalarKind TypeKind = iota
+	ObjectKind
+	InterfaceKind
+	UnionKind
+	EnumKind
+	InputObjectKind
+)
+
+func (k TypeKind) String() string {
+	return [...]string{"scalar", "type", "interface", "union", "enum", "input"}[k]
+}
+
+type InputValue struct {
+	Name    string
+	Type    *TypeRef
+	Default *Value
+}
+
+type FieldDef struct {
+	Name string
+	Args []*InputValue
+	Type *TypeRef
+}
+
+func (f *FieldDef) Arg(name string) *InputValue {
+	for _, a := range f.Args {
+		if a.Name == name {
+			return a
+		}
+	}
+
+	return nil
+}
+
+type TypeDef struct {
+	Kind        TypeKind
+	Name        string
+	Fields      []*FieldDef   // Objects and interfaces
+	InputFields []*InputValue // Input objects
+	Interfaces  []string      // Objects and interfaces
+	Members     []string      // Unions
+	EnumValues  []string
+}
+
+func (t *TypeDef) Field(name string) *FieldDef {
+	for _, f := range t.Fields {
+		if f.Name == name {
+			return f
+		}
+	}
+
+	return nil
+}
+
+func (t *TypeDef) InputField(name string) *InputValue {
+	for _, f := range t.InputFields {
+		if f.Name == name {
+			return f
+		}
+	}
+
+	return nil
+}
+
+// Whether values of the type need a selection set.
+func (t *TypeDef) IsComposite() bool {
+	return t.Kind == ObjectKind || t.Kind == InterfaceKind || t.Kind == UnionKind
+}
+
+// The types described by a schema definition (SDL), directives aside.
+type Schema struct {
+	Types        map[string]*TypeDef
+	Query        string
+	Mutation     string   // Empty if the schema has no mutations
+	Subscription string   // Empty if the schema has no subscriptions
+	Directives   []string // Names of the custom directives the schema declares
+}
+
+// The type operations of opType start from, nil if the schema doesn't have them.
+func (s *Schema) RootType(opType string) *TypeDef {
+	name := map[string]string{"query": s.Query, "mutation": s.Mutation, "subscription": s.Subscription}[opType]
+	if name == "" {
+		return nil
+	}
+
+	return s.Types[name]
+}
+
+// The object types a value of the named type can be, in the order the schema declares them.
+func (s *Schema) PossibleTypes(name string) []string {
+	t := s.Types[name]
+	if t == nil {
+		return nil
+	}
+
+	switch t.Kind {
+	case ObjectKind:
+		return []string{name}
+	case UnionKind:
+		return t.Members
+	case InterfaceKind:
+		var possible []string
+		for _, candidate := range s.sortedTypes() {
+			if candidate.Kind == ObjectKind && slices.Contains(candidate.Interfaces, name) {
+				possible = append(possible, candidate.Name)
+			}
+		}
+		return possible
+	}
+
+	return nil
+}
+
+func (s *Schema) sortedTypes() []*TypeDef {
+	types := make([]*TypeDef, 0, len(s.Types))
+	for _, t := range s.Types {
+		types = append(types, t)
+	}
+	slices.SortFunc(types, func(a, b *TypeDef) int {
+		if a.Name < b.Name {
+			return -1
+		}
+		if a.Name > b.Name {
+			return 1
+		}
+		return 0
+	})
+
+	return types
+}
+
+func newSchema() *Schema {
+	s := &Schema{Types: map[string]*TypeDef{}}
+	for _, name := range []string{"Int", "Float", "String", "Boolean", "ID"} {
+		s.Types[name] = &TypeDef{Kind: ScalarKind, Name: name}
+	}
+
+	return s
+}
+
+// Skips an optional description string.
+func (p *parser) description() error {
+	if p.tok.kind == tokenString || p.tok.kind == tokenBlockString {
+		return p.advance()
+	}
+
+	return nil
+}
+
+func (p *parser) inputValues() ([]*InputValue, error) {
+	var values []*InputValue
+
+	item := func() error {
+		err := p.description()
+		if err != nil {
+			return err
+		}
+		v := &InputValue{}
+		v.Name, err = p.name()
+		if err != nil {
+			return err
+		}
+		err = p.expect(":")
+		if err != nil {
+			return err
+		}
+		v.Type, err = p.typeRef()
+		if err != nil {
+			return err
+		}
+		hasDefault, err := p.skip("=")
+		if err != nil {
+			return err
+		}
+		if hasDefault {
+			v.Default, err = p.value(true)
+			if err != nil {
+				return err
+			}
+		}
+		_, err = p.directives(true)
+		values = append(values, v)
+		return err
+	}
+
+	var err error
+	if p.peek("(") {
+		err = p.many("(", item, ")")
+	} else {
+		err = p.optionalMany("{", item, "}")
+	}
+
+	return values, err
+}
+
+func (p *parser) fieldDefs() ([]*FieldDef, error) {
+	var fields []*FieldDef
+
+	err := p.optionalMany("{", func() error {
+		err := p.description()
+		if err != nil {
+			return err
+		}
+		f := &FieldDef{}
+		f.Name, err = p.name()
+		if err != nil {
+			return err
+		}
+		if p.peek("(") {
+			f.Args, err = p.inputValues()
+			if err != nil {
+				return err
+			}
+		}
+		err = p.expect(":")
+		if err != nil {
+			return err
+		}
+		f.Type, err = p.typeRef()
+		if err != nil {
+			return err
+		}
+		_, err = p.directives(true)
+		fields = append(fields, f)
+		return err
+	}, "}")
+
+	return fields, err
+}
+
+// Parses name (|name)*, with an optional leading separator, as implements and union members are written.
+func (p *parser) namesSeparatedBy(separator string) ([]string, error) {
+	var names []string
+
+	_, err := p.skip(separator)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+
+		more, err := p.skip(separator)
+		if err != nil {
+			return nil, err
+		}
+		if !more {
+			return names, nil
+		}
+	}
+}
+
+func (p *parser) typeDefinition(kind TypeKind) (*TypeDef, error) {
+	err := p.advance() // The keyword
+	if err != nil {
+		return nil, err
+	}
+
+	t := &TypeDef{Kind: kind}
+	t.Name, err = p.name()
+	if err != nil {
+		return nil, err
+	}
+
+	if (kind == ObjectKind || kind == InterfaceKind) && p.peekName("implements") {
+		err = p.advance()
+		if err != nil {
+			return nil, err
+		}
+		t.Interfaces, err = p.namesSeparatedBy("&")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = p.directives(true)
+	if err != nil {
+		return nil, err
+	}
+
+	switch kind {
+	case ObjectKind, InterfaceKind:
+		t.Fields, err = p.fieldDefs()
+	case InputObjectKind:
+		t.InputFields, err = p.inputValues()
+	case UnionKind:
+		var hasMembers bool
+		hasMembers, err = p.skip("=")
+		if err == nil && hasMembers {
+			t.Members, err = p.namesSeparatedBy("|")
+		}
+	case EnumKind:
+		err = p.optionalMany("{", func() error {
+			err := p.description()
+			if err != nil {
+				return err
+			}
+			value, err := p.name()
+			if err != nil {
+				return err
+			}
+			t.EnumValues = append(t.EnumValues, value)
+			_, err = p.directives(true)
+			return err
+		}, "}")
+	}
+
+	return t, err
+}
+
+// Parses directive @name(args) repeatable on LOCATION | LOCATION, keeping only the name so queries may use it.
+func (p *parser) directiveDefinition(s *Schema) error {
+	err := p.advance()
+	if err != nil {
+		return err
+	}
+	err = p.expect("@")
+	if err != nil {
+		return err
+	}
+	name, err := p.name()
+	if err != nil {
+		return err
+	}
+	s.Directives = append(s.Directives, name)
+	if p.peek("(") {
+		_, err = p.inputValues()
+		if err != nil {
+			return err
+		}
+	}
+	if p.peekName("repeatable") {
+		err = p.advance()
+		if err != nil {
+			return err
+		}
+	}
+	err = p.expectKeyword("on")
+	if err != nil {
+		return err
+	}
+	_, err = p.namesSeparatedBy("|")
+	return err
+}
+
+func (p *parser) schemaDefinition(s *Schema) error {
+	err := p.advance()
+	if err != nil {
+		return err
+	}
+	_, err = p.directives(true)
+	if err != nil {
+		return err
+	}
+
+	return p.optionalMany("{", func() error {
+		loc := p.tok.loc
+		opType, err := p.name()
+		if err != nil {
+			return err
+		}
+		err = p.expect(":")
+		if err != nil {
+			return err
+		}
+		name, err := p.name()
+		if err != nil {
+			return err
+		}
+
+		switch opType {
+		case "query":
+			s.Query = name
+		case "mutation":
+			s.Mutation = name
+		case "subscription":
+			s.Subscription = name
+		default:
+			return p.lexer.errorf(loc, "Unexpected Name \"%s\".", opType)
+		}
+		return nil
+	}, "}")
+}
+
+var typeKeywords = map[string]TypeKind{
+	"scalar":    ScalarKind,
+	"type":      ObjectKind,
+	"interface": InterfaceKind,
+	"union":     UnionKind,
+	"enum":      EnumKind,
+	"input":     InputObjectKind,
+}
+
+// Adds what an extend type (and so on) declares to the type it extends.
+func (s *Schema) extend(ext *TypeDef) error {
+	t := s.Types[ext.Name]
+	if t == nil {
+		return fmt.Errorf("Cannot extend type \"%s\" because it is not defined.", ext.Name)
+	}
+	if t.Kind != ext.Kind {
+		return fmt.Errorf("Cannot extend non-%s type \"%s\".", ext.Kind, ext.Name)
+	}
+
+	t.Fields = append(t.Fields, ext.Fields...)
+	t.InputFields = append(t.InputFields, ext.InputFields...)
+	t.Interfaces = append(t.Interfaces, ext.Interfaces...)
+	t.Members = append(t.Members, ext.Members...)
+	t.EnumValues = append(t.EnumValues, ext.EnumValues...)
+	return nil
+}
+
+// Parses a schema definition (SDL). Descriptions and directives are read and dropped.
+func ParseSchema(src string) (*Schema, error) {
+	p, err := newParser(src)
+	if err != nil {
+		return nil, fmt.Errorf("ParseSchema: %w", err)
+	}
+
+	s := newSchema()
+	var extensions []*TypeDef
+	for p.tok.kind != tokenEOF {
+		err = p.description()
+		if err != nil {
+			return nil, fmt.Errorf("ParseSchema: %w", err)
+		}
+
+		extending := p.peekName("extend")
+		if extending {
+			err = p.advance()
+			if err != nil {
+				return nil, fmt.Errorf("ParseSchema: %w", err)
+			}
+		}
+
+		kind, isType := typeKeywords[p.tok.value]
+		switch {
+		case p.tok.kind == tokenName && isType:
+			loc := p.tok.loc
+			t, err := p.typeDefinition(kind)
+			if err != nil {
+				return nil, fmt.Errorf("ParseSchema: %w", err)
+			}
+			if extending {
+				extensions = append(extensions, t)
+				continue
+			}
+			if _, exists := s.Types[t.Name]; exists {
+				return nil, fmt.Errorf("ParseSchema: %w", &Error{Message: fmt.Sprintf("There can be only one type named \"%s\".", t.Name), Locations: []Location{loc}})
+			}
+			s.Types[t.Name] = t
+		case p.peekName("schema"):
+			err = p.schemaDefinition(s)
+		case p.peekName("directive") && !extending:
+			err = p.directiveDefinition(s)
+		default:
+			err = p.unexpected()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ParseSchema: %w", err)
+		}
+	}
+
+	for _, ext := range extensions {
+		err = s.extend(ext)
+		if err != nil {
+			return nil, fmt.Errorf("ParseSchema: %w", err)
+		}
+	}
+
+	err = s.check()
+	if err != nil {
+		return nil, fmt.Errorf("ParseSchema: %w", err)
+	}
+
+	return s, nil
+}
+
+// Reads and parses the schema definition in fileName.
+func LoadSchema(fileName string) (*Schema, error) {
+	b, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("LoadSchema: %w", err)
+	}
+
+	s, err := ParseSchema(string(b))
+	if err != nil {
+		return nil, fmt.Errorf("LoadSchema: %s: %w", fileName, err)
+	}
+
+	return s, nil
+}
+
+// Makes sure every type the schema mentions is defined and fits where it's used, and that there's a query type.
+func (s *Schema) check() error {
+	if s.Query == "" {
+		s.Query = "Query"
+		if _, ok := s.Types["Mutation"]; ok && s.Mutation == "" {
+			s.Mutation = "Mutation"
+		}
+		if _, ok := s.Types["Subscription"]; ok && s.Subscription == "" {
+			s.Subscription = "Subscription"
+		}
+	}
+
+	for _, root := range []string{s.Query, s.Mutation, s.Subscription} {
+		if root == "" {
+			continue
+		}
+		if t := s.Types[root]; t == nil || t.Kind != ObjectKind {
+			return fmt.Errorf("root type \"%s\" must be a defined object type", root)
+		}
+	}
+
+	isInput := func(name string) bool {
+		t := s.Types[name]
+		return t != nil && (t.Kind == ScalarKind || t.Kind == EnumKind || t.Kind == InputObjectKind)
+	}
+
+	for _, t := range s.sortedTypes() {
+		for _, f := range t.Fields {
+			if s.Types[f.Type.NamedType()] == nil {
+				return fmt.Errorf("unknown type \"%s\" for field \"%s.%s\"", f.Type.NamedType(), t.Name, f.Name)
+			}
+			if s.Types[f.Type.NamedType()].Kind == InputObjectKind {
+				return fmt.Errorf("field \"%s.%s\" can't be of input type \"%s\"", t.Name, f.Name, f.Type.NamedType())
+			}
+			for _, a := range f.Args {
+				if !isInput(a.Type.NamedType()) {
+					return fmt.Errorf("argument \"%s.%s(%s:)\" must be of a defined input type, not \"%s\"", t.Name, f.Name, a.Name, a.Type.NamedType())
+				}
+			}
+		}
+		for _, f := range t.InputFields {
+			if !isInput(f.Type.NamedType()) {
+				return fmt.Errorf("input field \"%s.%s\" must be of a defined input type, not \"%s\"", t.Name, f.Name, f.Type.NamedType())
+			}
+		}
+		for _, i := range t.Interfaces {
+			if it := s.Types[i]; it == nil || it.Kind != InterfaceKind {
+				return fmt.Errorf("type \"%s\" can only implement interfaces, \"%s\" isn't one", t.Name, i)
+			}
+		}
+		for _, m := range t.Members {
+			if mt := s.Types[m]; mt == nil || mt.Kind != ObjectKind {
+				return fmt.Errorf("union \"%s\" can only include object types, \"%s\" isn't one", t.Name, m)
+			}
+		}
+		if (t.Kind == ObjectKind || t.Kind == InterfaceKind) && len(t.Fields) == 0 {
+			return fmt.Errorf("type \"%s\" must define one or more fields", t.Name)
+		}
+		if t.Kind == EnumKind && len(t.EnumValues) == 0 {
+			return fmt.Errorf("enum \"%s\" must define one or more values", t.Name)
+		}
+	}
+
+	return nil
+}
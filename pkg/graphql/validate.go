@@ -0,0 +1,398 @@
+package graphql
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Checks what a mock needs checked, with the messages the reference implementation uses: fields exist where they're
+// asked for, leaves and objects are selected properly, fields sharing a response key can be merged, arguments,
+// fragments, variables and directives are known, and literals and variables fit the types they're used as. The variables' values are checked by CoerceVariables.
+func (s *Schema) Validate(doc *Document) []*Error {
+	v := &validator{schema: s, doc: doc}
+
+	names := map[string]bool{}
+	for _, op := range doc.Operations {
+		if op.Name == "" && len(doc.Operations) > 1 {
+			v.errorf(op.Loc, "This anonymous operation must be the only defined operation.")
+		}
+		if op.Name != "" && names[op.Name] {
+			v.errorf(op.Loc, "There can be only one operation named \"%s\".", op.Name)
+		}
+		names[op.Name] = true
+
+		root := s.RootType(op.Type)
+		if root == nil {
+			v.errorf(op.Loc, "Schema is not configured to execute %s operation.", op.Type)
+			continue
+		}
+
+		v.operation = op
+		v.variableDefinitions(op)
+		v.directives(op.Directives)
+		v.selectionSet(root, op.SelectionSet, nil)
+		v.overlappingFields(root, op.SelectionSet)
+	}
+
+	fragments := map[string]bool{}
+	for _, f := range doc.Fragments {
+		if fragments[f.Name] {
+			v.errorf(f.Loc, "There can be only one fragment named \"%s\".", f.Name)
+		}
+		fragments[f.Name] = true
+	}
+
+	return v.errors
+}
+
+type validator struct {
+	schema    *Schema
+	doc       *Document
+	operation *Operation
+	errors    []*Error
+}
+
+func (v *validator) errorf(loc Location, format string, args ...any) {
+	v.add(&Error{Message: fmt.Sprintf(format, args...), Locations: []Location{loc}})
+}
+
+func (v *validator) add(err *Error) {
+	// The same fragment spread in two places would say the same thing twice
+	for _, e := range v.errors {
+		if e.Message == err.Message && e.Locations[0] == err.Locations[0] {
+			return
+		}
+	}
+	v.errors = append(v.errors, err)
+}
+
+// Variables have to be declared once, with input types, and their defaults have to fit them.
+func (v *validator) variableDefinitions(op *Operation) {
+	names := map[string]bool{}
+	for _, d := range op.Variables {
+		if names[d.Name] {
+			v.errorf(d.Loc, "There can be only one variable named \"$%s\".", d.Name)
+		}
+		names[d.Name] = true
+
+		t := v.schema.Types[d.Type.NamedType()]
+		switch {
+		case t == nil:
+			v.errorf(d.Loc, "Unknown type \"%s\".", d.Type.NamedType())
+		case t.Kind != ScalarKind && t.Kind != EnumKind && t.Kind != InputObjectKind:
+			v.errorf(d.Loc, "Variable \"$%s\" cannot be non-input type \"%s\".", d.Name, d.Type)
+		case d.Default != nil:
+			v.value(d.Default, d.Type)
+		}
+	}
+}
+
+func (v *validator) variable(name string) *VariableDefinition {
+	for _, d := range v.operation.Variables {
+		if d.Name == name {
+			return d
+		}
+	}
+
+	return nil
+}
+
+// Checks value fits t, any variables in it included. t is nil where the type isn't known (an unknown argument, say), so
+// only the variables are checked.
+func (v *validator) value(value *Value, t *TypeRef) {
+	if value.Kind == VariableValue {
+		d := v.variable(value.Raw)
+		switch {
+		case d == nil && v.operation.Name == "":
+			v.errorf(value.Loc, "Variable \"$%s\" is not defined.", value.Raw)
+		case d == nil:
+			v.errorf(value.Loc, "Variable \"$%s\" is not defined by operation \"%s\".", value.Raw, v.operation.Name)
+		case t != nil && v.schema.Types[d.Type.NamedType()] != nil && !variableFits(d, t):
+			v.errorf(value.Loc, "Variable \"$%s\" of type \"%s\" used in position expecting type \"%s\".", d.Name, d.Type, t)
+		}
+		return
+	}
+
+	var named *TypeDef
+	if t != nil {
+		named = v.schema.Types[t.NamedType()]
+	}
+	if named == nil {
+		for _, item := range value.List {
+			v.value(item, nil)
+		}
+		for _, f := range value.Fields {
+			v.value(f.Value, nil)
+		}
+		return
+	}
+
+	if value.Kind == NullValue {
+		if t.NonNull {
+			v.errorf(value.Loc, "Expected value of type \"%s\", found null.", t)
+		}
+		return
+	}
+
+	if t.Elem != nil {
+		if value.Kind != ListValue {
+			v.value(value, t.Elem) // A single item stands for a list of one
+			return
+		}
+		for _, item := range value.List {
+			v.value(item, t.Elem)
+		}
+		return
+	}
+
+	switch named.Kind {
+	case InputObjectKind:
+		if value.Kind != ObjectValue {
+			v.errorf(value.Loc, "Expected value of type \"%s\", found %s.", t, printValue(value))
+			return
+		}
+		for _, f := range value.Fields {
+			def := named.InputField(f.Name)
+			if def == nil {
+				v.errorf(f.Value.Loc, "Field \"%s\" is not defined by type \"%s\".", f.Name, named.Name)
+			}
+			v.value(f.Value, def.typeRef())
+		}
+		for _, def := range named.InputFields {
+			given := slices.ContainsFunc(value.Fields, func(f *ObjectField) bool { return f.Name == def.Name })
+			if !given && def.Type.NonNull && def.Default == nil {
+				v.errorf(value.Loc, "Field \"%s.%s\" of required type \"%s\" was not provided.", named.Name, def.Name, def.Type)
+			}
+		}
+	case EnumKind:
+		switch {
+		case value.Kind != EnumValue:
+			v.errorf(value.Loc, "Expected value of type \"%s\", found %s; Enum \"%s\" cannot represent non-enum value: %s.", t, printValue(value), named.Name, printValue(value))
+		case !slices.Contains(named.EnumValues, value.Raw):
+			v.errorf(value.Loc, "Expected value of type \"%s\", found %s; Value \"%s\" does not exist in \"%s\" enum.", t, value.Raw, value.Raw, named.Name)
+		}
+	case ScalarKind:
+		if reason := literalScalarError(named.Name, value); reason != "" {
+			v.errorf(value.Loc, "Expected value of type \"%s\", found %s; %s", t, printValue(value), reason)
+		}
+	}
+}
+
+func (i *InputValue) typeRef() *TypeRef {
+	if i == nil {
+		return nil
+	}
+
+	return i.Type
+}
+
+// Whether a variable of d's type can be used where t is expected: the same type, or a non-null one where null is
+// allowed. A nullable variable with a default can go where null isn't.
+func variableFits(d *VariableDefinition, t *TypeRef) bool {
+	if t.NonNull && !d.Type.NonNull && (d.Default == nil || d.Default.Kind == NullValue) {
+		return false
+	}
+
+	nullable := *t
+	nullable.NonNull = d.Type.NonNull || t.NonNull
+	variable := *d.Type
+	variable.NonNull = nullable.NonNull
+	return typeFits(&variable, &nullable)
+}
+
+func typeFits(variable *TypeRef, t *TypeRef) bool {
+	if t.NonNull && !variable.NonNull {
+		return false
+	}
+	if (variable.Elem == nil) != (t.Elem == nil) {
+		return false
+	}
+	if variable.Elem != nil {
+		return typeFits(variable.Elem, t.Elem)
+	}
+
+	return variable.Name == t.Name
+}
+
+// Why a literal can't be a value of the built in scalar name, empty if it can (or it's a custom scalar, which takes
+// anything).
+func literalScalarError(name string, value *Value) string {
+	switch name {
+	case "Int":
+		if value.Kind != IntValue {
+			return fmt.Sprintf("Int cannot represent non-integer value: %s", printValue(value))
+		}
+		if _, err := strconv.ParseInt(value.Raw, 10, 32); err != nil {
+			return fmt.Sprintf("Int cannot represent non 32-bit signed integer value: %s", value.Raw)
+		}
+	case "Float":
+		if value.Kind != IntValue && value.Kind != FloatValue {
+			return fmt.Sprintf("Float cannot represent non numeric value: %s", printValue(value))
+		}
+	case "String":
+		if value.Kind != StringValue {
+			return fmt.Sprintf("String cannot represent a non string value: %s", printValue(value))
+		}
+	case "Boolean":
+		if value.Kind != BooleanValue {
+			return fmt.Sprintf("Boolean cannot represent a non boolean value: %s", printValue(value))
+		}
+	case "ID":
+		if value.Kind != StringValue && value.Kind != IntValue {
+			return fmt.Sprintf("ID cannot represent a non-string and non-integer value: %s", printValue(value))
+		}
+	}
+
+	return ""
+}
+
+// The literal as a query would write it.
+func printValue(value *Value) string {
+	switch value.Kind {
+	case VariableValue:
+		return "$" + value.Raw
+	case StringValue:
+		return strconv.Quote(value.Raw)
+	case NullValue:
+		return "null"
+	case ListValue:
+		items := []string{}
+		for _, item := range value.List {
+			items = append(items, printValue(item))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case ObjectValue:
+		fields := []string{}
+		for _, f := range value.Fields {
+			fields = append(fields, f.Name+": "+printValue(f.Value))
+		}
+		return "{" + strings.Join(fields, ", ") + "}"
+	}
+
+	return value.Raw
+}
+
+// @skip and @include take a Boolean! if, custom directives aren't described beyond their names.
+var booleanIf = &TypeRef{Name: "Boolean", NonNull: true}
+
+func (v *validator) directives(directives []*Directive) {
+	for _, d := range directives {
+		builtIn := d.Name == "skip" || d.Name == "include"
+		if !builtIn && !slices.Contains(v.schema.Directives, d.Name) {
+			v.errorf(d.Loc, "Unknown directive \"@%s\".", d.Name)
+		}
+
+		for _, a := range d.Arguments {
+			switch {
+			case !builtIn:
+				v.value(a.Value, nil)
+			case a.Name == "if":
+				v.value(a.Value, booleanIf)
+			default:
+				v.errorf(a.Loc, "Unknown argument \"%s\" on directive \"@%s\".", a.Name, d.Name)
+				v.value(a.Value, nil)
+			}
+		}
+
+		if builtIn && !slices.ContainsFunc(d.Arguments, func(a *Argument) bool { return a.Name == "if" }) {
+			v.errorf(d.Loc, "Directive \"@%s\" argument \"if\" of type \"Boolean!\" is required, but it was not provided.", d.Name)
+		}
+	}
+}
+
+// The type a fragment applies to, nil (and an error) if it can't.
+func (v *validator) typeCondition(name string, loc Location) *TypeDef {
+	t := v.schema.Types[name]
+	if t == nil {
+		v.errorf(loc, "Unknown type \"%s\".", name)
+		return nil
+	}
+	if !t.IsComposite() {
+		v.errorf(loc, "Fragment cannot condition on non composite type \"%s\".", name)
+		return nil
+	}
+
+	return t
+}
+
+// Validates selections made on parent. spreading holds the fragments being spread, to catch cycles.
+func (v *validator) selectionSet(parent *TypeDef, selections []*Selection, spreading []string) {
+	for _, sel := range selections {
+		v.directives(sel.Directives)
+
+		switch sel.Kind {
+		case FieldSelection:
+			v.field(parent, sel, spreading)
+		case InlineFragment:
+			t := parent
+			if sel.TypeCondition != "" {
+				t = v.typeCondition(sel.TypeCondition, sel.Loc)
+			}
+			if t != nil {
+				v.selectionSet(t, sel.SelectionSet, spreading)
+			}
+		case FragmentSpread:
+			f := v.doc.Fragment(sel.Name)
+			if f == nil {
+				v.errorf(sel.Loc, "Unknown fragment \"%s\".", sel.Name)
+				continue
+			}
+			if slices.Contains(spreading, f.Name) {
+				v.errorf(sel.Loc, "Cannot spread fragment \"%s\" within itself.", f.Name)
+				continue
+			}
+			v.directives(f.Directives)
+			if t := v.typeCondition(f.TypeCondition, f.Loc); t != nil {
+				v.selectionSet(t, f.SelectionSet, append(slices.Clone(spreading), f.Name))
+			}
+		}
+	}
+}
+
+func (v *validator) field(parent *TypeDef, sel *Selection, spreading []string) {
+	if sel.Name == "__typename" {
+		for _, a := range sel.Arguments {
+			v.value(a.Value, nil)
+		}
+		if len(sel.SelectionSet) > 0 {
+			v.errorf(sel.Loc, "Field \"__typename\" must not have a selection since type \"String!\" has no subfields.")
+		}
+		return
+	}
+
+	def := parent.Field(sel.Name)
+	if def == nil {
+		v.errorf(sel.Loc, "Cannot query field \"%s\" on type \"%s\".", sel.Name, parent.Name)
+		for _, a := range sel.Arguments {
+			v.value(a.Value, nil)
+		}
+		return
+	}
+
+	for _, a := range sel.Arguments {
+		arg := def.Arg(a.Name)
+		if arg == nil {
+			v.errorf(a.Loc, "Unknown argument \"%s\" on field \"%s.%s\".", a.Name, parent.Name, def.Name)
+		}
+		v.value(a.Value, arg.typeRef())
+	}
+	for _, arg := range def.Args {
+		provided := slices.ContainsFunc(sel.Arguments, func(a *Argument) bool { return a.Name == arg.Name })
+		if !provided && arg.Type.NonNull && arg.Default == nil {
+			v.errorf(sel.Loc, "Field \"%s\" argument \"%s\" of type \"%s\" is required, but it was not provided.", def.Name, arg.Name, arg.Type)
+		}
+	}
+
+	t := v.schema.Types[def.Type.NamedType()]
+	switch {
+	case t.IsComposite() && len(sel.SelectionSet) == 0:
+		v.errorf(sel.Loc, "Field \"%s\" of type \"%s\" must have a selection of subfields. Did you mean \"%s { ... }\"?", def.Name, def.Type, def.Name)
+	case !t.IsComposite() && len(sel.SelectionSet) > 0:
+		v.errorf(sel.Loc, "Field \"%s\" must not have a selection since type \"%s\" has no subfields.", def.Name, def.Type)
+	case t.IsComposite():
+		v.selectionSet(t, sel.SelectionSet, spreading)
+		v.overlappingFields(t, sel.SelectionSet)
+	}
+}
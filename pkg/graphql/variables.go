@@ -0,0 +1,177 @@
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+)
+
+// The variables op runs with: the values sent, as decoded json, checked against the types the operation declares, and
+// the defaults for what wasn't sent. Values for variables the operation doesn't declare are passed along untouched.
+func (s *Schema) CoerceVariables(op *Operation, values map[string]any) (map[string]any, []*Error) {
+	coerced := make(map[string]any, len(values))
+	for k, v := range values {
+		coerced[k] = v
+	}
+
+	var errs []*Error
+	for _, d := range op.Variables {
+		value, ok := values[d.Name]
+		switch {
+		case !ok && d.Default != nil:
+			coerced[d.Name] = d.Default.Resolve(nil)
+		case !ok && d.Type.NonNull:
+			errs = append(errs, &Error{
+				Message:   fmt.Sprintf("Variable \"$%s\" of required type \"%s\" was not provided.", d.Name, d.Type),
+				Locations: []Location{d.Loc},
+			})
+		case ok && value == nil && d.Type.NonNull:
+			errs = append(errs, &Error{
+				Message:   fmt.Sprintf("Variable \"$%s\" of non-null type \"%s\" must not be null.", d.Name, d.Type),
+				Locations: []Location{d.Loc},
+			})
+		case ok:
+			if invalid, path, reason := s.inputError(value, d.Type, d.Name); reason != "" {
+				at := ""
+				if path != d.Name {
+					at = fmt.Sprintf(" at \"%s\"", path)
+				}
+				errs = append(errs, &Error{
+					Message:   fmt.Sprintf("Variable \"$%s\" got invalid value %s%s; %s", d.Name, printJSON(invalid), at, reason),
+					Locations: []Location{d.Loc},
+				})
+			}
+		}
+	}
+
+	return coerced, errs
+}
+
+// Why value can't be a t, with the part of it that's at fault and where that is. The reason is empty if it can.
+func (s *Schema) inputError(value any, t *TypeRef, path string) (any, string, string) {
+	if value == nil {
+		if t.NonNull {
+			return value, path, fmt.Sprintf("Expected non-nullable type \"%s\" not to be null.", t)
+		}
+		return nil, "", ""
+	}
+
+	if t.Elem != nil {
+		list, ok := value.([]any)
+		if !ok {
+			return s.inputError(value, t.Elem, path) // A single item stands for a list of one
+		}
+		for i, item := range list {
+			if invalid, at, reason := s.inputError(item, t.Elem, fmt.Sprintf("%s[%d]", path, i)); reason != "" {
+				return invalid, at, reason
+			}
+		}
+		return nil, "", ""
+	}
+
+	named := s.Types[t.Name]
+	if named == nil {
+		return nil, "", "" // Validate has already said so
+	}
+
+	switch named.Kind {
+	case InputObjectKind:
+		object, ok := value.(map[string]any)
+		if !ok {
+			return value, path, fmt.Sprintf("Expected type \"%s\" to be an object.", named.Name)
+		}
+		for name := range object {
+			if named.InputField(name) == nil {
+				return value, path, fmt.Sprintf("Field \"%s\" is not defined by type \"%s\".", name, named.Name)
+			}
+		}
+		for _, f := range named.InputFields {
+			v, ok := object[f.Name]
+			if !ok {
+				if f.Type.NonNull && f.Default == nil {
+					return value, path, fmt.Sprintf("Field \"%s\" of required type \"%s\" was not provided.", f.Name, f.Type)
+				}
+				continue
+			}
+			if invalid, at, reason := s.inputError(v, f.Type, path+"."+f.Name); reason != "" {
+				return invalid, at, reason
+			}
+		}
+	case EnumKind:
+		name, ok := value.(string)
+		if !ok {
+			return value, path, fmt.Sprintf("Enum \"%s\" cannot represent non-string value: %s.", named.Name, printJSON(value))
+		}
+		if !slices.Contains(named.EnumValues, name) {
+			return value, path, fmt.Sprintf("Value \"%s\" does not exist in \"%s\" enum.", name, named.Name)
+		}
+	case ScalarKind:
+		if reason := scalarError(named.Name, value); reason != "" {
+			return value, path, reason
+		}
+	}
+
+	return nil, "", ""
+}
+
+// Why a json value can't be a value of the built in scalar name, empty if it can (or it's a custom scalar, which takes
+// anything).
+func scalarError(name string, value any) string {
+	number, isNumber := jsonNumber(value)
+
+	switch name {
+	case "Int":
+		if !isNumber || number != math.Trunc(number) {
+			return fmt.Sprintf("Int cannot represent non-integer value: %s", printJSON(value))
+		}
+		if number < math.MinInt32 || number > math.MaxInt32 {
+			return fmt.Sprintf("Int cannot represent non 32-bit signed integer value: %s", printJSON(value))
+		}
+	case "Float":
+		if !isNumber {
+			return fmt.Sprintf("Float cannot represent non numeric value: %s", printJSON(value))
+		}
+	case "String":
+		if _, ok := value.(string); !ok {
+			return fmt.Sprintf("String cannot represent a non string value: %s", printJSON(value))
+		}
+	case "Boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Sprintf("Boolean cannot represent a non boolean value: %s", printJSON(value))
+		}
+	case "ID":
+		if _, ok := value.(string); !ok && (!isNumber || number != math.Trunc(number)) {
+			return fmt.Sprintf("ID cannot represent value: %s", printJSON(value))
+		}
+	}
+
+	return ""
+}
+
+// A number as json decodes them, whichever way it was decoded.
+func jsonNumber(value any) (float64, bool) {
+	switch n := value.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+
+	return 0, false
+}
+
+func printJSON(value any) string {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return strconv.Quote(fmt.Sprint(value))
+	}
+
+	return string(b)
+}
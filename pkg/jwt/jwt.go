@@ -0,0 +1,272 @@
+// Package jwt verifies and signs JSON Web Tokens with HMAC (HS256, HS384, HS512) or RSA (RS256, RS384, RS512) keys,
+// enough to stand in for an identity provider in tests. Claims aren't checked here, beyond decoding them.
+package jwt
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+)
+
+var (
+	ErrMalformed    = errors.New("malformed token")
+	ErrUnknownKey   = errors.New("no key for the token")
+	ErrBadSignature = errors.New("signature does not match")
+)
+
+var hashes = map[string]crypto.Hash{
+	"HS256": crypto.SHA256, "HS384": crypto.SHA384, "HS512": crypto.SHA512,
+	"RS256": crypto.SHA256, "RS384": crypto.SHA384, "RS512": crypto.SHA512,
+}
+
+// A verification key: a shared secret for HS algorithms, or an RSA public key for RS ones.
+type Key struct {
+	Id     string // The kid it's picked by, empty matches any token
+	Secret []byte
+	RSA    *rsa.PublicKey
+}
+
+func (k Key) accepts(alg string) bool {
+	switch {
+	case strings.HasPrefix(alg, "HS"):
+		return k.Secret != nil
+	case strings.HasPrefix(alg, "RS"):
+		return k.RSA != nil
+	}
+
+	return false
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid,omitempty"`
+	Typ string `json:"typ,omitempty"`
+}
+
+// Checks token's signature against keys and returns its claims. Numbers are left as json.Number.
+func Verify(token string, keys []Key) (map[string]any, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("Verify: %w", ErrMalformed)
+	}
+
+	var h header
+	err := decodeSegment(parts[0], &h)
+	if err != nil {
+		return nil, fmt.Errorf("Verify: header: %w", err)
+	}
+
+	hash, ok := hashes[h.Alg]
+	if !ok {
+		return nil, fmt.Errorf("Verify: unsupported algorithm: \"%s\"", h.Alg)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("Verify: signature: %w", ErrMalformed)
+	}
+
+	signed := []byte(parts[0] + "." + parts[1])
+	tried := false
+	for _, k := range keys {
+		if !k.accepts(h.Alg) || (h.Kid != "" && k.Id != "" && k.Id != h.Kid) {
+			continue
+		}
+		tried = true
+
+		if verifySignature(k, hash, signed, signature) {
+			var claims map[string]any
+			err = decodeSegment(parts[1], &claims)
+			if err != nil {
+				return nil, fmt.Errorf("Verify: claims: %w", err)
+			}
+			return claims, nil
+		}
+	}
+
+	if !tried {
+		return nil, fmt.Errorf("Verify: %w", ErrUnknownKey)
+	}
+
+	return nil, fmt.Errorf("Verify: %w", ErrBadSignature)
+}
+
+func verifySignature(k Key, hash crypto.Hash, signed []byte, signature []byte) bool {
+	if k.Secret != nil {
+		mac := hmac.New(hash.New, k.Secret)
+		mac.Write(signed)
+		return hmac.Equal(mac.Sum(nil), signature)
+	}
+
+	h := hash.New()
+	h.Write(signed)
+	return rsa.VerifyPKCS1v15(k.RSA, hash, h.Sum(nil), signature) == nil
+}
+
+func decodeSegment(segment string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return ErrMalformed
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	err = decoder.Decode(v)
+	if err != nil {
+		return ErrMalformed
+	}
+
+	return nil
+}
+
+// Makes a token for claims. key is a []byte secret for HS algorithms or an *rsa.PrivateKey for RS ones, kid is left out
+// of the header if empty.
+func Sign(claims map[string]any, alg string, key any, kid string) (string, error) {
+	hash, ok := hashes[alg]
+	if !ok {
+		return "", fmt.Errorf("Sign: unsupported algorithm: \"%s\"", alg)
+	}
+
+	h, err := json.Marshal(header{Alg: alg, Kid: kid, Typ: "JWT"})
+	if err != nil {
+		return "", fmt.Errorf("Sign: %w", err)
+	}
+	c, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("Sign: %w", err)
+	}
+
+	signed := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+
+	var signature []byte
+	switch k := key.(type) {
+	case []byte:
+		if !strings.HasPrefix(alg, "HS") {
+			return "", fmt.Errorf("Sign: %s needs an rsa key", alg)
+		}
+		mac := hmac.New(hash.New, k)
+		mac.Write([]byte(signed))
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		if !strings.HasPrefix(alg, "RS") {
+			return "", fmt.Errorf("Sign: %s needs a secret", alg)
+		}
+		digest := hash.New()
+		digest.Write([]byte(signed))
+		signature, err = rsa.SignPKCS1v15(nil, k, hash, digest.Sum(nil))
+		if err != nil {
+			return "", fmt.Errorf("Sign: %w", err)
+		}
+	default:
+		return "", fmt.Errorf("Sign: unsupported key type: %T", key)
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// Reads an RSA public key from a PEM file, as a PUBLIC KEY, RSA PUBLIC KEY or CERTIFICATE block.
+func LoadPublicKey(fileName string) (*rsa.PublicKey, error) {
+	b, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("LoadPublicKey: %w", err)
+	}
+
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("LoadPublicKey: %s: no pem block found", fileName)
+	}
+
+	var key any
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		var cert *x509.Certificate
+		cert, err = x509.ParseCertificate(block.Bytes)
+		if err == nil {
+			key = cert.PublicKey
+		}
+	default:
+		return nil, fmt.Errorf("LoadPublicKey: %s: unexpected pem block: %s", fileName, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("LoadPublicKey: %s: %w", fileName, err)
+	}
+
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("LoadPublicKey: %s: not an rsa key", fileName)
+	}
+
+	return rsaKey, nil
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	K   string `json:"k"`
+}
+
+// Reads the RSA and oct (HMAC secret) keys of a JWKS file, skipping keys of other types and ones marked for
+// encryption.
+func LoadJWKS(fileName string) ([]Key, error) {
+	b, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("LoadJWKS: %w", err)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	err = json.Unmarshal(b, &set)
+	if err != nil {
+		return nil, fmt.Errorf("LoadJWKS: %s: %w", fileName, err)
+	}
+
+	keys := []Key{}
+	for i, k := range set.Keys {
+		if k.Use == "enc" {
+			continue
+		}
+
+		switch k.Kty {
+		case "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(k.N)
+			e, errE := base64.RawURLEncoding.DecodeString(k.E)
+			if errN != nil || errE != nil || len(n) == 0 || len(e) == 0 || len(e) > 4 {
+				return nil, fmt.Errorf("LoadJWKS: %s: key %d: invalid rsa modulus or exponent", fileName, i)
+			}
+			keys = append(keys, Key{Id: k.Kid, RSA: &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}})
+		case "oct":
+			secret, err := base64.RawURLEncoding.DecodeString(k.K)
+			if err != nil || len(secret) == 0 {
+				return nil, fmt.Errorf("LoadJWKS: %s: key %d: invalid secret", fileName, i)
+			}
+			keys = append(keys, Key{Id: k.Kid, Secret: secret})
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("LoadJWKS: %s: no rsa or oct signing keys", fileName)
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,160 @@
+package jwt
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	t.Parallel()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := []byte("s3cret")
+	claims := map[string]any{"sub": "alice", "exp": 2000000000}
+
+	sign := func(alg string, key any, kid string) string {
+		token, err := Sign(claims, alg, key, kid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	testCases := []struct {
+		name        string
+		token       string
+		keys        []Key
+		expectedErr error
+	}{
+		{name: "hs256", token: sign("HS256", secret, ""), keys: []Key{{Secret: secret}}},
+		{name: "hs512", token: sign("HS512", secret, ""), keys: []Key{{Secret: secret}}},
+		{name: "rs256", token: sign("RS256", rsaKey, ""), keys: []Key{{RSA: &rsaKey.PublicKey}}},
+		{name: "picked by kid", token: sign("RS256", rsaKey, "b"), keys: []Key{{Id: "a", RSA: &otherKey.PublicKey}, {Id: "b", RSA: &rsaKey.PublicKey}}},
+		{name: "wrong secret", token: sign("HS256", []byte("other"), ""), keys: []Key{{Secret: secret}}, expectedErr: ErrBadSignature},
+		{name: "wrong rsa key", token: sign("RS256", otherKey, ""), keys: []Key{{RSA: &rsaKey.PublicKey}}, expectedErr: ErrBadSignature},
+		{name: "no key for the algorithm", token: sign("HS256", secret, ""), keys: []Key{{RSA: &rsaKey.PublicKey}}, expectedErr: ErrUnknownKey},
+		{name: "no key for the kid", token: sign("RS256", rsaKey, "c"), keys: []Key{{Id: "a", RSA: &rsaKey.PublicKey}}, expectedErr: ErrUnknownKey},
+		{name: "malformed", token: "not.a-token", keys: []Key{{Secret: secret}}, expectedErr: ErrMalformed},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Verify(tc.token, tc.keys)
+			if tc.expectedErr != nil {
+				if !errors.Is(err, tc.expectedErr) {
+					t.Fatalf("expected %v, got %v", tc.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got["sub"] != "alice" || got["exp"] != json.Number("2000000000") {
+				t.Fatalf("unexpected claims: %v", got)
+			}
+		})
+	}
+}
+
+func TestVerify_UnsupportedAlgorithm(t *testing.T) {
+	t.Parallel()
+
+	// alg none tokens have to be turned away whatever keys there are
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"mallory"}`))
+
+	_, err := Verify(header+"."+payload+".", []Key{{Secret: []byte("s3cret")}})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestLoadKeys(t *testing.T) {
+	t.Parallel()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+
+	der, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemFile := filepath.Join(dir, "public.pem")
+	err = os.WriteFile(pemFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b64 := base64.RawURLEncoding.EncodeToString
+	jwks, _ := json.Marshal(map[string]any{"keys": []map[string]string{
+		{"kty": "RSA", "kid": "rsa-1", "use": "sig", "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+		{"kty": "RSA", "kid": "rsa-enc", "use": "enc", "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+		{"kty": "oct", "kid": "hmac-1", "k": b64([]byte("s3cret"))},
+		{"kty": "EC", "kid": "ec-1"},
+	}})
+	jwksFile := filepath.Join(dir, "jwks.json")
+	err = os.WriteFile(jwksFile, jwks, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publicKey, err := LoadPublicKey(pemFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := LoadJWKS(jwksFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("expected the rsa signing key and the oct key, got %d keys", len(keys))
+	}
+
+	rsaToken, err := Sign(map[string]any{"sub": "alice"}, "RS256", rsaKey, "rsa-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hmacToken, err := Sign(map[string]any{"sub": "bob"}, "HS256", []byte("s3cret"), "hmac-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Verify(rsaToken, []Key{{RSA: publicKey}})
+	if err != nil {
+		t.Fatalf("pem key: %v", err)
+	}
+	for _, token := range []string{rsaToken, hmacToken} {
+		_, err = Verify(token, keys)
+		if err != nil {
+			t.Fatalf("jwks: %v", err)
+		}
+	}
+
+	_, err = LoadJWKS(pemFile)
+	if err == nil {
+		t.Fatal("expected an error loading a pem file as a jwks")
+	}
+}
@@ -0,0 +1,359 @@
+package mockapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	se "github.com/nrexception/mockapi/pkg/settings"
+)
+
+// Anything that can produce a web listener's settings, a ListenerBuilder or the BindingBuilder at the end of its chain.
+type ListenerSource interface {
+	Build() (se.UnmarshalledRootSettingWebListener, error)
+}
+
+// Builds settings for a whole server:
+//
+//	mockapi.Config().
+//		Listener(mockapi.Listener("api").Port(0).
+//			Bind("/users").Method("GET").Status(200).JSON(users).
+//			Bind("/users").Method("POST").Status(201).Header("Location", "/users/1")).
+//		Start()
+type ConfigBuilder struct {
+	settings se.UnmarshalledRootSettings
+	sources  []ListenerSource
+}
+
+// Starts building settings, Id, Schema and Description are filled in if they aren't set.
+func Config() *ConfigBuilder {
+	return &ConfigBuilder{}
+}
+
+func (c *ConfigBuilder) Id(id string) *ConfigBuilder {
+	c.settings.Id = id
+	return c
+}
+
+func (c *ConfigBuilder) Description(description string) *ConfigBuilder {
+	c.settings.Description = description
+	return c
+}
+
+// Adds an admin listener (metrics, health and readiness) on port, 0 picks a free one.
+func (c *ConfigBuilder) Admin(port int) *ConfigBuilder {
+	c.settings.Admin = &se.UnmarshalledRootSettingAdminListener{ListenerPort: port}
+	return c
+}
+
+func (c *ConfigBuilder) Listener(listeners ...ListenerSource) *ConfigBuilder {
+	c.sources = append(c.sources, listeners...)
+	return c
+}
+
+// Validated settings for every listener added so far.
+func (c *ConfigBuilder) Build() (se.UnmarshalledRootSettings, error) {
+	settings := c.settings
+	settings.WebListeners = nil
+	withDefaults(&settings)
+
+	for _, source := range c.sources {
+		ls, err := source.Build()
+		if err != nil {
+			return se.UnmarshalledRootSettings{}, fmt.Errorf("ConfigBuilder.Build: %w", err)
+		}
+		settings.WebListeners = append(settings.WebListeners, ls)
+	}
+
+	err := settings.Validate()
+	if err != nil {
+		return se.UnmarshalledRootSettings{}, fmt.Errorf("ConfigBuilder.Build: %w", err)
+	}
+
+	return settings, nil
+}
+
+// The built settings as yaml, ready to be saved and run with mockapi -f.
+func (c *ConfigBuilder) YAML() ([]byte, error) {
+	settings, err := c.Build()
+	if err != nil {
+		return nil, fmt.Errorf("ConfigBuilder.YAML: %w", err)
+	}
+
+	b, err := se.MarshalSettings(&settings)
+	if err != nil {
+		return nil, fmt.Errorf("ConfigBuilder.YAML: %w", err)
+	}
+
+	return b, nil
+}
+
+// Builds the settings and starts a Server with them.
+func (c *ConfigBuilder) Start() (*Server, error) {
+	settings, err := c.Build()
+	if err != nil {
+		return nil, fmt.Errorf("ConfigBuilder.Start: %w", err)
+	}
+
+	return New(settings)
+}
+
+type ListenerBuilder struct {
+	settings se.UnmarshalledRootSettingWebListener
+	bindings []*BindingBuilder
+}
+
+// Starts building a listener, on a free port unless Port is called.
+func Listener(name string) *ListenerBuilder {
+	return &ListenerBuilder{settings: se.UnmarshalledRootSettingWebListener{ListenerName: name}}
+}
+
+func (l *ListenerBuilder) Port(port int) *ListenerBuilder {
+	l.settings.ListenerPort = port
+	return l
+}
+
+// Binds an IPv4 or IPv6 address rather than every interface.
+func (l *ListenerBuilder) ListenAddress(address string) *ListenerBuilder {
+	l.settings.ListenAddress = address
+	return l
+}
+
+// Listens on a unix socket instead of a tcp port, such listeners have no URL.
+func (l *ListenerBuilder) UnixSocket(path string) *ListenerBuilder {
+	l.settings.UnixSocket = path
+	return l
+}
+
+func (l *ListenerBuilder) TLS(certFile string, keyFile string) *ListenerBuilder {
+	l.settings.EnableTLS = true
+	if l.settings.CertDetails == nil {
+		l.settings.CertDetails = &se.UnmarshalledRootSettingWebListenerHTTPSCertFiles{}
+	}
+	l.settings.CertDetails.CertFile = certFile
+	l.settings.CertDetails.KeyFile = keyFile
+	return l
+}
+
+// Asks clients of a listener set up with TLS or AutoTLS for a certificate, verified against the CAs in clientCAFile when
+// mode is verify.
+func (l *ListenerBuilder) ClientAuth(mode se.ClientAuthMode, clientCAFile string) *ListenerBuilder {
+	l.settings.ClientAuth = &se.ClientAuthSettings{Mode: mode, CAFile: clientCAFile}
+	return l
+}
+
+// Serves tls with a certificate for hostnames (localhost if none) issued by the local CA, see Server.Client.
+func (l *ListenerBuilder) AutoTLS(hostnames ...string) *ListenerBuilder {
+	l.settings.EnableTLS = true
+	l.settings.AutoTLS = &se.AutoTLSSettings{Hostnames: hostnames}
+	return l
+}
+
+// Restricts the versions, cipher suites and alpn protocols a tls listener negotiates.
+func (l *ListenerBuilder) TLSOptions(options se.TLSOptions) *ListenerBuilder {
+	l.settings.TLSOptions = &options
+	return l
+}
+
+// Serves certFile to clients asking for one of hostnames, on a listener set up with TLS.
+func (l *ListenerBuilder) SNICert(certFile string, keyFile string, hostnames ...string) *ListenerBuilder {
+	if l.settings.CertDetails == nil {
+		l.settings.CertDetails = &se.UnmarshalledRootSettingWebListenerHTTPSCertFiles{}
+	}
+	l.settings.CertDetails.SNICerts = append(l.settings.CertDetails.SNICerts, se.SNICertificate{Hostnames: hostnames, CertFile: certFile, KeyFile: keyFile})
+	return l
+}
+
+// Whether connections stay open between requests, they do unless told otherwise.
+func (l *ListenerBuilder) KeepAlive(enabled bool) *ListenerBuilder {
+	l.settings.OnConnectKeepAlive = &enabled
+	return l
+}
+
+// Protocols, timeouts and header limits, eg. to turn HTTP/2 off or accept h2c.
+func (l *ListenerBuilder) HTTPOptions(options se.HTTPOptions) *ListenerBuilder {
+	l.settings.HTTPOptions = &options
+	return l
+}
+
+func (l *ListenerBuilder) AccessLog(settings se.AccessLogSettings) *ListenerBuilder {
+	l.settings.AccessLog = &settings
+	return l
+}
+
+// Serves the services in a descriptor set over gRPC as well, h2c is turned on for listeners without tls.
+func (l *ListenerBuilder) GRPC(settings se.GRPCSettings) *ListenerBuilder {
+	l.settings.GRPC = &settings
+	return l
+}
+
+// Credentials every binding needs, unless it has auth of its own.
+func (l *ListenerBuilder) Auth(settings se.AuthSettings) *ListenerBuilder {
+	l.settings.Auth = &settings
+	return l
+}
+
+func (l *ListenerBuilder) MetricsPath(path string) *ListenerBuilder {
+	l.settings.MetricsPath = path
+	return l
+}
+
+// Adds a binding, answering 200 to any method until told otherwise.
+func (l *ListenerBuilder) Bind(path string) *BindingBuilder {
+	b := &BindingBuilder{listener: l, binding: se.ResponseBinding{Path: path, ResponseCode: http.StatusOK, ResponseBodyType: se.Inline}}
+	l.bindings = append(l.bindings, b)
+
+	return b
+}
+
+// Validated settings for the listener and its bindings.
+func (l *ListenerBuilder) Build() (se.UnmarshalledRootSettingWebListener, error) {
+	settings := l.settings
+	settings.ContentBindings = nil
+
+	for _, b := range l.bindings {
+		if b.err != nil {
+			return se.UnmarshalledRootSettingWebListener{}, fmt.Errorf("ListenerBuilder.Build: listener \"%s\": binding \"%s\": %w", settings.ListenerName, b.binding.Path, b.err)
+		}
+		settings.ContentBindings = append(settings.ContentBindings, b.binding)
+	}
+
+	err := settings.Validate()
+	if err != nil {
+		return se.UnmarshalledRootSettingWebListener{}, fmt.Errorf("ListenerBuilder.Build: %w", err)
+	}
+
+	return settings, nil
+}
+
+type BindingBuilder struct {
+	listener *ListenerBuilder
+	binding  se.ResponseBinding
+	err      error // First error hit while building, reported by Build
+}
+
+func (b *BindingBuilder) Method(method string) *BindingBuilder {
+	b.binding.Method = method
+	return b
+}
+
+func (b *BindingBuilder) Status(code int) *BindingBuilder {
+	b.binding.ResponseCode = code
+	return b
+}
+
+func (b *BindingBuilder) Header(key string, value string) *BindingBuilder {
+	b.binding.ResponseHeaders = append(b.binding.ResponseHeaders, se.ResponseHeader{Key: key, Value: value})
+	return b
+}
+
+// Only answers clients presenting a certificate with this common name, and one of sans if any are given.
+func (b *BindingBuilder) ClientCert(commonName string, sans ...string) *BindingBuilder {
+	b.binding.Match = &se.BindingMatch{ClientCert: &se.ClientCertMatch{CommonName: commonName, SANs: sans}}
+	return b
+}
+
+// Credentials the binding needs, in place of its listener's. Disabled opens it up.
+func (b *BindingBuilder) Auth(settings se.AuthSettings) *BindingBuilder {
+	b.binding.Auth = &settings
+	return b
+}
+
+// Only answers requests whose credentials carry these claims, see se.BindingMatch.
+func (b *BindingBuilder) Claims(claims map[string]any) *BindingBuilder {
+	if b.binding.Match == nil {
+		b.binding.Match = &se.BindingMatch{}
+	}
+	b.binding.Match.Claims = claims
+	return b
+}
+
+// Responds with body as is.
+func (b *BindingBuilder) Body(body string) *BindingBuilder {
+	b.binding.ResponseBodyType = se.Inline
+	b.binding.ResponseBody = body
+	return b
+}
+
+// Responds with v marshalled to json, setting the content type to match.
+func (b *BindingBuilder) JSON(v any) *BindingBuilder {
+	body, err := json.Marshal(v)
+	if err != nil {
+		b.err = errors.Join(b.err, fmt.Errorf("BindingBuilder.JSON: %w", err))
+		return b
+	}
+
+	return b.Header("Content-Type", "application/json").Body(string(body))
+}
+
+// Responds with the contents of a file, read when the binding is served.
+func (b *BindingBuilder) File(path string) *BindingBuilder {
+	b.binding.ResponseBodyType = se.File
+	b.binding.ResponseBody = path
+	return b
+}
+
+// Serves the files under dir, see DirectoryOptions for index files and listings.
+func (b *BindingBuilder) Directory(dir string, options *se.DirectoryOptions) *BindingBuilder {
+	b.binding.ResponseBodyType = se.Directory
+	b.binding.ResponseBody = dir
+	b.binding.DirectoryOptions = options
+	return b
+}
+
+// Upgrades requests to a websocket holding conversation, see se.WebSocketSettings.
+func (b *BindingBuilder) WebSocket(conversation se.WebSocketSettings) *BindingBuilder {
+	b.binding.ResponseBodyType = se.WebSocket
+	b.binding.ResponseBody = ""
+	b.binding.WebSocket = &conversation
+	return b
+}
+
+// Streams chunks to the client, flushing each one, see se.StreamSettings.
+func (b *BindingBuilder) Stream(stream se.StreamSettings) *BindingBuilder {
+	b.binding.ResponseBodyType = se.Stream
+	b.binding.ResponseBody = ""
+	b.binding.Stream = &stream
+	return b
+}
+
+// Answers GraphQL queries against a schema, see se.GraphQLSettings.
+func (b *BindingBuilder) GraphQL(graphQL se.GraphQLSettings) *BindingBuilder {
+	b.binding.ResponseBodyType = se.GraphQL
+	b.binding.ResponseBody = ""
+	b.binding.GraphQL = &graphQL
+	return b
+}
+
+// Serves an in-memory collection with CRUD on every method, see se.ResourceSettings.
+func (b *BindingBuilder) Resource(resource se.ResourceSettings) *BindingBuilder {
+	b.binding.ResponseBodyType = se.Resource
+	b.binding.ResponseBody = ""
+	b.binding.Resource = &resource
+	return b
+}
+
+// Makes up a body for every request from a template, schema or example, see se.GeneratedSettings.
+func (b *BindingBuilder) Generated(generated se.GeneratedSettings) *BindingBuilder {
+	b.binding.ResponseBodyType = se.Generated
+	b.binding.ResponseBody = ""
+	b.binding.Generated = &generated
+	return b
+}
+
+// Sends c once the binding has responded, see se.Callback.
+func (b *BindingBuilder) Callback(c se.Callback) *BindingBuilder {
+	b.binding.Callbacks = append(b.binding.Callbacks, c)
+	return b
+}
+
+// Adds another binding to the same listener.
+func (b *BindingBuilder) Bind(path string) *BindingBuilder {
+	return b.listener.Bind(path)
+}
+
+// Validated settings for the listener this binding belongs to.
+func (b *BindingBuilder) Build() (se.UnmarshalledRootSettingWebListener, error) {
+	return b.listener.Build()
+}
@@ -0,0 +1,152 @@
+package mockapi
+
+import (
+	"crypto/tls"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/nrexception/mockapi/pkg/certs"
+	se "github.com/nrexception/mockapi/pkg/settings"
+)
+
+func TestConfigBuilder(t *testing.T) {
+	t.Parallel()
+
+	config := Config().Id("builder-test").Listener(Listener("api").
+		Bind("/users").Method(http.MethodGet).JSON([]map[string]string{{"name": "bob"}}).
+		Bind("/users").Method(http.MethodPost).Status(http.StatusCreated).Header("Location", "/users/1").Body("created"))
+
+	s, err := config.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+
+	testCases := []struct {
+		method string
+		code   int
+		body   string
+	}{
+		{method: http.MethodGet, code: http.StatusOK, body: `[{"name":"bob"}]`},
+		{method: http.MethodPost, code: http.StatusCreated, body: "created"},
+		{method: http.MethodDelete, code: http.StatusMethodNotAllowed, body: "Method Not Allowed\n"},
+	}
+
+	for _, tc := range testCases {
+		r, err := http.NewRequest(tc.method, s.URL("api")+"/users", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := http.DefaultClient.Do(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(res.Body)
+		_ = res.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if res.StatusCode != tc.code || string(b) != tc.body {
+			t.Errorf("%s: unexpected response: %d %s", tc.method, res.StatusCode, b)
+		}
+	}
+
+	// Whatever is built can be shared as yaml and read back unchanged
+	built, err := config.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	y, err := config.YAML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	read, err := se.UnmarshalSettings(y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(*read, built) {
+		t.Errorf("settings changed through yaml:\ngot: %+v\nwant:%+v\n%s", *read, built, y)
+	}
+}
+
+func TestConfigBuilder_AutoTLSClientAuth(t *testing.T) {
+	t.Parallel()
+
+	clients, err := certs.NewCA()
+	if err != nil {
+		t.Fatal(err)
+	}
+	caFile := filepath.Join(t.TempDir(), "clients.pem")
+	err = os.WriteFile(caFile, clients.CertPEM(), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Config().Listener(Listener("partners").AutoTLS().ClientAuth(se.ClientAuthVerify, caFile).Bind("/").Body("ok")).Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+
+	// The listener's own certificate comes from the local CA, the client's is checked against caFile
+	_, err = s.Client().Get(s.URL("partners") + "/")
+	if err == nil {
+		t.Fatal("expected a client without a certificate to be turned away")
+	}
+
+	clientCert, err := clients.IssueClient("partner-a", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := s.Client()
+	client.Transport.(*http.Transport).TLSClientConfig.Certificates = []tls.Certificate{clientCert}
+	res, err := client.Get(s.URL("partners") + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "ok" {
+		t.Errorf("unexpected body: %s", b)
+	}
+}
+
+func TestConfigBuilder_Errors(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name   string
+		config *ConfigBuilder
+	}{
+		{name: "no listeners", config: Config()},
+		{name: "bad json", config: Config().Listener(Listener("api").Bind("/").JSON(math.Inf(1)))},
+		{name: "bad status", config: Config().Listener(Listener("api").Bind("/").Status(42).Body("ok"))},
+		{name: "duplicate listener names", config: Config().Listener(Listener("api").Bind("/").Body("a"), Listener("api").Bind("/").Body("b"))},
+		{name: "client auth without tls", config: Config().Listener(Listener("api").ClientAuth(se.ClientAuthRequest, "").Bind("/").Body("ok"))},
+		{name: "verify without a ca", config: Config().Listener(Listener("api").AutoTLS().ClientAuth(se.ClientAuthVerify, "").Bind("/").Body("ok"))},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s, err := tc.config.Start()
+			if err == nil {
+				_ = s.Close()
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+// Package mockapi runs MockAPI listeners in-process, for use from Go tests:
+//
+//	s, err := mockapi.NewFromYAML(config)
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	t.Cleanup(func() { _ = s.Close() })
+//
+//	res, err := http.Get(s.URL("api") + "/users")
+//
+// Every Server owns its own listeners, so any number of them can run side by side in parallel tests.
+package mockapi
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"sync"
+
+	ser "github.com/nrexception/mockapi/pkg/server"
+	se "github.com/nrexception/mockapi/pkg/settings"
+)
+
+// Filled in when settings built in Go leave them empty, the yaml file format requires them.
+const (
+	defaultId          = "mockapi"
+	defaultSchema      = "1"
+	defaultDescription = "in-process mockapi server"
+)
+
+type Server struct {
+	mu       sync.Mutex
+	manager  *ser.ListenerManager
+	settings se.UnmarshalledRootSettings
+	closed   bool
+}
+
+// Starts a server with every listener in settings, a listener port of 0 picks a free port (see URL). The journal is
+// enabled, settings is copied so later changes to it have no effect.
+func New(settings se.UnmarshalledRootSettings) (*Server, error) {
+	settings.WebListeners = cloneListeners(settings.WebListeners)
+	withDefaults(&settings)
+
+	err := settings.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("New: %w", err)
+	}
+
+	s := &Server{manager: ser.NewListenerManager(nil, nil), settings: settings}
+	s.manager.Journal().Enable(0)
+
+	err = s.manager.Apply(&s.settings)
+	if err != nil {
+		_ = s.manager.Close()
+		return nil, fmt.Errorf("New: %w", err)
+	}
+
+	return s, nil
+}
+
+// Starts a server from yaml in the same format as a -f settings file.
+func NewFromYAML(yaml string) (*Server, error) {
+	settings, err := se.UnmarshalSettings([]byte(yaml))
+	if err != nil {
+		return nil, fmt.Errorf("NewFromYAML: %w", err)
+	}
+
+	return New(*settings)
+}
+
+func withDefaults(settings *se.UnmarshalledRootSettings) {
+	if settings.Id == "" {
+		settings.Id = defaultId
+	}
+	if settings.Schema == "" {
+		settings.Schema = defaultSchema
+	}
+	if settings.Description == "" {
+		settings.Description = defaultDescription
+	}
+}
+
+func cloneListeners(listeners []se.UnmarshalledRootSettingWebListener) []se.UnmarshalledRootSettingWebListener {
+	cloned := slices.Clone(listeners)
+	for i := range cloned {
+		cloned[i].ContentBindings = slices.Clone(cloned[i].ContentBindings)
+	}
+
+	return cloned
+}
+
+// Base URL of the named listener, eg. http://127.0.0.1:41234, or "" if there's no such listener running or it's on a
+// unix socket.
+func (s *Server) URL(listenerName string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, ls := range s.manager.Status().Listeners {
+		if ls.Name != listenerName || ls.State != ser.LISTENERSTATE_BOUND {
+			continue
+		}
+
+		host, port, err := net.SplitHostPort(ls.Address)
+		if err != nil {
+			return "" // Unix socket
+		}
+
+		// Listeners on every interface are reached over loopback, Go's [::] takes IPv4 too
+		if net.ParseIP(host).IsUnspecified() {
+			host = "127.0.0.1"
+		}
+
+		scheme := "http"
+		i := slices.IndexFunc(s.settings.WebListeners, func(l se.UnmarshalledRootSettingWebListener) bool { return l.ListenerName == listenerName })
+		if i >= 0 && s.settings.WebListeners[i].EnableTLS {
+			scheme = "https"
+		}
+
+		return scheme + "://" + net.JoinHostPort(host, port)
+	}
+
+	return ""
+}
+
+// Base URLs of every running listener, keyed by listener name.
+func (s *Server) URLs() map[string]string {
+	urls := map[string]string{}
+	for _, ls := range s.Settings().WebListeners {
+		if u := s.URL(ls.ListenerName); u != "" {
+			urls[ls.ListenerName] = u
+		}
+	}
+
+	return urls
+}
+
+// Copy of the settings currently applied.
+func (s *Server) Settings() se.UnmarshalledRootSettings {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	settings := s.settings
+	settings.WebListeners = cloneListeners(settings.WebListeners)
+
+	return settings
+}
+
+// Adds a binding to a running listener. The listener keeps its port and connections, only its bindings are swapped.
+func (s *Server) AddBinding(listenerName string, binding se.ResponseBinding) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return fmt.Errorf("Server.AddBinding: server is closed")
+	}
+
+	err := binding.Validate()
+	if err != nil {
+		return fmt.Errorf("Server.AddBinding: %w", err)
+	}
+
+	updated := s.settings
+	updated.WebListeners = cloneListeners(updated.WebListeners)
+
+	i := slices.IndexFunc(updated.WebListeners, func(l se.UnmarshalledRootSettingWebListener) bool { return l.ListenerName == listenerName })
+	if i < 0 {
+		return fmt.Errorf("Server.AddBinding: no listener named \"%s\"", listenerName)
+	}
+	updated.WebListeners[i].ContentBindings = append(updated.WebListeners[i].ContentBindings, binding)
+
+	err = s.manager.Apply(&updated)
+	if err != nil {
+		return fmt.Errorf("Server.AddBinding: %w", err)
+	}
+	s.settings = updated
+
+	return nil
+}
+
+// An http client that trusts the server's certificates, for listeners using auto tls.
+func (s *Server) Client() *http.Client {
+	pool := x509.NewCertPool()
+	if ca := s.manager.LocalCA(); ca != nil {
+		pool.AddCert(ca.Cert)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+
+	return &http.Client{Transport: transport}
+}
+
+// Every request received so far, oldest first.
+func (s *Server) Journal() []ser.JournalEntry {
+	return s.manager.Journal().Entries()
+}
+
+// Forgets every request received so far.
+func (s *Server) ResetJournal() {
+	s.manager.Journal().Reset()
+}
+
+// Every callback fired so far and whether it was delivered, oldest first. Callbacks still being tried aren't in it yet.
+func (s *Server) Callbacks() []ser.CallbackDelivery {
+	return s.manager.Callbacks().Entries()
+}
+
+// Stops every listener, waiting for in-flight requests. Safe to call more than once.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	err := s.manager.Close()
+	if err != nil {
+		return fmt.Errorf("Server.Close: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,162 @@
+package mockapi
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	se "github.com/nrexception/mockapi/pkg/settings"
+)
+
+const testConfig = `
+id: "server-test"
+schema: "1"
+description: "library test"
+weblisteners:
+  - listenername: "api"
+    listenerport: 0
+    contentbindings:
+      - bindingpath: "/users"
+        responsecode: 200
+        responsebodytype: "inline"
+        responsebody: '[{"name": "bob"}]'
+`
+
+func get(t *testing.T, url string) (int, string) {
+	t.Helper()
+
+	res, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return res.StatusCode, string(b)
+}
+
+func TestServer(t *testing.T) {
+	t.Parallel()
+
+	s, err := NewFromYAML(testConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+
+	url := s.URL("api")
+	if !strings.HasPrefix(url, "http://127.0.0.1:") || strings.HasSuffix(url, ":0") {
+		t.Fatalf("unexpected url: %s", url)
+	}
+	if s.URLs()["api"] != url {
+		t.Errorf("unexpected urls: %v", s.URLs())
+	}
+
+	code, body := get(t, url+"/users?page=2")
+	if code != http.StatusOK || body != `[{"name": "bob"}]` {
+		t.Errorf("unexpected response: %d %s", code, body)
+	}
+
+	// Bindings added at runtime are served from the same port
+	err = s.AddBinding("api", se.ResponseBinding{Path: "/orders", ResponseCode: http.StatusCreated, ResponseBody: "ok", ResponseBodyType: se.Inline})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.URL("api") != url {
+		t.Errorf("listener moved: %s", s.URL("api"))
+	}
+	code, body = get(t, url+"/orders")
+	if code != http.StatusCreated || body != "ok" {
+		t.Errorf("unexpected response: %d %s", code, body)
+	}
+
+	err = s.AddBinding("missing", se.ResponseBinding{Path: "/", ResponseCode: http.StatusOK, ResponseBody: "ok", ResponseBodyType: se.Inline})
+	if err == nil {
+		t.Error("expected an error adding to a missing listener")
+	}
+
+	journal := s.Journal()
+	if len(journal) != 2 {
+		t.Fatalf("unexpected journal length: %d", len(journal))
+	}
+	if journal[0].Binding != "/users" || journal[0].Query.Get("page") != "2" || journal[1].Status != http.StatusCreated {
+		t.Errorf("unexpected journal: %+v", journal)
+	}
+
+	s.ResetJournal()
+	if len(s.Journal()) != 0 {
+		t.Error("expected an empty journal after reset")
+	}
+
+	err = s.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Close()
+	if err != nil {
+		t.Errorf("unexpected error closing twice: %s", err)
+	}
+}
+
+func TestServer_Parallel(t *testing.T) {
+	t.Parallel()
+
+	for i := 0; i < 2; i++ {
+		t.Run("instance", func(t *testing.T) {
+			t.Parallel()
+
+			s, err := New(se.UnmarshalledRootSettings{WebListeners: []se.UnmarshalledRootSettingWebListener{{
+				ListenerName:    "api",
+				ContentBindings: []se.ResponseBinding{{Path: "/", ResponseCode: http.StatusOK, ResponseBody: "hello", ResponseBodyType: se.Inline}},
+			}}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { _ = s.Close() })
+
+			_, body := get(t, s.URL("api")+"/")
+			if body != "hello" {
+				t.Errorf("unexpected body: %s", body)
+			}
+		})
+	}
+}
+
+func TestServer_AutoTLS(t *testing.T) {
+	t.Parallel()
+
+	s, err := New(se.UnmarshalledRootSettings{WebListeners: []se.UnmarshalledRootSettingWebListener{{
+		ListenerName:    "secure",
+		EnableTLS:       true,
+		AutoTLS:         &se.AutoTLSSettings{},
+		ContentBindings: []se.ResponseBinding{{Path: "/", ResponseCode: http.StatusOK, ResponseBody: "secret", ResponseBodyType: se.Inline}},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+
+	url := s.URL("secure")
+	if !strings.HasPrefix(url, "https://") {
+		t.Fatalf("unexpected url: %s", url)
+	}
+
+	res, err := s.Client().Get(url + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "secret" {
+		t.Errorf("unexpected body: %s", b)
+	}
+}
@@ -0,0 +1,495 @@
+package protobuf
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+type FieldType int
+
+// As numbered in descriptor.proto.
+const (
+	TypeDouble   FieldType = 1
+	TypeFloat    FieldType = 2
+	TypeInt64    FieldType = 3
+	TypeUint64   FieldType = 4
+	TypeInt32    FieldType = 5
+	TypeFixed64  FieldType = 6
+	TypeFixed32  FieldType = 7
+	TypeBool     FieldType = 8
+	TypeString   FieldType = 9
+	TypeGroup    FieldType = 10
+	TypeMessage  FieldType = 11
+	TypeBytes    FieldType = 12
+	TypeUint32   FieldType = 13
+	TypeEnum     FieldType = 14
+	TypeSfixed32 FieldType = 15
+	TypeSfixed64 FieldType = 16
+	TypeSint32   FieldType = 17
+	TypeSint64   FieldType = 18
+)
+
+const labelRepeated = 3
+
+type Field struct {
+	Name     string
+	JSONName string
+	Number   int
+	Type     FieldType
+	TypeName string // Full name of the message or enum, without the leading dot
+	Repeated bool
+	Packed   bool // Repeated scalars written as one length delimited field
+	Optional bool // Explicit presence, proto3 optional or part of a oneof
+}
+
+type Message struct {
+	FullName string
+	Fields   []*Field
+	MapEntry bool // The key (1) and value (2) of a map field's entries
+}
+
+func (m *Message) FieldByNumber(number int) *Field {
+	for _, f := range m.Fields {
+		if f.Number == number {
+			return f
+		}
+	}
+
+	return nil
+}
+
+// By JSON name, or the name in the .proto as parsers also accept.
+func (m *Message) FieldByName(name string) *Field {
+	for _, f := range m.Fields {
+		if f.JSONName == name || f.Name == name {
+			return f
+		}
+	}
+
+	return nil
+}
+
+type EnumValue struct {
+	Name   string
+	Number int32
+}
+
+type Enum struct {
+	FullName string
+	Values   []EnumValue
+}
+
+type Method struct {
+	Name            string
+	FullName        string // package.Service.Method
+	Input           string // Full message names
+	Output          string
+	ClientStreaming bool
+	ServerStreaming bool
+}
+
+// The path gRPC calls the method on: /package.Service/Method.
+func (m *Method) Path() string {
+	i := strings.LastIndex(m.FullName, ".")
+	return "/" + m.FullName[:i] + "/" + m.FullName[i+1:]
+}
+
+type Service struct {
+	FullName string
+	Methods  []*Method
+}
+
+type File struct {
+	Name         string
+	Package      string
+	Syntax       string // proto2 or proto3, editions aren't told apart from proto2
+	Dependencies []string
+	Services     []*Service
+	Raw          []byte // The FileDescriptorProto as it was in the set
+}
+
+// Everything in a descriptor set, by full name.
+type Registry struct {
+	Files    []*File
+	Messages map[string]*Message
+	Enums    map[string]*Enum
+	Services map[string]*Service
+	symbols  map[string]*File // Every message, enum, service and method, to the file declaring it
+}
+
+// Finds a method by its gRPC path, /package.Service/Method.
+func (r *Registry) MethodByPath(path string) *Method {
+	service, name, ok := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	if !ok || r.Services[service] == nil {
+		return nil
+	}
+
+	for _, m := range r.Services[service].Methods {
+		if m.Name == name {
+			return m
+		}
+	}
+
+	return nil
+}
+
+func (r *Registry) FileByName(name string) *File {
+	for _, f := range r.Files {
+		if f.Name == name {
+			return f
+		}
+	}
+
+	return nil
+}
+
+func (r *Registry) FileContainingSymbol(symbol string) *File {
+	return r.symbols[strings.TrimPrefix(symbol, ".")]
+}
+
+// The service names, sorted.
+func (r *Registry) ServiceNames() []string {
+	names := make([]string, 0, len(r.Services))
+	for name := range r.Services {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	return names
+}
+
+// The file and everything it imports (that's in the set), dependencies after the files needing them.
+func (r *Registry) FileWithDependencies(f *File) []*File {
+	files := []*File{f}
+	for i := 0; i < len(files); i++ {
+		for _, dep := range files[i].Dependencies {
+			if d := r.FileByName(dep); d != nil && !slices.Contains(files, d) {
+				files = append(files, d)
+			}
+		}
+	}
+
+	return files
+}
+
+// Protoc's json name: underscores dropped, each letter after one upper cased.
+func jsonName(name string) string {
+	var b strings.Builder
+	upper := false
+	for _, c := range name {
+		if c == '_' {
+			upper = true
+			continue
+		}
+		if upper && c >= 'a' && c <= 'z' {
+			c -= 'a' - 'A'
+		}
+		upper = false
+		b.WriteRune(c)
+	}
+
+	return b.String()
+}
+
+func fieldString(f RawField) string {
+	return string(f.Bytes)
+}
+
+func (r *Registry) addSymbol(name string, file *File) {
+	r.symbols[name] = file
+}
+
+func (r *Registry) parseEnum(b []byte, scope string, file *File) error {
+	fields, err := ParseFields(b)
+	if err != nil {
+		return err
+	}
+
+	e := &Enum{}
+	for _, f := range fields {
+		switch f.Number {
+		case 1:
+			e.FullName = scope + fieldString(f)
+		case 2:
+			valueFields, err := ParseFields(f.Bytes)
+			if err != nil {
+				return err
+			}
+			v := EnumValue{}
+			for _, vf := range valueFields {
+				switch vf.Number {
+				case 1:
+					v.Name = fieldString(vf)
+				case 2:
+					v.Number = int32(vf.Value)
+				}
+			}
+			e.Values = append(e.Values, v)
+		}
+	}
+
+	r.Enums[e.FullName] = e
+	r.addSymbol(e.FullName, file)
+	return nil
+}
+
+func parseField(b []byte, syntax string) (*Field, error) {
+	fields, err := ParseFields(b)
+	if err != nil {
+		return nil, err
+	}
+
+	field := &Field{}
+	packed := syntax == "proto3"
+	for _, f := range fields {
+		switch f.Number {
+		case 1:
+			field.Name = fieldString(f)
+		case 3:
+			field.Number = int(f.Value)
+		case 4:
+			field.Repeated = f.Value == labelRepeated
+		case 5:
+			field.Type = FieldType(f.Value)
+		case 6:
+			field.TypeName = strings.TrimPrefix(fieldString(f), ".")
+		case 8: // FieldOptions
+			options, err := ParseFields(f.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			for _, o := range options {
+				if o.Number == 2 {
+					packed = o.Value != 0
+				}
+			}
+		case 9:
+			field.Optional = true // In a oneof
+		case 10:
+			field.JSONName = fieldString(f)
+		case 17:
+			field.Optional = f.Value != 0
+		}
+	}
+
+	if field.JSONName == "" {
+		field.JSONName = jsonName(field.Name)
+	}
+	if syntax != "proto3" && !field.Repeated {
+		field.Optional = true
+	}
+	switch field.Type {
+	case TypeString, TypeBytes, TypeMessage, TypeGroup:
+	default:
+		field.Packed = field.Repeated && packed
+	}
+
+	return field, nil
+}
+
+func (r *Registry) parseMessage(b []byte, scope string, file *File) error {
+	fields, err := ParseFields(b)
+	if err != nil {
+		return err
+	}
+
+	m := &Message{}
+	for _, f := range fields {
+		if f.Number == 1 {
+			m.FullName = scope + fieldString(f)
+		}
+	}
+
+	for _, f := range fields {
+		switch f.Number {
+		case 2:
+			field, err := parseField(f.Bytes, file.Syntax)
+			if err != nil {
+				return err
+			}
+			m.Fields = append(m.Fields, field)
+		case 3:
+			err = r.parseMessage(f.Bytes, m.FullName+".", file)
+		case 4:
+			err = r.parseEnum(f.Bytes, m.FullName+".", file)
+		case 7: // MessageOptions
+			options, err := ParseFields(f.Bytes)
+			if err != nil {
+				return err
+			}
+			for _, o := range options {
+				if o.Number == 7 {
+					m.MapEntry = o.Value != 0
+				}
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	r.Messages[m.FullName] = m
+	r.addSymbol(m.FullName, file)
+	return nil
+}
+
+func (r *Registry) parseService(b []byte, scope string, file *File) error {
+	fields, err := ParseFields(b)
+	if err != nil {
+		return err
+	}
+
+	s := &Service{}
+	for _, f := range fields {
+		if f.Number == 1 {
+			s.FullName = scope + fieldString(f)
+		}
+	}
+
+	for _, f := range fields {
+		if f.Number != 2 {
+			continue
+		}
+
+		methodFields, err := ParseFields(f.Bytes)
+		if err != nil {
+			return err
+		}
+		m := &Method{}
+		for _, mf := range methodFields {
+			switch mf.Number {
+			case 1:
+				m.Name = fieldString(mf)
+			case 2:
+				m.Input = strings.TrimPrefix(fieldString(mf), ".")
+			case 3:
+				m.Output = strings.TrimPrefix(fieldString(mf), ".")
+			case 5:
+				m.ClientStreaming = mf.Value != 0
+			case 6:
+				m.ServerStreaming = mf.Value != 0
+			}
+		}
+		m.FullName = s.FullName + "." + m.Name
+		s.Methods = append(s.Methods, m)
+		r.addSymbol(m.FullName, file)
+	}
+
+	file.Services = append(file.Services, s)
+	r.Services[s.FullName] = s
+	r.addSymbol(s.FullName, file)
+	return nil
+}
+
+func (r *Registry) parseFile(b []byte) error {
+	fields, err := ParseFields(b)
+	if err != nil {
+		return err
+	}
+
+	file := &File{Raw: b, Syntax: "proto2"}
+	for _, f := range fields {
+		switch f.Number {
+		case 1:
+			file.Name = fieldString(f)
+		case 2:
+			file.Package = fieldString(f)
+		case 3:
+			file.Dependencies = append(file.Dependencies, fieldString(f))
+		case 12:
+			file.Syntax = fieldString(f)
+		}
+	}
+
+	scope := ""
+	if file.Package != "" {
+		scope = file.Package + "."
+	}
+
+	for _, f := range fields {
+		switch f.Number {
+		case 4:
+			err = r.parseMessage(f.Bytes, scope, file)
+		case 5:
+			err = r.parseEnum(f.Bytes, scope, file)
+		case 6:
+			err = r.parseService(f.Bytes, scope, file)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", file.Name, err)
+		}
+	}
+
+	r.Files = append(r.Files, file)
+	return nil
+}
+
+// Makes sure every message and enum the fields and methods refer to is in the set, as they won't be without
+// --include_imports.
+func (r *Registry) check() error {
+	for _, m := range r.Messages {
+		for _, f := range m.Fields {
+			if f.Type == TypeMessage && r.Messages[f.TypeName] == nil || f.Type == TypeEnum && r.Enums[f.TypeName] == nil {
+				return fmt.Errorf("field %s.%s refers to \"%s\", which isn't in the set (was it built with --include_imports?)", m.FullName, f.Name, f.TypeName)
+			}
+		}
+	}
+
+	for _, s := range r.Services {
+		for _, m := range s.Methods {
+			if r.Messages[m.Input] == nil || r.Messages[m.Output] == nil {
+				return fmt.Errorf("method %s uses messages that aren't in the set (was it built with --include_imports?)", m.FullName)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Reads a serialized FileDescriptorSet.
+func ParseDescriptorSet(b []byte) (*Registry, error) {
+	r := &Registry{Messages: map[string]*Message{}, Enums: map[string]*Enum{}, Services: map[string]*Service{}, symbols: map[string]*File{}}
+
+	fields, err := ParseFields(b)
+	if err != nil {
+		return nil, fmt.Errorf("ParseDescriptorSet: %w", err)
+	}
+
+	for _, f := range fields {
+		if f.Number != 1 || f.WireType != wireBytes {
+			continue
+		}
+
+		err = r.parseFile(f.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("ParseDescriptorSet: %w", err)
+		}
+	}
+
+	if len(r.Files) == 0 {
+		return nil, fmt.Errorf("ParseDescriptorSet: the set has no files")
+	}
+
+	err = r.check()
+	if err != nil {
+		return nil, fmt.Errorf("ParseDescriptorSet: %w", err)
+	}
+
+	return r, nil
+}
+
+func LoadDescriptorSet(fileName string) (*Registry, error) {
+	b, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("LoadDescriptorSet: %w", err)
+	}
+
+	r, err := ParseDescriptorSet(b)
+	if err != nil {
+		return nil, fmt.Errorf("LoadDescriptorSet: %s: %w", fileName, err)
+	}
+
+	return r, nil
+}
@@ -0,0 +1,692 @@
+package protobuf
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Encodes v, a message in its JSON form as encoding/json (or yaml) decodes it, as the named message. Field names may be
+// JSON or .proto names, 64 bit integers strings or numbers, and enums names or numbers, as the proto3 JSON mapping
+// allows. Timestamps, durations, wrappers and structs take their special JSON forms.
+func (r *Registry) Marshal(messageName string, v any) ([]byte, error) {
+	b, err := r.marshalMessage(nil, messageName, v)
+	if err != nil {
+		return nil, fmt.Errorf("Registry.Marshal: %w", err)
+	}
+
+	return b, nil
+}
+
+func (r *Registry) marshalMessage(b []byte, messageName string, v any) ([]byte, error) {
+	if wkt, ok := wellKnownMarshalers[messageName]; ok {
+		return wkt(r, b, v)
+	}
+
+	m := r.Messages[messageName]
+	if m == nil {
+		return nil, fmt.Errorf("unknown message \"%s\"", messageName)
+	}
+
+	object, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s: expected an object, got %T", messageName, v)
+	}
+
+	for key := range object {
+		if m.FieldByName(key) == nil {
+			return nil, fmt.Errorf("%s has no field \"%s\"", messageName, key)
+		}
+	}
+
+	for _, f := range m.Fields {
+		value, ok := object[f.JSONName]
+		if !ok {
+			value, ok = object[f.Name]
+		}
+		if !ok || value == nil && f.TypeName != "google.protobuf.Value" {
+			continue
+		}
+
+		var err error
+		b, err = r.marshalField(b, f, value)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", messageName, f.Name, err)
+		}
+	}
+
+	return b, nil
+}
+
+func (r *Registry) marshalField(b []byte, f *Field, v any) ([]byte, error) {
+	if f.Type == TypeMessage && r.Messages[f.TypeName] != nil && r.Messages[f.TypeName].MapEntry {
+		return r.marshalMap(b, f, v)
+	}
+
+	if !f.Repeated {
+		return r.marshalSingle(b, f, v)
+	}
+
+	list, ok := v.([]any)
+	if !ok {
+		return nil, fmt.Errorf("expected a list, got %T", v)
+	}
+
+	if f.Packed {
+		var packed []byte
+		for _, item := range list {
+			var err error
+			packed, err = r.appendScalar(packed, f, item)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return AppendBytesField(b, f.Number, packed), nil
+	}
+
+	for _, item := range list {
+		var err error
+		b, err = r.marshalSingle(b, f, item)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return b, nil
+}
+
+func (r *Registry) marshalMap(b []byte, f *Field, v any) ([]byte, error) {
+	object, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("expected an object for a map, got %T", v)
+	}
+
+	entry := r.Messages[f.TypeName]
+	keyField, valueField := entry.FieldByNumber(1), entry.FieldByNumber(2)
+
+	keys := make([]string, 0, len(object))
+	for k := range object {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys) // Same input, same bytes
+
+	for _, k := range keys {
+		var key any = k
+		if keyField.Type == TypeBool {
+			key = k == "true"
+		}
+
+		e, err := r.marshalSingle(nil, keyField, key)
+		if err != nil {
+			return nil, fmt.Errorf("key \"%s\": %w", k, err)
+		}
+		e, err = r.marshalSingle(e, valueField, object[k])
+		if err != nil {
+			return nil, fmt.Errorf("key \"%s\": %w", k, err)
+		}
+		b = AppendBytesField(b, f.Number, e)
+	}
+
+	return b, nil
+}
+
+func wireTypeOf(t FieldType) int {
+	switch t {
+	case TypeDouble, TypeFixed64, TypeSfixed64:
+		return wireFixed64
+	case TypeFloat, TypeFixed32, TypeSfixed32:
+		return wireFixed32
+	case TypeString, TypeBytes, TypeMessage:
+		return wireBytes
+	}
+
+	return wireVarint
+}
+
+func (r *Registry) marshalSingle(b []byte, f *Field, v any) ([]byte, error) {
+	switch f.Type {
+	case TypeMessage:
+		message, err := r.marshalMessage(nil, f.TypeName, v)
+		if err != nil {
+			return nil, err
+		}
+		return AppendBytesField(b, f.Number, message), nil
+	case TypeString:
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a string, got %T", v)
+		}
+		return AppendBytesField(b, f.Number, []byte(s)), nil
+	case TypeBytes:
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected base64 encoded bytes, got %T", v)
+		}
+		decoded, err := decodeBase64(s)
+		if err != nil {
+			return nil, err
+		}
+		return AppendBytesField(b, f.Number, decoded), nil
+	case TypeGroup:
+		return nil, fmt.Errorf("groups aren't supported")
+	}
+
+	return r.appendScalar(AppendTag(b, f.Number, wireTypeOf(f.Type)), f, v)
+}
+
+func decodeBase64(s string) ([]byte, error) {
+	s = strings.TrimRight(s, "=")
+	if strings.ContainsAny(s, "-_") {
+		return base64.RawURLEncoding.DecodeString(s)
+	}
+
+	return base64.RawStdEncoding.DecodeString(s)
+}
+
+// Appends a number, bool or enum without its tag.
+func (r *Registry) appendScalar(b []byte, f *Field, v any) ([]byte, error) {
+	switch f.Type {
+	case TypeBool:
+		bv, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("expected a bool, got %T", v)
+		}
+		if bv {
+			return AppendVarint(b, 1), nil
+		}
+		return AppendVarint(b, 0), nil
+	case TypeEnum:
+		n, err := r.enumNumber(f.TypeName, v)
+		if err != nil {
+			return nil, err
+		}
+		return AppendVarint(b, uint64(int64(n))), nil
+	case TypeDouble:
+		d, err := toFloat(v)
+		return binary.LittleEndian.AppendUint64(b, math.Float64bits(d)), err
+	case TypeFloat:
+		d, err := toFloat(v)
+		return binary.LittleEndian.AppendUint32(b, uint32(float32bits(d))), err
+	}
+
+	switch f.Type {
+	case TypeUint64, TypeFixed64:
+		u, err := toUint(v, math.MaxUint64)
+		if f.Type == TypeFixed64 {
+			return binary.LittleEndian.AppendUint64(b, u), err
+		}
+		return AppendVarint(b, u), err
+	case TypeUint32, TypeFixed32:
+		u, err := toUint(v, math.MaxUint32)
+		if f.Type == TypeFixed32 {
+			return binary.LittleEndian.AppendUint32(b, uint32(u)), err
+		}
+		return AppendVarint(b, u), err
+	}
+
+	bits := 64
+	if f.Type == TypeInt32 || f.Type == TypeSint32 || f.Type == TypeSfixed32 {
+		bits = 32
+	}
+	i, err := toInt(v, bits)
+	if err != nil {
+		return nil, err
+	}
+
+	switch f.Type {
+	case TypeSint32, TypeSint64:
+		return AppendVarint(b, zigzag(i)), nil
+	case TypeSfixed32:
+		return binary.LittleEndian.AppendUint32(b, uint32(int32(i))), nil
+	case TypeSfixed64:
+		return binary.LittleEndian.AppendUint64(b, uint64(i)), nil
+	}
+
+	return AppendVarint(b, uint64(i)), nil // Negative int32s take ten bytes too, as the spec says
+}
+
+func (r *Registry) enumNumber(enumName string, v any) (int32, error) {
+	e := r.Enums[enumName]
+	if e == nil {
+		return 0, fmt.Errorf("unknown enum \"%s\"", enumName)
+	}
+
+	if name, ok := v.(string); ok {
+		for _, value := range e.Values {
+			if value.Name == name {
+				return value.Number, nil
+			}
+		}
+		return 0, fmt.Errorf("%s has no value \"%s\"", enumName, name)
+	}
+
+	i, err := toInt(v, 32)
+	return int32(i), err
+}
+
+func toFloat(v any) (float64, error) {
+	switch n := v.(type) {
+	case float64:
+		return n, nil
+	case int:
+		return float64(n), nil
+	case int64:
+		return float64(n), nil
+	case uint64:
+		return float64(n), nil
+	case json.Number:
+		return n.Float64()
+	case string:
+		switch n {
+		case "NaN":
+			return math.NaN(), nil
+		case "Infinity":
+			return math.Inf(1), nil
+		case "-Infinity":
+			return math.Inf(-1), nil
+		}
+		return strconv.ParseFloat(n, 64)
+	}
+
+	return 0, fmt.Errorf("expected a number, got %T", v)
+}
+
+func toInt(v any, bits int) (int64, error) {
+	var i int64
+	switch n := v.(type) {
+	case float64:
+		if n != math.Trunc(n) {
+			return 0, fmt.Errorf("expected an integer, got %v", n)
+		}
+		i = int64(n)
+	case int:
+		i = int64(n)
+	case int64:
+		i = n
+	case json.Number:
+		return strconv.ParseInt(n.String(), 10, bits)
+	case string:
+		return strconv.ParseInt(n, 10, bits)
+	default:
+		return 0, fmt.Errorf("expected an integer, got %T", v)
+	}
+
+	if bits == 32 && (i < math.MinInt32 || i > math.MaxInt32) {
+		return 0, fmt.Errorf("%d is out of range", i)
+	}
+
+	return i, nil
+}
+
+func toUint(v any, max uint64) (uint64, error) {
+	if u, ok := v.(uint64); ok && u <= max {
+		return u, nil // yaml's numbers past MaxInt64
+	}
+	if s, ok := v.(string); ok {
+		u, err := strconv.ParseUint(s, 10, 64)
+		if err == nil && u > max {
+			err = fmt.Errorf("%d is out of range", u)
+		}
+		return u, err
+	}
+
+	i, err := toInt(v, 64)
+	if err != nil {
+		return 0, err
+	}
+	if i < 0 || uint64(i) > max {
+		return 0, fmt.Errorf("%d is out of range", i)
+	}
+
+	return uint64(i), nil
+}
+
+// How deeply messages may nest before Unmarshal gives up, as protobuf-go does. Recursive message types would otherwise
+// let a small request exhaust the stack.
+const maxNestingDepth = 100
+
+var errTooDeep = fmt.Errorf("messages nest more than %d deep", maxNestingDepth)
+
+// Decodes b as the named message into its JSON form, the way Marshal takes it. Fields that weren't set show their
+// defaults (unless they have explicit presence), so matching on false or 0 works.
+func (r *Registry) Unmarshal(messageName string, b []byte) (any, error) {
+	v, err := r.unmarshalMessage(messageName, b, 0)
+	if err != nil {
+		return nil, fmt.Errorf("Registry.Unmarshal: %w", err)
+	}
+
+	return v, nil
+}
+
+func (r *Registry) unmarshalMessage(messageName string, b []byte, depth int) (any, error) {
+	if depth > maxNestingDepth {
+		return nil, errTooDeep
+	}
+
+	m := r.Messages[messageName]
+	if m == nil {
+		return nil, fmt.Errorf("unknown message \"%s\"", messageName)
+	}
+
+	raw, err := ParseFields(b)
+	if err != nil {
+		return nil, err
+	}
+
+	object := map[string]any{}
+	for _, f := range m.Fields {
+		isMap := f.Type == TypeMessage && r.Messages[f.TypeName].MapEntry
+		var value any
+		var values []any
+		entries := map[string]any{}
+		set := false
+
+		for _, rf := range raw {
+			if rf.Number != f.Number {
+				continue
+			}
+			set = true
+
+			if f.Repeated && rf.WireType == wireBytes && wireTypeOf(f.Type) != wireBytes {
+				items, err := unpack(f, rf.Bytes)
+				if err != nil {
+					return nil, fmt.Errorf("%s.%s: %w", messageName, f.Name, err)
+				}
+				for _, item := range items {
+					values = append(values, r.scalarValue(f, item))
+				}
+				continue
+			}
+
+			v, err := r.unmarshalSingle(f, rf, depth)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", messageName, f.Name, err)
+			}
+
+			switch {
+			case isMap:
+				entry := v.(map[string]any)
+				entries[fmt.Sprint(entry["key"])] = entry["value"]
+			case f.Repeated:
+				values = append(values, v)
+			case f.Type == TypeMessage && value != nil:
+				value = mergeObjects(value, v) // Repeated occurrences of a message merge
+			default:
+				value = v
+			}
+		}
+
+		switch {
+		case isMap:
+			object[f.JSONName] = entries
+		case f.Repeated:
+			if values == nil {
+				values = []any{}
+			}
+			object[f.JSONName] = values
+		case set:
+			object[f.JSONName] = value
+		case f.Type != TypeMessage && !f.Optional:
+			object[f.JSONName] = r.defaultValue(f)
+		}
+	}
+
+	if unmarshal, ok := wellKnownUnmarshalers[messageName]; ok {
+		return unmarshal(object)
+	}
+
+	return object, nil
+}
+
+func mergeObjects(a any, b any) any {
+	ao, aok := a.(map[string]any)
+	bo, bok := b.(map[string]any)
+	if !aok || !bok {
+		return b
+	}
+
+	for k, v := range bo {
+		ao[k] = v
+	}
+	return ao
+}
+
+func unpack(f *Field, b []byte) ([]uint64, error) {
+	var values []uint64
+
+	for len(b) > 0 {
+		switch wireTypeOf(f.Type) {
+		case wireFixed64:
+			if len(b) < 8 {
+				return nil, errTruncated
+			}
+			values, b = append(values, binary.LittleEndian.Uint64(b)), b[8:]
+		case wireFixed32:
+			if len(b) < 4 {
+				return nil, errTruncated
+			}
+			values, b = append(values, uint64(binary.LittleEndian.Uint32(b))), b[4:]
+		default:
+			v, n, err := readVarint(b)
+			if err != nil {
+				return nil, err
+			}
+			values, b = append(values, v), b[n:]
+		}
+	}
+
+	return values, nil
+}
+
+func (r *Registry) unmarshalSingle(f *Field, rf RawField, depth int) (any, error) {
+	switch f.Type {
+	case TypeMessage:
+		return r.unmarshalMessage(f.TypeName, rf.Bytes, depth+1)
+	case TypeString:
+		return string(rf.Bytes), nil
+	case TypeBytes:
+		return base64.StdEncoding.EncodeToString(rf.Bytes), nil
+	case TypeGroup:
+		return nil, fmt.Errorf("groups aren't supported")
+	}
+
+	return r.scalarValue(f, rf.Value), nil
+}
+
+// A number, bool or enum as JSON shows it: 64 bit integers as strings, enums by name where the number is known.
+func (r *Registry) scalarValue(f *Field, v uint64) any {
+	switch f.Type {
+	case TypeBool:
+		return v != 0
+	case TypeEnum:
+		for _, value := range r.Enums[f.TypeName].Values {
+			if value.Number == int32(v) {
+				return value.Name
+			}
+		}
+		return float64(int32(v))
+	case TypeDouble:
+		return jsonFloat(math.Float64frombits(v))
+	case TypeFloat:
+		return jsonFloat(float64(math.Float32frombits(uint32(v))))
+	case TypeInt32, TypeSfixed32:
+		return float64(int32(v))
+	case TypeSint32:
+		return float64(int32(unzigzag(v)))
+	case TypeUint32, TypeFixed32:
+		return float64(uint32(v))
+	case TypeInt64, TypeSfixed64:
+		return strconv.FormatInt(int64(v), 10)
+	case TypeSint64:
+		return strconv.FormatInt(unzigzag(v), 10)
+	}
+
+	return strconv.FormatUint(v, 10) // uint64, fixed64
+}
+
+func jsonFloat(f float64) any {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	}
+
+	return f
+}
+
+func (r *Registry) defaultValue(f *Field) any {
+	if f.Type == TypeString || f.Type == TypeBytes {
+		return ""
+	}
+
+	return r.scalarValue(f, 0)
+}
+
+var wrapperTypes = []string{
+	"google.protobuf.DoubleValue", "google.protobuf.FloatValue", "google.protobuf.Int64Value", "google.protobuf.UInt64Value",
+	"google.protobuf.Int32Value", "google.protobuf.UInt32Value", "google.protobuf.BoolValue", "google.protobuf.StringValue",
+	"google.protobuf.BytesValue",
+}
+
+// Well known types with JSON forms of their own. They're encoded through their descriptors, which protoc includes in
+// sets built with --include_imports.
+var (
+	wellKnownMarshalers   = map[string]func(r *Registry, b []byte, v any) ([]byte, error){}
+	wellKnownUnmarshalers = map[string]func(object map[string]any) (any, error){}
+)
+
+func init() {
+	wellKnownMarshalers["google.protobuf.Timestamp"] = func(r *Registry, b []byte, v any) ([]byte, error) {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected an RFC 3339 timestamp, got %T", v)
+		}
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil, err
+		}
+		return appendSecondsNanos(b, t.Unix(), int64(t.Nanosecond())), nil
+	}
+	wellKnownUnmarshalers["google.protobuf.Timestamp"] = func(object map[string]any) (any, error) {
+		seconds, nanos := secondsNanos(object)
+		return time.Unix(seconds, nanos).UTC().Format(timestampLayout(nanos)), nil
+	}
+
+	wellKnownMarshalers["google.protobuf.Duration"] = func(r *Registry, b []byte, v any) ([]byte, error) {
+		s, ok := v.(string)
+		if !ok || !strings.HasSuffix(s, "s") {
+			return nil, fmt.Errorf("expected a duration in seconds such as \"1.5s\", got %v", v)
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, err
+		}
+		return appendSecondsNanos(b, int64(d/time.Second), int64(d%time.Second)), nil
+	}
+	wellKnownUnmarshalers["google.protobuf.Duration"] = func(object map[string]any) (any, error) {
+		seconds, nanos := secondsNanos(object)
+		return strconv.FormatFloat((time.Duration(seconds)*time.Second+time.Duration(nanos)).Seconds(), 'f', -1, 64) + "s", nil
+	}
+
+	for _, name := range wrapperTypes {
+		wellKnownMarshalers[name] = func(r *Registry, b []byte, v any) ([]byte, error) {
+			return r.marshalWrapped(b, name, v)
+		}
+		wellKnownUnmarshalers[name] = func(object map[string]any) (any, error) {
+			return object["value"], nil
+		}
+	}
+
+	wellKnownMarshalers["google.protobuf.Struct"] = func(r *Registry, b []byte, v any) ([]byte, error) {
+		return r.marshalWrapped(b, "google.protobuf.Struct", v)
+	}
+	wellKnownUnmarshalers["google.protobuf.Struct"] = func(object map[string]any) (any, error) {
+		return object["fields"], nil
+	}
+
+	wellKnownMarshalers["google.protobuf.ListValue"] = func(r *Registry, b []byte, v any) ([]byte, error) {
+		return r.marshalWrapped(b, "google.protobuf.ListValue", v)
+	}
+	wellKnownUnmarshalers["google.protobuf.ListValue"] = func(object map[string]any) (any, error) {
+		return object["values"], nil
+	}
+
+	wellKnownMarshalers["google.protobuf.Value"] = func(r *Registry, b []byte, v any) ([]byte, error) {
+		switch value := v.(type) {
+		case nil:
+			return AppendVarintField(b, 1, 0), nil
+		case float64, int, int64, json.Number:
+			f, _ := toFloat(value)
+			return binary.LittleEndian.AppendUint64(AppendTag(b, 2, wireFixed64), math.Float64bits(f)), nil
+		case string:
+			return AppendBytesField(b, 3, []byte(value)), nil
+		case bool:
+			return r.appendScalar(AppendTag(b, 4, wireVarint), &Field{Type: TypeBool}, value)
+		case map[string]any:
+			s, err := r.marshalMessage(nil, "google.protobuf.Struct", value)
+			return AppendBytesField(b, 5, s), err
+		case []any:
+			l, err := r.marshalMessage(nil, "google.protobuf.ListValue", value)
+			return AppendBytesField(b, 6, l), err
+		}
+		return nil, fmt.Errorf("can't hold a %T in a google.protobuf.Value", v)
+	}
+	wellKnownUnmarshalers["google.protobuf.Value"] = func(object map[string]any) (any, error) {
+		for _, k := range []string{"numberValue", "stringValue", "boolValue", "structValue", "listValue"} {
+			if v, ok := object[k]; ok {
+				return v, nil
+			}
+		}
+		return nil, nil
+	}
+}
+
+// The mapping wants 0, 3, 6 or 9 fractional digits, whichever is the fewest that keeps the nanoseconds.
+func timestampLayout(nanos int64) string {
+	switch {
+	case nanos == 0:
+		return "2006-01-02T15:04:05Z07:00"
+	case nanos%1e6 == 0:
+		return "2006-01-02T15:04:05.000Z07:00"
+	case nanos%1e3 == 0:
+		return "2006-01-02T15:04:05.000000Z07:00"
+	}
+	return "2006-01-02T15:04:05.000000000Z07:00"
+}
+
+// Encodes v as the first field of a message that stands in for it in JSON: wrappers, Struct and ListValue.
+func (r *Registry) marshalWrapped(b []byte, messageName string, v any) ([]byte, error) {
+	m := r.Messages[messageName]
+	if m == nil || m.FieldByNumber(1) == nil {
+		return nil, fmt.Errorf("%s isn't in the set", messageName)
+	}
+
+	return r.marshalField(b, m.FieldByNumber(1), v)
+}
+
+func appendSecondsNanos(b []byte, seconds int64, nanos int64) []byte {
+	if seconds != 0 {
+		b = AppendVarintField(b, 1, uint64(seconds))
+	}
+	if nanos != 0 {
+		b = AppendVarintField(b, 2, uint64(nanos))
+	}
+
+	return b
+}
+
+func secondsNanos(object map[string]any) (int64, int64) {
+	seconds, _ := strconv.ParseInt(fmt.Sprint(object["seconds"]), 10, 64)
+	nanos, _ := object["nanos"].(float64)
+
+	return seconds, int64(nanos)
+}
@@ -0,0 +1,231 @@
+package protobuf
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func loadTestRegistry(t *testing.T) *Registry {
+	t.Helper()
+
+	r, err := LoadDescriptorSet("testdata/greet.pb")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return r
+}
+
+func TestLoadDescriptorSet(t *testing.T) {
+	t.Parallel()
+
+	r := loadTestRegistry(t)
+
+	if got := strings.Join(r.ServiceNames(), ","); got != "greet.v1.Greeter" {
+		t.Errorf("unexpected services: %s", got)
+	}
+
+	m := r.MethodByPath("/greet.v1.Greeter/SayHellos")
+	if m == nil || !m.ServerStreaming || m.ClientStreaming || m.Input != "greet.v1.HelloRequest" {
+		t.Fatalf("unexpected method: %+v", m)
+	}
+	if m.Path() != "/greet.v1.Greeter/SayHellos" {
+		t.Errorf("unexpected path: %s", m.Path())
+	}
+	if r.MethodByPath("/greet.v1.Greeter/Missing") != nil {
+		t.Error("found a method that isn't there")
+	}
+
+	if !r.Messages["greet.v1.HelloReply.LabelsEntry"].MapEntry {
+		t.Error("map entry not recognised")
+	}
+	if f := r.Messages["greet.v1.HelloReply"].FieldByName("counts"); f == nil || !f.Packed {
+		t.Errorf("repeated proto3 scalars should be packed: %+v", f)
+	}
+	if f := r.Messages["greet.v1.HelloReply"].FieldByName("payload"); f == nil || !f.Optional {
+		t.Errorf("proto3 optional not recognised: %+v", f)
+	}
+
+	f := r.FileContainingSymbol("greet.v1.Greeter.SayHello")
+	if f == nil || f.Name != "greet.proto" {
+		t.Fatalf("unexpected file: %+v", f)
+	}
+	files := r.FileWithDependencies(f)
+	if len(files) != 2 || files[1].Name != "google/protobuf/timestamp.proto" {
+		t.Errorf("unexpected dependencies: %d files", len(files))
+	}
+}
+
+func TestParseDescriptorSet_Invalid(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		set  []byte
+		want string
+	}{
+		{name: "empty", set: nil, want: "the set has no files"},
+		{name: "truncated", set: []byte{0x0a, 0x10, 0x01}, want: "truncated message"},
+		{
+			name: "missing imports",
+			set: AppendBytesField(nil, 1, AppendBytesField(AppendBytesField(nil, 1, []byte("a.proto")), 4,
+				AppendBytesField(AppendBytesField(nil, 1, []byte("A")), 2,
+					AppendBytesField(AppendVarintField(AppendVarintField(AppendBytesField(nil, 1, []byte("b")), 3, 1), 5, 11), 6, []byte(".other.B"))))),
+			want: "--include_imports",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := ParseDescriptorSet(tc.set)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("expected an error containing %q, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestRegistry_MarshalUnmarshal(t *testing.T) {
+	t.Parallel()
+
+	r := loadTestRegistry(t)
+
+	testCases := []struct {
+		name    string
+		message string
+		in      string
+		want    string
+	}{
+		{
+			name:    "defaults",
+			message: "greet.v1.HelloRequest",
+			in:      `{}`,
+			want:    `{"name":"","times":0}`,
+		},
+		{
+			name:    "proto names",
+			message: "greet.v1.HelloRequest",
+			in:      `{"name":"ada","times":3}`,
+			want:    `{"name":"ada","times":3}`,
+		},
+		{
+			name:    "everything",
+			message: "greet.v1.HelloReply",
+			in:      `{"message":"hi","mood":"MOOD_GRUMPY","counts":["1",-2,9007199254740993],"labels":{"a":"b"},"at":"2024-01-02T03:04:05.5Z","payload":"aGk="}`,
+			want:    `{"message":"hi","mood":"MOOD_GRUMPY","counts":["1","-2","9007199254740993"],"labels":{"a":"b"},"at":"2024-01-02T03:04:05.500Z","payload":"aGk="}`,
+		},
+		{
+			name:    "enum by number",
+			message: "greet.v1.HelloReply",
+			in:      `{"mood":1}`,
+			want:    `{"message":"","mood":"MOOD_HAPPY","counts":[],"labels":{}}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			d := json.NewDecoder(strings.NewReader(tc.in))
+			d.UseNumber()
+			var in any
+			if err := d.Decode(&in); err != nil {
+				t.Fatal(err)
+			}
+
+			b, err := r.Marshal(tc.message, in)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			out, err := r.Unmarshal(tc.message, b)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, _ := json.Marshal(out)
+			var want bytes.Buffer
+			_ = json.Compact(&want, []byte(tc.want))
+			if !jsonEqual(got, want.Bytes()) {
+				t.Errorf("expected %s, got %s", tc.want, got)
+			}
+		})
+	}
+}
+
+func jsonEqual(a []byte, b []byte) bool {
+	var av, bv any
+	if json.Unmarshal(a, &av) != nil || json.Unmarshal(b, &bv) != nil {
+		return false
+	}
+	ab, _ := json.Marshal(av)
+	bb, _ := json.Marshal(bv)
+	return bytes.Equal(ab, bb)
+}
+
+func TestRegistry_Marshal_Invalid(t *testing.T) {
+	t.Parallel()
+
+	r := loadTestRegistry(t)
+
+	testCases := []struct {
+		name string
+		in   any
+		want string
+	}{
+		{name: "unknown field", in: map[string]any{"nope": 1}, want: "nope"},
+		{name: "wrong type", in: map[string]any{"message": 1}, want: "message"},
+		{name: "unknown enum", in: map[string]any{"mood": "MOOD_SLEEPY"}, want: "MOOD_SLEEPY"},
+		{name: "bad bytes", in: map[string]any{"payload": "!!"}, want: "payload"},
+		{name: "bad timestamp", in: map[string]any{"at": "yesterday"}, want: "at"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := r.Marshal("greet.v1.HelloReply", tc.in)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("expected an error containing %q, got %v", tc.want, err)
+			}
+		})
+	}
+
+	_, err := r.Marshal("greet.v1.Missing", map[string]any{})
+	if err == nil || !strings.Contains(err.Error(), "greet.v1.Missing") {
+		t.Errorf("expected an unknown message error, got %v", err)
+	}
+}
+
+func TestRegistry_Unmarshal_Depth(t *testing.T) {
+	t.Parallel()
+
+	// message R { R r = 1; }
+	r := &Registry{Messages: map[string]*Message{
+		"R": {FullName: "R", Fields: []*Field{{Name: "r", JSONName: "r", Number: 1, Type: TypeMessage, TypeName: "R"}}},
+	}}
+	nested := func(depth int) []byte {
+		var b []byte
+		for range depth {
+			b = AppendBytesField(nil, 1, b)
+		}
+		return b
+	}
+
+	_, err := r.Unmarshal("R", nested(maxNestingDepth))
+	if err != nil {
+		t.Fatalf("unexpected error at the limit: %v", err)
+	}
+
+	_, err = r.Unmarshal("R", nested(maxNestingDepth+50))
+	if err == nil || !strings.Contains(err.Error(), "nest more than") {
+		t.Fatalf("expected a nesting error, got %v", err)
+	}
+}
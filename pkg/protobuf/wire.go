@@ -0,0 +1,170 @@
+// Package protobuf reads compiled descriptor sets (protoc --descriptor_set_out) and converts messages they describe
+// between the protobuf wire format and their JSON form, without generated code.
+package protobuf
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+const (
+	wireVarint     = 0
+	wireFixed64    = 1
+	wireBytes      = 2
+	wireStartGroup = 3
+	wireEndGroup   = 4
+	wireFixed32    = 5
+)
+
+var errTruncated = errors.New("truncated message")
+
+// A field as it was read off the wire, before anything knows what it means.
+type RawField struct {
+	Number   int
+	WireType int
+	Value    uint64 // Varints and fixed width values
+	Bytes    []byte // Length delimited values and groups
+}
+
+func AppendVarint(b []byte, v uint64) []byte {
+	return binary.AppendUvarint(b, v)
+}
+
+func AppendTag(b []byte, number int, wireType int) []byte {
+	return AppendVarint(b, uint64(number)<<3|uint64(wireType))
+}
+
+// Appends a length delimited field: strings, bytes, nested messages and packed values.
+func AppendBytesField(b []byte, number int, v []byte) []byte {
+	b = AppendTag(b, number, wireBytes)
+	b = AppendVarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+func AppendVarintField(b []byte, number int, v uint64) []byte {
+	return AppendVarint(AppendTag(b, number, wireVarint), v)
+}
+
+func readVarint(b []byte) (uint64, int, error) {
+	v, n := binary.Uvarint(b)
+	if n <= 0 {
+		return 0, 0, errTruncated
+	}
+
+	return v, n, nil
+}
+
+// Splits a message into its fields, in the order they were written.
+func ParseFields(b []byte) ([]RawField, error) {
+	var fields []RawField
+
+	for len(b) > 0 {
+		tag, n, err := readVarint(b)
+		if err != nil {
+			return nil, fmt.Errorf("ParseFields: %w", err)
+		}
+		b = b[n:]
+
+		f := RawField{Number: int(tag >> 3), WireType: int(tag & 7)}
+		if f.Number <= 0 {
+			return nil, fmt.Errorf("ParseFields: invalid field number %d", f.Number)
+		}
+
+		switch f.WireType {
+		case wireVarint:
+			f.Value, n, err = readVarint(b)
+		case wireFixed64:
+			if len(b) < 8 {
+				err = errTruncated
+				break
+			}
+			f.Value, n = binary.LittleEndian.Uint64(b), 8
+		case wireFixed32:
+			if len(b) < 4 {
+				err = errTruncated
+				break
+			}
+			f.Value, n = uint64(binary.LittleEndian.Uint32(b)), 4
+		case wireBytes:
+			var length uint64
+			length, n, err = readVarint(b)
+			if err == nil && uint64(len(b)-n) < length {
+				err = errTruncated
+			}
+			if err == nil {
+				f.Bytes = b[n : n+int(length)]
+				n += int(length)
+			}
+		case wireStartGroup:
+			n, err = skipGroup(b, f.Number)
+			if err == nil {
+				f.Bytes = b[:n]
+			}
+		default:
+			err = fmt.Errorf("unexpected wire type %d", f.WireType)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ParseFields: field %d: %w", f.Number, err)
+		}
+
+		b = b[n:]
+		fields = append(fields, f)
+	}
+
+	return fields, nil
+}
+
+// Length of a (deprecated) group, up to and including its end tag.
+func skipGroup(b []byte, number int) (int, error) {
+	read := 0
+	for {
+		tag, n, err := readVarint(b[read:])
+		if err != nil {
+			return 0, err
+		}
+		read += n
+
+		switch int(tag & 7) {
+		case wireEndGroup:
+			if int(tag>>3) != number {
+				return 0, errors.New("mismatched end group")
+			}
+			return read, nil
+		case wireVarint:
+			_, n, err = readVarint(b[read:])
+		case wireFixed64:
+			n = 8
+		case wireFixed32:
+			n = 4
+		case wireBytes:
+			var length uint64
+			length, n, err = readVarint(b[read:])
+			n += int(length)
+		case wireStartGroup:
+			n, err = skipGroup(b[read:], int(tag>>3))
+		default:
+			err = fmt.Errorf("unexpected wire type %d", tag&7)
+		}
+		if err != nil {
+			return 0, err
+		}
+		if read+n > len(b) {
+			return 0, errTruncated
+		}
+		read += n
+	}
+}
+
+func zigzag(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
+
+func unzigzag(v uint64) int64 {
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func float32bits(f float64) uint64 {
+	return uint64(math.Float32bits(float32(f)))
+}
@@ -0,0 +1,316 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	se "github.com/nrexception/mockapi/pkg/settings"
+)
+
+const redactedValue = "[REDACTED]"
+
+// Writes one line per request handled by a listener, in the format chosen by its access log settings.
+type accessLogger struct {
+	settings    se.AccessLogSettings
+	redact      map[string]bool // Canonical header names
+	redactQuery map[string]bool // Query parameters masked in the logged request uri
+	maxBody     int
+
+	mu     sync.Mutex
+	out    io.Writer
+	file   *os.File
+	users  int  // Requests holding the logger, see acquire
+	closed bool // The file is closed once closed is set and the last user lets go
+}
+
+// Where API keys can be presented to a listener, by header or query parameter, from its auth and its bindings'.
+type apiKeyLocations struct {
+	headers []string
+	queries []string
+}
+
+func listenerAPIKeyLocations(ls se.UnmarshalledRootSettingWebListener) apiKeyLocations {
+	var locations apiKeyLocations
+	add := func(auth *se.AuthSettings) {
+		if auth == nil || auth.APIKeys == nil {
+			return
+		}
+		if name := auth.APIKeys.HeaderName(); name != "" {
+			locations.headers = append(locations.headers, name)
+		}
+		if auth.APIKeys.Query != "" {
+			locations.queries = append(locations.queries, auth.APIKeys.Query)
+		}
+	}
+
+	add(ls.Auth)
+	for _, b := range ls.ContentBindings {
+		add(b.Auth)
+	}
+
+	return locations
+}
+
+// Logs as settings say, keeping the API keys presented at apiKeys out of the log whatever headers are redacted.
+func newAccessLogger(settings se.AccessLogSettings, apiKeys apiKeyLocations) (*accessLogger, error) {
+	a := &accessLogger{settings: settings, redact: map[string]bool{}, redactQuery: map[string]bool{}, maxBody: settings.MaxBodyBytes}
+
+	if a.maxBody == 0 {
+		a.maxBody = se.DefaultAccessLogMaxBodyBytes
+	}
+
+	redactHeaders := settings.RedactHeaders
+	if len(redactHeaders) == 0 {
+		redactHeaders = se.DefaultRedactedHeaders
+	}
+	for _, h := range append(slices.Clone(redactHeaders), apiKeys.headers...) {
+		a.redact[http.CanonicalHeaderKey(h)] = true
+	}
+	for _, q := range apiKeys.queries {
+		a.redactQuery[q] = true
+	}
+
+	if settings.File != "" {
+		f, err := os.OpenFile(settings.File, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("newAccessLogger: %w", err)
+		}
+		a.file = f
+		a.out = f
+	}
+
+	return a, nil
+}
+
+// Holds the logger open for a request, false if it's been closed already (replaced by a settings change, say).
+func (a *accessLogger) acquire() bool {
+	if a == nil {
+		return true
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.closed {
+		return false
+	}
+	a.users++
+
+	return true
+}
+
+func (a *accessLogger) release() {
+	if a == nil {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.users--
+	if a.closed && a.users == 0 && a.file != nil {
+		_ = a.file.Close()
+	}
+}
+
+// Closes the file, or has the last request still holding the logger close it.
+func (a *accessLogger) close() error {
+	if a == nil {
+		return nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.closed {
+		return nil
+	}
+	a.closed = true
+	if a.users > 0 || a.file == nil {
+		return nil
+	}
+
+	return a.file.Close()
+}
+
+type accessLogEntry struct {
+	listenerName string
+	bindingPath  string
+	request      *http.Request
+	start        time.Time
+	latency      time.Duration
+	rec          *responseRecorder
+	requestBody  []byte
+}
+
+func (a *accessLogger) log(e accessLogEntry) {
+	var line []byte
+
+	switch a.settings.Format {
+	case se.AccessLogJSON:
+		line = a.formatJSON(e)
+	case se.AccessLogCombined:
+		line = a.formatCombined(e)
+	default:
+		line = a.formatCommon(e)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	out := a.out
+	if out == nil {
+		out = log.Writer() // Follows the process log output, including any -l log file
+	}
+	_, _ = out.Write(append(line, '\n'))
+}
+
+func clfValue(v string) string {
+	if v == "" {
+		return "-"
+	}
+
+	return v
+}
+
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// The request uri with the values of redacted query parameters masked.
+func (a *accessLogger) requestURI(r *http.Request) string {
+	path, query, ok := strings.Cut(r.RequestURI, "?")
+	if !ok || len(a.redactQuery) == 0 {
+		return r.RequestURI
+	}
+
+	params := strings.Split(query, "&")
+	for i, p := range params {
+		key, _, _ := strings.Cut(p, "=")
+		if name, err := url.QueryUnescape(key); err == nil && a.redactQuery[name] {
+			params[i] = key + "=" + redactedValue
+		}
+	}
+
+	return path + "?" + strings.Join(params, "&")
+}
+
+// host ident authuser [date] "request line" status bytes
+func (a *accessLogger) formatCommon(e accessLogEntry) []byte {
+	user, _, _ := e.request.BasicAuth()
+
+	size := "-"
+	if e.rec.bytes > 0 {
+		size = strconv.FormatInt(e.rec.bytes, 10)
+	}
+
+	return []byte(fmt.Sprintf("%s - %s [%s] \"%s %s %s\" %d %s",
+		remoteHost(e.request),
+		clfValue(user),
+		e.start.Format("02/Jan/2006:15:04:05 -0700"),
+		e.request.Method, a.requestURI(e.request), e.request.Proto,
+		e.rec.status,
+		size,
+	))
+}
+
+// Common log format, followed by "referer" "user agent"
+func (a *accessLogger) formatCombined(e accessLogEntry) []byte {
+	return append(a.formatCommon(e), []byte(fmt.Sprintf(" %s %s",
+		strconv.Quote(clfValue(e.request.Referer())),
+		strconv.Quote(clfValue(e.request.UserAgent())),
+	))...)
+}
+
+func (a *accessLogger) redactHeaders(h http.Header) map[string][]string {
+	redacted := make(map[string][]string, len(h))
+	for k, v := range h {
+		if a.redact[http.CanonicalHeaderKey(k)] {
+			redacted[k] = []string{redactedValue}
+			continue
+		}
+		redacted[k] = v
+	}
+
+	return redacted
+}
+
+type accessLogJSONLine struct {
+	Time            string              `json:"time"`
+	Listener        string              `json:"listener"`
+	Binding         string              `json:"binding,omitempty"`
+	RemoteAddr      string              `json:"remote_addr"`
+	Method          string              `json:"method"`
+	URI             string              `json:"uri"`
+	Proto           string              `json:"proto"`
+	Status          int                 `json:"status"`
+	Bytes           int64               `json:"bytes"`
+	LatencyMs       float64             `json:"latency_ms"`
+	Referer         string              `json:"referer,omitempty"`
+	UserAgent       string              `json:"user_agent,omitempty"`
+	RequestHeaders  map[string][]string `json:"request_headers,omitempty"`
+	ResponseHeaders map[string][]string `json:"response_headers,omitempty"`
+	RequestBody     *string             `json:"request_body,omitempty"`
+	ResponseBody    *string             `json:"response_body,omitempty"`
+}
+
+func (a *accessLogger) formatJSON(e accessLogEntry) []byte {
+	line := accessLogJSONLine{
+		Time:       e.start.Format(time.RFC3339Nano),
+		Listener:   e.listenerName,
+		Binding:    e.bindingPath,
+		RemoteAddr: e.request.RemoteAddr,
+		Method:     e.request.Method,
+		URI:        a.requestURI(e.request),
+		Proto:      e.request.Proto,
+		Status:     e.rec.status,
+		Bytes:      e.rec.bytes,
+		LatencyMs:  float64(e.latency.Microseconds()) / 1000,
+		Referer:    e.request.Referer(),
+		UserAgent:  e.request.UserAgent(),
+	}
+
+	if a.settings.CaptureHeaders {
+		line.RequestHeaders = a.redactHeaders(e.request.Header)
+		line.ResponseHeaders = a.redactHeaders(e.rec.Header())
+	}
+
+	if a.settings.CaptureBodies {
+		requestBody := string(e.requestBody)
+		responseBody := e.rec.captured.String()
+		line.RequestBody = &requestBody
+		line.ResponseBody = &responseBody
+	}
+
+	b, err := json.Marshal(line)
+	if err != nil {
+		return []byte(fmt.Sprintf(`{"error":%s}`, strconv.Quote(err.Error())))
+	}
+
+	return b
+}
+
+// How much of request and response bodies to capture, zero when bodies aren't being captured.
+func (a *accessLogger) bodyCaptureLimit() int {
+	if a == nil || !a.settings.CaptureBodies {
+		return 0
+	}
+
+	return a.maxBody
+}
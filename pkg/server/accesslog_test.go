@@ -0,0 +1,160 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	se "github.com/nrexception/mockapi/pkg/settings"
+)
+
+func TestAccessLogger(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		settings se.AccessLogSettings
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "common",
+			settings: se.AccessLogSettings{Format: se.AccessLogCommon},
+			contains: []string{`192.0.2.1 - - [`, `] "POST /users?page=2&api_key=[REDACTED] HTTP/1.1" 201 9`},
+			excludes: []string{`curl/8.0`, `hunter2`},
+		},
+		{
+			name:     "combined",
+			settings: se.AccessLogSettings{Format: se.AccessLogCombined},
+			contains: []string{`" 201 9 "https://example.com/" "curl/8.0"`},
+			excludes: []string{`hunter2`},
+		},
+		{
+			name:     "json with redaction",
+			settings: se.AccessLogSettings{Format: se.AccessLogJSON, CaptureHeaders: true, CaptureBodies: true, MaxBodyBytes: 5},
+			contains: []string{`"status":201`, `"binding":"/users"`, `"uri":"/users?page=2\u0026api_key=[REDACTED]"`, `"Authorization":["[REDACTED]"]`, `"X-Api-Token":["[REDACTED]"]`, `"X-Trace":["abc"]`, `"request_body":"{\"nam"`, `"response_body":"creat"`},
+			excludes: []string{`secret`, `hunter2`},
+		},
+		{
+			name:     "api keys redacted alongside chosen headers",
+			settings: se.AccessLogSettings{Format: se.AccessLogJSON, CaptureHeaders: true, RedactHeaders: []string{"X-Trace"}},
+			contains: []string{`"X-Trace":["[REDACTED]"]`, `"X-Api-Token":["[REDACTED]"]`},
+			excludes: []string{`hunter2`},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tc.settings.File = filepath.Join(t.TempDir(), "access.log")
+			a, err := newAccessLogger(tc.settings, apiKeyLocations{headers: []string{"X-API-Token"}, queries: []string{"api_key"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			l := &webListener{listenerName: "api", threaduuid: uuid.New()}
+			l.accessLog.Store(a)
+			sMux := http.NewServeMux()
+			sMux.HandleFunc("/users", func(w http.ResponseWriter, r *http.Request) {
+				setRequestBinding(r, "/users")
+				w.WriteHeader(http.StatusCreated)
+				_, _ = w.Write([]byte("created!!"))
+			})
+			l.mux.Store(sMux)
+
+			r := httptest.NewRequest(http.MethodPost, "/users?page=2&api_key=hunter2", strings.NewReader(`{"name":"bob"}`))
+			r.RemoteAddr = "192.0.2.1:1234"
+			r.Header.Set("Authorization", "Bearer secret")
+			r.Header.Set("X-Trace", "abc")
+			r.Header.Set("X-API-Token", "hunter2")
+			r.Header.Set("Referer", "https://example.com/")
+			r.Header.Set("User-Agent", "curl/8.0")
+			l.ServeHTTP(httptest.NewRecorder(), r)
+
+			err = a.close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			b, err := os.ReadFile(tc.settings.File)
+			if err != nil {
+				t.Fatal(err)
+			}
+			line := string(b)
+
+			if tc.settings.Format == se.AccessLogJSON && !json.Valid(b) {
+				t.Errorf("invalid json line: %s", line)
+			}
+			for _, c := range tc.contains {
+				if !strings.Contains(line, c) {
+					t.Errorf("expected %s in: %s", c, line)
+				}
+			}
+			for _, c := range tc.excludes {
+				if strings.Contains(line, c) {
+					t.Errorf("unexpected %s in: %s", c, line)
+				}
+			}
+		})
+	}
+}
+
+func TestAccessLogger_ReplacedMidRequest(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	previous, err := newAccessLogger(se.AccessLogSettings{File: filepath.Join(dir, "previous.log")}, apiKeyLocations{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	replacement, err := newAccessLogger(se.AccessLogSettings{File: filepath.Join(dir, "replacement.log")}, apiKeyLocations{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = replacement.close() })
+
+	l := &webListener{listenerName: "api", threaduuid: uuid.New()}
+	l.accessLog.Store(previous)
+	started, finish := make(chan struct{}), make(chan struct{})
+	sMux := http.NewServeMux()
+	sMux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-finish
+	})
+	l.mux.Store(sMux)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		l.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil))
+	}()
+	<-started
+
+	// What Apply does with the bindings updated in place
+	err = l.accessLog.Swap(replacement).close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	close(finish)
+	<-done
+
+	b, err := os.ReadFile(filepath.Join(dir, "previous.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"GET /slow HTTP/1.1" 200`) {
+		t.Errorf("the request was not logged to the access log it started with: %q", b)
+	}
+	if previous.file.Close() == nil {
+		t.Error("the replaced access log was left open")
+	}
+}
@@ -0,0 +1,131 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+
+	co "github.com/nrexception/mockapi/pkg/common"
+	se "github.com/nrexception/mockapi/pkg/settings"
+)
+
+const (
+	defaultMetricsPath   = "/metrics"
+	defaultLivenessPath  = "/healthz"
+	defaultReadinessPath = "/readyz"
+	localCAPath          = "/ca.pem"
+	callbackLogPath      = "/callbacks"
+)
+
+// Built-in listener serving MockAPI's own endpoints, separate from any mocked listener.
+type adminListener struct {
+	settings se.UnmarshalledRootSettingAdminListener
+	server   *http.Server
+	addr     net.Addr
+	serveErr atomic.Pointer[error]
+}
+
+func (m *ListenerManager) createAdminMux(settings se.UnmarshalledRootSettingAdminListener) (*http.ServeMux, error) {
+	orDefault := func(path string, def string) string {
+		if path == "" {
+			return def
+		}
+		return path
+	}
+
+	handlers := []struct {
+		path    string
+		handler http.Handler
+	}{
+		{orDefault(settings.MetricsPath, defaultMetricsPath), m.metrics},
+		{orDefault(settings.LivenessPath, defaultLivenessPath), http.HandlerFunc(m.serveLiveness)},
+		{orDefault(settings.ReadinessPath, defaultReadinessPath), http.HandlerFunc(m.serveReadiness)},
+		{localCAPath, http.HandlerFunc(m.serveLocalCA)},
+		{callbackLogPath, m.callbacks.log},
+	}
+
+	sMux := http.NewServeMux()
+	for _, h := range handlers {
+		err := registerHandler(sMux, h.path, h.handler)
+		if err != nil {
+			return nil, fmt.Errorf("createAdminMux: %w", err)
+		}
+	}
+
+	return sMux, nil
+}
+
+func (a *adminListener) start(handler http.Handler) error {
+	ln, err := listenTCP(a.settings.ListenAddress, a.settings.ListenerPort)
+	if err != nil {
+		return fmt.Errorf("adminListener.start: %w", err)
+	}
+
+	a.server = &http.Server{Handler: handler}
+	a.addr = ln.Addr()
+
+	co.LogNonVerbose("starting admin listener...", co.MSGTYPE_INFO)
+	go func() {
+		err := a.server.Serve(ln)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			a.serveErr.Store(&err)
+			co.LogNonVerbose("admin listener stopped", co.MSGTYPE_ERROR, co.LOGKEY_ERROR, err)
+		}
+	}()
+
+	return nil
+}
+
+func (a *adminListener) stop() error {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	err := a.server.Shutdown(ctx)
+	if err != nil {
+		return fmt.Errorf("adminListener.stop: %w", err)
+	}
+
+	return nil
+}
+
+// Starts, restarts or stops the admin listener to match settings, which may be nil.
+func (m *ListenerManager) applyAdmin(settings *se.UnmarshalledRootSettingAdminListener) error {
+	if m.admin != nil && settings != nil && m.admin.settings == *settings {
+		return nil
+	}
+
+	// Build the new mux first, so bad settings leave the running admin listener alone
+	var sMux *http.ServeMux
+	if settings != nil {
+		var err error
+		sMux, err = m.createAdminMux(*settings)
+		if err != nil {
+			return fmt.Errorf("applyAdmin: %w", err)
+		}
+	}
+
+	if m.admin != nil {
+		err := m.admin.stop()
+		m.admin = nil
+		if err != nil {
+			return fmt.Errorf("applyAdmin: %w", err)
+		}
+	}
+
+	if settings == nil {
+		return nil
+	}
+
+	admin := &adminListener{settings: *settings}
+	err := admin.start(sMux)
+	if err != nil {
+		return fmt.Errorf("applyAdmin: %w", err)
+	}
+	m.admin = admin
+	m.respond(fmt.Sprintf("admin listener listening on %s", admin.addr))
+
+	return nil
+}
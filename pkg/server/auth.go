@@ -0,0 +1,294 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	co "github.com/nrexception/mockapi/pkg/common"
+	"github.com/nrexception/mockapi/pkg/jwt"
+	se "github.com/nrexception/mockapi/pkg/settings"
+)
+
+// Why a request was or wasn't let through.
+type authResult struct {
+	status int            // 0 if authenticated, otherwise 401 or 403
+	reason string         // Sent back in the error
+	bearer string         // The bearer error code for WWW-Authenticate, if a token was at fault
+	claims map[string]any // Of the credentials that got the request through
+}
+
+type authenticator struct {
+	settings se.AuthSettings
+	keys     []jwt.Key
+}
+
+// The auth a binding needs: its own if it has any, its listener's otherwise. nil if neither needs any.
+func newAuthenticator(listenerAuth *se.AuthSettings, binding se.ResponseBinding, threaduuid uuid.UUID) *authenticator {
+	settings := listenerAuth
+	if binding.Auth != nil {
+		settings = binding.Auth
+	}
+	if settings == nil || settings.Disabled {
+		return nil
+	}
+
+	a := &authenticator{settings: *settings}
+	if settings.JWT != nil {
+		var err error
+		a.keys, err = settings.JWT.Keys()
+		if err != nil {
+			// Without keys no token verifies, so the binding stays locked rather than open
+			co.LogNonVerboseOnThread(threaduuid, co.MSGTYPE_WARN, "jwt keys could not be loaded", co.LOGKEY_BINDING, binding.Path, co.LOGKEY_ERROR, err)
+		}
+	}
+
+	return a
+}
+
+// Authenticates r, once per request however many times it's asked.
+func (a *authenticator) check(r *http.Request) authResult {
+	info, _ := r.Context().Value(requestInfoKey{}).(*requestInfo)
+	if info == nil {
+		return a.authenticate(r)
+	}
+
+	if result, ok := info.auth[a]; ok {
+		return result
+	}
+
+	result := a.authenticate(r)
+	if info.auth == nil {
+		info.auth = map[*authenticator]authResult{}
+	}
+	info.auth[a] = result
+
+	return result
+}
+
+func secretsEqual(a string, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+func withClaims(claims map[string]any, defaults map[string]any) map[string]any {
+	merged := maps.Clone(defaults)
+	if merged == nil {
+		merged = map[string]any{}
+	}
+	maps.Copy(merged, claims)
+
+	return merged
+}
+
+// Tries each scheme the settings allow in turn. The first that recognises the credentials decides.
+func (a *authenticator) authenticate(r *http.Request) authResult {
+	s := a.settings
+	unauthorized := authResult{status: http.StatusUnauthorized, reason: "credentials required"}
+
+	if username, password, ok := r.BasicAuth(); ok && len(s.Basic) > 0 {
+		for _, u := range s.Basic {
+			if secretsEqual(u.Username, username) && secretsEqual(u.Password, password) {
+				return authResult{claims: withClaims(u.Claims, map[string]any{"sub": u.Username})}
+			}
+		}
+		unauthorized.reason = "invalid username or password"
+	}
+
+	if s.APIKeys != nil {
+		presented := ""
+		if name := s.APIKeys.HeaderName(); name != "" {
+			presented = r.Header.Get(name)
+		}
+		if presented == "" && s.APIKeys.Query != "" {
+			presented = r.URL.Query().Get(s.APIKeys.Query)
+		}
+
+		if presented != "" {
+			for _, k := range s.APIKeys.Keys {
+				if secretsEqual(k.Key, presented) {
+					return authResult{claims: withClaims(k.Claims, nil)}
+				}
+			}
+			unauthorized.reason = "invalid api key"
+		}
+	}
+
+	scheme, token, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+	if strings.EqualFold(scheme, "Bearer") && token != "" && (len(s.Bearer) > 0 || s.JWT != nil) {
+		for _, t := range s.Bearer {
+			if secretsEqual(t.Token, token) {
+				return authResult{claims: withClaims(t.Claims, nil)}
+			}
+		}
+
+		if s.JWT == nil {
+			return authResult{status: http.StatusUnauthorized, reason: "invalid token", bearer: "invalid_token"}
+		}
+		return a.checkJWT(token)
+	}
+
+	return unauthorized
+}
+
+// A numeric date claim (exp, nbf), and whether there was one.
+func numericDate(claims map[string]any, name string) (time.Time, bool) {
+	n, ok := claims[name].(json.Number)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	f, err := n.Float64()
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	// Whole seconds and the fraction apart, nanoseconds since 1970 running out in 2262
+	sec, frac := math.Modf(f)
+	return time.Unix(int64(sec), int64(frac*float64(time.Second))), true
+}
+
+func (a *authenticator) checkJWT(token string) authResult {
+	settings := a.settings.JWT
+	invalid := func(reason string) authResult {
+		return authResult{status: http.StatusUnauthorized, reason: reason, bearer: "invalid_token"}
+	}
+
+	claims, err := jwt.Verify(token, a.keys)
+	if err != nil {
+		return invalid(fmt.Sprintf("invalid token: %v", strings.TrimPrefix(err.Error(), "Verify: ")))
+	}
+
+	now := time.Now()
+	if exp, ok := numericDate(claims, "exp"); ok && !now.Before(exp.Add(settings.Leeway)) {
+		return invalid("token has expired")
+	}
+	if nbf, ok := numericDate(claims, "nbf"); ok && now.Add(settings.Leeway).Before(nbf) {
+		return invalid("token is not valid yet")
+	}
+
+	if settings.Issuer != "" && claims["iss"] != settings.Issuer {
+		return invalid("token was issued by someone else")
+	}
+	if settings.Audience != "" && !slices.Contains(claimValues("aud", claims["aud"]), settings.Audience) {
+		return invalid("token is for another audience")
+	}
+
+	if !claimsMatch(settings.Claims, claims) {
+		return authResult{status: http.StatusForbidden, reason: "token is missing required claims", bearer: "insufficient_scope", claims: claims}
+	}
+
+	return authResult{claims: claims}
+}
+
+// The values a claim holds: each item of an array, or a string. scope is also each of its space separated words.
+func claimValues(name string, v any) []string {
+	switch value := v.(type) {
+	case []any:
+		values := []string{}
+		for _, item := range value {
+			values = append(values, idString(item))
+		}
+		return values
+	case string:
+		if name == "scope" {
+			return append([]string{value}, strings.Fields(value)...)
+		}
+		return []string{value}
+	case nil:
+		return nil
+	}
+
+	return []string{idString(v)}
+}
+
+// Whether claims holds everything required, see se.BindingMatch.
+func claimsMatch(required map[string]any, claims map[string]any) bool {
+	for name, want := range required {
+		v, ok := fieldValue(claims, name)
+		if !ok {
+			return false
+		}
+
+		wanted := []any{want}
+		if list, ok := want.([]any); ok {
+			wanted = list
+		}
+
+		values := claimValues(name, v)
+		if !slices.ContainsFunc(wanted, func(w any) bool { return slices.Contains(values, idString(w)) }) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Sends the failure, as configured or as json with the challenge for the schemes on offer.
+func (a *authenticator) writeFailure(w http.ResponseWriter, result authResult) {
+	response := a.settings.Unauthorized
+	if result.status == http.StatusForbidden {
+		response = a.settings.Forbidden
+	}
+
+	realm := strconv.Quote(a.settings.RealmName())
+	if len(a.settings.Basic) > 0 && result.status == http.StatusUnauthorized {
+		w.Header().Add("WWW-Authenticate", "Basic realm="+realm)
+	}
+	if len(a.settings.Bearer) > 0 || a.settings.JWT != nil {
+		challenge := "Bearer realm=" + realm
+		if result.bearer != "" {
+			challenge += ", error=\"" + result.bearer + "\""
+		}
+		w.Header().Add("WWW-Authenticate", challenge)
+	}
+
+	status := result.status
+	if response != nil {
+		for _, h := range response.ResponseHeaders {
+			w.Header().Add(h.Key, h.Value)
+		}
+		if response.ResponseCode != 0 {
+			status = response.ResponseCode
+		}
+		if response.ResponseBody != "" {
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(response.ResponseBody))
+			return
+		}
+	}
+
+	writeResourceJSON(w, status, map[string]string{"error": result.reason})
+}
+
+// Lets the request through if it's authenticated, noting its claims for templates. Otherwise it's answered here.
+func (a *authenticator) allow(w http.ResponseWriter, r *http.Request) bool {
+	result := a.check(r)
+	if result.status != 0 {
+		a.writeFailure(w, result)
+		return false
+	}
+
+	if info, ok := r.Context().Value(requestInfoKey{}).(*requestInfo); ok {
+		info.claims = result.claims
+	}
+
+	return true
+}
+
+// Claims of the credentials that got r to its binding, nil without auth.
+func requestClaims(r *http.Request) map[string]any {
+	if info, ok := r.Context().Value(requestInfoKey{}).(*requestInfo); ok {
+		return info.claims
+	}
+
+	return nil
+}
@@ -0,0 +1,197 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nrexception/mockapi/pkg/jwt"
+	se "github.com/nrexception/mockapi/pkg/settings"
+)
+
+func TestListenerManager_Auth(t *testing.T) {
+	t.Parallel()
+
+	secret := []byte("s3cret")
+	token := func(claims map[string]any) string {
+		s, err := jwt.Sign(claims, "HS256", secret, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	exp := time.Now().Add(time.Hour).Unix()
+	forged, err := jwt.Sign(map[string]any{"sub": "bob"}, "HS256", []byte("guessed"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewListenerManager(nil, nil)
+	t.Cleanup(func() { _ = m.Close() })
+
+	err = m.Apply(&se.UnmarshalledRootSettings{WebListeners: []se.UnmarshalledRootSettingWebListener{{
+		ListenerName: "api",
+		Auth: &se.AuthSettings{
+			Basic:   []se.BasicAuthUser{{Username: "alice", Password: "wonderland", Claims: map[string]any{"role": "admin"}}},
+			APIKeys: &se.APIKeyAuth{Header: "X-API-Key", Query: "api_key", Keys: []se.APIKey{{Key: "k-123", Claims: map[string]any{"sub": "service"}}}},
+			Bearer:  []se.BearerToken{{Token: "static-token", Claims: map[string]any{"sub": "ci"}}},
+			JWT:     &se.JWTAuth{Secret: string(secret), Issuer: "https://issuer.example", Audience: "mockapi", Claims: map[string]any{"scope": "orders:read"}},
+		},
+		ContentBindings: []se.ResponseBinding{
+			{Path: "/health", ResponseCode: http.StatusOK, ResponseBody: "ok", ResponseBodyType: se.Inline, Auth: &se.AuthSettings{Disabled: true}},
+			{Path: "/orders", ResponseCode: http.StatusOK, ResponseBody: "admin orders", ResponseBodyType: se.Inline, Match: &se.BindingMatch{Claims: map[string]any{"role": "admin"}}},
+			{Path: "/orders", ResponseCode: http.StatusOK, ResponseBody: "orders", ResponseBodyType: se.Inline},
+			{Path: "/me", ResponseCode: http.StatusOK, ResponseBodyType: se.Generated, Generated: &se.GeneratedSettings{Template: `{{.Claims.sub}}`}},
+			{
+				Path: "/admin", ResponseCode: http.StatusOK, ResponseBody: "admin", ResponseBodyType: se.Inline,
+				Auth: &se.AuthSettings{
+					Realm:     "admin",
+					JWT:       &se.JWTAuth{Secret: string(secret), Claims: map[string]any{"realm_access.roles": "admin"}},
+					Forbidden: &se.AuthResponse{ResponseCode: http.StatusNotFound, ResponseBody: "nothing here"},
+				},
+			},
+		},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listenerPort(t, m, "api")
+
+	testCases := []struct {
+		name      string
+		path      string
+		header    map[string]string
+		basicAuth []string
+		code      int
+		body      string
+		challenge string // Expected WWW-Authenticate, if any
+	}{
+		{name: "auth disabled", path: "/health", code: http.StatusOK, body: "ok"},
+		{name: "no credentials", path: "/orders", code: http.StatusUnauthorized, body: `{"error":"credentials required"}`, challenge: `Basic realm="mockapi"`},
+		{name: "basic", path: "/orders", basicAuth: []string{"alice", "wonderland"}, code: http.StatusOK, body: "admin orders"},
+		{name: "basic wrong password", path: "/orders", basicAuth: []string{"alice", "looking-glass"}, code: http.StatusUnauthorized, body: `{"error":"invalid username or password"}`},
+		{name: "api key header", path: "/orders", header: map[string]string{"X-API-Key": "k-123"}, code: http.StatusOK, body: "orders"},
+		{name: "api key query", path: "/orders?api_key=k-123", code: http.StatusOK, body: "orders"},
+		{name: "wrong api key", path: "/orders?api_key=nope", code: http.StatusUnauthorized, body: `{"error":"invalid api key"}`},
+		{name: "static bearer", path: "/me", header: map[string]string{"Authorization": "Bearer static-token"}, code: http.StatusOK, body: "ci"},
+		{
+			name: "jwt", path: "/me", code: http.StatusOK, body: "bob",
+			header: map[string]string{"Authorization": "Bearer " + token(map[string]any{"sub": "bob", "iss": "https://issuer.example", "aud": []string{"mockapi"}, "scope": "orders:read orders:write", "exp": exp})},
+		},
+		{
+			name: "jwt expiring after 2262", path: "/me", code: http.StatusOK, body: "bob",
+			header: map[string]string{"Authorization": "Bearer " + token(map[string]any{"sub": "bob", "iss": "https://issuer.example", "aud": "mockapi", "scope": "orders:read", "exp": 9999999999})},
+		},
+		{
+			name: "jwt for an audience with a space", path: "/me", code: http.StatusUnauthorized, body: `{"error":"token is for another audience"}`,
+			header: map[string]string{"Authorization": "Bearer " + token(map[string]any{"sub": "bob", "iss": "https://issuer.example", "aud": "mockapi other", "scope": "orders:read"})},
+		},
+		{
+			name: "jwt expired", path: "/me", code: http.StatusUnauthorized, body: `{"error":"token has expired"}`, challenge: `Bearer realm="mockapi", error="invalid_token"`,
+			header: map[string]string{"Authorization": "Bearer " + token(map[string]any{"sub": "bob", "iss": "https://issuer.example", "aud": "mockapi", "scope": "orders:read", "exp": time.Now().Add(-time.Minute).Unix()})},
+		},
+		{
+			name: "jwt wrong issuer", path: "/me", code: http.StatusUnauthorized, body: `{"error":"token was issued by someone else"}`,
+			header: map[string]string{"Authorization": "Bearer " + token(map[string]any{"sub": "bob", "iss": "https://elsewhere.example", "aud": "mockapi", "scope": "orders:read"})},
+		},
+		{
+			name: "jwt missing scope", path: "/me", code: http.StatusForbidden, body: `{"error":"token is missing required claims"}`, challenge: `Bearer realm="mockapi", error="insufficient_scope"`,
+			header: map[string]string{"Authorization": "Bearer " + token(map[string]any{"sub": "bob", "iss": "https://issuer.example", "aud": "mockapi", "scope": "orders:write"})},
+		},
+		{
+			name: "jwt bad signature", path: "/me", code: http.StatusUnauthorized, body: `{"error":"invalid token: signature does not match"}`,
+			header: map[string]string{"Authorization": "Bearer " + forged},
+		},
+		{
+			name: "binding auth", path: "/admin", code: http.StatusOK, body: "admin",
+			header: map[string]string{"Authorization": "Bearer " + token(map[string]any{"realm_access": map[string]any{"roles": []string{"user", "admin"}}})},
+		},
+		{
+			name: "binding auth replaces the listener's", path: "/admin", basicAuth: []string{"alice", "wonderland"}, code: http.StatusUnauthorized, challenge: `Bearer realm="admin"`,
+		},
+		{
+			name: "custom forbidden", path: "/admin", code: http.StatusNotFound, body: "nothing here",
+			header: map[string]string{"Authorization": "Bearer " + token(map[string]any{"realm_access": map[string]any{"roles": []string{"user"}}})},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(http.MethodGet, "http://127.0.0.1:"+strconv.Itoa(port)+tc.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for k, v := range tc.header {
+				req.Header.Set(k, v)
+			}
+			if tc.basicAuth != nil {
+				req.SetBasicAuth(tc.basicAuth[0], tc.basicAuth[1])
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			b, _ := io.ReadAll(resp.Body)
+
+			if resp.StatusCode != tc.code {
+				t.Fatalf("expected %d, got %d: %s", tc.code, resp.StatusCode, b)
+			}
+			if tc.body != "" && strings.TrimSpace(string(b)) != tc.body {
+				t.Fatalf("expected body %q, got %q", tc.body, b)
+			}
+			if tc.challenge != "" && !strings.Contains(strings.Join(resp.Header.Values("WWW-Authenticate"), "\n"), tc.challenge) {
+				t.Fatalf("expected challenge %q, got %q", tc.challenge, resp.Header.Values("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestClaimsMatch(t *testing.T) {
+	t.Parallel()
+
+	var claims map[string]any
+	err := json.Unmarshal([]byte(`{"sub":"alice","scope":"read write","aud":"api admin","tier":3,"admin":true,"groups":["ops","dev"],"org":{"id":"acme"}}`), &claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name     string
+		required map[string]any
+		expected bool
+	}{
+		{name: "nothing required", expected: true},
+		{name: "string", required: map[string]any{"sub": "alice"}, expected: true},
+		{name: "scope word", required: map[string]any{"scope": "write"}, expected: true},
+		{name: "array member", required: map[string]any{"groups": "dev"}, expected: true},
+		{name: "any of", required: map[string]any{"sub": []any{"bob", "alice"}}, expected: true},
+		{name: "number", required: map[string]any{"tier": 3}, expected: true},
+		{name: "bool", required: map[string]any{"admin": true}, expected: true},
+		{name: "nested", required: map[string]any{"org.id": "acme"}, expected: true},
+		{name: "all have to match", required: map[string]any{"sub": "alice", "groups": "sales"}, expected: false},
+		{name: "missing claim", required: map[string]any{"email": "alice@example.com"}, expected: false},
+		{name: "partial word", required: map[string]any{"scope": "rea"}, expected: false},
+		{name: "only scope is split", required: map[string]any{"aud": "api"}, expected: false},
+		{name: "whole aud", required: map[string]any{"aud": "api admin"}, expected: true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := claimsMatch(tc.required, claims); got != tc.expected {
+				t.Fatalf("expected %t, got %t", tc.expected, got)
+			}
+		})
+	}
+}
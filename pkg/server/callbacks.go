@@ -0,0 +1,297 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+
+	co "github.com/nrexception/mockapi/pkg/common"
+	"github.com/nrexception/mockapi/pkg/fake"
+	se "github.com/nrexception/mockapi/pkg/settings"
+)
+
+const (
+	callbackMaxBodyBytes   = 1 << 20 // Of the original request, as seen by callback templates
+	callbackLogMaxEntries  = 1000
+	defaultCallbackBackoff = time.Second
+	defaultCallbackTimeout = 10 * time.Second
+)
+
+// A callback's outcome, as recorded in the callback log.
+type CallbackDelivery struct {
+	Time      time.Time `json:"time"` // When the last attempt finished
+	Listener  string    `json:"listener"`
+	Binding   string    `json:"binding"`
+	Method    string    `json:"method"`
+	URL       string    `json:"url"`
+	Attempts  int       `json:"attempts"`
+	Status    int       `json:"status,omitempty"` // Of the last attempt, 0 if it got no response
+	Delivered bool      `json:"delivered"`
+	Error     string    `json:"error,omitempty"` // Why the last attempt failed
+}
+
+// The latest callbacks fired by a manager's listeners, oldest first.
+type CallbackLog struct {
+	mu      sync.Mutex
+	entries []CallbackDelivery
+}
+
+func (l *CallbackLog) record(d CallbackDelivery) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries = append(l.entries, d)
+	if len(l.entries) > callbackLogMaxEntries {
+		l.entries = l.entries[len(l.entries)-callbackLogMaxEntries:]
+	}
+}
+
+// Copy of the recorded deliveries, oldest first.
+func (l *CallbackLog) Entries() []CallbackDelivery {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return append([]CallbackDelivery{}, l.entries...)
+}
+
+// Drops everything recorded so far.
+func (l *CallbackLog) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries = nil
+}
+
+func (l *CallbackLog) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b, err := marshalJSON(l.Entries())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(b)
+}
+
+// Sends callbacks in the background, until the manager closes.
+type callbackDispatcher struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	client *http.Client
+	log    *CallbackLog
+}
+
+func newCallbackDispatcher() *callbackDispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &callbackDispatcher{ctx: ctx, cancel: cancel, client: &http.Client{}, log: &CallbackLog{}}
+}
+
+// Abandons callbacks still waiting or retrying, and waits for any in flight.
+func (d *callbackDispatcher) close() {
+	d.cancel()
+	d.wg.Wait()
+}
+
+// What callback and generated body templates see of the request that triggered them.
+type templateRequest struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Header http.Header
+	Body   string
+	JSON   any            // The body decoded, if it's json
+	Claims map[string]any // Of the request's credentials, when the binding needs auth
+}
+
+func newTemplateRequest(r *http.Request, body []byte) templateRequest {
+	req := templateRequest{Method: r.Method, Path: r.URL.Path, Query: r.URL.Query(), Header: r.Header.Clone(), Body: string(body), Claims: requestClaims(r)}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if decoder.Decode(&req.JSON) != nil {
+		req.JSON = nil
+	}
+
+	return req
+}
+
+type bindingCallback struct {
+	settings se.Callback
+	url      *template.Template
+	body     *template.Template
+	headers  []*template.Template // Values, by settings.Headers' index
+}
+
+// A binding's callbacks, with their templates parsed. They share a generator for the fake data functions, so rendering
+// takes turns.
+type bindingCallbacks struct {
+	mu        sync.Mutex
+	callbacks []bindingCallback
+}
+
+func newBindingCallbacks(callbacks []se.Callback) (*bindingCallbacks, error) {
+	g := fake.New(nil)
+	b := &bindingCallbacks{}
+
+	for i, c := range callbacks {
+		bc := bindingCallback{settings: c}
+
+		var err error
+		bc.url, err = g.Template(fmt.Sprintf("callback %d url", i), c.URL)
+		if err == nil {
+			bc.body, err = g.Template(fmt.Sprintf("callback %d body", i), c.Body)
+		}
+		for _, h := range c.Headers {
+			var t *template.Template
+			if err == nil {
+				t, err = g.Template(fmt.Sprintf("callback %d header %s", i, h.Key), h.Value)
+			}
+			bc.headers = append(bc.headers, t)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("newBindingCallbacks: %w", err)
+		}
+
+		b.callbacks = append(b.callbacks, bc)
+	}
+
+	return b, nil
+}
+
+func executeTemplate(t *template.Template, data any) (string, error) {
+	var b strings.Builder
+	err := t.Execute(&b, data)
+	return b.String(), err
+}
+
+// The callback's url, headers and body for req.
+func (b *bindingCallbacks) render(c bindingCallback, req templateRequest) (string, http.Header, string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	u, err := executeTemplate(c.url, req)
+	if err != nil {
+		return "", nil, "", fmt.Errorf("render: url: %w", err)
+	}
+
+	headers := http.Header{}
+	for i, h := range c.settings.Headers {
+		v, err := executeTemplate(c.headers[i], req)
+		if err != nil {
+			return "", nil, "", fmt.Errorf("render: header %s: %w", h.Key, err)
+		}
+		headers.Add(h.Key, v)
+	}
+
+	body, err := executeTemplate(c.body, req)
+	if err != nil {
+		return "", nil, "", fmt.Errorf("render: body: %w", err)
+	}
+
+	return u, headers, body, nil
+}
+
+// Renders each callback for the request just answered and sends it in the background.
+func (d *callbackDispatcher) fire(callbacks *bindingCallbacks, listenerName string, bindingPath string, r *http.Request, body []byte, threaduuid uuid.UUID) {
+	req := newTemplateRequest(r, body)
+
+	for _, c := range callbacks.callbacks {
+		delivery := CallbackDelivery{Listener: listenerName, Binding: bindingPath, Method: c.settings.RequestMethod()}
+
+		u, headers, body, err := callbacks.render(c, req)
+		if err != nil {
+			delivery.Time, delivery.URL, delivery.Error = time.Now(), c.settings.URL, err.Error()
+			d.log.record(delivery)
+			co.LogNonVerboseOnThread(threaduuid, co.MSGTYPE_ERROR, "callback could not be rendered", co.LOGKEY_BINDING, bindingPath, co.LOGKEY_ERROR, err)
+			continue
+		}
+		delivery.URL = u
+
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			d.deliver(c.settings, delivery, headers, body, threaduuid)
+		}()
+	}
+}
+
+// Sends the callback until it gets a 2xx or runs out of retries, then records how it went.
+func (d *callbackDispatcher) deliver(c se.Callback, delivery CallbackDelivery, headers http.Header, body string, threaduuid uuid.UUID) {
+	backoff := c.Backoff
+	if backoff == 0 {
+		backoff = defaultCallbackBackoff
+	}
+
+	for attempt := 1; attempt <= c.Retries+1; attempt++ {
+		wait := c.Delay
+		if attempt > 1 {
+			wait = backoff << (attempt - 2)
+		}
+		if !sleepContext(d.ctx, wait) {
+			delivery.Error = "abandoned, mockapi is shutting down"
+			break
+		}
+
+		delivery.Attempts = attempt
+		delivery.Status, delivery.Error = d.attempt(c, delivery.Method, delivery.URL, headers, body)
+		if delivery.Error == "" {
+			delivery.Delivered = true
+			break
+		}
+
+		co.LogVerboseOnThread(threaduuid, co.MSGTYPE_WARN, "callback attempt failed", co.LOGKEY_BINDING, delivery.Binding, co.LOGKEY_URL, delivery.URL, co.LOGKEY_ATTEMPT, attempt, co.LOGKEY_STATUS, delivery.Status, co.LOGKEY_ERROR, delivery.Error)
+	}
+
+	delivery.Time = time.Now()
+	d.log.record(delivery)
+
+	if delivery.Delivered {
+		co.LogVerboseOnThread(threaduuid, co.MSGTYPE_INFO, "callback delivered", co.LOGKEY_BINDING, delivery.Binding, co.LOGKEY_METHOD, delivery.Method, co.LOGKEY_URL, delivery.URL, co.LOGKEY_STATUS, delivery.Status, co.LOGKEY_ATTEMPT, delivery.Attempts)
+		return
+	}
+	co.LogNonVerboseOnThread(threaduuid, co.MSGTYPE_ERROR, "callback was not delivered", co.LOGKEY_BINDING, delivery.Binding, co.LOGKEY_METHOD, delivery.Method, co.LOGKEY_URL, delivery.URL, co.LOGKEY_ATTEMPT, delivery.Attempts, co.LOGKEY_ERROR, delivery.Error)
+}
+
+// One try at sending a callback, returning the status it got and, unless it was a 2xx, what went wrong.
+func (d *callbackDispatcher) attempt(c se.Callback, method string, u string, headers http.Header, body string) (int, string) {
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = defaultCallbackTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(d.ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, u, strings.NewReader(body))
+	if err != nil {
+		return 0, err.Error()
+	}
+	req.Header = headers.Clone()
+	if req.Header.Get("Content-Type") == "" && json.Valid([]byte(body)) {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err.Error()
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, callbackMaxBodyBytes))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Sprintf("unexpected status: %s", resp.Status)
+	}
+
+	return resp.StatusCode, ""
+}
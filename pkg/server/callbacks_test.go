@@ -0,0 +1,173 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	se "github.com/nrexception/mockapi/pkg/settings"
+)
+
+func waitForCallbacks(t *testing.T, log *CallbackLog, n int) []CallbackDelivery {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if entries := log.Entries(); len(entries) >= n {
+			return entries
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("expected %d callbacks, got %+v", n, log.Entries())
+	return nil
+}
+
+func TestListenerManager_Callbacks(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	attempts := 0
+	var received []string
+	var signatures []string
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable) // Retried...
+			return
+		}
+
+		b, _ := io.ReadAll(r.Body)
+		received = append(received, r.Method+" "+r.URL.Path+" "+r.Header.Get("Content-Type")+" "+string(b))
+		signatures = append(signatures, r.Header.Get("X-Signature"))
+	}))
+	t.Cleanup(target.Close)
+
+	// Nothing listens where a closed server was
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	unreachable := closed.URL + "/hook"
+
+	m := NewListenerManager(nil, nil)
+	t.Cleanup(func() { _ = m.Close() })
+
+	err := m.Apply(&se.UnmarshalledRootSettings{
+		Admin: &se.UnmarshalledRootSettingAdminListener{},
+		WebListeners: []se.UnmarshalledRootSettingWebListener{{
+			ListenerName: "payments",
+			ContentBindings: []se.ResponseBinding{{
+				Path:             "/payments",
+				Method:           http.MethodPost,
+				ResponseCode:     http.StatusAccepted,
+				ResponseBodyType: se.Inline,
+				ResponseBody:     `{"status":"pending"}`,
+				Callbacks: []se.Callback{
+					{
+						URL:     target.URL + "/webhooks/{{.JSON.id}}",
+						Headers: []se.ResponseHeader{{Key: "X-Signature", Value: `{{.Header.Get "X-Request-Id"}}`}},
+						Body:    `{"id":{{.JSON.id | json}},"status":"paid","ref":{{.Query.Get "ref" | json}}}`,
+						Delay:   10 * time.Millisecond,
+						Retries: 2,
+						Backoff: 10 * time.Millisecond,
+					},
+					{URL: unreachable, Method: http.MethodPut, Retries: 1, Backoff: time.Millisecond, Timeout: time.Second},
+				},
+			}},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, adminPort := listenerPort(t, m, "payments"), listenerPort(t, m, "admin")
+
+	req, _ := http.NewRequest(http.MethodPost, "http://127.0.0.1:"+strconv.Itoa(port)+"/payments?ref=abc", strings.NewReader(`{"id":"pay_1","amount":10}`))
+	req.Header.Set("X-Request-Id", "req-7")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("unexpected status: %d", resp.StatusCode)
+	}
+
+	entries := waitForCallbacks(t, m.Callbacks(), 2)
+
+	byURL := map[string]CallbackDelivery{}
+	for _, e := range entries {
+		byURL[e.URL] = e
+	}
+
+	delivered := byURL[target.URL+"/webhooks/pay_1"]
+	if !delivered.Delivered || delivered.Attempts != 2 || delivered.Status != http.StatusOK || delivered.Listener != "payments" || delivered.Binding != "/payments" {
+		t.Errorf("unexpected delivery: %+v", delivered)
+	}
+
+	failed := byURL[unreachable]
+	if failed.Delivered || failed.Attempts != 2 || failed.Method != http.MethodPut || failed.Error == "" {
+		t.Errorf("unexpected failed delivery: %+v", failed)
+	}
+
+	mu.Lock()
+	if len(received) != 1 || received[0] != `POST /webhooks/pay_1 application/json {"id":"pay_1","status":"paid","ref":"abc"}` || signatures[0] != "req-7" {
+		t.Errorf("unexpected callback received: %q %q", received, signatures)
+	}
+	mu.Unlock()
+
+	// The log is on the admin listener too
+	resp, err = http.Get("http://127.0.0.1:" + strconv.Itoa(adminPort) + "/callbacks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var logged []CallbackDelivery
+	if err := json.NewDecoder(resp.Body).Decode(&logged); err != nil || len(logged) != 2 {
+		t.Errorf("unexpected admin callback log: %+v (%v)", logged, err)
+	}
+}
+
+func TestListenerManager_CallbacksAbandonedOnClose(t *testing.T) {
+	t.Parallel()
+
+	m := NewListenerManager(nil, nil)
+
+	err := m.Apply(&se.UnmarshalledRootSettings{WebListeners: []se.UnmarshalledRootSettingWebListener{{
+		ListenerName: "slow",
+		ContentBindings: []se.ResponseBinding{{
+			Path:             "/",
+			ResponseCode:     http.StatusOK,
+			ResponseBodyType: se.Inline,
+			ResponseBody:     "ok",
+			Callbacks:        []se.Callback{{URL: "http://127.0.0.1:1/never", Delay: time.Hour}},
+		}},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := http.Get("http://127.0.0.1:" + strconv.Itoa(listenerPort(t, m, "slow")) + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+
+	start := time.Now()
+	_ = m.Close()
+	if time.Since(start) > shutdownTimeout {
+		t.Errorf("close waited for the callback's delay")
+	}
+
+	entries := m.Callbacks().Entries()
+	if len(entries) != 1 || entries[0].Delivered || entries[0].Attempts != 0 {
+		t.Errorf("expected the callback to be abandoned: %+v", entries)
+	}
+}
@@ -0,0 +1,159 @@
+package server
+
+import (
+	"crypto/tls"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	co "github.com/nrexception/mockapi/pkg/common"
+	se "github.com/nrexception/mockapi/pkg/settings"
+)
+
+// A certificate loaded from files, swapped for the new one whenever they change.
+type storedCertificate struct {
+	hostnames []string // Empty for the default certificate
+	certFile  string
+	keyFile   string
+	cert      atomic.Pointer[tls.Certificate]
+}
+
+func (c *storedCertificate) load() error {
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("storedCertificate.load: %w", err)
+	}
+	c.cert.Store(&cert)
+
+	return nil
+}
+
+// Whether serverName is one of the certificate's hostnames, a wildcard such as *.example.com covering one label.
+func (c *storedCertificate) matches(serverName string) bool {
+	for _, h := range c.hostnames {
+		if strings.EqualFold(h, serverName) {
+			return true
+		}
+
+		suffix, ok := strings.CutPrefix(h, "*")
+		if !ok {
+			continue
+		}
+
+		label, found := strings.CutSuffix(strings.ToLower(serverName), strings.ToLower(suffix))
+		if found && label != "" && !strings.Contains(label, ".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// The certificates of a listener with certdetails, picked by SNI and reloaded from disk as their files change so they
+// can be rotated without restarting the listener.
+type certificateStore struct {
+	listenerName string
+	certs        []*storedCertificate // SNI certificates in the order configured, then the default one
+	watcher      *co.FileWatcher
+	done         chan struct{}
+}
+
+// Loads every certificate in certDetails, watching their files if possible. A store that can't watch still serves, it
+// just won't pick up changes.
+func newCertificateStore(listenerName string, certDetails se.UnmarshalledRootSettingWebListenerHTTPSCertFiles) (*certificateStore, error) {
+	s := &certificateStore{listenerName: listenerName, done: make(chan struct{})}
+
+	for _, sni := range certDetails.SNICerts {
+		s.certs = append(s.certs, &storedCertificate{hostnames: sni.Hostnames, certFile: filepath.Clean(sni.CertFile), keyFile: filepath.Clean(sni.KeyFile)})
+	}
+	s.certs = append(s.certs, &storedCertificate{certFile: filepath.Clean(certDetails.CertFile), keyFile: filepath.Clean(certDetails.KeyFile)})
+
+	for _, c := range s.certs {
+		err := c.load()
+		if err != nil {
+			return nil, fmt.Errorf("newCertificateStore: %w", err)
+		}
+	}
+
+	watcher, err := co.NewFileWatcher(co.DefaultWatchDebounce)
+	if err != nil {
+		co.LogNonVerbose("not watching certificate files", co.MSGTYPE_WARN, co.LOGKEY_LISTENER, listenerName, co.LOGKEY_ERROR, err)
+		return s, nil
+	}
+	s.watcher = watcher
+
+	for _, c := range s.certs {
+		for _, f := range []string{c.certFile, c.keyFile} {
+			err = watcher.Add(f)
+			if err != nil {
+				co.LogNonVerbose("not watching certificate file", co.MSGTYPE_WARN, co.LOGKEY_LISTENER, listenerName, co.LOGKEY_FILE, f, co.LOGKEY_ERROR, err)
+			}
+		}
+	}
+	go s.run()
+
+	return s, nil
+}
+
+func (s *certificateStore) run() {
+	for {
+		select {
+		case e, ok := <-s.watcher.Events:
+			if !ok {
+				return
+			}
+			s.reload(e.FileName)
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// Reloads every certificate using fileName. One that fails to load (eg. the key was written but the cert not yet) keeps
+// serving the previous pair, the next change to either file tries again.
+func (s *certificateStore) reload(fileName string) {
+	for _, c := range s.certs {
+		if c.certFile != fileName && c.keyFile != fileName {
+			continue
+		}
+
+		err := c.load()
+		if err != nil {
+			co.LogNonVerbose("could not reload certificate, keeping the previous one", co.MSGTYPE_WARN, co.LOGKEY_LISTENER, s.listenerName, co.LOGKEY_FILE, fileName, co.LOGKEY_ERROR, err)
+			continue
+		}
+		co.LogNonVerbose("certificate reloaded", co.MSGTYPE_INFO, co.LOGKEY_LISTENER, s.listenerName, co.LOGKEY_FILE, c.certFile)
+	}
+}
+
+// For tls.Config.GetCertificate, clients not sending a known hostname get the default certificate.
+func (s *certificateStore) getCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	for _, c := range s.certs[:len(s.certs)-1] {
+		if c.matches(hello.ServerName) {
+			return c.cert.Load(), nil
+		}
+	}
+
+	return s.certs[len(s.certs)-1].cert.Load(), nil
+}
+
+// Stops watching the certificate files, safe to call on a nil store.
+func (s *certificateStore) close() error {
+	if s == nil {
+		return nil
+	}
+
+	select {
+	case <-s.done:
+		return nil
+	default:
+	}
+	close(s.done)
+
+	if s.watcher == nil {
+		return nil
+	}
+
+	return s.watcher.Close()
+}
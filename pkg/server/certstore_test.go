@@ -0,0 +1,161 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/nrexception/mockapi/pkg/certs"
+	se "github.com/nrexception/mockapi/pkg/settings"
+)
+
+func issueToFiles(t *testing.T, ca *certs.CA, dir string, name string, hostnames ...string) (string, string) {
+	t.Helper()
+
+	cert, err := ca.Issue(hostnames, []net.IP{net.ParseIP("127.0.0.1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certPath, keyPath := filepath.Join(dir, name+".pem"), filepath.Join(dir, name+"-key.pem")
+	err = certs.SaveKeyPair(cert, certPath, keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return certPath, keyPath
+}
+
+// Handshakes with the listener, returning the leaf certificate it served and the negotiated state.
+func handshake(t *testing.T, port int, config *tls.Config) (*x509.Certificate, tls.ConnectionState, error) {
+	t.Helper()
+
+	conn, err := tls.Dial("tcp", "127.0.0.1:"+strconv.Itoa(port), config)
+	if err != nil {
+		return nil, tls.ConnectionState{}, err
+	}
+	defer conn.Close()
+
+	state := conn.ConnectionState()
+	return state.PeerCertificates[0], state, nil
+}
+
+func TestListenerManager_SNICertificates(t *testing.T) {
+	t.Parallel()
+
+	ca, err := certs.NewCA()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	defaultCert, defaultKey := issueToFiles(t, ca, dir, "default", "localhost")
+	apiCert, apiKey := issueToFiles(t, ca, dir, "api", "api.example.com")
+	wildCert, wildKey := issueToFiles(t, ca, dir, "wild", "*.example.org")
+
+	m := NewListenerManager(nil, nil)
+	defer m.Close()
+
+	settings := se.UnmarshalledRootSettings{WebListeners: []se.UnmarshalledRootSettingWebListener{{
+		ListenerName: "sni",
+		EnableTLS:    true,
+		CertDetails: &se.UnmarshalledRootSettingWebListenerHTTPSCertFiles{
+			CertFile: defaultCert,
+			KeyFile:  defaultKey,
+			SNICerts: []se.SNICertificate{
+				{Hostnames: []string{"api.example.com"}, CertFile: apiCert, KeyFile: apiKey},
+				{Hostnames: []string{"*.example.org"}, CertFile: wildCert, KeyFile: wildKey},
+			},
+		},
+		ContentBindings: []se.ResponseBinding{{Path: "/", ResponseCode: http.StatusOK, ResponseBody: "ok", ResponseBodyType: se.Inline}},
+	}}}
+	err = m.Apply(&settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listenerPort(t, m, "sni")
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Cert)
+
+	testCases := []struct {
+		serverName string
+		commonName string
+	}{
+		{serverName: "api.example.com", commonName: "api.example.com"},
+		{serverName: "www.example.org", commonName: "*.example.org"},
+		{serverName: "a.b.example.org", commonName: "localhost"},
+		{serverName: "localhost", commonName: "localhost"},
+		{serverName: "", commonName: "localhost"},
+	}
+
+	for _, tc := range testCases {
+		cert, _, err := handshake(t, port, &tls.Config{ServerName: tc.serverName, InsecureSkipVerify: true})
+		if err != nil {
+			t.Fatalf("%q: %s", tc.serverName, err)
+		}
+		if cert.Subject.CommonName != tc.commonName {
+			t.Errorf("%q: expected %s, got %s", tc.serverName, tc.commonName, cert.Subject.CommonName)
+		}
+	}
+
+	// Rotating the files is picked up without restarting the listener
+	before, _, err := handshake(t, port, &tls.Config{ServerName: "api.example.com", RootCAs: roots})
+	if err != nil {
+		t.Fatal(err)
+	}
+	issueToFiles(t, ca, dir, "api", "api.example.com")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		after, _, err := handshake(t, port, &tls.Config{ServerName: "api.example.com", RootCAs: roots})
+		if err == nil && after.SerialNumber.Cmp(before.SerialNumber) != 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("certificate was not reloaded")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestListenerManager_TLSOptions(t *testing.T) {
+	t.Parallel()
+
+	m := NewListenerManager(nil, nil)
+	defer m.Close()
+
+	err := m.Apply(&se.UnmarshalledRootSettings{WebListeners: []se.UnmarshalledRootSettingWebListener{{
+		ListenerName: "legacy",
+		EnableTLS:    true,
+		AutoTLS:      &se.AutoTLSSettings{SelfSigned: true},
+		TLSOptions: &se.TLSOptions{
+			MinVersion:   "1.2",
+			MaxVersion:   "1.2",
+			CipherSuites: []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"},
+			ALPN:         []string{"http/1.1"},
+		},
+		ContentBindings: []se.ResponseBinding{{Path: "/", ResponseCode: http.StatusOK, ResponseBody: "ok", ResponseBodyType: se.Inline}},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listenerPort(t, m, "legacy")
+
+	_, state, err := handshake(t, port, &tls.Config{InsecureSkipVerify: true, NextProtos: []string{"h2", "http/1.1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Version != tls.VersionTLS12 || state.CipherSuite != tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256 || state.NegotiatedProtocol != "http/1.1" {
+		t.Errorf("unexpected connection state: version %x, cipher %s, alpn %q", state.Version, tls.CipherSuiteName(state.CipherSuite), state.NegotiatedProtocol)
+	}
+
+	_, _, err = handshake(t, port, &tls.Config{InsecureSkipVerify: true, MinVersion: tls.VersionTLS13})
+	if err == nil {
+		t.Error("expected a tls 1.3 only client to be refused")
+	}
+}
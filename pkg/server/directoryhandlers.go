@@ -0,0 +1,189 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+
+	co "github.com/nrexception/mockapi/pkg/common"
+	se "github.com/nrexception/mockapi/pkg/settings"
+)
+
+var defaultIndexFiles = []string{"index.html"}
+
+// Turns a bindings path into a subtree pattern, so sub-paths of the binding resolve against the served directory.
+func directoryBindingPattern(bindingPath string) string {
+	if strings.HasSuffix(bindingPath, "/") {
+		return bindingPath
+	}
+
+	return bindingPath + "/"
+}
+
+// Resolves a request path to a file path underneath root, refusing anything that escapes it (including via symlinks).
+func resolveDirectoryPath(root string, requestPath string) (string, error) {
+	if strings.ContainsAny(requestPath, "\\\x00") {
+		return "", fmt.Errorf("resolveDirectoryPath: invalid character in path \"%s\"", requestPath)
+	}
+
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return "", fmt.Errorf("resolveDirectoryPath: %w", err)
+	}
+	absRoot, err = filepath.EvalSymlinks(absRoot)
+	if err != nil {
+		return "", fmt.Errorf("resolveDirectoryPath: %w", err)
+	}
+
+	// Cleaning a rooted path drops any ".." that would climb above it...
+	target := filepath.Join(absRoot, filepath.FromSlash(path.Clean("/"+requestPath)))
+
+	resolved, err := filepath.EvalSymlinks(target)
+	if err != nil {
+		return "", fmt.Errorf("resolveDirectoryPath: %w", err)
+	}
+
+	if resolved != absRoot && !strings.HasPrefix(resolved, absRoot+string(filepath.Separator)) {
+		return "", fmt.Errorf("resolveDirectoryPath: %w", fs.ErrPermission)
+	}
+
+	return resolved, nil
+}
+
+// Weak validator built from size and modification time, cheap enough to compute per request.
+func fileETag(stat os.FileInfo) string {
+	return fmt.Sprintf("W/\"%x-%x\"", stat.Size(), stat.ModTime().UnixNano())
+}
+
+// Serves a single file, http.ServeContent takes care of Range, If-Range, If-None-Match and If-Modified-Since for us.
+func serveDirectoryFile(w http.ResponseWriter, r *http.Request, filePath string, stat os.FileInfo) error {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("serveDirectoryFile: %w", err)
+	}
+	defer f.Close()
+
+	w.Header().Set("ETag", fileETag(stat))
+	http.ServeContent(w, r, stat.Name(), stat.ModTime(), f)
+
+	return nil
+}
+
+func writeDirectoryListing(w http.ResponseWriter, r *http.Request, dirPath string) error {
+	entries, err := os.ReadDir(dirPath)
+	if err != nil {
+		return fmt.Errorf("writeDirectoryListing: %w", err)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		return nil
+	}
+
+	title := html.EscapeString(r.URL.Path)
+	_, _ = io.WriteString(w, "<!doctype html>\n<html><head><title>"+title+"</title></head><body>\n<h1>"+title+"</h1>\n<pre>\n")
+	_, _ = io.WriteString(w, "<a href=\"../\">../</a>\n")
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() {
+			name += "/"
+		}
+		href := (&url.URL{Path: name}).String()
+		_, _ = fmt.Fprintf(w, "<a href=\"%s\">%s</a>\n", html.EscapeString(href), html.EscapeString(name))
+	}
+	_, _ = io.WriteString(w, "</pre>\n</body></html>\n")
+
+	return nil
+}
+
+func serveDirectory(w http.ResponseWriter, r *http.Request, binding se.ResponseBinding, threaduuid uuid.UUID) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	requestPath := strings.TrimPrefix(r.URL.Path, strings.TrimSuffix(binding.Path, "/"))
+
+	filePath, err := resolveDirectoryPath(binding.ResponseBody, requestPath)
+	if err != nil {
+		co.LogVerboseOnThread(threaduuid, co.MSGTYPE_WARN, "could not resolve directory path", co.LOGKEY_BINDING, binding.Path, co.LOGKEY_PATH, r.URL.Path, co.LOGKEY_ERROR, err)
+		if errors.Is(err, fs.ErrPermission) {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+		http.NotFound(w, r)
+		return
+	}
+
+	stat, err := os.Stat(filePath)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	if stat.IsDir() {
+		// Keep relative links working, the same way http.FileServer does...
+		if !strings.HasSuffix(r.URL.Path, "/") {
+			target := path.Base(r.URL.Path) + "/"
+			if r.URL.RawQuery != "" {
+				target += "?" + r.URL.RawQuery
+			}
+			http.Redirect(w, r, target, http.StatusMovedPermanently)
+			return
+		}
+
+		indexFiles := defaultIndexFiles
+		enableListing := false
+		if binding.DirectoryOptions != nil {
+			enableListing = binding.DirectoryOptions.EnableListing
+			if len(binding.DirectoryOptions.IndexFiles) > 0 {
+				indexFiles = binding.DirectoryOptions.IndexFiles
+			}
+		}
+
+		for _, i := range indexFiles {
+			indexPath := filepath.Join(filePath, i)
+			indexStat, err := os.Stat(indexPath)
+			if err != nil || indexStat.IsDir() {
+				continue
+			}
+
+			err = serveDirectoryFile(w, r, indexPath, indexStat)
+			if err != nil {
+				co.LogVerboseOnThread(threaduuid, co.MSGTYPE_WARN, "failed to serve index", co.LOGKEY_BINDING, binding.Path, co.LOGKEY_ERROR, err)
+				http.NotFound(w, r)
+			}
+			return
+		}
+
+		if !enableListing {
+			http.NotFound(w, r)
+			return
+		}
+
+		err = writeDirectoryListing(w, r, filePath)
+		if err != nil {
+			co.LogVerboseOnThread(threaduuid, co.MSGTYPE_WARN, "failed to list directory", co.LOGKEY_BINDING, binding.Path, co.LOGKEY_ERROR, err)
+			http.NotFound(w, r)
+		}
+		return
+	}
+
+	err = serveDirectoryFile(w, r, filePath, stat)
+	if err != nil {
+		co.LogVerboseOnThread(threaduuid, co.MSGTYPE_WARN, "failed to serve file", co.LOGKEY_BINDING, binding.Path, co.LOGKEY_ERROR, err)
+		http.NotFound(w, r)
+	}
+}
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+
+	se "github.com/nrexception/mockapi/pkg/settings"
+)
+
+func newDirectoryFixture(t *testing.T) string {
+	t.Helper()
+
+	root := t.TempDir()
+	err := os.WriteFile(filepath.Join(root, "index.html"), []byte("<h1>index</h1>"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Mkdir(filepath.Join(root, "assets"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(root, "assets", "app.js"), []byte("0123456789"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return root
+}
+
+func TestServeDirectory(t *testing.T) {
+	t.Parallel()
+
+	root := newDirectoryFixture(t)
+
+	testCases := []struct {
+		name           string
+		path           string
+		headers        map[string]string
+		listing        bool
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "index file",
+			path:           "/static/",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "<h1>index</h1>",
+		},
+		{
+			name:           "nested file",
+			path:           "/static/assets/app.js",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "0123456789",
+		},
+		{
+			name:           "missing file",
+			path:           "/static/nope.txt",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "directory redirect",
+			path:           "/static/assets",
+			expectedStatus: http.StatusMovedPermanently,
+		},
+		{
+			name:           "directory without listing",
+			path:           "/static/assets/",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "directory with listing",
+			path:           "/static/assets/",
+			listing:        true,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "traversal",
+			path:           "/static/../../etc/passwd",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "range",
+			path:           "/static/assets/app.js",
+			headers:        map[string]string{"Range": "bytes=2-4"},
+			expectedStatus: http.StatusPartialContent,
+			expectedBody:   "234",
+		},
+		{
+			name:           "not modified",
+			path:           "/static/assets/app.js",
+			headers:        map[string]string{"If-Modified-Since": "Fri, 01 Jan 2100 00:00:00 GMT"},
+			expectedStatus: http.StatusNotModified,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			binding := se.ResponseBinding{
+				Path:             "/static",
+				ResponseBody:     root,
+				ResponseBodyType: se.Directory,
+				DirectoryOptions: &se.DirectoryOptions{EnableListing: tc.listing},
+			}
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.URL.Path = tc.path // Bypass NewRequest cleaning so traversal reaches the handler
+			for k, v := range tc.headers {
+				r.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+
+			serveDirectory(w, r, binding, uuid.New())
+
+			if w.Code != tc.expectedStatus {
+				t.Fatalf("unexpected status:\ngot: %d\nwant:%d", w.Code, tc.expectedStatus)
+			}
+			if tc.expectedBody != "" && w.Body.String() != tc.expectedBody {
+				t.Errorf("unexpected body:\ngot: %s\nwant:%s", w.Body.String(), tc.expectedBody)
+			}
+		})
+	}
+}
+
+func TestServeDirectory_ETag(t *testing.T) {
+	t.Parallel()
+
+	binding := se.ResponseBinding{Path: "/static/", ResponseBody: newDirectoryFixture(t), ResponseBodyType: se.Directory}
+
+	w := httptest.NewRecorder()
+	serveDirectory(w, httptest.NewRequest(http.MethodGet, "/static/assets/app.js", nil), binding, uuid.New())
+
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expected an ETag header")
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/static/assets/app.js", nil)
+	r.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	serveDirectory(w, r, binding, uuid.New())
+
+	if w.Code != http.StatusNotModified {
+		t.Errorf("unexpected status:\ngot: %d\nwant:%d", w.Code, http.StatusNotModified)
+	}
+}
@@ -4,17 +4,116 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sync"
+
+	co "github.com/nrexception/mockapi/pkg/common"
 )
 
 func readFileContent(filePath string) (string, error) {
 	b, err := os.ReadFile(filePath)
-
+	if err != nil {
+		return "", fmt.Errorf("readFileContent: %w", err)
+	}
 	if len(b) <= 0 {
 		return "", fmt.Errorf("readFileContent: %w", errors.New("file has no content."))
 	}
+
+	return string(b), nil
+}
+
+// In-memory cache of file-backed response bodies, entries are dropped as soon as the watcher sees their file change.
+type fileContentCache struct {
+	mu          sync.Mutex
+	entries     map[string]string
+	generations map[string]uint64 // Bumped by every invalidation, so a read that raced one isn't cached
+	read        func(filePath string) (string, error)
+	watcher     *co.FileWatcher
+	startOnce   sync.Once
+	stopped     chan struct{} // Closed once run has returned
+}
+
+func newFileContentCache() *fileContentCache {
+	return &fileContentCache{entries: map[string]string{}, generations: map[string]uint64{}, read: readFileContent, stopped: make(chan struct{})}
+}
+
+func (c *fileContentCache) get(filePath string) (string, error) {
+	filePath = filepath.Clean(filePath) // Matches the paths reported by the watcher
+
+	c.mu.Lock()
+	content, ok := c.entries[filePath]
+	generation := c.generations[filePath]
+	c.mu.Unlock()
+	if ok {
+		return content, nil
+	}
+
+	// Watch before reading, so a change landing in between still invalidates what we're about to cache...
+	err := c.watch(filePath)
 	if err != nil {
-		return "", fmt.Errorf("readFileContent: %w", err)
+		co.LogNonVerbose("not caching response body file", co.MSGTYPE_WARN, co.LOGKEY_FILE, filePath, co.LOGKEY_ERROR, err)
+		return c.read(filePath)
 	}
 
-	return string(b), nil
+	content, err = c.read(filePath)
+	if err != nil {
+		return "", fmt.Errorf("fileContentCache.get: %w", err)
+	}
+
+	// Invalidated while we were reading, what we read may already be stale so the next get reads it again
+	c.mu.Lock()
+	if c.generations[filePath] == generation {
+		c.entries[filePath] = content
+	}
+	c.mu.Unlock()
+
+	return content, nil
+}
+
+func (c *fileContentCache) invalidate(filePath string) {
+	c.mu.Lock()
+	delete(c.entries, filePath)
+	c.generations[filePath]++
+	c.mu.Unlock()
+}
+
+func (c *fileContentCache) watch(filePath string) error {
+	var err error
+	c.startOnce.Do(func() {
+		c.watcher, err = co.NewFileWatcher(co.DefaultWatchDebounce)
+		if err == nil {
+			go c.run()
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("fileContentCache.watch: %w", err)
+	}
+	if c.watcher == nil {
+		return fmt.Errorf("fileContentCache.watch: no file watcher available")
+	}
+
+	return c.watcher.Add(filePath)
+}
+
+// Drops cached content as files change, until the watcher is closed.
+func (c *fileContentCache) run() {
+	defer close(c.stopped)
+
+	for e := range c.watcher.Events {
+		co.LogVerbose(fmt.Sprintf("response body file was %s, dropping cached content", e.Type), co.MSGTYPE_INFO, co.LOGKEY_FILE, e.FileName)
+		c.invalidate(e.FileName)
+	}
+}
+
+// Stops watching and waits for run to return. Files read afterwards aren't cached.
+func (c *fileContentCache) close() error {
+	c.startOnce.Do(func() {}) // No watcher is started once closing
+	if c.watcher == nil {
+		return nil
+	}
+
+	err := c.watcher.Close()
+	<-c.stopped
+
+	return err
 }
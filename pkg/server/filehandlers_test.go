@@ -0,0 +1,210 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	se "github.com/nrexception/mockapi/pkg/settings"
+)
+
+// Polls until done holds, the watcher debounces so the cache sees changes a moment after they land.
+func eventually(t *testing.T, what string, done func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !done() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestFileContentCache(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "body.json")
+	err := os.WriteFile(path, []byte(`{"v":1}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := newFileContentCache()
+	t.Cleanup(func() { _ = c.close() })
+
+	got, err := c.get(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != `{"v":1}` {
+		t.Fatalf("unexpected content: %s", got)
+	}
+
+	// A hit is answered from the entry, not the file
+	c.mu.Lock()
+	c.entries[path] = "cached"
+	c.mu.Unlock()
+	got, err = c.get(path)
+	if err != nil || got != "cached" {
+		t.Fatalf("expected the cached content, got %q, %v", got, err)
+	}
+
+	err = os.WriteFile(path, []byte(`{"v":2}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, "the changed file to be read", func() bool {
+		got, err := c.get(path)
+		return err == nil && got == `{"v":2}`
+	})
+
+	err = os.Remove(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, "the removed file to be dropped", func() bool {
+		_, err := c.get(path)
+		return err != nil
+	})
+}
+
+func TestFileContentCache_InvalidatedWhileReading(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "body.json")
+	err := os.WriteFile(path, []byte(`{"v":1}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := newFileContentCache()
+	t.Cleanup(func() { _ = c.close() })
+
+	// The file changes after it's read but before what was read is cached
+	c.read = func(filePath string) (string, error) {
+		content, err := readFileContent(filePath)
+		c.invalidate(filePath)
+		return content, err
+	}
+	got, err := c.get(path)
+	if err != nil || got != `{"v":1}` {
+		t.Fatalf("unexpected content: %q, %v", got, err)
+	}
+	c.mu.Lock()
+	_, cached := c.entries[path]
+	c.mu.Unlock()
+	if cached {
+		t.Error("content read before an invalidation was cached")
+	}
+
+	c.read = readFileContent
+	_, err = c.get(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.mu.Lock()
+	_, cached = c.entries[path]
+	c.mu.Unlock()
+	if !cached {
+		t.Error("content read without an invalidation wasn't cached")
+	}
+}
+
+func TestFileContentCache_Close(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "body.json")
+	err := os.WriteFile(path, []byte(`{"v":1}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := newFileContentCache()
+	_, err = c.get(path) // Starts the watcher
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-c.stopped:
+	default:
+		t.Error("close returned with the watching goroutine still running")
+	}
+}
+
+func TestListenerManager_FileErrorResponse(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	emptyFile := filepath.Join(dir, "empty.json")
+	err := os.WriteFile(emptyFile, nil, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	missingFile := filepath.Join(dir, "missing.json")
+
+	m := NewListenerManager(nil, nil)
+	t.Cleanup(func() { _ = m.Close() })
+
+	custom := &se.FileErrorResponse{ResponseCode: http.StatusNotFound, ResponseBody: `{"error":"gone"}`}
+	err = m.Apply(&se.UnmarshalledRootSettings{WebListeners: []se.UnmarshalledRootSettingWebListener{{
+		ListenerName: "files",
+		ContentBindings: []se.ResponseBinding{
+			{Path: "/missing", ResponseCode: http.StatusOK, ResponseBody: missingFile, ResponseBodyType: se.File},
+			{Path: "/empty", ResponseCode: http.StatusOK, ResponseBody: emptyFile, ResponseBodyType: se.File},
+			{Path: "/missing-custom", ResponseCode: http.StatusOK, ResponseBody: missingFile, ResponseBodyType: se.File, FileErrorResponse: custom},
+			{Path: "/empty-custom", ResponseCode: http.StatusOK, ResponseBody: emptyFile, ResponseBodyType: se.File, FileErrorResponse: custom},
+			{Path: "/code-only", ResponseCode: http.StatusOK, ResponseBody: missingFile, ResponseBodyType: se.File, FileErrorResponse: &se.FileErrorResponse{ResponseCode: http.StatusServiceUnavailable}},
+		},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listenerPort(t, m, "files")
+
+	testCases := []struct {
+		path                string
+		expectedCode        int
+		expectedBody        string
+		expectedContentType string
+	}{
+		{path: "/missing", expectedCode: http.StatusInternalServerError, expectedBody: `response body file for "/missing" is missing or empty`, expectedContentType: "text/plain; charset=utf-8"},
+		{path: "/empty", expectedCode: http.StatusInternalServerError, expectedBody: `response body file for "/empty" is missing or empty`, expectedContentType: "text/plain; charset=utf-8"},
+		{path: "/missing-custom", expectedCode: http.StatusNotFound, expectedBody: `{"error":"gone"}`},
+		{path: "/empty-custom", expectedCode: http.StatusNotFound, expectedBody: `{"error":"gone"}`},
+		{path: "/code-only", expectedCode: http.StatusServiceUnavailable, expectedBody: `response body file for "/code-only" is missing or empty`, expectedContentType: "text/plain; charset=utf-8"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.path, func(t *testing.T) {
+			t.Parallel()
+
+			res, err := http.Get("http://127.0.0.1:" + strconv.Itoa(port) + tc.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+			b, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if res.StatusCode != tc.expectedCode || string(b) != tc.expectedBody {
+				t.Errorf("unexpected response: %d %s", res.StatusCode, b)
+			}
+			if tc.expectedContentType != "" && res.Header.Get("Content-Type") != tc.expectedContentType {
+				t.Errorf("unexpected content type: %s", res.Header.Get("Content-Type"))
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sync"
+	"text/template"
+
+	"github.com/google/uuid"
+
+	co "github.com/nrexception/mockapi/pkg/common"
+	"github.com/nrexception/mockapi/pkg/fake"
+	se "github.com/nrexception/mockapi/pkg/settings"
+)
+
+// Makes up a fresh body for every request. Requests take turns with the generator, so a seeded binding's bodies come
+// out in the same order every run.
+type generatedBody struct {
+	mu       sync.Mutex
+	g        *fake.Generator
+	template *template.Template
+	schema   map[string]any
+	example  any
+	count    int
+}
+
+func newGeneratedBody(settings se.GeneratedSettings) (*generatedBody, error) {
+	b := &generatedBody{g: fake.New(settings.Seed), example: settings.Example, count: settings.Count}
+
+	var err error
+	if settings.Template != "" {
+		b.template, err = b.g.Template("template", settings.Template)
+		if err != nil {
+			return nil, fmt.Errorf("newGeneratedBody: %w", err)
+		}
+	}
+
+	b.schema, err = settings.LoadSchema()
+	if err != nil {
+		return nil, fmt.Errorf("newGeneratedBody: %w", err)
+	}
+
+	return b, nil
+}
+
+func (b *generatedBody) one() (any, error) {
+	if b.schema != nil {
+		return b.g.FromSchema(b.schema)
+	}
+
+	return b.g.FromExample(b.example), nil
+}
+
+// The next body for req, and whether it's json.
+func (b *generatedBody) generate(req templateRequest) ([]byte, bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.template != nil {
+		var buf bytes.Buffer
+		err := b.template.Execute(&buf, req)
+		if err != nil {
+			return nil, false, fmt.Errorf("generate: %w", err)
+		}
+		return buf.Bytes(), false, nil
+	}
+
+	var v any
+	var err error
+	if b.count > 0 {
+		values := make([]any, b.count)
+		for i := range values {
+			values[i], err = b.one()
+			if err != nil {
+				break
+			}
+		}
+		v = values
+	} else {
+		v, err = b.one()
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("generate: %w", err)
+	}
+
+	body, err := marshalJSON(v)
+	if err != nil {
+		return nil, false, fmt.Errorf("generate: %w", err)
+	}
+
+	return body, true, nil
+}
+
+func (b *generatedBody) serve(w http.ResponseWriter, r *http.Request, binding se.ResponseBinding, threaduuid uuid.UUID) {
+	var req templateRequest
+	if b.template != nil {
+		req = newTemplateRequest(r, captureRequestBody(r, callbackMaxBodyBytes)())
+	}
+
+	body, isJSON, err := b.generate(req)
+	if err != nil {
+		co.LogNonVerboseOnThread(threaduuid, co.MSGTYPE_WARN, "could not generate response body", co.LOGKEY_BINDING, binding.Path, co.LOGKEY_ERROR, err)
+		http.Error(w, "response body could not be generated", http.StatusInternalServerError)
+		return
+	}
+
+	if isJSON && w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.WriteHeader(binding.ResponseCode)
+	_, _ = w.Write(body)
+}
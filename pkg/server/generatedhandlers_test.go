@@ -0,0 +1,100 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"testing"
+
+	se "github.com/nrexception/mockapi/pkg/settings"
+)
+
+func TestListenerManager_Generated(t *testing.T) {
+	t.Parallel()
+
+	seed := int64(2024)
+	bindings := func() []se.ResponseBinding {
+		return []se.ResponseBinding{
+			{
+				Path:             "/users",
+				ResponseCode:     http.StatusOK,
+				ResponseBodyType: se.Generated,
+				Generated: &se.GeneratedSettings{
+					Schema: map[string]any{"type": "object", "properties": map[string]any{
+						"id":    map[string]any{"type": "string", "format": "uuid"},
+						"email": map[string]any{"type": "string"},
+					}},
+					Count: 3,
+					Seed:  &seed,
+				},
+			},
+			{
+				Path:             "/profile",
+				ResponseCode:     http.StatusOK,
+				ResponseBodyType: se.Generated,
+				Generated:        &se.GeneratedSettings{Example: map[string]any{"name": "Ada", "age": 36}},
+			},
+			{
+				Path:             "/greeting",
+				ResponseCode:     http.StatusAccepted,
+				ResponseHeaders:  []se.ResponseHeader{{Key: "Content-Type", Value: "text/plain"}},
+				ResponseBodyType: se.Generated,
+				Generated:        &se.GeneratedSettings{Template: `Hello {{firstName}}, you have {{int 1 5}} messages`, Seed: &seed},
+			},
+		}
+	}
+
+	start := func() string {
+		t.Helper()
+
+		m := NewListenerManager(nil, nil)
+		t.Cleanup(func() { _ = m.Close() })
+
+		err := m.Apply(&se.UnmarshalledRootSettings{WebListeners: []se.UnmarshalledRootSettingWebListener{{ListenerName: "generated", ContentBindings: bindings()}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return "http://127.0.0.1:" + strconv.Itoa(listenerPort(t, m, "generated"))
+	}
+
+	get := func(url string) (*http.Response, string) {
+		t.Helper()
+		resp, err := http.Get(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		return resp, string(b)
+	}
+
+	first, second := start(), start()
+
+	resp, users := get(first + "/users")
+	var v []map[string]any
+	if err := json.Unmarshal([]byte(users), &v); err != nil || len(v) != 3 || resp.Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("unexpected users: %s (%v)", users, err)
+	}
+
+	// Seeded bindings make the same bodies, in the same order, on another server
+	if _, again := get(second + "/users"); again != users {
+		t.Errorf("seeded bodies differ:\n%s\n%s", users, again)
+	}
+	if _, next := get(first + "/users"); next == users {
+		t.Errorf("expected the next body to be different: %s", next)
+	}
+
+	_, profile := get(first + "/profile")
+	var p map[string]any
+	if err := json.Unmarshal([]byte(profile), &p); err != nil || p["name"] == "" || p["age"] == nil {
+		t.Errorf("unexpected profile: %s (%v)", profile, err)
+	}
+
+	resp, greeting := get(first + "/greeting")
+	_, again := get(second + "/greeting")
+	if resp.StatusCode != http.StatusAccepted || resp.Header.Get("Content-Type") != "text/plain" || greeting != again {
+		t.Errorf("unexpected greeting: %d %s, %s", resp.StatusCode, greeting, again)
+	}
+}
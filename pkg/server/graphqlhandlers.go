@@ -0,0 +1,479 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/google/uuid"
+
+	co "github.com/nrexception/mockapi/pkg/common"
+	"github.com/nrexception/mockapi/pkg/graphql"
+	se "github.com/nrexception/mockapi/pkg/settings"
+)
+
+const (
+	graphQLMaxRequestBytes = 1 << 20
+	graphQLPairedLists     = 3 // Made up lists nested deeper than this get one item, so results don't double with each
+)
+
+type graphQLOperation struct {
+	settings  se.GraphQLOperation
+	variables any            // settings.Variables as decoded json would hold them
+	data      map[string]any // settings.Data, likewise
+}
+
+// A graphql binding's schema and canned results, loaded once rather than per request.
+type graphQLMock struct {
+	schema     *graphql.Schema
+	operations []graphQLOperation
+	fields     map[string]any // Values by Type.field
+}
+
+// Round trips v through json, so yaml's ints and so on compare equal to what's in a request.
+func asDecodedJSON(v any) (any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var decoded any
+	err = json.Unmarshal(b, &decoded)
+	return decoded, err
+}
+
+// Like json.Marshal, without escaping <, > and & (which a GraphQL result has no reason to).
+func marshalJSON(v any) ([]byte, error) {
+	var b bytes.Buffer
+
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	err := encoder.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(b.Bytes(), []byte("\n")), nil
+}
+
+func newGraphQLMock(settings se.GraphQLSettings) (*graphQLMock, error) {
+	schema, err := graphql.LoadSchema(settings.SchemaFile)
+	if err != nil {
+		return nil, fmt.Errorf("newGraphQLMock: %w", err)
+	}
+
+	m := &graphQLMock{schema: schema, fields: map[string]any{}}
+
+	for _, o := range settings.Operations {
+		op := graphQLOperation{settings: o}
+		if o.Variables != nil {
+			op.variables, err = asDecodedJSON(o.Variables)
+			if err != nil {
+				return nil, fmt.Errorf("newGraphQLMock: variables for \"%s\": %w", o.Name, err)
+			}
+		}
+		if o.Data != nil {
+			data, err := asDecodedJSON(o.Data)
+			if err != nil {
+				return nil, fmt.Errorf("newGraphQLMock: data for \"%s\": %w", o.Name, err)
+			}
+			op.data, _ = data.(map[string]any)
+		}
+		m.operations = append(m.operations, op)
+	}
+
+	for _, f := range settings.Fields {
+		m.fields[f.Field], err = asDecodedJSON(f.Value)
+		if err != nil {
+			return nil, fmt.Errorf("newGraphQLMock: value for \"%s\": %w", f.Field, err)
+		}
+	}
+
+	return m, nil
+}
+
+type graphQLRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+type graphQLError struct {
+	Message    string             `json:"message"`
+	Locations  []graphql.Location `json:"locations,omitempty"`
+	Path       []any              `json:"path,omitempty"`
+	Extensions map[string]any     `json:"extensions,omitempty"`
+}
+
+type graphQLResponse struct {
+	Errors []graphQLError  `json:"errors,omitempty"`
+	Data   json.RawMessage `json:"data,omitempty"` // Left out when the query never ran
+}
+
+func graphQLErrors(errs ...*graphql.Error) []graphQLError {
+	converted := make([]graphQLError, 0, len(errs))
+	for _, e := range errs {
+		converted = append(converted, graphQLError{Message: e.Message, Locations: e.Locations})
+	}
+
+	return converted
+}
+
+// Reads a query the way GraphQL over HTTP sends them: in the query string for GET, as json (or a bare
+// application/graphql query) for POST.
+func readGraphQLRequest(r *http.Request) (graphQLRequest, error) {
+	var req graphQLRequest
+
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		q := r.URL.Query()
+		req.Query, req.OperationName = q.Get("query"), q.Get("operationName")
+		if v := q.Get("variables"); v != "" {
+			err := json.Unmarshal([]byte(v), &req.Variables)
+			if err != nil {
+				return req, fmt.Errorf("variables must be a json object: %w", err)
+			}
+		}
+	} else {
+		body, err := io.ReadAll(io.LimitReader(r.Body, graphQLMaxRequestBytes))
+		if err != nil {
+			return req, fmt.Errorf("reading the request: %w", err)
+		}
+
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if mediaType == "application/graphql" {
+			req.Query = string(body)
+		} else {
+			err = json.Unmarshal(body, &req)
+			if err != nil {
+				return req, fmt.Errorf("the request body must be a json object: %w", err)
+			}
+		}
+	}
+
+	if req.Query == "" {
+		return req, errors.New("no query was sent")
+	}
+
+	return req, nil
+}
+
+func writeGraphQLResponse(w http.ResponseWriter, code int, response graphQLResponse) {
+	b, err := marshalJSON(response)
+	if err != nil {
+		code, b = http.StatusInternalServerError, []byte(`{"errors":[{"message":"the result could not be encoded"}]}`)
+	}
+
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.WriteHeader(code)
+	_, _ = w.Write(b)
+}
+
+// The canned result for the operation, nil if none match.
+func (m *graphQLMock) cannedOperation(op *graphql.Operation, variables map[string]any) *graphQLOperation {
+	if op.Name == "" {
+		return nil
+	}
+
+	for i, o := range m.operations {
+		if o.settings.Name == op.Name && (o.variables == nil || jsonContains(o.variables, variables)) {
+			return &m.operations[i]
+		}
+	}
+
+	return nil
+}
+
+// Answers a GraphQL request: errors for queries that don't parse or don't fit the schema, otherwise the canned result
+// for the operation with any fields it leaves out made up.
+func (m *graphQLMock) serve(w http.ResponseWriter, r *http.Request, binding se.ResponseBinding, threaduuid uuid.UUID) {
+	code := http.StatusOK
+	if binding.ResponseCode != 0 {
+		code = binding.ResponseCode
+	}
+
+	req, err := readGraphQLRequest(r)
+	if err != nil {
+		co.LogVerboseOnThread(threaduuid, co.MSGTYPE_WARN, "bad graphql request", co.LOGKEY_BINDING, binding.Path, co.LOGKEY_ERROR, err)
+		writeGraphQLResponse(w, http.StatusBadRequest, graphQLResponse{Errors: []graphQLError{{Message: err.Error()}}})
+		return
+	}
+
+	var queryErrors []*graphql.Error
+	doc, err := graphql.ParseQuery(req.Query)
+	var gqlErr *graphql.Error
+	switch {
+	case errors.As(err, &gqlErr):
+		queryErrors = append(queryErrors, gqlErr)
+	case err != nil:
+		queryErrors = append(queryErrors, &graphql.Error{Message: err.Error()})
+	default:
+		queryErrors = m.schema.Validate(doc)
+	}
+
+	var op *graphql.Operation
+	if len(queryErrors) == 0 {
+		op, err = doc.Operation(req.OperationName)
+		if errors.As(err, &gqlErr) {
+			queryErrors = append(queryErrors, gqlErr)
+		}
+	}
+
+	if len(queryErrors) > 0 {
+		co.LogVerboseOnThread(threaduuid, co.MSGTYPE_INFO, "invalid graphql query", co.LOGKEY_BINDING, binding.Path, co.LOGKEY_ERROR, queryErrors[0])
+		writeGraphQLResponse(w, code, graphQLResponse{Errors: graphQLErrors(queryErrors...)})
+		return
+	}
+
+	if op.Type != "query" && (r.Method == http.MethodGet || r.Method == http.MethodHead) {
+		w.Header().Set("Allow", http.MethodPost)
+		writeGraphQLResponse(w, http.StatusMethodNotAllowed, graphQLResponse{Errors: []graphQLError{{Message: fmt.Sprintf("Can only perform a %s operation from a POST request.", op.Type)}}})
+		return
+	}
+
+	// Defaults fill in what the client left out, for @skip and @include and for matching canned variables
+	variables, variableErrors := m.schema.CoerceVariables(op, req.Variables)
+	if len(variableErrors) > 0 {
+		co.LogVerboseOnThread(threaduuid, co.MSGTYPE_INFO, "invalid graphql variables", co.LOGKEY_BINDING, binding.Path, co.LOGKEY_ERROR, variableErrors[0])
+		writeGraphQLResponse(w, code, graphQLResponse{Errors: graphQLErrors(variableErrors...)})
+		return
+	}
+
+	var response graphQLResponse
+	canned := m.cannedOperation(op, variables)
+	if canned != nil {
+		for _, e := range canned.settings.Errors {
+			response.Errors = append(response.Errors, graphQLError{Message: e.Message, Path: e.Path, Extensions: e.Extensions})
+		}
+	}
+
+	if canned != nil && len(canned.settings.Errors) > 0 && canned.data == nil {
+		response.Data = json.RawMessage("null")
+	} else {
+		var data map[string]any
+		if canned != nil {
+			data = canned.data
+		}
+		e := &graphQLExecution{mock: m, doc: doc, variables: variables}
+		var b bytes.Buffer
+		err = writeResult(&b, e.object(m.schema.RootType(op.Type).Name, op.SelectionSet, data))
+		response.Data = b.Bytes()
+		if err != nil {
+			co.LogNonVerboseOnThread(threaduuid, co.MSGTYPE_WARN, "graphql result could not be encoded", co.LOGKEY_BINDING, binding.Path, co.LOGKEY_ERROR, err)
+			response.Data = json.RawMessage("null")
+		}
+	}
+
+	writeGraphQLResponse(w, code, response)
+}
+
+type resultField struct {
+	key   string
+	value any
+}
+
+// An object in a result, its fields in the order the query asked for them.
+type resultObject []resultField
+
+// Encodes a result in one pass. encoding/json would go over each object again for every object it's nested in.
+func writeResult(b *bytes.Buffer, v any) error {
+	switch value := v.(type) {
+	case resultObject:
+		b.WriteByte('{')
+		for i, f := range value {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			key, _ := marshalJSON(f.key)
+			b.Write(key)
+			b.WriteByte(':')
+			err := writeResult(b, f.value)
+			if err != nil {
+				return err
+			}
+		}
+		b.WriteByte('}')
+	case []any:
+		b.WriteByte('[')
+		for i, item := range value {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			err := writeResult(b, item)
+			if err != nil {
+				return err
+			}
+		}
+		b.WriteByte(']')
+	default:
+		leaf, err := marshalJSON(v)
+		if err != nil {
+			return err
+		}
+		b.Write(leaf)
+	}
+
+	return nil
+}
+
+// The fields selected under one response key, which may be asked for more than once (through fragments say).
+type fieldGroup struct {
+	key        string
+	selections []*graphql.Selection
+}
+
+// Builds one result. Values come from the canned data first, then canned fields, and are made up when neither has
+// them.
+type graphQLExecution struct {
+	mock      *graphQLMock
+	doc       *graphql.Document
+	variables map[string]any
+	ids       int // Made up IDs count up, so lists don't repeat them
+	lists     int // Made up lists the value being completed is in
+}
+
+// Whether a fragment on condition applies to an object of typeName.
+func (e *graphQLExecution) fragmentApplies(typeName string, condition string) bool {
+	return condition == "" || condition == typeName || slices.Contains(e.mock.schema.PossibleTypes(condition), typeName)
+}
+
+func (e *graphQLExecution) collectFields(typeName string, selections []*graphql.Selection, groups []fieldGroup, spread map[string]bool) []fieldGroup {
+	for _, sel := range selections {
+		if graphql.Skipped(sel.Directives, e.variables) {
+			continue
+		}
+
+		switch sel.Kind {
+		case graphql.FieldSelection:
+			i := slices.IndexFunc(groups, func(g fieldGroup) bool { return g.key == sel.ResponseKey() })
+			if i < 0 {
+				groups = append(groups, fieldGroup{key: sel.ResponseKey()})
+				i = len(groups) - 1
+			}
+			groups[i].selections = append(groups[i].selections, sel)
+		case graphql.InlineFragment:
+			if e.fragmentApplies(typeName, sel.TypeCondition) {
+				groups = e.collectFields(typeName, sel.SelectionSet, groups, spread)
+			}
+		case graphql.FragmentSpread:
+			f := e.doc.Fragment(sel.Name)
+			if spread[sel.Name] || f == nil || !e.fragmentApplies(typeName, f.TypeCondition) {
+				continue
+			}
+			spread[sel.Name] = true
+			groups = e.collectFields(typeName, f.SelectionSet, groups, spread)
+		}
+	}
+
+	return groups
+}
+
+func (e *graphQLExecution) object(typeName string, selections []*graphql.Selection, canned map[string]any) resultObject {
+	t := e.mock.schema.Types[typeName]
+	result := resultObject{}
+
+	for _, g := range e.collectFields(typeName, selections, nil, map[string]bool{}) {
+		field := g.selections[0]
+		if field.Name == "__typename" {
+			result = append(result, resultField{key: g.key, value: typeName})
+			continue
+		}
+
+		value, present := canned[g.key]
+		if !present {
+			value, present = e.mock.fields[typeName+"."+field.Name]
+		}
+
+		var subselections []*graphql.Selection
+		for _, sel := range g.selections {
+			subselections = append(subselections, sel.SelectionSet...)
+		}
+
+		def := t.Field(field.Name)
+		result = append(result, resultField{key: g.key, value: e.complete(def.Type, field.Name, subselections, value, present)})
+	}
+
+	return result
+}
+
+// Shapes value to fit t, or makes one up if there isn't one.
+func (e *graphQLExecution) complete(t *graphql.TypeRef, fieldName string, selections []*graphql.Selection, value any, present bool) any {
+	if present && value == nil {
+		return nil
+	}
+
+	if t.Elem != nil {
+		var list []any
+		if present {
+			items, ok := value.([]any)
+			if !ok {
+				items = []any{value}
+			}
+			for _, item := range items {
+				list = append(list, e.complete(t.Elem, fieldName, selections, item, true))
+			}
+		} else {
+			items := 2
+			if e.lists >= graphQLPairedLists {
+				items = 1
+			}
+			e.lists++
+			for range items {
+				list = append(list, e.complete(t.Elem, fieldName, selections, nil, false))
+			}
+			e.lists--
+		}
+		return list
+	}
+
+	def := e.mock.schema.Types[t.Name]
+	if !def.IsComposite() {
+		if present {
+			return value
+		}
+		return e.generate(def, fieldName)
+	}
+
+	canned, _ := value.(map[string]any)
+	typeName := def.Name
+	if def.Kind != graphql.ObjectKind {
+		possible := e.mock.schema.PossibleTypes(def.Name)
+		if len(possible) == 0 {
+			return nil
+		}
+		typeName = possible[0]
+		if named, ok := canned["__typename"].(string); ok && slices.Contains(possible, named) {
+			typeName = named
+		}
+	}
+
+	return e.object(typeName, selections, canned)
+}
+
+// A made up value of the scalar or enum type.
+func (e *graphQLExecution) generate(t *graphql.TypeDef, fieldName string) any {
+	if t.Kind == graphql.EnumKind {
+		return t.EnumValues[0]
+	}
+
+	switch t.Name {
+	case "ID":
+		e.ids++
+		return strconv.Itoa(e.ids)
+	case "Int":
+		return 42
+	case "Float":
+		return 4.2
+	case "Boolean":
+		return true
+	}
+
+	return fieldName // Strings, and custom scalars we can't know the shape of
+}
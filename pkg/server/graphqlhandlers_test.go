@@ -0,0 +1,172 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	se "github.com/nrexception/mockapi/pkg/settings"
+)
+
+func TestListenerManager_GraphQL(t *testing.T) {
+	t.Parallel()
+
+	schemaFile := filepath.Join(t.TempDir(), "schema.graphql")
+	err := os.WriteFile(schemaFile, []byte(`
+type Query { user(id: ID!): User, users: [User!]!, node(id: ID!): Node }
+type Mutation { rename(id: ID!, name: String!): User }
+interface Node { id: ID! }
+type User implements Node { id: ID!, name: String!, age: Int, role: Role!, friends: [User!]! }
+type Team implements Node { id: ID! }
+enum Role { ADMIN MEMBER }
+`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	graphQL := &se.GraphQLSettings{
+		SchemaFile: schemaFile,
+		Operations: []se.GraphQLOperation{
+			{Name: "GetUser", Variables: map[string]any{"id": "7"}, Data: map[string]any{"user": map[string]any{"id": "7", "name": "Ada", "age": nil}}},
+			{Name: "GetUser", Errors: []se.GraphQLError{{Message: "user not found", Path: []any{"user"}, Extensions: map[string]any{"code": "NOT_FOUND"}}}},
+		},
+		Fields: []se.GraphQLField{{Field: "User.name", Value: "Grace"}},
+	}
+
+	m := NewListenerManager(nil, nil)
+	t.Cleanup(func() { _ = m.Close() })
+
+	err = m.Apply(&se.UnmarshalledRootSettings{WebListeners: []se.UnmarshalledRootSettingWebListener{{
+		ListenerName:    "graphql",
+		ContentBindings: []se.ResponseBinding{{Path: "/graphql", ResponseBodyType: se.GraphQL, GraphQL: graphQL}},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listenerPort(t, m, "graphql")
+
+	endpoint := "http://127.0.0.1:" + strconv.Itoa(port) + "/graphql"
+
+	testCases := []struct {
+		name         string
+		method       string
+		body         string
+		expectedCode int
+		expected     string
+	}{
+		{
+			name:         "canned operation",
+			body:         `{"query":"query GetUser($id: ID!) { user(id: $id) { id name age role } }","variables":{"id":"7"}}`,
+			expectedCode: http.StatusOK,
+			expected:     `{"data":{"user":{"id":"7","name":"Ada","age":null,"role":"ADMIN"}}}`,
+		},
+		{
+			name:         "canned errors",
+			body:         `{"query":"query GetUser($id: ID!) { user(id: $id) { id } }","variables":{"id":"8"}}`,
+			expectedCode: http.StatusOK,
+			expected:     `{"errors":[{"message":"user not found","path":["user"],"extensions":{"code":"NOT_FOUND"}}],"data":null}`,
+		},
+		{
+			name:         "generated values, canned fields, fragments and aliases",
+			body:         `{"query":"{ users { ...U } first: node(id: \"1\") { __typename ... on User { role } } } fragment U on User { id name }"}`,
+			expectedCode: http.StatusOK,
+			expected:     `{"data":{"users":[{"id":"1","name":"Grace"},{"id":"2","name":"Grace"}],"first":{"__typename":"Team"}}}`,
+		},
+		{
+			name:         "skip",
+			body:         `{"query":"query($skip: Boolean = true) { users { id @skip(if: $skip) age } }"}`,
+			expectedCode: http.StatusOK,
+			expected:     `{"data":{"users":[{"age":42},{"age":42}]}}`,
+		},
+		{
+			name:         "nested made up lists",
+			body:         `{"query":"{ users { friends { friends { friends { id } } } } }"}`,
+			expectedCode: http.StatusOK,
+			expected:     `{"data":{"users":[{"friends":[{"friends":[{"friends":[{"id":"1"}]},{"friends":[{"id":"2"}]}]},{"friends":[{"friends":[{"id":"3"}]},{"friends":[{"id":"4"}]}]}]},{"friends":[{"friends":[{"friends":[{"id":"5"}]},{"friends":[{"id":"6"}]}]},{"friends":[{"friends":[{"id":"7"}]},{"friends":[{"id":"8"}]}]}]}]}}`,
+		},
+		{
+			name:         "deeply nested made up lists",
+			body:         `{"query":"{ users ` + strings.Repeat("{ friends ", 30) + "{ id }" + strings.Repeat(" }", 31) + `"}`,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "invalid query",
+			body:         `{"query":"{ users { email } }"}`,
+			expectedCode: http.StatusOK,
+			expected:     `{"errors":[{"message":"Cannot query field \"email\" on type \"User\".","locations":[{"line":1,"column":11}]}]}`,
+		},
+		{
+			name:         "conflicting aliases",
+			body:         `{"query":"query Q { a: users { id } a: user(id: \"1\") { name } }"}`,
+			expectedCode: http.StatusOK,
+			expected:     `{"errors":[{"message":"Fields \"a\" conflict because \"users\" and \"user\" are different fields. Use different aliases on the fields to fetch both if this was intentional.","locations":[{"line":1,"column":11},{"line":1,"column":27}]}]}`,
+		},
+		{
+			name:         "missing variable",
+			body:         `{"query":"query GetUser($id: ID!) { user(id: $id) { id } }"}`,
+			expectedCode: http.StatusOK,
+			expected:     `{"errors":[{"message":"Variable \"$id\" of required type \"ID!\" was not provided.","locations":[{"line":1,"column":15}]}]}`,
+		},
+		{
+			name:         "syntax error",
+			body:         `{"query":"{ users { id }"}`,
+			expectedCode: http.StatusOK,
+			expected:     `{"errors":[{"message":"Syntax Error: Expected Name, found <EOF>.","locations":[{"line":1,"column":15}]}]}`,
+		},
+		{
+			name:         "not json",
+			body:         `users`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "mutation over GET",
+			method:       http.MethodGet,
+			body:         `mutation { rename(id: "1", name: "x") { id } }`,
+			expectedCode: http.StatusMethodNotAllowed,
+			expected:     `{"errors":[{"message":"Can only perform a mutation operation from a POST request."}]}`,
+		},
+		{
+			name:         "query over GET",
+			method:       http.MethodGet,
+			body:         `{ user(id: "1") { role } }`,
+			expectedCode: http.StatusOK,
+			expected:     `{"data":{"user":{"role":"ADMIN"}}}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var res *http.Response
+			var err error
+			if tc.method == http.MethodGet {
+				res, err = http.Get(endpoint + "?query=" + url.QueryEscape(tc.body))
+			} else {
+				res, err = http.Post(endpoint, "application/json", strings.NewReader(tc.body))
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+
+			body, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.StatusCode != tc.expectedCode || res.Header.Get("Content-Type") != "application/json" {
+				t.Errorf("unexpected response: %d %s", res.StatusCode, res.Header.Get("Content-Type"))
+			}
+			if tc.expected != "" && string(body) != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, body)
+			}
+		})
+	}
+}
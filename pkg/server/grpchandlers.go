@@ -0,0 +1,282 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+
+	co "github.com/nrexception/mockapi/pkg/common"
+	"github.com/nrexception/mockapi/pkg/protobuf"
+	se "github.com/nrexception/mockapi/pkg/settings"
+)
+
+const grpcMaxMessageBytes = 4 << 20 // gRPC's default limit
+
+const (
+	grpcCodeOK            = 0
+	grpcCodeInternal      = 13
+	grpcCodeUnimplemented = 12
+)
+
+type grpcResponse struct {
+	settings se.GRPCResponse
+	match    any      // settings.Match as decoded json would hold it
+	code     int      // settings.Status as a number
+	messages [][]byte // settings.Messages, encoded
+}
+
+// A listener's gRPC services: their descriptors and canned responses, encoded once rather than per call.
+type grpcMock struct {
+	registry   *protobuf.Registry
+	responses  map[string][]grpcResponse // By method path, /package.Service/Method
+	reflection bool
+	threaduuid uuid.UUID
+}
+
+func newGRPCMock(settings se.GRPCSettings, threaduuid uuid.UUID) (*grpcMock, error) {
+	registry, err := protobuf.LoadDescriptorSet(settings.DescriptorSet)
+	if err != nil {
+		return nil, fmt.Errorf("newGRPCMock: %w", err)
+	}
+
+	g := &grpcMock{registry: registry, responses: map[string][]grpcResponse{}, reflection: settings.Reflection, threaduuid: threaduuid}
+
+	for _, m := range settings.Methods {
+		method := registry.MethodByPath("/" + m.Method)
+		if method == nil {
+			return nil, fmt.Errorf("newGRPCMock: unknown method \"%s\"", m.Method)
+		}
+
+		for _, r := range m.Responses {
+			response := grpcResponse{settings: r, code: se.GRPCCodes[r.Status]}
+
+			if r.Match != nil {
+				response.match, err = asDecodedJSON(r.Match)
+				if err != nil {
+					return nil, fmt.Errorf("newGRPCMock: %s: %w", m.Method, err)
+				}
+			}
+
+			for _, message := range r.Messages {
+				b, err := registry.Marshal(method.Output, message)
+				if err != nil {
+					return nil, fmt.Errorf("newGRPCMock: %s: %w", m.Method, err)
+				}
+				response.messages = append(response.messages, b)
+			}
+
+			g.responses[method.Path()] = append(g.responses[method.Path()], response)
+		}
+	}
+
+	return g, nil
+}
+
+// The mux patterns the mock answers on, one per service.
+func (g *grpcMock) patterns() []string {
+	var patterns []string
+	for _, name := range g.registry.ServiceNames() {
+		patterns = append(patterns, "/"+name+"/")
+	}
+
+	if g.reflection {
+		for _, name := range reflectionServices {
+			patterns = append(patterns, "/"+name+"/")
+		}
+	}
+
+	return patterns
+}
+
+func isGRPCRequest(r *http.Request) bool {
+	contentType := r.Header.Get("Content-Type")
+	return contentType == "application/grpc" || strings.HasPrefix(contentType, "application/grpc+") || strings.HasPrefix(contentType, "application/grpc;")
+}
+
+// Reads one length prefixed message, io.EOF if the client has finished sending.
+func readGRPCMessage(r io.Reader, encoding string) ([]byte, error) {
+	var prefix [5]byte
+	_, err := io.ReadFull(r, prefix[:])
+	if err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, fmt.Errorf("readGRPCMessage: truncated message prefix")
+		}
+		return nil, err
+	}
+
+	length := binary.BigEndian.Uint32(prefix[1:])
+	if length > grpcMaxMessageBytes {
+		return nil, fmt.Errorf("readGRPCMessage: %d byte message is over the %d byte limit", length, grpcMaxMessageBytes)
+	}
+
+	message := make([]byte, length)
+	_, err = io.ReadFull(r, message)
+	if err != nil {
+		return nil, fmt.Errorf("readGRPCMessage: truncated message: %w", err)
+	}
+
+	if prefix[0] == 0 {
+		return message, nil
+	}
+
+	if encoding != "gzip" {
+		return nil, fmt.Errorf("readGRPCMessage: unsupported compression \"%s\"", encoding)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(message))
+	if err != nil {
+		return nil, fmt.Errorf("readGRPCMessage: %w", err)
+	}
+	message, err = io.ReadAll(io.LimitReader(zr, grpcMaxMessageBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("readGRPCMessage: %w", err)
+	}
+	if len(message) > grpcMaxMessageBytes {
+		return nil, fmt.Errorf("readGRPCMessage: decompressed message is over the %d byte limit", grpcMaxMessageBytes)
+	}
+
+	return message, nil
+}
+
+// Writes one length prefixed, uncompressed message and flushes it so streamed messages arrive as they're sent.
+func writeGRPCMessage(w http.ResponseWriter, message []byte) error {
+	prefix := binary.BigEndian.AppendUint32([]byte{0}, uint32(len(message)))
+
+	_, err := w.Write(append(prefix, message...))
+	if err != nil {
+		return fmt.Errorf("writeGRPCMessage: %w", err)
+	}
+
+	return http.NewResponseController(w).Flush()
+}
+
+// Percent encodes what the spec says grpc-message can't hold as it is.
+func grpcPercentEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < ' ' || c > '~' || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+
+	return b.String()
+}
+
+// Ends the call with a status (and any extra trailers). Sent before anything else, it makes a trailers-only response.
+func setGRPCStatus(w http.ResponseWriter, code int, message string, trailers map[string]string) {
+	for k, v := range trailers {
+		w.Header().Set(http.TrailerPrefix+k, v)
+	}
+
+	w.Header().Set(http.TrailerPrefix+"Grpc-Status", strconv.Itoa(code))
+	if message != "" {
+		w.Header().Set(http.TrailerPrefix+"Grpc-Message", grpcPercentEncode(message))
+	}
+}
+
+// The first response whose match the request meets, nil if none do.
+func (g *grpcMock) response(method *protobuf.Method, request []byte) (*grpcResponse, error) {
+	responses := g.responses[method.Path()]
+	if len(responses) == 0 {
+		return nil, nil
+	}
+
+	decoded, err := g.registry.Unmarshal(method.Input, request)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, r := range responses {
+		if r.match == nil || jsonContains(r.match, decoded) {
+			return &responses[i], nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (g *grpcMock) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	setRequestBinding(r, r.URL.Path)
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "gRPC calls are POSTs", http.StatusMethodNotAllowed)
+		return
+	}
+	if !isGRPCRequest(r) {
+		http.Error(w, "expected a gRPC request, with a content type of application/grpc", http.StatusUnsupportedMediaType)
+		return
+	}
+	if r.ProtoMajor != 2 {
+		http.Error(w, "gRPC needs HTTP/2", http.StatusHTTPVersionNotSupported)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/grpc")
+	encoding := r.Header.Get("Grpc-Encoding")
+
+	if g.reflection && isReflectionPath(r.URL.Path) {
+		g.serveReflection(w, r, encoding)
+		return
+	}
+
+	method := g.registry.MethodByPath(r.URL.Path)
+	if method == nil {
+		setGRPCStatus(w, grpcCodeUnimplemented, fmt.Sprintf("unknown method %s", r.URL.Path), nil)
+		return
+	}
+	if method.ClientStreaming {
+		setGRPCStatus(w, grpcCodeUnimplemented, fmt.Sprintf("%s is client streaming, which can't be mocked", method.FullName), nil)
+		return
+	}
+
+	request, err := readGRPCMessage(r.Body, encoding)
+	if err != nil {
+		co.LogVerboseOnThread(g.threaduuid, co.MSGTYPE_WARN, "bad grpc request", co.LOGKEY_PATH, r.URL.Path, co.LOGKEY_ERROR, err)
+		setGRPCStatus(w, grpcCodeInternal, "could not read the request message", nil)
+		return
+	}
+
+	response, err := g.response(method, request)
+	if err != nil {
+		co.LogVerboseOnThread(g.threaduuid, co.MSGTYPE_WARN, "bad grpc request", co.LOGKEY_PATH, r.URL.Path, co.LOGKEY_ERROR, err)
+		setGRPCStatus(w, grpcCodeInternal, fmt.Sprintf("could not decode the request as %s", method.Input), nil)
+		return
+	}
+	if response == nil {
+		setGRPCStatus(w, grpcCodeUnimplemented, fmt.Sprintf("no response is configured for this request to %s", method.FullName), nil)
+		return
+	}
+
+	for k, v := range response.settings.Headers {
+		w.Header().Set(k, v)
+	}
+	w.WriteHeader(http.StatusOK)
+
+	for i, message := range response.messages {
+		if i > 0 && !sleepContext(r.Context(), response.settings.Interval) {
+			return // The client went away
+		}
+
+		err = writeGRPCMessage(w, message)
+		if err != nil {
+			return
+		}
+	}
+
+	message := ""
+	if response.code != grpcCodeOK {
+		message = response.settings.Message
+	}
+	setGRPCStatus(w, response.code, message, response.settings.Trailers)
+}
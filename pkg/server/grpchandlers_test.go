@@ -0,0 +1,225 @@
+package server
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/nrexception/mockapi/pkg/protobuf"
+	se "github.com/nrexception/mockapi/pkg/settings"
+)
+
+const testDescriptorSet = "../protobuf/testdata/greet.pb"
+
+func grpcFrame(message []byte) []byte {
+	return append(binary.BigEndian.AppendUint32([]byte{0}, uint32(len(message))), message...)
+}
+
+// Calls a method with one request message, returning the response messages and trailers.
+func grpcCall(t *testing.T, client *http.Client, url string, request []byte) (*http.Response, [][]byte) {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(grpcFrame(request)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var messages [][]byte
+	for {
+		message, err := readGRPCMessage(resp.Body, "")
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		messages = append(messages, message)
+	}
+
+	return resp, messages
+}
+
+func TestListenerManager_GRPC(t *testing.T) {
+	t.Parallel()
+
+	grpc := &se.GRPCSettings{
+		DescriptorSet: testDescriptorSet,
+		Reflection:    true,
+		Methods: []se.GRPCMethod{
+			{
+				Method: "greet.v1.Greeter/SayHello",
+				Responses: []se.GRPCResponse{
+					{Match: map[string]any{"name": "nobody"}, Status: "NOT_FOUND", Message: "no such person: nobody", Trailers: map[string]string{"X-Reason": "unknown"}},
+					{Headers: map[string]string{"X-Greeter": "mock"}, Messages: []map[string]any{{"message": "hello", "mood": "MOOD_HAPPY", "counts": []any{1, "2"}}}},
+				},
+			},
+			{
+				Method: "greet.v1.Greeter/SayHellos",
+				Responses: []se.GRPCResponse{
+					{Messages: []map[string]any{{"message": "one"}, {"message": "two"}, {"message": "three"}}, Interval: 10 * time.Millisecond},
+				},
+			},
+		},
+	}
+
+	m := NewListenerManager(nil, nil)
+	t.Cleanup(func() { _ = m.Close() })
+
+	err := m.Apply(&se.UnmarshalledRootSettings{WebListeners: []se.UnmarshalledRootSettingWebListener{{
+		ListenerName:    "grpc",
+		GRPC:            grpc,
+		ContentBindings: []se.ResponseBinding{{Path: "/health", ResponseCode: http.StatusOK, ResponseBodyType: se.Inline, ResponseBody: "ok"}},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	registry, err := protobuf.LoadDescriptorSet(testDescriptorSet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	protocols := new(http.Protocols)
+	protocols.SetUnencryptedHTTP2(true)
+	client := &http.Client{Transport: &http.Transport{Protocols: protocols}}
+	base := "http://127.0.0.1:" + strconv.Itoa(listenerPort(t, m, "grpc"))
+
+	request := func(v map[string]any) []byte {
+		b, err := registry.Marshal("greet.v1.HelloRequest", v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	decode := func(message []byte) any {
+		v, err := registry.Unmarshal("greet.v1.HelloReply", message)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	t.Run("unary", func(t *testing.T) {
+		resp, messages := grpcCall(t, client, base+"/greet.v1.Greeter/SayHello", request(map[string]any{"name": "ada"}))
+
+		if resp.Trailer.Get("Grpc-Status") != "0" || len(messages) != 1 {
+			t.Fatalf("expected one message and OK, got %d and %q", len(messages), resp.Trailer.Get("Grpc-Status"))
+		}
+		if resp.Header.Get("X-Greeter") != "mock" || resp.Header.Get("Content-Type") != "application/grpc" {
+			t.Errorf("unexpected headers: %v", resp.Header)
+		}
+		got := decode(messages[0]).(map[string]any)
+		if got["message"] != "hello" || got["mood"] != "MOOD_HAPPY" || len(got["counts"].([]any)) != 2 {
+			t.Errorf("unexpected message: %v", got)
+		}
+	})
+
+	t.Run("error status", func(t *testing.T) {
+		resp, messages := grpcCall(t, client, base+"/greet.v1.Greeter/SayHello", request(map[string]any{"name": "nobody"}))
+
+		if len(messages) != 0 || resp.Trailer.Get("Grpc-Status") != "5" {
+			t.Fatalf("expected NOT_FOUND, got %d messages and %q", len(messages), resp.Trailer.Get("Grpc-Status"))
+		}
+		if resp.Trailer.Get("Grpc-Message") != "no such person: nobody" || resp.Trailer.Get("X-Reason") != "unknown" {
+			t.Errorf("unexpected trailers: %v", resp.Trailer)
+		}
+	})
+
+	t.Run("server streaming", func(t *testing.T) {
+		start := time.Now()
+		resp, messages := grpcCall(t, client, base+"/greet.v1.Greeter/SayHellos", request(nil))
+
+		if len(messages) != 3 || resp.Trailer.Get("Grpc-Status") != "0" {
+			t.Fatalf("expected three messages and OK, got %d and %q", len(messages), resp.Trailer.Get("Grpc-Status"))
+		}
+		if got := decode(messages[2]).(map[string]any)["message"]; got != "three" {
+			t.Errorf("unexpected last message: %v", got)
+		}
+		if time.Since(start) < 20*time.Millisecond {
+			t.Error("messages were not spaced by the interval")
+		}
+	})
+
+	t.Run("unimplemented", func(t *testing.T) {
+		for _, path := range []string{"/greet.v1.Greeter/Missing", "/greet.v1.Greeter/Chat"} {
+			resp, _ := grpcCall(t, client, base+path, request(nil))
+			if resp.Trailer.Get("Grpc-Status") != "12" {
+				t.Errorf("%s: expected UNIMPLEMENTED, got %q", path, resp.Trailer.Get("Grpc-Status"))
+			}
+		}
+	})
+
+	t.Run("reflection", func(t *testing.T) {
+		resp, messages := grpcCall(t, client, base+"/grpc.reflection.v1.ServerReflection/ServerReflectionInfo", protobuf.AppendBytesField(nil, 7, []byte("*")))
+		if len(messages) != 1 || resp.Trailer.Get("Grpc-Status") != "0" {
+			t.Fatalf("expected one response and OK, got %d and %q", len(messages), resp.Trailer.Get("Grpc-Status"))
+		}
+
+		fields, err := protobuf.ParseFields(messages[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		var services []string
+		for _, f := range fields {
+			if f.Number != 6 {
+				continue
+			}
+			list, _ := protobuf.ParseFields(f.Bytes)
+			for _, s := range list {
+				name, _ := protobuf.ParseFields(s.Bytes)
+				services = append(services, string(name[0].Bytes))
+			}
+		}
+		if len(services) != 1 || services[0] != "greet.v1.Greeter" {
+			t.Errorf("unexpected services: %v", services)
+		}
+
+		_, messages = grpcCall(t, client, base+"/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo", protobuf.AppendBytesField(nil, 4, []byte("greet.v1.HelloReply")))
+		fields, _ = protobuf.ParseFields(messages[0])
+		files := 0
+		for _, f := range fields {
+			if f.Number == 4 {
+				descriptors, _ := protobuf.ParseFields(f.Bytes)
+				files = len(descriptors)
+			}
+		}
+		if files != 2 {
+			t.Errorf("expected the file and its import, got %d files", files)
+		}
+	})
+
+	t.Run("bindings still served", func(t *testing.T) {
+		resp, err := client.Get(base + "/health")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		if string(body) != "ok" {
+			t.Errorf("unexpected body: %s", body)
+		}
+	})
+
+	t.Run("not grpc", func(t *testing.T) {
+		resp, err := client.Post(base+"/greet.v1.Greeter/SayHello", "application/json", bytes.NewReader(nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnsupportedMediaType {
+			t.Errorf("expected 415, got %d", resp.StatusCode)
+		}
+	})
+}
@@ -0,0 +1,157 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	co "github.com/nrexception/mockapi/pkg/common"
+	"github.com/nrexception/mockapi/pkg/protobuf"
+)
+
+// Both versions of the reflection service, grpcurl tries v1 and falls back to v1alpha. Their messages are the same.
+var reflectionServices = []string{"grpc.reflection.v1.ServerReflection", "grpc.reflection.v1alpha.ServerReflection"}
+
+const grpcCodeNotFound = 5
+
+// Field numbers in ServerReflectionRequest and ServerReflectionResponse.
+const (
+	reflectHost                 = 1
+	reflectFileByFilename       = 3
+	reflectFileContainingSymbol = 4
+	reflectFileContainingExt    = 5
+	reflectAllExtensionNumbers  = 6
+	reflectListServices         = 7
+	reflectValidHost            = 1
+	reflectOriginalRequest      = 2
+	reflectFileDescriptorResp   = 4
+	reflectAllExtensionNumsResp = 5
+	reflectListServicesResp     = 6
+	reflectErrorResp            = 7
+)
+
+func isReflectionPath(path string) bool {
+	for _, name := range reflectionServices {
+		if path == "/"+name+"/ServerReflectionInfo" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Answers reflection requests as they arrive on the stream, until the client is done.
+func (g *grpcMock) serveReflection(w http.ResponseWriter, r *http.Request, encoding string) {
+	// Reading requests while writing responses needs full duplex on HTTP/1, HTTP/2 has it anyway
+	_ = http.NewResponseController(w).EnableFullDuplex()
+
+	w.WriteHeader(http.StatusOK)
+	err := http.NewResponseController(w).Flush()
+	if err != nil {
+		return
+	}
+
+	sent := map[string]bool{} // Files already sent on this stream, clients keep the ones they have
+	for {
+		request, err := readGRPCMessage(r.Body, encoding)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			co.LogVerboseOnThread(g.threaduuid, co.MSGTYPE_WARN, "bad grpc reflection request", co.LOGKEY_PATH, r.URL.Path, co.LOGKEY_ERROR, err)
+			setGRPCStatus(w, grpcCodeInternal, "could not read the request message", nil)
+			return
+		}
+
+		response, err := g.reflect(request, sent)
+		if err != nil {
+			co.LogVerboseOnThread(g.threaduuid, co.MSGTYPE_WARN, "bad grpc reflection request", co.LOGKEY_PATH, r.URL.Path, co.LOGKEY_ERROR, err)
+			setGRPCStatus(w, grpcCodeInternal, "could not decode the request", nil)
+			return
+		}
+
+		err = writeGRPCMessage(w, response)
+		if err != nil {
+			return
+		}
+	}
+
+	setGRPCStatus(w, grpcCodeOK, "", nil)
+}
+
+func reflectionError(code int, message string) []byte {
+	var b []byte
+	b = protobuf.AppendVarintField(b, 1, uint64(code))
+	return protobuf.AppendBytesField(b, 2, []byte(message))
+}
+
+// The files with their dependencies, less those already sent.
+func reflectionFiles(files []*protobuf.File, sent map[string]bool) []byte {
+	var b []byte
+	for _, f := range files {
+		if sent[f.Name] {
+			continue
+		}
+		sent[f.Name] = true
+		b = protobuf.AppendBytesField(b, 1, f.Raw)
+	}
+
+	return b
+}
+
+// Builds the ServerReflectionResponse for one ServerReflectionRequest.
+func (g *grpcMock) reflect(request []byte, sent map[string]bool) ([]byte, error) {
+	fields, err := protobuf.ParseFields(request)
+	if err != nil {
+		return nil, fmt.Errorf("reflect: %w", err)
+	}
+
+	var response []byte
+	for _, f := range fields {
+		if f.Number == reflectHost {
+			response = protobuf.AppendBytesField(response, reflectValidHost, f.Bytes)
+		}
+	}
+	response = protobuf.AppendBytesField(response, reflectOriginalRequest, request)
+
+	for _, f := range fields {
+		switch f.Number {
+		case reflectFileByFilename:
+			file := g.registry.FileByName(string(f.Bytes))
+			if file == nil {
+				return protobuf.AppendBytesField(response, reflectErrorResp, reflectionError(grpcCodeNotFound, fmt.Sprintf("unknown file %s", f.Bytes))), nil
+			}
+			return protobuf.AppendBytesField(response, reflectFileDescriptorResp, reflectionFiles(g.registry.FileWithDependencies(file), sent)), nil
+
+		case reflectFileContainingSymbol:
+			file := g.registry.FileContainingSymbol(string(f.Bytes))
+			if file == nil {
+				return protobuf.AppendBytesField(response, reflectErrorResp, reflectionError(grpcCodeNotFound, fmt.Sprintf("unknown symbol %s", f.Bytes))), nil
+			}
+			return protobuf.AppendBytesField(response, reflectFileDescriptorResp, reflectionFiles(g.registry.FileWithDependencies(file), sent)), nil
+
+		case reflectFileContainingExt:
+			return protobuf.AppendBytesField(response, reflectErrorResp, reflectionError(grpcCodeNotFound, "extensions aren't supported")), nil
+
+		case reflectAllExtensionNumbers:
+			// No extensions are known, so every type has none of them
+			typeName := strings.TrimPrefix(string(f.Bytes), ".")
+			if g.registry.Messages[typeName] == nil {
+				return protobuf.AppendBytesField(response, reflectErrorResp, reflectionError(grpcCodeNotFound, fmt.Sprintf("unknown type %s", typeName))), nil
+			}
+			return protobuf.AppendBytesField(response, reflectAllExtensionNumsResp, protobuf.AppendBytesField(nil, 1, []byte(typeName))), nil
+
+		case reflectListServices:
+			// Reflection itself is left out, its descriptors aren't in the set for clients to then ask for
+			var list []byte
+			for _, name := range g.registry.ServiceNames() {
+				list = protobuf.AppendBytesField(list, 1, protobuf.AppendBytesField(nil, 1, []byte(name)))
+			}
+			return protobuf.AppendBytesField(response, reflectListServicesResp, list), nil
+		}
+	}
+
+	return protobuf.AppendBytesField(response, reflectErrorResp, reflectionError(grpcCodeUnimplemented, "unsupported reflection request")), nil
+}
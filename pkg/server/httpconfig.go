@@ -0,0 +1,31 @@
+package server
+
+import (
+	"net/http"
+
+	se "github.com/nrexception/mockapi/pkg/settings"
+)
+
+// Applies a listener's keep-alive and httpoptions settings to its server. gRPC listeners always take h2c, gRPC clients
+// don't speak anything else without tls.
+func configureHTTPServer(server *http.Server, ls se.UnmarshalledRootSettingWebListener) {
+	server.SetKeepAlivesEnabled(ls.OnConnectKeepAlive == nil || *ls.OnConnectKeepAlive)
+
+	protocols := &http.Protocols{}
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(ls.GRPC != nil)
+	server.Protocols = protocols
+
+	options := ls.HTTPOptions
+	if options == nil {
+		return
+	}
+
+	protocols.SetHTTP2(!options.DisableHTTP2)
+	protocols.SetUnencryptedHTTP2(options.H2C || ls.GRPC != nil)
+	server.IdleTimeout = options.IdleTimeout
+	server.ReadTimeout = options.ReadTimeout
+	server.WriteTimeout = options.WriteTimeout
+	server.MaxHeaderBytes = options.MaxHeaderBytes
+}
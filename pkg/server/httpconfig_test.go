@@ -0,0 +1,89 @@
+package server
+
+import (
+	"crypto/tls"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	se "github.com/nrexception/mockapi/pkg/settings"
+)
+
+func TestListenerManager_HTTPOptions(t *testing.T) {
+	t.Parallel()
+
+	keepAliveOff := false
+
+	testCases := []struct {
+		name          string
+		tls           bool
+		keepAlive     *bool
+		options       *se.HTTPOptions
+		clientH2C     bool
+		largeHeader   bool
+		expectedProto int
+		expectedCode  int
+		expectedClose bool
+	}{
+		{name: "tls negotiates h2", tls: true, expectedProto: 2, expectedCode: http.StatusOK},
+		{name: "tls with http2 disabled", tls: true, options: &se.HTTPOptions{DisableHTTP2: true}, expectedProto: 1, expectedCode: http.StatusOK},
+		{name: "cleartext defaults to http/1.1", expectedProto: 1, expectedCode: http.StatusOK},
+		{name: "h2c", options: &se.HTTPOptions{H2C: true}, clientH2C: true, expectedProto: 2, expectedCode: http.StatusOK},
+		{name: "keep-alive off", keepAlive: &keepAliveOff, expectedProto: 1, expectedCode: http.StatusOK, expectedClose: true},
+		{name: "header too large", options: &se.HTTPOptions{MaxHeaderBytes: 1}, largeHeader: true, expectedProto: 1, expectedCode: http.StatusRequestHeaderFieldsTooLarge, expectedClose: true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ls := inlineListener("http", 0, map[string]string{"/": "ok"})
+			ls.OnConnectKeepAlive = tc.keepAlive
+			ls.HTTPOptions = tc.options
+			if tc.tls {
+				ls.EnableTLS = true
+				ls.AutoTLS = &se.AutoTLSSettings{SelfSigned: true}
+			}
+
+			m := NewListenerManager(nil, nil)
+			defer m.Close()
+
+			err := m.Apply(&se.UnmarshalledRootSettings{WebListeners: []se.UnmarshalledRootSettingWebListener{ls}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			port := listenerPort(t, m, "http")
+
+			protocols := &http.Protocols{}
+			protocols.SetHTTP1(!tc.clientH2C)
+			protocols.SetHTTP2(true)
+			protocols.SetUnencryptedHTTP2(tc.clientH2C)
+			client := &http.Client{Transport: &http.Transport{Protocols: protocols, TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+
+			scheme := "http"
+			if tc.tls {
+				scheme = "https"
+			}
+			req, err := http.NewRequest(http.MethodGet, scheme+"://127.0.0.1:"+strconv.Itoa(port)+"/", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.largeHeader {
+				req.Header.Set("X-Large", strings.Repeat("a", 8192))
+			}
+
+			res, err := client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+
+			if res.ProtoMajor != tc.expectedProto || res.StatusCode != tc.expectedCode || res.Close != tc.expectedClose {
+				t.Errorf("unexpected response: %s %d, close %t", res.Proto, res.StatusCode, res.Close)
+			}
+		})
+	}
+}
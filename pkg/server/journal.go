@@ -0,0 +1,87 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Request bodies are kept up to this size in the journal, anything past it is dropped.
+const journalMaxBodyBytes = 1 << 20
+
+// A request received by a listener, as recorded in the journal.
+type JournalEntry struct {
+	Time     time.Time
+	Listener string
+	Binding  string // Empty if no binding matched
+	Method   string
+	Path     string
+	Query    url.Values
+	Header   http.Header
+	Body     []byte
+	Status   int
+}
+
+// Every request received by a manager's listeners, in the order they finished. Recording is off until Enable is called,
+// so long running processes don't grow it forever.
+type RequestJournal struct {
+	mu         sync.Mutex
+	enabled    bool
+	maxEntries int
+	entries    []JournalEntry
+}
+
+// Starts recording requests, keeping at most maxEntries of the latest (0 keeps everything).
+func (j *RequestJournal) Enable(maxEntries int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.enabled = true
+	j.maxEntries = maxEntries
+}
+
+func (j *RequestJournal) isEnabled() bool {
+	if j == nil {
+		return false
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.enabled
+}
+
+func (j *RequestJournal) record(e JournalEntry) {
+	if j == nil {
+		return
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if !j.enabled {
+		return
+	}
+
+	j.entries = append(j.entries, e)
+	if j.maxEntries > 0 && len(j.entries) > j.maxEntries {
+		j.entries = j.entries[len(j.entries)-j.maxEntries:]
+	}
+}
+
+// Copy of the recorded requests, oldest first.
+func (j *RequestJournal) Entries() []JournalEntry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return append([]JournalEntry{}, j.entries...)
+}
+
+// Drops everything recorded so far.
+func (j *RequestJournal) Reset() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.entries = nil
+}
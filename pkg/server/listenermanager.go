@@ -0,0 +1,420 @@
+package server
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/nrexception/mockapi/pkg/certs"
+	co "github.com/nrexception/mockapi/pkg/common"
+	se "github.com/nrexception/mockapi/pkg/settings"
+)
+
+// How long a stopping listener waits for in-flight requests before its connections are dropped.
+const shutdownTimeout = 5 * time.Second
+
+// Owns every running web and socket listener. New settings are diffed against what's running, so only listeners that
+// actually changed are touched.
+type ListenerManager struct {
+	mu              sync.Mutex
+	listeners       map[string]*webListener // Keyed by listener name
+	socketListeners map[string]*socketListener
+	admin           *adminListener
+	bodyCache       *fileContentCache
+	resources       resourceCollections // Kept across reloads, so resource bindings don't lose their state
+	metrics         *metrics
+	journal         *RequestJournal
+	callbacks       *callbackDispatcher
+	ca              atomic.Pointer[certs.CA] // Signs auto tls certificates, nil until a listener needs it
+	caDir           string                   // Where ca was loaded from, empty if it only lives in memory
+	activeListeners atomic.Int64             // len(listeners) + len(socketListeners), readable without taking mu
+	status          atomic.Pointer[statusSnapshot]
+	responseChannel chan ListenerResponse
+}
+
+// Creates a manager, commands received on commandChannel are applied to the listener they identify and status updates
+// are sent on responseChannel. Either channel may be nil.
+func NewListenerManager(commandChannel chan ListenerCommandPacket, responseChannel chan ListenerResponse) *ListenerManager {
+	m := &ListenerManager{
+		listeners:       map[string]*webListener{},
+		socketListeners: map[string]*socketListener{},
+		bodyCache:       newFileContentCache(),
+		journal:         &RequestJournal{},
+		callbacks:       newCallbackDispatcher(),
+		responseChannel: responseChannel,
+	}
+	m.metrics = newMetrics(func() int { return int(m.activeListeners.Load()) })
+
+	if commandChannel != nil {
+		go m.handleCommands(commandChannel)
+	}
+
+	return m
+}
+
+func (m *ListenerManager) respond(msg string) {
+	if m.responseChannel != nil {
+		m.responseChannel <- ListenerResponse(msg)
+	}
+}
+
+func (m *ListenerManager) handleCommands(commandChannel chan ListenerCommandPacket) {
+	for c := range commandChannel {
+		if c.Command != VLC_Close {
+			continue
+		}
+
+		m.mu.Lock()
+		for name, l := range m.listeners {
+			if l.threaduuid != c.Identifier {
+				continue
+			}
+
+			co.LogVerboseOnThread(l.threaduuid, co.MSGTYPE_WARN, "closing listener thread...", co.LOGKEY_LISTENER, name)
+			err := l.stop()
+			if err != nil {
+				co.LogNonVerboseOnThread(l.threaduuid, co.MSGTYPE_ERROR, "error closing listener", co.LOGKEY_LISTENER, name, co.LOGKEY_ERROR, err)
+			}
+			delete(m.listeners, name)
+			m.publishListenerClosed(name)
+			m.respond(fmt.Sprintf("listener \"%s\" closed", name))
+		}
+		for name, l := range m.socketListeners {
+			if l.threaduuid != c.Identifier {
+				continue
+			}
+
+			co.LogVerboseOnThread(l.threaduuid, co.MSGTYPE_WARN, "closing listener thread...", co.LOGKEY_LISTENER, name)
+			err := l.stop()
+			if err != nil {
+				co.LogNonVerboseOnThread(l.threaduuid, co.MSGTYPE_ERROR, "error closing listener", co.LOGKEY_LISTENER, name, co.LOGKEY_ERROR, err)
+			}
+			delete(m.socketListeners, name)
+			m.publishListenerClosed(name)
+			m.respond(fmt.Sprintf("listener \"%s\" closed", name))
+		}
+		m.activeListeners.Store(int64(len(m.listeners) + len(m.socketListeners)))
+		m.mu.Unlock()
+	}
+}
+
+// Where a listener binds, its TLS and how its server is tuned are baked into the server, so they can only change with a
+// restart. Everything else (bindings, access log, metrics, gRPC services, auth) is served from the mux and swapped in
+// place. gRPC being on at all is the exception, it switches on h2c.
+func listenerNeedsRestart(running se.UnmarshalledRootSettingWebListener, updated se.UnmarshalledRootSettingWebListener) bool {
+	served := func(ls se.UnmarshalledRootSettingWebListener) []any {
+		return []any{
+			ls.ListenerPort, ls.ListenAddress, ls.UnixSocket,
+			ls.EnableTLS, ls.CertDetails, ls.AutoTLS, ls.TLSOptions, ls.ClientAuth,
+			ls.OnConnectKeepAlive, ls.HTTPOptions, ls.GRPC != nil,
+		}
+	}
+
+	return !reflect.DeepEqual(served(running), served(updated))
+}
+
+// A listener left to pick its own port keeps the one it picked across restarts, so clients that read it from Status can
+// still reach it.
+func keepsBoundAddress(running se.UnmarshalledRootSettingWebListener, updated se.UnmarshalledRootSettingWebListener) bool {
+	return running.UnixSocket == "" && updated.UnixSocket == "" &&
+		running.ListenerPort == 0 && updated.ListenerPort == 0 &&
+		running.ListenAddress == updated.ListenAddress
+}
+
+// What Apply is going to do with a web listener.
+type plannedListener struct {
+	settings   se.UnmarshalledRootSettingWebListener
+	running    *webListener
+	sMux       *http.ServeMux
+	accessLog  *accessLogger
+	threaduuid uuid.UUID
+	restart    bool
+	tlsConfig  *tls.Config       // Only built for listeners being (re)started
+	certStore  *certificateStore // Serving tlsConfig's certificates, if they come from files
+}
+
+// Wrapped by the error Apply returns when the settings were applied but some listeners couldn't be (re)started. Any other
+// error means the settings were rejected and nothing changed.
+var ErrListenersNotStarted = errors.New("settings applied, but not every listener is running")
+
+// Brings the running listeners in line with rootSettings. Every listener's bindings are built before anything running is
+// touched, so settings that can't be applied are rejected as a whole and the current ones keep serving.
+func (m *ListenerManager) Apply(rootSettings *se.UnmarshalledRootSettings) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer func() { m.activeListeners.Store(int64(len(m.listeners) + len(m.socketListeners))) }()
+
+	plan := []plannedListener{}
+	configured := map[string]bool{}
+
+	// Anything opened for a plan we end up rejecting needs closing again
+	rejectPlan := func(err error) error {
+		m.resources.discard()
+		for _, p := range plan {
+			if p.running == nil || p.accessLog != p.running.accessLog.Load() {
+				_ = p.accessLog.close()
+			}
+			_ = p.certStore.close()
+		}
+
+		return err
+	}
+
+	ca, caDir, err := m.planLocalCA(rootSettings)
+	if err != nil {
+		return fmt.Errorf("ListenerManager.Apply: %w", err)
+	}
+	caChanged := ca != m.ca.Load()
+
+	for _, ls := range rootSettings.WebListeners {
+		if configured[ls.ListenerName] {
+			return rejectPlan(fmt.Errorf("ListenerManager.Apply: listener name \"%s\" is used more than once", ls.ListenerName))
+		}
+		configured[ls.ListenerName] = true
+
+		running := m.listeners[ls.ListenerName]
+		reissue := caChanged && usesLocalCA(ls)
+		if running != nil && reflect.DeepEqual(running.settings, ls) && !reissue {
+			continue // Untouched, leave it be...
+		}
+
+		threaduuid := uuid.New()
+		if running != nil {
+			threaduuid = running.threaduuid
+		}
+
+		sMux, err := m.createListenerMux(ls, threaduuid)
+		if err != nil {
+			return rejectPlan(fmt.Errorf("ListenerManager.Apply: listener \"%s\": %w", ls.ListenerName, err))
+		}
+
+		// Keep the running access log if its settings (and the API keys it keeps out) are unchanged, so its file isn't
+		// reopened
+		var accessLog *accessLogger
+		apiKeys := listenerAPIKeyLocations(ls)
+		switch {
+		case running != nil && reflect.DeepEqual(running.settings.AccessLog, ls.AccessLog) && reflect.DeepEqual(listenerAPIKeyLocations(running.settings), apiKeys):
+			accessLog = running.accessLog.Load()
+		case ls.AccessLog != nil:
+			accessLog, err = newAccessLogger(*ls.AccessLog, apiKeys)
+			if err != nil {
+				return rejectPlan(fmt.Errorf("ListenerManager.Apply: listener \"%s\": %w", ls.ListenerName, err))
+			}
+		}
+
+		p := plannedListener{settings: ls, running: running, sMux: sMux, accessLog: accessLog, threaduuid: threaduuid}
+		p.restart = running == nil || reissue || listenerNeedsRestart(running.settings, ls)
+		if p.restart {
+			p.tlsConfig, p.certStore, err = listenerTLSConfig(ls, ca)
+			if err != nil {
+				plan = append(plan, p) // So its access log is closed too
+				return rejectPlan(fmt.Errorf("ListenerManager.Apply: listener \"%s\": %w", ls.ListenerName, err))
+			}
+		}
+
+		plan = append(plan, p)
+	}
+
+	socketPlan, err := m.planSocketListeners(rootSettings.SocketListeners, configured)
+	if err != nil {
+		return rejectPlan(fmt.Errorf("ListenerManager.Apply: %w", err))
+	}
+
+	m.ca.Store(ca)
+	m.caDir = caDir
+
+	rebuilt := map[string]bool{}
+	for _, p := range plan {
+		rebuilt[p.settings.ListenerName] = true
+	}
+	m.resources.commit(rebuilt, configured)
+
+	errs := []error{}
+	startErrs := map[string]error{}
+
+	// Listeners that are no longer configured go first, freeing their ports for anything that's moved onto them
+	for name, l := range m.listeners {
+		if configured[name] {
+			continue
+		}
+
+		co.LogVerboseOnThread(l.threaduuid, co.MSGTYPE_WARN, "listener removed from settings, closing...", co.LOGKEY_LISTENER, name)
+		err := l.stop()
+		if err != nil {
+			errs = append(errs, err)
+		}
+		_ = l.accessLog.Load().close()
+		delete(m.listeners, name)
+		m.respond(fmt.Sprintf("listener \"%s\" closed", name))
+	}
+	errs = append(errs, m.stopRemovedSocketListeners(configured)...)
+
+	for _, p := range plan {
+		if !p.restart {
+			p.running.mux.Store(p.sMux)
+			if previous := p.running.accessLog.Swap(p.accessLog); previous != p.accessLog {
+				_ = previous.close()
+			}
+			p.running.settings = p.settings
+			m.respond(fmt.Sprintf("listener \"%s\" bindings updated", p.settings.ListenerName))
+			continue
+		}
+
+		l := &webListener{settings: p.settings, listenerName: p.settings.ListenerName, threaduuid: p.threaduuid, metrics: m.metrics, journal: m.journal, certStore: p.certStore}
+		l.mux.Store(p.sMux)
+		l.accessLog.Store(p.accessLog)
+
+		err := m.replaceListener(p, l)
+		if err != nil {
+			startErrs[p.settings.ListenerName] = err
+			errs = append(errs, fmt.Errorf("listener \"%s\": %w", p.settings.ListenerName, err))
+			continue
+		}
+
+		m.listeners[p.settings.ListenerName] = l
+		m.respond(fmt.Sprintf("listener \"%s\" listening on %s", p.settings.ListenerName, l.addr))
+	}
+
+	errs = append(errs, m.startSocketListeners(socketPlan, startErrs)...)
+
+	adminErr := m.applyAdmin(rootSettings.Admin)
+	if adminErr != nil {
+		errs = append(errs, adminErr)
+	}
+
+	m.publishStatus(rootSettings, startErrs, adminErr)
+
+	if len(errs) > 0 {
+		return fmt.Errorf("ListenerManager.Apply: %w: %w", ErrListenersNotStarted, errors.Join(errs...))
+	}
+
+	return nil
+}
+
+// Starts l in place of the planned listener's running one, if any. The new listener is bound first, and only if the
+// running one holds the address does it stop beforehand. If l still can't bind, the running listener is started again
+// so the last good settings keep serving.
+func (m *ListenerManager) replaceListener(p plannedListener, l *webListener) error {
+	if p.running != nil && p.running.addr != nil && keepsBoundAddress(p.running.settings, p.settings) {
+		l.addr = p.running.addr
+	}
+
+	ln, err := l.listen()
+	if err != nil && p.running != nil {
+		co.LogVerboseOnThread(p.running.threaduuid, co.MSGTYPE_WARN, "listener settings changed, restarting...", co.LOGKEY_LISTENER, p.settings.ListenerName)
+		shutdownErr := p.running.shutdown()
+
+		ln, err = l.listen()
+		if err != nil {
+			restartErr := p.running.start(p.running.tlsConfig)
+			if restartErr != nil {
+				m.closeWebListener(p.running)
+				err = errors.Join(err, shutdownErr, restartErr)
+			} else {
+				co.LogNonVerboseOnThread(p.running.threaduuid, co.MSGTYPE_WARN, "listener could not be restarted, the previous settings are still serving", co.LOGKEY_LISTENER, p.settings.ListenerName, co.LOGKEY_ERROR, err)
+			}
+		}
+	} else if err == nil && p.running != nil {
+		co.LogVerboseOnThread(p.running.threaduuid, co.MSGTYPE_WARN, "listener settings changed, restarting...", co.LOGKEY_LISTENER, p.settings.ListenerName)
+		shutdownErr := p.running.shutdown()
+		if shutdownErr != nil {
+			co.LogNonVerboseOnThread(p.running.threaduuid, co.MSGTYPE_ERROR, "error closing listener", co.LOGKEY_LISTENER, p.settings.ListenerName, co.LOGKEY_ERROR, shutdownErr)
+		}
+	}
+
+	if err != nil {
+		_ = p.certStore.close()
+		if p.running == nil || p.accessLog != p.running.accessLog.Load() {
+			_ = p.accessLog.close()
+		}
+		return err
+	}
+
+	if p.running != nil {
+		_ = p.running.certStore.close()
+		if previous := p.running.accessLog.Load(); previous != p.accessLog {
+			_ = previous.close()
+		}
+		delete(m.listeners, p.settings.ListenerName)
+	}
+	l.serveOn(ln, p.tlsConfig)
+
+	return nil
+}
+
+// Lets go of a listener that couldn't be kept running.
+func (m *ListenerManager) closeWebListener(l *webListener) {
+	_ = l.certStore.close()
+	_ = l.accessLog.Load().close()
+	delete(m.listeners, l.listenerName)
+}
+
+// Requests received by every listener, once enabled.
+func (m *ListenerManager) Journal() *RequestJournal {
+	return m.journal
+}
+
+// Callbacks fired by every listener's bindings, and whether they were delivered.
+func (m *ListenerManager) Callbacks() *CallbackLog {
+	return m.callbacks.log
+}
+
+// Counts a configuration reload towards the reload metrics, err being why it was rejected (if it was).
+func (m *ListenerManager) RecordConfigReload(err error) {
+	m.metrics.configReloaded(err)
+}
+
+// Stops every listener, waiting for in-flight requests to finish.
+func (m *ListenerManager) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	co.LogVerbose("Closing all listener threads...", co.MSGTYPE_WARN)
+
+	errs := []error{}
+	for name, l := range m.listeners {
+		co.LogVerboseOnThread(l.threaduuid, co.MSGTYPE_WARN, "closing listener thread...", co.LOGKEY_LISTENER, name)
+		err := l.stop()
+		if err != nil {
+			errs = append(errs, err)
+		}
+		_ = l.accessLog.Load().close()
+		delete(m.listeners, name)
+	}
+	for name, l := range m.socketListeners {
+		co.LogVerboseOnThread(l.threaduuid, co.MSGTYPE_WARN, "closing listener thread...", co.LOGKEY_LISTENER, name)
+		err := l.stop()
+		if err != nil {
+			errs = append(errs, err)
+		}
+		delete(m.socketListeners, name)
+	}
+	m.activeListeners.Store(0)
+	m.status.Store(nil)
+
+	// With the listeners stopped nothing else fires, so pending callbacks can be abandoned
+	m.callbacks.close()
+
+	err := m.applyAdmin(nil)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	err = m.bodyCache.close()
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("ListenerManager.Close: %w", errors.Join(errs...))
+	}
+
+	return nil
+}
@@ -0,0 +1,318 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	se "github.com/nrexception/mockapi/pkg/settings"
+)
+
+// The port a listener ended up on. Tests apply port 0 and ask afterwards, rather than picking a free port up front that
+// something else could take before the listener binds it.
+func listenerPort(t *testing.T, m *ListenerManager, name string) int {
+	t.Helper()
+
+	status := m.Status()
+	for _, ls := range status.Listeners {
+		if ls.Name == name && ls.State == LISTENERSTATE_BOUND {
+			return ls.Port
+		}
+	}
+	if name == "admin" && status.Admin != nil && status.Admin.State == LISTENERSTATE_BOUND {
+		return status.Admin.Port
+	}
+
+	t.Fatalf("listener \"%s\" is not bound", name)
+	return 0
+}
+
+func inlineListener(name string, port int, bindings map[string]string) se.UnmarshalledRootSettingWebListener {
+	ls := se.UnmarshalledRootSettingWebListener{ListenerName: name, ListenerPort: port}
+	for path, body := range bindings {
+		ls.ContentBindings = append(ls.ContentBindings, se.ResponseBinding{Path: path, ResponseCode: http.StatusOK, ResponseBody: body, ResponseBodyType: se.Inline})
+	}
+
+	return ls
+}
+
+func expectBody(t *testing.T, port int, path string, want string) {
+	t.Helper()
+
+	res, err := http.Get("http://127.0.0.1:" + strconv.Itoa(port) + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != want {
+		t.Errorf("unexpected body:\ngot: %s\nwant:%s", b, want)
+	}
+}
+
+func TestListenerManager_Apply(t *testing.T) {
+	t.Parallel()
+
+	m := NewListenerManager(nil, nil)
+	defer m.Close()
+
+	err := m.Apply(&se.UnmarshalledRootSettings{WebListeners: []se.UnmarshalledRootSettingWebListener{
+		inlineListener("a", 0, map[string]string{"/": "a1"}),
+		inlineListener("b", 0, map[string]string{"/": "b1"}),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	portA, portB := listenerPort(t, m, "a"), listenerPort(t, m, "b")
+	expectBody(t, portA, "/", "a1")
+	expectBody(t, portB, "/", "b1")
+
+	serverA, serverB := m.listeners["a"].server, m.listeners["b"].server
+
+	// Changing a binding swaps the mux in place, changing the address restarts the listener
+	movedB := inlineListener("b", 0, map[string]string{"/": "b1"})
+	movedB.ListenAddress = "127.0.0.1"
+	err = m.Apply(&se.UnmarshalledRootSettings{WebListeners: []se.UnmarshalledRootSettingWebListener{
+		inlineListener("a", 0, map[string]string{"/": "a2"}),
+		movedB,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	portB2 := listenerPort(t, m, "b")
+	expectBody(t, portA, "/", "a2")
+	expectBody(t, portB2, "/", "b1")
+
+	if m.listeners["a"].server != serverA {
+		t.Error("listener with changed bindings was restarted")
+	}
+	if m.listeners["b"].server == serverB {
+		t.Error("listener with changed port was not restarted")
+	}
+
+	// Conflicting bindings are rejected without touching what's running
+	broken := inlineListener("a", 0, map[string]string{"/": "a3"})
+	broken.ContentBindings = append(broken.ContentBindings, broken.ContentBindings[0])
+	err = m.Apply(&se.UnmarshalledRootSettings{WebListeners: []se.UnmarshalledRootSettingWebListener{broken}})
+	if err == nil || errors.Is(err, ErrListenersNotStarted) {
+		t.Fatalf("expected conflicting bindings to be rejected, got %v", err)
+	}
+	expectBody(t, portA, "/", "a2")
+	expectBody(t, portB2, "/", "b1")
+
+	// Moving a listener onto a port that's taken fails, leaving the listener serving where it was
+	occupied, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer occupied.Close()
+	err = m.Apply(&se.UnmarshalledRootSettings{WebListeners: []se.UnmarshalledRootSettingWebListener{
+		inlineListener("a", 0, map[string]string{"/": "a2"}),
+		inlineListener("b", occupied.Addr().(*net.TCPAddr).Port, map[string]string{"/": "b2"}),
+	}})
+	if !errors.Is(err, ErrListenersNotStarted) {
+		t.Fatalf("expected the occupied port to fail after applying, got %v", err)
+	}
+	expectBody(t, portB2, "/", "b1")
+
+	// A restart on the address the listener already holds has it let go first
+	restarted := inlineListener("b", portB2, map[string]string{"/": "b3"})
+	restarted.ListenAddress = "127.0.0.1"
+	err = m.Apply(&se.UnmarshalledRootSettings{WebListeners: []se.UnmarshalledRootSettingWebListener{
+		inlineListener("a", 0, map[string]string{"/": "a2"}),
+		restarted,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectBody(t, portB2, "/", "b3")
+
+	// Dropping a listener from the settings closes it
+	err = m.Apply(&se.UnmarshalledRootSettings{WebListeners: []se.UnmarshalledRootSettingWebListener{
+		inlineListener("a", 0, map[string]string{"/": "a2"}),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.listeners["b"]; ok {
+		t.Error("removed listener is still registered")
+	}
+}
+
+func TestListenerManager_ApplyKeepsPickedPort(t *testing.T) {
+	t.Parallel()
+
+	m := NewListenerManager(nil, nil)
+	t.Cleanup(func() { _ = m.Close() })
+
+	ls := inlineListener("picked", 0, map[string]string{"/": "ok"})
+	err := m.Apply(&se.UnmarshalledRootSettings{WebListeners: []se.UnmarshalledRootSettingWebListener{ls}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listenerPort(t, m, "picked")
+	server := m.listeners["picked"].server
+
+	// Metrics and auth are served from the mux, so they're swapped in without a restart
+	ls.MetricsPath = "/metrics"
+	ls.Auth = &se.AuthSettings{Basic: []se.BasicAuthUser{{Username: "u", Password: "p"}}}
+	err = m.Apply(&se.UnmarshalledRootSettings{WebListeners: []se.UnmarshalledRootSettingWebListener{ls}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.listeners["picked"].server != server || listenerPort(t, m, "picked") != port {
+		t.Error("listener was restarted for a change to its mux")
+	}
+
+	// A restart rebinds the port picked the first time
+	keepAlive := false
+	ls.OnConnectKeepAlive = &keepAlive
+	err = m.Apply(&se.UnmarshalledRootSettings{WebListeners: []se.UnmarshalledRootSettingWebListener{ls}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.listeners["picked"].server == server {
+		t.Error("listener was not restarted for a change to its server")
+	}
+	if got := listenerPort(t, m, "picked"); got != port {
+		t.Errorf("restarted listener moved from port %d to %d", port, got)
+	}
+}
+
+func TestListenerManager_LiteralPaths(t *testing.T) {
+	t.Parallel()
+
+	m := NewListenerManager(nil, nil)
+	t.Cleanup(func() { _ = m.Close() })
+
+	err := m.Apply(&se.UnmarshalledRootSettings{WebListeners: []se.UnmarshalledRootSettingWebListener{
+		inlineListener("literal", 0, map[string]string{"/users/{id}": "braces", "/GET /x": "spaced", "/100%": "percent", "/": "fallback"}),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listenerPort(t, m, "literal")
+
+	// Paths are never patterns, whichever ServeMux is running
+	expectBody(t, port, "/users/%7Bid%7D", "braces")
+	expectBody(t, port, "/users/42", "fallback")
+	expectBody(t, port, "/GET%20/x", "spaced")
+	expectBody(t, port, "/100%25", "percent")
+}
+
+func TestLiteralPattern(t *testing.T) {
+	t.Parallel()
+
+	if muxPatternsLiteral {
+		t.Skip("ServeMux takes patterns as written")
+	}
+
+	testCases := []struct {
+		pattern  string
+		expected string
+	}{
+		{pattern: "/users/", expected: "/users/"},
+		{pattern: "/users/{id}", expected: "/users/%7Bid%7D"},
+		{pattern: "/a b", expected: "/a%20b"},
+		{pattern: "/100%", expected: "/100%25"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.pattern, func(t *testing.T) {
+			t.Parallel()
+
+			if got := literalPattern(tc.pattern); got != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestListenerManager_ListenAddress(t *testing.T) {
+	t.Parallel()
+
+	socket := filepath.Join(t.TempDir(), "mock.sock")
+
+	// A socket left behind by a process that died is replaced
+	stale, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	_ = stale.Close()
+
+	testCases := []struct {
+		name         string
+		address      string
+		unixSocket   string
+		network      string
+		expectedHost string
+		requiresIPv6 bool
+	}{
+		{name: "ipv4 loopback", address: "127.0.0.1", network: "tcp", expectedHost: "127.0.0.1"},
+		{name: "ipv6 loopback", address: "::1", network: "tcp", expectedHost: "::1", requiresIPv6: true},
+		{name: "unix socket", unixSocket: socket, network: "unix"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if tc.requiresIPv6 {
+				ln, err := net.Listen("tcp6", "[::1]:0")
+				if err != nil {
+					t.Skip("no IPv6 loopback")
+				}
+				_ = ln.Close()
+			}
+
+			ls := inlineListener("local", 0, map[string]string{"/": "ok"})
+			ls.ListenAddress, ls.UnixSocket = tc.address, tc.unixSocket
+
+			m := NewListenerManager(nil, nil)
+			defer m.Close()
+
+			err := m.Apply(&se.UnmarshalledRootSettings{WebListeners: []se.UnmarshalledRootSettingWebListener{ls}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			st := m.Status().Listeners[0]
+			addr := st.Address
+			if tc.network == "tcp" {
+				host, port, err := net.SplitHostPort(st.Address)
+				if err != nil || host != tc.expectedHost || port != strconv.Itoa(st.Port) || st.Port == 0 {
+					t.Fatalf("unexpected status: %+v", st)
+				}
+			} else if addr != socket || st.Port != 0 {
+				t.Fatalf("unexpected status: %+v", st)
+			}
+
+			client := &http.Client{Transport: &http.Transport{DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, tc.network, addr)
+			}}}
+			res, err := client.Get("http://mock/")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+
+			b, err := io.ReadAll(res.Body)
+			if err != nil || string(b) != "ok" {
+				t.Errorf("unexpected body: %s (%v)", b, err)
+			}
+		})
+	}
+}
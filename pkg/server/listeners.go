@@ -1,9 +1,21 @@
 package server
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"net"
 	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,12 +24,12 @@ import (
 	se "github.com/nrexception/mockapi/pkg/settings"
 )
 
-func getListenerContent(binding se.ResponseBinding) (string, error) {
+func getListenerContent(binding se.ResponseBinding, bodyCache *fileContentCache) (string, error) {
 	switch binding.ResponseBodyType {
 	case se.Inline:
 		return binding.ResponseBody, nil
 	case se.File:
-		c, err := readFileContent(binding.ResponseBody)
+		c, err := bodyCache.get(binding.ResponseBody)
 		if err != nil {
 			return "", fmt.Errorf("getListenerContent: %w", err)
 		}
@@ -28,32 +40,143 @@ func getListenerContent(binding se.ResponseBinding) (string, error) {
 	return "", fmt.Errorf("getListenerContent(): response type does not match known type of inline, file or proxy")
 }
 
-func createListenerBinding(commandChannel chan ListenerCommandPacket, responseChannel chan ListenerResponse, binding se.ResponseBinding, sMux *http.ServeMux, threaduuid uuid.UUID) error {
-	co.LogVerboseOnThread(threaduuid, co.MSGTYPE_INFO, fmt.Sprintf("creating binding for %s", binding.Path))
+func writeFileErrorResponse(w http.ResponseWriter, binding se.ResponseBinding) {
+	code := http.StatusInternalServerError
+	body := fmt.Sprintf("response body file for \"%s\" is missing or empty", binding.Path)
 
-	sMux.HandleFunc(binding.Path, func(w http.ResponseWriter, r *http.Request) {
-		co.LogNonVerboseOnThread(threaduuid, co.MSGTYPE_INFO, fmt.Sprintf("\t binding \"%s\" got valid request from %s on %s. sending response...", binding.Path, r.RemoteAddr, r.RequestURI))
+	if binding.FileErrorResponse != nil {
+		if binding.FileErrorResponse.ResponseCode != 0 {
+			code = binding.FileErrorResponse.ResponseCode
+		}
+		if binding.FileErrorResponse.ResponseBody != "" {
+			body = binding.FileErrorResponse.ResponseBody
+		}
+	}
+
+	if binding.FileErrorResponse == nil || binding.FileErrorResponse.ResponseBody == "" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+
+	w.WriteHeader(code)
+	_, _ = io.WriteString(w, body)
+}
+
+func (m *ListenerManager) createListenerBinding(listenerName string, binding se.ResponseBinding, auth *authenticator, threaduuid uuid.UUID) http.Handler {
+	co.LogVerboseOnThread(threaduuid, co.MSGTYPE_INFO, "creating binding", co.LOGKEY_BINDING, binding.Path, co.LOGKEY_METHOD, binding.Method)
+
+	var script *webSocketScript
+	if binding.ResponseBodyType == se.WebSocket && binding.WebSocket != nil {
+		script = newWebSocketScript(*binding.WebSocket, threaduuid)
+	}
+
+	var graphQL *graphQLMock
+	if binding.ResponseBodyType == se.GraphQL && binding.GraphQL != nil {
+		var err error
+		graphQL, err = newGraphQLMock(*binding.GraphQL)
+		if err != nil {
+			co.LogNonVerboseOnThread(threaduuid, co.MSGTYPE_WARN, "graphql binding can't answer queries", co.LOGKEY_BINDING, binding.Path, co.LOGKEY_ERROR, err)
+		}
+	}
+
+	var resource *resourceCollection
+	if binding.ResponseBodyType == se.Resource && binding.Resource != nil {
+		var err error
+		resource, err = m.resources.collection(listenerName, binding)
+		if err != nil {
+			co.LogNonVerboseOnThread(threaduuid, co.MSGTYPE_WARN, "resource binding could not be loaded", co.LOGKEY_BINDING, binding.Path, co.LOGKEY_ERROR, err)
+		}
+	}
+
+	var callbacks *bindingCallbacks
+	if len(binding.Callbacks) > 0 {
+		var err error
+		callbacks, err = newBindingCallbacks(binding.Callbacks)
+		if err != nil {
+			co.LogNonVerboseOnThread(threaduuid, co.MSGTYPE_WARN, "binding's callbacks can't be sent", co.LOGKEY_BINDING, binding.Path, co.LOGKEY_ERROR, err)
+		}
+	}
+
+	var generated *generatedBody
+	if binding.ResponseBodyType == se.Generated && binding.Generated != nil {
+		var err error
+		generated, err = newGeneratedBody(*binding.Generated)
+		if err != nil {
+			co.LogNonVerboseOnThread(threaduuid, co.MSGTYPE_WARN, "generated binding can't make bodies", co.LOGKEY_BINDING, binding.Path, co.LOGKEY_ERROR, err)
+		}
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		setRequestBinding(r, binding.Path)
 
 		// Add headers to response and write, along with response body
 		for _, h := range binding.ResponseHeaders {
 			w.Header().Add(h.Key, h.Value)
 		}
 
-		w.WriteHeader(binding.ResponseCode)
+		// Directory bindings pick their own status (200, 206, 304, 404...) based on the request
+		if binding.ResponseBodyType == se.Directory {
+			serveDirectory(w, r, binding, threaduuid)
+			return
+		}
 
-		// Handle and return body type
-		switch binding.ResponseBodyType {
-		case se.Inline:
-			_, err := io.WriteString(w, binding.ResponseBody)
-			if err != nil {
+		// Response headers go out with the handshake
+		if script != nil {
+			script.serve(w, r, threaduuid)
+			return
+		}
+
+		if binding.ResponseBodyType == se.Stream {
+			serveStream(w, r, binding, threaduuid)
+			return
+		}
+
+		if binding.ResponseBodyType == se.GraphQL {
+			if graphQL == nil {
+				writeGraphQLResponse(w, http.StatusInternalServerError, graphQLResponse{Errors: []graphQLError{{Message: "the binding's schema could not be loaded"}}})
 				return
 			}
-		case se.File:
-			lc, err := getListenerContent(binding)
+			graphQL.serve(w, r, binding, threaduuid)
+			return
+		}
+
+		if binding.ResponseBodyType == se.Resource {
+			if resource == nil {
+				writeResourceError(w, http.StatusInternalServerError, "the binding's resource could not be loaded")
+				return
+			}
+			resource.serve(w, r, binding.Path, threaduuid)
+			return
+		}
+
+		if binding.ResponseBodyType == se.Generated {
+			if generated == nil {
+				http.Error(w, "response body could not be generated", http.StatusInternalServerError)
+				return
+			}
+			generated.serve(w, r, binding, threaduuid)
+			return
+		}
+
+		// File content is resolved before the status is written, so a missing file can still change it
+		if binding.ResponseBodyType == se.File {
+			lc, err := getListenerContent(binding, m.bodyCache)
 			if err != nil {
+				co.LogNonVerboseOnThread(threaduuid, co.MSGTYPE_WARN, "could not read response body file", co.LOGKEY_BINDING, binding.Path, co.LOGKEY_FILE, binding.ResponseBody, co.LOGKEY_ERROR, err)
+				writeFileErrorResponse(w, binding)
 				return
 			}
-			_, err = io.WriteString(w, lc)
+
+			w.WriteHeader(binding.ResponseCode)
+			_, _ = io.WriteString(w, lc)
+			return
+		}
+
+		w.WriteHeader(binding.ResponseCode)
+
+		// Handle and return body type
+		switch binding.ResponseBodyType {
+		case se.Inline:
+			_, err := io.WriteString(w, binding.ResponseBody)
 			if err != nil {
 				return
 			}
@@ -64,70 +187,412 @@ func createListenerBinding(commandChannel chan ListenerCommandPacket, responseCh
 		}
 	})
 
+	if callbacks != nil {
+		inner := handler
+		handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body := captureRequestBody(r, callbackMaxBodyBytes)
+			inner.ServeHTTP(w, r)
+
+			// The client should have its response before any callback could beat it there
+			_ = http.NewResponseController(w).Flush()
+			m.callbacks.fire(callbacks, listenerName, binding.Path, r, body(), threaduuid)
+		})
+	}
+
+	if auth == nil {
+		return handler
+	}
+
+	// Requests without the credentials never reach the binding, nor fire its callbacks
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		setRequestBinding(r, binding.Path)
+		if auth.allow(w, r) {
+			handler.ServeHTTP(w, r)
+		}
+	})
+}
+
+type bindingRoute struct {
+	method  string           // Empty matches any method
+	match   *se.BindingMatch // nil matches any request
+	auth    *authenticator   // nil if the binding needs no auth
+	handler http.Handler
+}
+
+func (route bindingRoute) acceptsMethod(method string) bool {
+	return route.method == "" || route.method == method || (route.method == http.MethodGet && method == http.MethodHead)
+}
+
+// Bindings sharing a path are told apart by method and match, the first one accepting the request handles it. GET
+// bindings answer HEAD too. Requests no binding accepts get a 405 if none take their method, otherwise the auth failure
+// of the first that turned them away for their credentials, or a 403.
+func dispatchBindings(path string, routes []bindingRoute) http.Handler {
+	if len(routes) == 1 && routes[0].method == "" && routes[0].match == nil {
+		return routes[0].handler
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methodAllowed := false
+		for _, route := range routes {
+			if !route.acceptsMethod(r.Method) {
+				continue
+			}
+			methodAllowed = true
+
+			if requestMatches(route.match, r, route.auth) {
+				route.handler.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		setRequestBinding(r, path)
+
+		if methodAllowed {
+			for _, route := range routes {
+				if route.auth == nil || !route.acceptsMethod(r.Method) {
+					continue
+				}
+				if result := route.auth.check(r); result.status != 0 {
+					route.auth.writeFailure(w, result)
+					return
+				}
+			}
+
+			http.Error(w, "no binding matched the request", http.StatusForbidden)
+			return
+		}
+
+		allowed := []string{}
+		for _, route := range routes {
+			if !slices.Contains(allowed, route.method) {
+				allowed = append(allowed, route.method)
+			}
+		}
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	})
+}
+
+// ServeMux panics on conflicting patterns, turn that into an error so the config can be rejected instead...
+func registerHandler(sMux *http.ServeMux, pattern string, handler http.Handler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("registerHandler: %v", r)
+		}
+	}()
+
+	sMux.Handle(literalPattern(pattern), handler)
+
 	return nil
 }
 
-func createListener(commandChannel chan ListenerCommandPacket, responseChannel chan ListenerResponse, webListenerSettings se.UnmarshalledRootSettingWebListener, sMux *http.ServeMux, threaduuid uuid.UUID) error {
-	co.LogVerboseOnThread(threaduuid, co.MSGTYPE_INFO, fmt.Sprintf("configuring %d content bindings for \"%s\"", len(webListenerSettings.ContentBindings), webListenerSettings.ListenerName))
+// Whether ServeMux takes patterns as written, as it did before Go 1.22 and still does for programs running with
+// GODEBUG=httpmuxgo121=1. Otherwise it reads methods, hosts and {wildcards} into them, which is found out by asking.
+var muxPatternsLiteral = func() bool {
+	mux := http.NewServeMux()
+	mux.Handle("/{probe}", http.NotFoundHandler())
+	_, pattern := mux.Handler(&http.Request{Method: http.MethodGet, URL: &url.URL{Path: "/anything"}})
 
-	for _, binding := range webListenerSettings.ContentBindings {
-		binding := binding                                                                       // Solve concurency issues by creating a copy of binding...
-		err := createListenerBinding(commandChannel, responseChannel, binding, sMux, threaduuid) // And call our bindings :)
-		if err != nil {
-			return fmt.Errorf("createListener: %w", err)
+	return pattern == ""
+}()
+
+// Binding paths are matched as written whichever ServeMux is running. The one since Go 1.22 unescapes each part of a
+// pattern, so anything it would read as a method, a wildcard or an escape is escaped for it.
+func literalPattern(pattern string) string {
+	if muxPatternsLiteral || !strings.ContainsAny(pattern, "{}% \t") {
+		return pattern
+	}
+
+	var b strings.Builder
+	for _, c := range []byte(pattern) {
+		switch c {
+		case '{', '}', '%', ' ', '\t':
+			fmt.Fprintf(&b, "%%%02X", c)
+		default:
+			b.WriteByte(c)
 		}
 	}
 
-	listenerRegister = append(listenerRegister, threaduuid) // Append our thread uuid for later reference if we need to close it...
+	return b.String()
+}
 
-	if webListenerSettings.EnableTLS {
-		co.LogNonVerboseOnThread(threaduuid, co.MSGTYPE_INFO, "starting tls listener...")
-		go http.ListenAndServeTLS(fmt.Sprintf("0.0.0.0:%d", webListenerSettings.ListenerPort), webListenerSettings.CertDetails.CertFile, webListenerSettings.CertDetails.KeyFile, sMux)
-	} else {
-		co.LogNonVerboseOnThread(threaduuid, co.MSGTYPE_INFO, "starting non-tls listener...")
-		go http.ListenAndServe(fmt.Sprintf("0.0.0.0:%d", webListenerSettings.ListenerPort), sMux)
+// A running web listener, its bindings live in a mux that can be swapped without touching the underlying server.
+type webListener struct {
+	settings     se.UnmarshalledRootSettingWebListener
+	listenerName string // Copy of settings.ListenerName, safe to read while settings are being swapped
+	threaduuid   uuid.UUID
+	mux          atomic.Pointer[http.ServeMux]
+	accessLog    atomic.Pointer[accessLogger] // nil when access logging is off
+	metrics      *metrics
+	journal      *RequestJournal
+	server       *http.Server
+	certStore    *certificateStore     // nil unless serving certificates from files
+	tlsConfig    *tls.Config           // nil without tls, kept so the listener can be started again
+	addr         net.Addr              // Where the server is actually listening, set by serve
+	ln           net.Listener          // Bound by serveOn, closed by shutdown even if the server never got to Serve it
+	serveErr     atomic.Pointer[error] // Set if the server stopped for any reason other than being shut down
+}
+
+func (l *webListener) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	r, info := withRequestInfo(r)
+	rec := newResponseRecorder(w)
+
+	accessLog := l.acquireAccessLog()
+	defer accessLog.release()
+	journaling := l.journal.isEnabled()
+
+	captureLimit := accessLog.bodyCaptureLimit()
+	if journaling {
+		captureLimit = max(captureLimit, journalMaxBodyBytes)
+	}
+	capturedBody := captureRequestBody(r, captureLimit)
+	rec.captureLimit = accessLog.bodyCaptureLimit()
+
+	l.metrics.requestStarted(l.listenerName)
+	l.mux.Load().ServeHTTP(rec, r)
+	l.metrics.requestFinished(l.listenerName, info.bindingPath, r.Method, rec.status, time.Since(start))
+	requestBody := capturedBody()
+
+	if accessLog != nil {
+		accessLog.log(accessLogEntry{
+			listenerName: l.listenerName,
+			bindingPath:  info.bindingPath,
+			request:      r,
+			start:        start,
+			latency:      time.Since(start),
+			rec:          rec,
+			requestBody:  requestBody[:min(len(requestBody), accessLog.bodyCaptureLimit())],
+		})
+	}
+
+	if journaling {
+		l.journal.record(JournalEntry{
+			Time:     start,
+			Listener: l.listenerName,
+			Binding:  info.bindingPath,
+			Method:   r.Method,
+			Path:     r.URL.Path,
+			Query:    r.URL.Query(),
+			Header:   r.Header.Clone(),
+			Body:     requestBody,
+			Status:   rec.status,
+		})
 	}
 
-	// Hang the go routine unless we close it...
+	msg := "request served"
+	if info.bindingPath == "" {
+		msg = "request unmatched"
+	}
+
+	co.LogNonVerboseOnThread(l.threaduuid, co.MSGTYPE_INFO, msg,
+		co.LOGKEY_LISTENER, l.listenerName,
+		co.LOGKEY_BINDING, info.bindingPath,
+		co.LOGKEY_METHOD, r.Method,
+		co.LOGKEY_PATH, r.URL.Path,
+		co.LOGKEY_STATUS, rec.status,
+		co.LOGKEY_BYTES, rec.bytes,
+		co.LOGKEY_LATENCY, time.Since(start),
+		co.LOGKEY_REMOTEADDR, r.RemoteAddr,
+	)
+}
+
+// The access log, held open until the request is done with it even if a settings change replaces it meanwhile. nil if
+// access logging is off, or the listener is closing.
+func (l *webListener) acquireAccessLog() *accessLogger {
 	for {
-		select {
-		case c := <-commandChannel:
-			if threaduuid == uuid.UUID(c.Identifier) && c.Command == VLC_Close {
-				return nil
+		a := l.accessLog.Load()
+		if a.acquire() {
+			return a
+		}
+		if l.accessLog.Load() == a {
+			return nil // Closed without a replacement
+		}
+	}
+}
+
+// Directories serve everything underneath their path, resources serve the collection on it and each object under it.
+func bindingPatterns(binding se.ResponseBinding) []string {
+	switch binding.ResponseBodyType {
+	case se.Directory:
+		return []string{directoryBindingPattern(binding.Path)}
+	case se.Resource:
+		if trimmed := strings.TrimSuffix(binding.Path, "/"); trimmed != "" {
+			return []string{trimmed, trimmed + "/"}
+		}
+		return []string{"/"}
+	}
+
+	return []string{binding.Path}
+}
+
+func (m *ListenerManager) createListenerMux(webListenerSettings se.UnmarshalledRootSettingWebListener, threaduuid uuid.UUID) (*http.ServeMux, error) {
+	co.LogVerboseOnThread(threaduuid, co.MSGTYPE_INFO, fmt.Sprintf("configuring %d content bindings", len(webListenerSettings.ContentBindings)), co.LOGKEY_LISTENER, webListenerSettings.ListenerName)
+
+	// Group bindings by the pattern they're served on, keeping the order they were configured in
+	patterns := []string{}
+	paths := map[string]string{} // Binding path each pattern was registered for
+	routes := map[string][]bindingRoute{}
+	for _, binding := range webListenerSettings.ContentBindings {
+		auth := newAuthenticator(webListenerSettings.Auth, binding, threaduuid)
+		handler := m.createListenerBinding(webListenerSettings.ListenerName, binding, auth, threaduuid)
+
+		for _, pattern := range bindingPatterns(binding) {
+			for _, route := range routes[pattern] {
+				if route.method == binding.Method && reflect.DeepEqual(route.match, binding.Match) {
+					return nil, fmt.Errorf("createListenerMux: binding path \"%s\" is used more than once for method \"%s\" with the same match", binding.Path, binding.Method)
+				}
 			}
-		default:
-			time.Sleep(5 * time.Second)
+
+			if _, ok := routes[pattern]; !ok {
+				patterns = append(patterns, pattern)
+				paths[pattern] = binding.Path
+			}
+			routes[pattern] = append(routes[pattern], bindingRoute{method: binding.Method, match: binding.Match, auth: auth, handler: handler})
+		}
+	}
+
+	sMux := http.NewServeMux()
+	for _, pattern := range patterns {
+		err := registerHandler(sMux, pattern, dispatchBindings(paths[pattern], routes[pattern]))
+		if err != nil {
+			return nil, fmt.Errorf("createListenerMux: %w", err)
+		}
+	}
+
+	if webListenerSettings.GRPC != nil {
+		grpc, err := newGRPCMock(*webListenerSettings.GRPC, threaduuid)
+		if err != nil {
+			return nil, fmt.Errorf("createListenerMux: %w", err)
+		}
+
+		for _, pattern := range grpc.patterns() {
+			err = registerHandler(sMux, pattern, grpc)
+			if err != nil {
+				return nil, fmt.Errorf("createListenerMux: %w", err)
+			}
+		}
+	}
+
+	if webListenerSettings.MetricsPath != "" {
+		err := registerHandler(sMux, webListenerSettings.MetricsPath, m.metrics)
+		if err != nil {
+			return nil, fmt.Errorf("createListenerMux: %w", err)
+		}
+	}
+
+	return sMux, nil
+}
+
+// Binds address and port (0 picks a free one). No address means every interface, IPv4 and IPv6, as does "::". An IPv4
+// address sticks to IPv4, Go would otherwise take 0.0.0.0 to mean both as well.
+func listenTCP(address string, port int) (net.Listener, error) {
+	network := "tcp"
+	if ip := net.ParseIP(address); ip != nil && ip.To4() != nil {
+		network = "tcp4"
+	}
+
+	return net.Listen(network, net.JoinHostPort(address, strconv.Itoa(port)))
+}
+
+// Binds a unix socket, replacing one left behind by a process that didn't get to clean up after itself. Sockets still
+// accepting connections, and anything that isn't a socket, are left alone.
+func listenUnix(path string) (net.Listener, error) {
+	if fi, err := os.Lstat(path); err == nil && fi.Mode()&fs.ModeSocket != 0 {
+		conn, err := net.DialTimeout("unix", path, time.Second)
+		if err != nil {
+			_ = os.Remove(path)
+		} else {
+			_ = conn.Close()
 		}
 	}
+
+	return net.Listen("unix", path)
 }
 
-var sMux = http.NewServeMux()
-var listenerRegister []uuid.UUID
+// Binds the listener's address: where it last listened if it's been started before, as configured otherwise.
+func (l *webListener) listen() (net.Listener, error) {
+	var ln net.Listener
+	var err error
+	switch {
+	case l.addr != nil:
+		ln, err = net.Listen(l.addr.Network(), l.addr.String())
+	case l.settings.UnixSocket != "":
+		ln, err = listenUnix(l.settings.UnixSocket)
+	default:
+		ln, err = listenTCP(l.settings.ListenAddress, l.settings.ListenerPort)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("webListener.listen: %w", err)
+	}
 
-func ClearAllListeners(commandChannel chan ListenerCommandPacket) {
-	co.LogVerbose("Closing all listener threads...", co.MSGTYPE_WARN)
+	return ln, nil
+}
 
-	for _, thread := range listenerRegister {
-		co.LogVerbose(fmt.Sprintf("Closing listener thread %s...", thread), co.MSGTYPE_WARN)
-		commandChannel <- ListenerCommandPacket{Identifier: uuid.UUID(thread), Command: VLC_Close}
+// Binds and starts serving, over tls if tlsConfig isn't nil.
+func (l *webListener) start(tlsConfig *tls.Config) error {
+	ln, err := l.listen()
+	if err != nil {
+		return fmt.Errorf("webListener.start: %w", err)
 	}
 
-	co.LogVerbose("De-registering listeners...", co.MSGTYPE_WARN)
-	listenerRegister = []uuid.UUID{}
+	l.serveOn(ln, tlsConfig)
+	return nil
+}
+
+// Starts serving on a bound ln, over tls if tlsConfig isn't nil.
+func (l *webListener) serveOn(ln net.Listener, tlsConfig *tls.Config) {
+	// Shutting down ends every request's context, so streams and websockets (which Shutdown doesn't track) stop too
+	// rather than holding the listener up
+	ctx, cancel := context.WithCancel(context.Background())
+	server := &http.Server{Handler: l, BaseContext: func(net.Listener) context.Context { return ctx }}
+	server.RegisterOnShutdown(cancel)
+	configureHTTPServer(server, l.settings)
+	l.server = server
+	l.tlsConfig = tlsConfig
+	l.addr = ln.Addr()
+	l.ln = ln
 
-	co.LogVerbose("Refreshing server Mux...", co.MSGTYPE_WARN)
-	*sMux = *http.NewServeMux()
+	if tlsConfig != nil {
+		// Wrapped by hand rather than through ServeTLS, which would add h2 and http/1.1 to whatever alpn was configured
+		co.LogNonVerboseOnThread(l.threaduuid, co.MSGTYPE_INFO, "starting tls listener...", co.LOGKEY_LISTENER, l.listenerName)
+		go l.serve(func() error { return server.Serve(tls.NewListener(ln, tlsConfig)) })
+	} else {
+		co.LogNonVerboseOnThread(l.threaduuid, co.MSGTYPE_INFO, "starting non-tls listener...", co.LOGKEY_LISTENER, l.listenerName)
+		go l.serve(func() error { return server.Serve(ln) })
+	}
 }
 
-func EstablishListener(commandChannel chan ListenerCommandPacket, responseChannel chan ListenerResponse, ls se.UnmarshalledRootSettingWebListener) error {
-	// Init some values...
-	threaduuid := uuid.New()
+func (l *webListener) serve(serveFunc func() error) {
+	err := serveFunc()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		l.serveErr.Store(&err)
+		co.LogNonVerboseOnThread(l.threaduuid, co.MSGTYPE_ERROR, "listener stopped", co.LOGKEY_LISTENER, l.listenerName, co.LOGKEY_ERROR, err)
+	}
+}
 
-	// Actually start listening...
-	err := createListener(commandChannel, responseChannel, ls, sMux, threaduuid)
+// Stops accepting connections and waits (up to shutdownTimeout) for in-flight requests to finish.
+func (l *webListener) stop() error {
+	err := errors.Join(l.shutdown(), l.certStore.close())
 	if err != nil {
-		return fmt.Errorf("EstablishListener: %w", err)
+		return fmt.Errorf("webListener.stop: %w", err)
 	}
 
-	return nil // no error, we're happy :)
+	return nil
+}
+
+// Like stop, but keeps watching certificate files so the listener can be started again.
+func (l *webListener) shutdown() error {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	err := l.server.Shutdown(ctx)
+
+	// Shutdown only closes listeners Serve has picked up, one whose goroutine hasn't run yet would keep the address
+	_ = l.ln.Close()
+	if err != nil {
+		return fmt.Errorf("webListener.shutdown: %w", err)
+	}
+
+	return nil
 }
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"crypto/x509"
+	"net/http"
+	"slices"
+
+	se "github.com/nrexception/mockapi/pkg/settings"
+)
+
+// Whether r meets every condition in match, a nil match accepts anything. Claims are those of the credentials auth
+// accepts, so a binding without auth never matches on them.
+func requestMatches(match *se.BindingMatch, r *http.Request, auth *authenticator) bool {
+	if match == nil {
+		return true
+	}
+
+	if match.ClientCert != nil && !clientCertMatches(match.ClientCert, r) {
+		return false
+	}
+
+	if match.Claims != nil {
+		if auth == nil {
+			return false
+		}
+		result := auth.check(r)
+		if result.status != 0 || !claimsMatch(match.Claims, result.claims) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Checks the leaf certificate the client presented. With client auth modes other than verify it hasn't been checked
+// against anything, which is fine for telling mocked clients apart but nothing more.
+func clientCertMatches(match *se.ClientCertMatch, r *http.Request) bool {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return false
+	}
+	cert := r.TLS.PeerCertificates[0]
+
+	if match.CommonName != "" && cert.Subject.CommonName != match.CommonName {
+		return false
+	}
+
+	if len(match.SANs) > 0 && !slices.ContainsFunc(certificateSANs(cert), func(san string) bool { return slices.Contains(match.SANs, san) }) {
+		return false
+	}
+
+	return true
+}
+
+func certificateSANs(cert *x509.Certificate) []string {
+	sans := append([]string{}, cert.DNSNames...)
+	sans = append(sans, cert.EmailAddresses...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	for _, uri := range cert.URIs {
+		sans = append(sans, uri.String())
+	}
+
+	return sans
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/nrexception/mockapi/pkg/certs"
+	se "github.com/nrexception/mockapi/pkg/settings"
+)
+
+func TestListenerManager_ClientCertMatch(t *testing.T) {
+	t.Parallel()
+
+	ca, err := certs.NewCA()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	serverCert, err := ca.Issue([]string{"localhost"}, []net.IP{net.ParseIP("127.0.0.1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = certs.SaveKeyPair(serverCert, filepath.Join(dir, "server.pem"), filepath.Join(dir, "server-key.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "clients.pem"), ca.CertPEM(), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewListenerManager(nil, nil)
+	t.Cleanup(func() { _ = m.Close() }) // Outlives the parallel subtests, unlike a defer
+
+	err = m.Apply(&se.UnmarshalledRootSettings{WebListeners: []se.UnmarshalledRootSettingWebListener{{
+		ListenerName: "partners",
+		EnableTLS:    true,
+		CertDetails: &se.UnmarshalledRootSettingWebListenerHTTPSCertFiles{
+			CertFile: filepath.Join(dir, "server.pem"),
+			KeyFile:  filepath.Join(dir, "server-key.pem"),
+		},
+		ClientAuth: &se.ClientAuthSettings{Mode: se.ClientAuthVerify, CAFile: filepath.Join(dir, "clients.pem")},
+		ContentBindings: []se.ResponseBinding{
+			{Path: "/", ResponseCode: http.StatusOK, ResponseBody: "partner a", ResponseBodyType: se.Inline, Match: &se.BindingMatch{ClientCert: &se.ClientCertMatch{CommonName: "partner-a"}}},
+			{Path: "/", ResponseCode: http.StatusOK, ResponseBody: "partner b", ResponseBodyType: se.Inline, Match: &se.BindingMatch{ClientCert: &se.ClientCertMatch{SANs: []string{"b.partners.example"}}}},
+			{Path: "/", ResponseCode: http.StatusForbidden, ResponseBody: "rejected", ResponseBodyType: se.Inline},
+		},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listenerPort(t, m, "partners")
+
+	testCases := []struct {
+		name        string
+		commonName  string
+		sans        []string
+		noCert      bool
+		expectedErr bool
+		code        int
+		body        string
+	}{
+		{name: "common name", commonName: "partner-a", code: http.StatusOK, body: "partner a"},
+		{name: "san", commonName: "someone", sans: []string{"b.partners.example"}, code: http.StatusOK, body: "partner b"},
+		{name: "unknown client", commonName: "partner-c", code: http.StatusForbidden, body: "rejected"},
+		{name: "no certificate", noCert: true, expectedErr: true},
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Cert)
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			config := &tls.Config{RootCAs: roots}
+			if !tc.noCert {
+				clientCert, err := ca.IssueClient(tc.commonName, tc.sans)
+				if err != nil {
+					t.Fatal(err)
+				}
+				config.Certificates = []tls.Certificate{clientCert}
+			}
+			client := &http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+
+			res, err := client.Get("https://127.0.0.1:" + strconv.Itoa(port) + "/")
+			if (err != nil) != tc.expectedErr {
+				t.Fatalf("unexpected error response: %v", err)
+			}
+			if err != nil {
+				return
+			}
+			defer res.Body.Close()
+
+			b, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.StatusCode != tc.code || string(b) != tc.body {
+				t.Errorf("unexpected response: %d %s", res.StatusCode, b)
+			}
+		})
+	}
+}
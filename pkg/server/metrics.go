@@ -0,0 +1,214 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Upper bounds (seconds) of the request latency histogram buckets, wide enough to cover injected delays.
+var latencyBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+type requestMetricKey struct {
+	listener string
+	binding  string
+	method   string
+	status   int
+}
+
+type latencyMetricKey struct {
+	listener string
+	binding  string
+}
+
+type histogram struct {
+	buckets []uint64 // Non-cumulative, one per latencyBuckets entry plus +Inf
+	sum     float64
+	count   uint64
+}
+
+func (h *histogram) observe(v float64) {
+	i, _ := slices.BinarySearch(latencyBuckets, v)
+	h.buckets[i]++
+	h.sum += v
+	h.count++
+}
+
+// Request and lifecycle counters for a ListenerManager, rendered in the Prometheus text exposition format.
+type metrics struct {
+	mu             sync.Mutex
+	requests       map[requestMetricKey]uint64
+	latency        map[latencyMetricKey]*histogram
+	inFlight       map[string]int64
+	unmatched      map[string]uint64
+	reloads        uint64
+	reloadFailures uint64
+
+	activeListeners func() int
+}
+
+func newMetrics(activeListeners func() int) *metrics {
+	return &metrics{
+		requests:        map[requestMetricKey]uint64{},
+		latency:         map[latencyMetricKey]*histogram{},
+		inFlight:        map[string]int64{},
+		unmatched:       map[string]uint64{},
+		activeListeners: activeListeners,
+	}
+}
+
+func (m *metrics) requestStarted(listener string) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	m.inFlight[listener]++
+	m.mu.Unlock()
+}
+
+func (m *metrics) requestFinished(listener string, binding string, method string, status int, latency time.Duration) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.inFlight[listener]--
+	m.requests[requestMetricKey{listener: listener, binding: binding, method: method, status: status}]++
+
+	if binding == "" {
+		m.unmatched[listener]++
+	}
+
+	h, ok := m.latency[latencyMetricKey{listener: listener, binding: binding}]
+	if !ok {
+		h = &histogram{buckets: make([]uint64, len(latencyBuckets)+1)}
+		m.latency[latencyMetricKey{listener: listener, binding: binding}] = h
+	}
+	h.observe(latency.Seconds())
+}
+
+func (m *metrics) configReloaded(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.reloads++
+	if err != nil {
+		m.reloadFailures++
+	}
+}
+
+func escapeLabelValue(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
+
+// Renders label pairs, eg. {listener="api",binding="/"}
+func formatLabels(pairs ...string) string {
+	labels := make([]string, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		labels = append(labels, fmt.Sprintf(`%s="%s"`, pairs[i], escapeLabelValue(pairs[i+1])))
+	}
+
+	return "{" + strings.Join(labels, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func writeMetricHeader(w io.Writer, name string, metricType string, help string) {
+	_, _ = fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+// Sorted map keys, so scrapes are stable and diffable.
+func sortedKeys[K comparable, V any](m map[K]V, compare func(a, b K) int) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.SortFunc(keys, compare)
+
+	return keys
+}
+
+func compareRequestMetricKeys(a, b requestMetricKey) int {
+	if c := strings.Compare(a.listener, b.listener); c != 0 {
+		return c
+	}
+	if c := strings.Compare(a.binding, b.binding); c != 0 {
+		return c
+	}
+	if c := strings.Compare(a.method, b.method); c != 0 {
+		return c
+	}
+
+	return a.status - b.status
+}
+
+func (m *metrics) writeTo(w io.Writer) {
+	activeListeners := 0
+	if m.activeListeners != nil {
+		activeListeners = m.activeListeners()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	writeMetricHeader(w, "mockapi_requests_total", "counter", "Requests handled, by listener, binding, method and status.")
+	for _, k := range sortedKeys(m.requests, compareRequestMetricKeys) {
+		_, _ = fmt.Fprintf(w, "mockapi_requests_total%s %d\n", formatLabels("listener", k.listener, "binding", k.binding, "method", k.method, "status", strconv.Itoa(k.status)), m.requests[k])
+	}
+
+	writeMetricHeader(w, "mockapi_request_duration_seconds", "histogram", "Request latency, including any injected delay, by listener and binding.")
+	for _, k := range sortedKeys(m.latency, func(a, b latencyMetricKey) int {
+		return strings.Compare(a.listener+"\x00"+a.binding, b.listener+"\x00"+b.binding)
+	}) {
+		h := m.latency[k]
+		cumulative := uint64(0)
+		for i, le := range latencyBuckets {
+			cumulative += h.buckets[i]
+			_, _ = fmt.Fprintf(w, "mockapi_request_duration_seconds_bucket%s %d\n", formatLabels("listener", k.listener, "binding", k.binding, "le", formatFloat(le)), cumulative)
+		}
+		_, _ = fmt.Fprintf(w, "mockapi_request_duration_seconds_bucket%s %d\n", formatLabels("listener", k.listener, "binding", k.binding, "le", "+Inf"), h.count)
+		_, _ = fmt.Fprintf(w, "mockapi_request_duration_seconds_sum%s %s\n", formatLabels("listener", k.listener, "binding", k.binding), formatFloat(h.sum))
+		_, _ = fmt.Fprintf(w, "mockapi_request_duration_seconds_count%s %d\n", formatLabels("listener", k.listener, "binding", k.binding), h.count)
+	}
+
+	writeMetricHeader(w, "mockapi_requests_in_flight", "gauge", "Requests currently being handled, by listener.")
+	for _, k := range sortedKeys(m.inFlight, strings.Compare) {
+		_, _ = fmt.Fprintf(w, "mockapi_requests_in_flight%s %d\n", formatLabels("listener", k), m.inFlight[k])
+	}
+
+	writeMetricHeader(w, "mockapi_unmatched_requests_total", "counter", "Requests that didn't match any binding, by listener.")
+	for _, k := range sortedKeys(m.unmatched, strings.Compare) {
+		_, _ = fmt.Fprintf(w, "mockapi_unmatched_requests_total%s %d\n", formatLabels("listener", k), m.unmatched[k])
+	}
+
+	writeMetricHeader(w, "mockapi_config_reloads_total", "counter", "Configuration reloads attempted.")
+	_, _ = fmt.Fprintf(w, "mockapi_config_reloads_total %d\n", m.reloads)
+
+	writeMetricHeader(w, "mockapi_config_reload_failures_total", "counter", "Configuration reloads rejected.")
+	_, _ = fmt.Fprintf(w, "mockapi_config_reload_failures_total %d\n", m.reloadFailures)
+
+	writeMetricHeader(w, "mockapi_active_listeners", "gauge", "Web and socket listeners currently serving.")
+	_, _ = fmt.Fprintf(w, "mockapi_active_listeners %d\n", activeListeners)
+}
+
+func (m *metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	setRequestBinding(r, r.URL.Path)
+
+	// Rendered up front, so a slow scraper doesn't hold up requests waiting to be counted
+	var b bytes.Buffer
+	m.writeTo(&b)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = w.Write(b.Bytes())
+}
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetrics_WriteTo(t *testing.T) {
+	t.Parallel()
+
+	m := newMetrics(func() int { return 2 })
+
+	m.requestStarted("api")
+	m.requestFinished("api", "/users", http.MethodGet, http.StatusOK, 3*time.Millisecond)
+	m.requestStarted("api")
+	m.requestFinished("api", "/users", http.MethodGet, http.StatusOK, 2*time.Second)
+	m.requestStarted("api")
+	m.requestFinished("api", "", http.MethodPost, http.StatusNotFound, time.Millisecond)
+	m.requestStarted("api \"quoted\"")
+	m.configReloaded(nil)
+	m.configReloaded(errors.New("bad config"))
+
+	var b bytes.Buffer
+	m.writeTo(&b)
+	got := b.String()
+
+	for _, want := range []string{
+		"# TYPE mockapi_requests_total counter\n",
+		`mockapi_requests_total{listener="api",binding="/users",method="GET",status="200"} 2` + "\n",
+		`mockapi_requests_total{listener="api",binding="",method="POST",status="404"} 1` + "\n",
+		`mockapi_request_duration_seconds_bucket{listener="api",binding="/users",le="0.005"} 1` + "\n",
+		`mockapi_request_duration_seconds_bucket{listener="api",binding="/users",le="2.5"} 2` + "\n",
+		`mockapi_request_duration_seconds_bucket{listener="api",binding="/users",le="+Inf"} 2` + "\n",
+		`mockapi_request_duration_seconds_count{listener="api",binding="/users"} 2` + "\n",
+		`mockapi_requests_in_flight{listener="api"} 0` + "\n",
+		`mockapi_requests_in_flight{listener="api \"quoted\""} 1` + "\n",
+		`mockapi_unmatched_requests_total{listener="api"} 1` + "\n",
+		"mockapi_config_reloads_total 2\n",
+		"mockapi_config_reload_failures_total 1\n",
+		"mockapi_active_listeners 2\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in:\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+)
+
+type requestInfoKey struct{}
+
+// Per request details filled in while the request is being handled, for anything that runs after the handler.
+type requestInfo struct {
+	bindingPath string                        // Empty if no binding matched
+	auth        map[*authenticator]authResult // Each binding's verdict, so credentials are checked once
+	claims      map[string]any                // Of the credentials the binding accepted
+}
+
+func withRequestInfo(r *http.Request) (*http.Request, *requestInfo) {
+	info := &requestInfo{}
+	return r.WithContext(context.WithValue(r.Context(), requestInfoKey{}, info)), info
+}
+
+// Records which binding handled r, a no-op for requests that didn't come through a webListener.
+func setRequestBinding(r *http.Request, bindingPath string) {
+	if info, ok := r.Context().Value(requestInfoKey{}).(*requestInfo); ok {
+		info.bindingPath = bindingPath
+	}
+}
+
+// Request bodies are captured up front (mocks rarely read them), then handed back to the handler untouched. gRPC
+// requests may be streams the client holds open until it hears back, so they're captured as the handler reads them
+// instead. The returned func gives what was captured once the handler is done.
+func captureRequestBody(r *http.Request, limit int) func() []byte {
+	if limit <= 0 || r.Body == nil || r.Body == http.NoBody {
+		return func() []byte { return nil }
+	}
+
+	if isGRPCRequest(r) {
+		capture := &capturingReader{Reader: r.Body, limit: limit}
+		r.Body = readCloser{Reader: capture, Closer: r.Body}
+		return func() []byte { return capture.captured.Bytes() }
+	}
+
+	captured, _ := io.ReadAll(io.LimitReader(r.Body, int64(limit)))
+	r.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(captured), r.Body), Closer: r.Body}
+
+	return func() []byte { return captured }
+}
+
+// Keeps the first limit bytes read through it.
+type capturingReader struct {
+	io.Reader
+	limit    int
+	captured bytes.Buffer
+}
+
+func (c *capturingReader) Read(p []byte) (int, error) {
+	n, err := c.Reader.Read(p)
+	if remaining := c.limit - c.captured.Len(); remaining > 0 {
+		c.captured.Write(p[:min(remaining, n)])
+	}
+
+	return n, err
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// Captures the status and size of a response as it's written, along with the first captureLimit bytes of its body.
+type responseRecorder struct {
+	http.ResponseWriter
+	status       int
+	bytes        int64
+	wroteHeader  bool
+	captureLimit int
+	captured     bytes.Buffer
+}
+
+func newResponseRecorder(w http.ResponseWriter) *responseRecorder {
+	return &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+}
+
+func (rec *responseRecorder) WriteHeader(code int) {
+	if !rec.wroteHeader {
+		rec.status = code
+		rec.wroteHeader = true
+	}
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += int64(n)
+
+	if remaining := rec.captureLimit - rec.captured.Len(); remaining > 0 {
+		rec.captured.Write(b[:min(remaining, n)])
+	}
+
+	return n, err
+}
+
+// Lets http.ResponseController reach the underlying writer (Flush, Hijack, deadlines...).
+func (rec *responseRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// Websocket upgrades take the connection over, the handshake is recorded as the response.
+func (rec *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(rec.ResponseWriter).Hijack()
+	if err == nil {
+		rec.status, rec.wroteHeader = http.StatusSwitchingProtocols, true
+	}
+
+	return conn, rw, err
+}
+
+func (rec *responseRecorder) Flush() {
+	_ = http.NewResponseController(rec.ResponseWriter).Flush()
+}
@@ -0,0 +1,495 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+
+	co "github.com/nrexception/mockapi/pkg/common"
+	se "github.com/nrexception/mockapi/pkg/settings"
+)
+
+const resourceMaxBodyBytes = 10 << 20
+
+// The state behind a resource binding. It outlives reloads that leave the binding's settings alone, so editing some
+// other binding doesn't wipe what a frontend has been building up.
+type resourceCollection struct {
+	settings se.ResourceSettings
+	idField  string
+	mu       sync.Mutex
+	items    []map[string]any // In the order they were added
+	nextId   int64            // For int ids
+}
+
+// Resource collections by listener and binding path, kept by the manager across reloads.
+type resourceCollections struct {
+	mu          sync.Mutex
+	collections map[string]*resourceCollection
+	planned     map[string]*resourceCollection // For the listeners Apply is rebuilding, kept once it commits
+}
+
+func resourceKey(listenerName string, bindingPath string) string {
+	return listenerName + "\x00" + bindingPath
+}
+
+// The collection for a binding: the one already running if its settings are unchanged, a freshly loaded one otherwise.
+// Either way it's only planned, see commit.
+func (c *resourceCollections) collection(listenerName string, binding se.ResponseBinding) (*resourceCollection, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := resourceKey(listenerName, binding.Path)
+	if planned := c.planned[key]; planned != nil && reflect.DeepEqual(planned.settings, *binding.Resource) {
+		return planned, nil
+	}
+
+	collection := c.collections[key]
+	if collection == nil || !reflect.DeepEqual(collection.settings, *binding.Resource) {
+		var err error
+		collection, err = newResourceCollection(*binding.Resource)
+		if err != nil {
+			return nil, fmt.Errorf("resourceCollections.collection: %w", err)
+		}
+	}
+
+	if c.planned == nil {
+		c.planned = map[string]*resourceCollection{}
+	}
+	c.planned[key] = collection
+
+	return collection, nil
+}
+
+// Keeps the planned collections of the rebuilt listeners, and the collections of configured listeners that weren't
+// rebuilt. Everything else, removed bindings and listeners included, is dropped.
+func (c *resourceCollections) commit(rebuilt map[string]bool, configured map[string]bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key := range c.collections {
+		listenerName, _, _ := strings.Cut(key, "\x00")
+		if rebuilt[listenerName] || !configured[listenerName] {
+			delete(c.collections, key)
+		}
+	}
+
+	if c.collections == nil {
+		c.collections = map[string]*resourceCollection{}
+	}
+	for key, collection := range c.planned {
+		c.collections[key] = collection
+	}
+	c.planned = nil
+}
+
+// Forgets the planned collections of a rejected Apply, the running ones carry on.
+func (c *resourceCollections) discard() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.planned = nil
+}
+
+// Loads the persisted collection if there is one, the seed otherwise.
+func newResourceCollection(settings se.ResourceSettings) (*resourceCollection, error) {
+	c := &resourceCollection{settings: settings, idField: settings.Id(), nextId: 1}
+
+	var err error
+	if settings.PersistFile != "" {
+		c.items, err = se.LoadResourceFile(settings.PersistFile)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("newResourceCollection: %w", err)
+		}
+	}
+	if c.items == nil && settings.SeedFile != "" {
+		c.items, err = se.LoadResourceFile(settings.SeedFile)
+		if err != nil {
+			return nil, fmt.Errorf("newResourceCollection: %w", err)
+		}
+	}
+	if c.items == nil {
+		c.items = []map[string]any{}
+	}
+
+	for _, item := range c.items {
+		if id, ok := item[c.idField]; ok {
+			c.countId(id)
+		}
+	}
+	for _, item := range c.items {
+		if _, ok := item[c.idField]; !ok {
+			item[c.idField] = c.newId()
+		}
+	}
+
+	return c, nil
+}
+
+// Keeps generated int ids clear of one that's been used.
+func (c *resourceCollection) countId(id any) {
+	n, err := strconv.ParseInt(idString(id), 10, 64)
+	if err == nil && n >= c.nextId {
+		c.nextId = n + 1
+	}
+}
+
+func (c *resourceCollection) newId() any {
+	if c.settings.IdType == se.ResourceIdUUID {
+		return uuid.NewString()
+	}
+
+	id := c.nextId
+	c.nextId++
+	return json.Number(strconv.FormatInt(id, 10))
+}
+
+// An id (or any other scalar) as it'd be written in a path or query string.
+func idString(v any) string {
+	switch value := v.(type) {
+	case string:
+		return value
+	case nil:
+		return "null"
+	}
+
+	return fmt.Sprint(v)
+}
+
+func (c *resourceCollection) index(id string) int {
+	return slices.IndexFunc(c.items, func(item map[string]any) bool { return idString(item[c.idField]) == id })
+}
+
+// Saves the collection, if it's persisted, via a temporary file so a crash never leaves half of it behind. Expects c.mu
+// to be held.
+func (c *resourceCollection) persist() error {
+	if c.settings.PersistFile == "" {
+		return nil
+	}
+
+	b, err := json.MarshalIndent(c.items, "", "  ")
+	if err != nil {
+		return fmt.Errorf("persist: %w", err)
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(c.settings.PersistFile), filepath.Base(c.settings.PersistFile)+".*")
+	if err != nil {
+		return fmt.Errorf("persist: %w", err)
+	}
+	_, err = f.Write(append(b, '\n'))
+	err = errors.Join(err, f.Close())
+	if err == nil {
+		err = os.Rename(f.Name(), c.settings.PersistFile)
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return fmt.Errorf("persist: %w", err)
+	}
+
+	return nil
+}
+
+// Persists after a change. The change has already been made in memory, so failing to save it is only worth a warning.
+func (c *resourceCollection) save(bindingPath string, threaduuid uuid.UUID) {
+	err := c.persist()
+	if err != nil {
+		co.LogNonVerboseOnThread(threaduuid, co.MSGTYPE_WARN, "could not save resource", co.LOGKEY_BINDING, bindingPath, co.LOGKEY_FILE, c.settings.PersistFile, co.LOGKEY_ERROR, err)
+	}
+}
+
+func writeResourceJSON(w http.ResponseWriter, status int, v any) {
+	b, err := marshalJSON(v)
+	if err != nil {
+		status, b = http.StatusInternalServerError, []byte(`{"error":"could not encode the response"}`)
+	}
+
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.WriteHeader(status)
+	_, _ = w.Write(b)
+}
+
+func writeResourceError(w http.ResponseWriter, status int, message string) {
+	writeResourceJSON(w, status, map[string]string{"error": message})
+}
+
+// Decodes a request body that has to be a json object.
+func readResourceObject(r *http.Request) (map[string]any, error) {
+	b, err := io.ReadAll(io.LimitReader(r.Body, resourceMaxBodyBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("readResourceObject: %w", err)
+	}
+	if len(b) > resourceMaxBodyBytes {
+		return nil, fmt.Errorf("readResourceObject: body is over %d bytes", resourceMaxBodyBytes)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+
+	var object map[string]any
+	err = decoder.Decode(&object)
+	if err != nil || object == nil {
+		return nil, fmt.Errorf("readResourceObject: expected a json object")
+	}
+
+	return object, nil
+}
+
+// Applies a json merge patch (RFC 7386): nulls remove fields, objects are merged, anything else replaces.
+func mergePatch(target map[string]any, patch map[string]any) map[string]any {
+	for k, v := range patch {
+		switch value := v.(type) {
+		case nil:
+			delete(target, k)
+		case map[string]any:
+			existing, _ := target[k].(map[string]any)
+			if existing == nil {
+				existing = map[string]any{}
+			}
+			target[k] = mergePatch(existing, value)
+		default:
+			target[k] = v
+		}
+	}
+
+	return target
+}
+
+// Looks up a field by a dotted path into nested objects, eg. address.city.
+func fieldValue(item map[string]any, path string) (any, bool) {
+	var v any = item
+	for _, name := range strings.Split(path, ".") {
+		object, ok := v.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if v, ok = object[name]; !ok {
+			return nil, false
+		}
+	}
+
+	return v, true
+}
+
+// Orders two field values: numbers numerically, anything else by its string form, missing fields first.
+func compareFieldValues(a any, aOk bool, b any, bOk bool) int {
+	if !aOk || !bOk {
+		switch {
+		case aOk:
+			return 1
+		case bOk:
+			return -1
+		}
+		return 0
+	}
+
+	an, aErr := strconv.ParseFloat(idString(a), 64)
+	bn, bErr := strconv.ParseFloat(idString(b), 64)
+	_, aNumber := a.(json.Number)
+	_, bNumber := b.(json.Number)
+	if aErr == nil && bErr == nil && aNumber && bNumber {
+		switch {
+		case an < bn:
+			return -1
+		case an > bn:
+			return 1
+		}
+		return 0
+	}
+
+	return strings.Compare(idString(a), idString(b))
+}
+
+// Filters, sorts and pages the collection as the query asks. Parameters starting with _ control the listing, the rest
+// filter on the field they name (any of the values given has to match). Returns the page and how many matched.
+func (c *resourceCollection) list(query url.Values) ([]map[string]any, int, error) {
+	items := []map[string]any{}
+	for _, item := range c.items {
+		matches := true
+		for field, values := range query {
+			if strings.HasPrefix(field, "_") {
+				continue
+			}
+			v, ok := fieldValue(item, field)
+			if !ok || !slices.Contains(values, idString(v)) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			items = append(items, item)
+		}
+	}
+
+	if sort := query.Get("_sort"); sort != "" {
+		descending := strings.EqualFold(query.Get("_order"), "desc")
+		fields := strings.Split(sort, ",")
+
+		slices.SortStableFunc(items, func(a map[string]any, b map[string]any) int {
+			for _, field := range fields {
+				order := 1
+				if strings.HasPrefix(field, "-") {
+					field, order = field[1:], -1
+				}
+				if descending {
+					order = -order
+				}
+
+				av, aOk := fieldValue(a, field)
+				bv, bOk := fieldValue(b, field)
+				if n := compareFieldValues(av, aOk, bv, bOk); n != 0 {
+					return n * order
+				}
+			}
+			return 0
+		})
+	}
+
+	total := len(items)
+
+	limit := c.settings.PageSize
+	if v := query.Get("_limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, 0, fmt.Errorf("_limit must be a number, 0 or above: \"%s\"", v)
+		}
+		limit = n
+	}
+
+	page := 1
+	if v := query.Get("_page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return nil, 0, fmt.Errorf("_page must be a number, 1 or above: \"%s\"", v)
+		}
+		page = n
+	}
+
+	if limit > 0 {
+		start := min((page-1)*limit, len(items))
+		items = items[start:min(start+limit, len(items))]
+	}
+
+	return items, total, nil
+}
+
+// Answers the collection's path (list and create) and the paths of its objects (read, replace, update and delete).
+func (c *resourceCollection) serve(w http.ResponseWriter, r *http.Request, bindingPath string, threaduuid uuid.UUID) {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, strings.TrimSuffix(bindingPath, "/")), "/")
+	if strings.Contains(rest, "/") {
+		writeResourceError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	id, err := url.PathUnescape(rest)
+	if err != nil {
+		writeResourceError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var object map[string]any
+	if r.Method == http.MethodPost || r.Method == http.MethodPut || r.Method == http.MethodPatch {
+		object, err = readResourceObject(r)
+		if err != nil {
+			writeResourceError(w, http.StatusBadRequest, "expected a json object")
+			return
+		}
+	}
+
+	if id == "" {
+		c.serveCollection(w, r, object, bindingPath, threaduuid)
+		return
+	}
+
+	i := c.index(id)
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		if i < 0 {
+			writeResourceError(w, http.StatusNotFound, "not found")
+			return
+		}
+		writeResourceJSON(w, http.StatusOK, c.items[i])
+
+	case http.MethodPut, http.MethodPatch:
+		if i < 0 {
+			writeResourceError(w, http.StatusNotFound, "not found")
+			return
+		}
+		if v, ok := object[c.idField]; ok && idString(v) != id {
+			writeResourceError(w, http.StatusBadRequest, fmt.Sprintf("%s can't be changed", c.idField))
+			return
+		}
+
+		if r.Method == http.MethodPatch {
+			object = mergePatch(c.items[i], object)
+		}
+		object[c.idField] = c.items[i][c.idField]
+		c.items[i] = object
+		c.save(bindingPath, threaduuid)
+		writeResourceJSON(w, http.StatusOK, object)
+
+	case http.MethodDelete:
+		if i < 0 {
+			writeResourceError(w, http.StatusNotFound, "not found")
+			return
+		}
+		c.items = slices.Delete(c.items, i, i+1)
+		c.save(bindingPath, threaduuid)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT, PATCH, DELETE")
+		writeResourceError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (c *resourceCollection) serveCollection(w http.ResponseWriter, r *http.Request, object map[string]any, bindingPath string, threaduuid uuid.UUID) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		items, total, err := c.list(r.URL.Query())
+		if err != nil {
+			writeResourceError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		w.Header().Set("X-Total-Count", strconv.Itoa(total))
+		writeResourceJSON(w, http.StatusOK, items)
+
+	case http.MethodPost:
+		if id, ok := object[c.idField]; ok {
+			if c.index(idString(id)) >= 0 {
+				writeResourceError(w, http.StatusConflict, fmt.Sprintf("%s %s already exists", c.idField, idString(id)))
+				return
+			}
+			c.countId(id)
+		} else {
+			object[c.idField] = c.newId()
+		}
+
+		c.items = append(c.items, object)
+		c.save(bindingPath, threaduuid)
+		w.Header().Set("Location", strings.TrimSuffix(r.URL.Path, "/")+"/"+url.PathEscape(idString(object[c.idField])))
+		writeResourceJSON(w, http.StatusCreated, object)
+
+	default:
+		w.Header().Set("Allow", "GET, HEAD, POST")
+		writeResourceError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
@@ -0,0 +1,313 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	se "github.com/nrexception/mockapi/pkg/settings"
+)
+
+func TestListenerManager_Resource(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	seedFile := filepath.Join(dir, "seed.json")
+	err := os.WriteFile(seedFile, []byte(`[
+		{"id": 1, "name": "Ada", "age": 36, "address": {"city": "London"}},
+		{"id": 2, "name": "Grace", "age": 85, "address": {"city": "New York"}},
+		{"id": 3, "name": "Alan", "age": 41, "address": {"city": "London"}}
+	]`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	persistFile := filepath.Join(dir, "users.json")
+
+	m := NewListenerManager(nil, nil)
+	t.Cleanup(func() { _ = m.Close() })
+
+	settings := func(pageSize int) *se.UnmarshalledRootSettings {
+		return &se.UnmarshalledRootSettings{WebListeners: []se.UnmarshalledRootSettingWebListener{{
+			ListenerName: "resource",
+			ContentBindings: []se.ResponseBinding{{
+				Path:             "/users",
+				ResponseBodyType: se.Resource,
+				Resource:         &se.ResourceSettings{SeedFile: seedFile, PageSize: pageSize, PersistFile: persistFile},
+			}},
+		}}}
+	}
+
+	err = m.Apply(settings(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listenerPort(t, m, "resource")
+
+	base := "http://127.0.0.1:" + strconv.Itoa(port)
+
+	// Each step builds on the state the last one left behind
+	steps := []struct {
+		name            string
+		method          string
+		path            string
+		body            string
+		expectedCode    int
+		expected        string
+		expectedHeaders map[string]string
+	}{
+		{
+			name:            "list",
+			method:          http.MethodGet,
+			path:            "/users",
+			expectedCode:    http.StatusOK,
+			expected:        `[{"address":{"city":"London"},"age":36,"id":1,"name":"Ada"},{"address":{"city":"New York"},"age":85,"id":2,"name":"Grace"},{"address":{"city":"London"},"age":41,"id":3,"name":"Alan"}]`,
+			expectedHeaders: map[string]string{"X-Total-Count": "3", "Content-Type": "application/json"},
+		},
+		{
+			name:            "filter, sort and page",
+			method:          http.MethodGet,
+			path:            "/users/?address.city=London&_sort=-age&_limit=1&_page=2",
+			expectedCode:    http.StatusOK,
+			expected:        `[{"address":{"city":"London"},"age":36,"id":1,"name":"Ada"}]`,
+			expectedHeaders: map[string]string{"X-Total-Count": "2"},
+		},
+		{
+			name:         "filter on any of several values",
+			method:       http.MethodGet,
+			path:         "/users?name=Ada&name=Alan&_sort=name&_order=desc",
+			expectedCode: http.StatusOK,
+			expected:     `[{"address":{"city":"London"},"age":41,"id":3,"name":"Alan"},{"address":{"city":"London"},"age":36,"id":1,"name":"Ada"}]`,
+		},
+		{
+			name:         "invalid page",
+			method:       http.MethodGet,
+			path:         "/users?_page=0",
+			expectedCode: http.StatusBadRequest,
+			expected:     `{"error":"_page must be a number, 1 or above: \"0\""}`,
+		},
+		{
+			name:         "get",
+			method:       http.MethodGet,
+			path:         "/users/2",
+			expectedCode: http.StatusOK,
+			expected:     `{"address":{"city":"New York"},"age":85,"id":2,"name":"Grace"}`,
+		},
+		{
+			name:         "get missing",
+			method:       http.MethodGet,
+			path:         "/users/9",
+			expectedCode: http.StatusNotFound,
+			expected:     `{"error":"not found"}`,
+		},
+		{
+			name:            "create",
+			method:          http.MethodPost,
+			path:            "/users",
+			body:            `{"name":"Edsger"}`,
+			expectedCode:    http.StatusCreated,
+			expected:        `{"id":4,"name":"Edsger"}`,
+			expectedHeaders: map[string]string{"Location": "/users/4"},
+		},
+		{
+			name:         "create with a taken id",
+			method:       http.MethodPost,
+			path:         "/users",
+			body:         `{"id":4,"name":"Barbara"}`,
+			expectedCode: http.StatusConflict,
+			expected:     `{"error":"id 4 already exists"}`,
+		},
+		{
+			name:         "create something other than an object",
+			method:       http.MethodPost,
+			path:         "/users",
+			body:         `["Barbara"]`,
+			expectedCode: http.StatusBadRequest,
+			expected:     `{"error":"expected a json object"}`,
+		},
+		{
+			name:         "replace",
+			method:       http.MethodPut,
+			path:         "/users/4",
+			body:         `{"name":"Edsger Dijkstra","age":72}`,
+			expectedCode: http.StatusOK,
+			expected:     `{"age":72,"id":4,"name":"Edsger Dijkstra"}`,
+		},
+		{
+			name:         "update",
+			method:       http.MethodPatch,
+			path:         "/users/1",
+			body:         `{"age":null,"address":{"postcode":"N1"}}`,
+			expectedCode: http.StatusOK,
+			expected:     `{"address":{"city":"London","postcode":"N1"},"id":1,"name":"Ada"}`,
+		},
+		{
+			name:         "change the id",
+			method:       http.MethodPatch,
+			path:         "/users/1",
+			body:         `{"id":5}`,
+			expectedCode: http.StatusBadRequest,
+			expected:     `{"error":"id can't be changed"}`,
+		},
+		{
+			name:         "delete",
+			method:       http.MethodDelete,
+			path:         "/users/2",
+			expectedCode: http.StatusNoContent,
+		},
+		{
+			name:         "delete missing",
+			method:       http.MethodDelete,
+			path:         "/users/2",
+			expectedCode: http.StatusNotFound,
+			expected:     `{"error":"not found"}`,
+		},
+		{
+			name:            "method not allowed",
+			method:          http.MethodDelete,
+			path:            "/users",
+			expectedCode:    http.StatusMethodNotAllowed,
+			expected:        `{"error":"method not allowed"}`,
+			expectedHeaders: map[string]string{"Allow": "GET, HEAD, POST"},
+		},
+		{
+			name:         "too deep",
+			method:       http.MethodGet,
+			path:         "/users/1/friends",
+			expectedCode: http.StatusNotFound,
+			expected:     `{"error":"not found"}`,
+		},
+	}
+
+	for _, step := range steps {
+		req, err := http.NewRequest(step.method, base+step.path, strings.NewReader(step.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		b, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+
+		if resp.StatusCode != step.expectedCode || string(b) != step.expected {
+			t.Errorf("%s: unexpected response: %d %s", step.name, resp.StatusCode, b)
+		}
+		for k, v := range step.expectedHeaders {
+			if got := resp.Header.Get(k); got != v {
+				t.Errorf("%s: unexpected %s header: %q", step.name, k, got)
+			}
+		}
+	}
+
+	get := func(path string) string {
+		t.Helper()
+		resp, err := http.Get(base + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		return string(b)
+	}
+
+	const expected = `[{"address":{"city":"London","postcode":"N1"},"id":1,"name":"Ada"},{"address":{"city":"London"},"age":41,"id":3,"name":"Alan"},{"age":72,"id":4,"name":"Edsger Dijkstra"}]`
+
+	// Reloading with the same resource settings keeps the state...
+	err = m.Apply(settings(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := get("/users"); got != expected {
+		t.Errorf("state was lost on reload: %s", got)
+	}
+
+	// ...and changing them reloads it, from the persisted file rather than the seed
+	err = m.Apply(settings(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := get("/users?_page=2"); got != `[{"age":72,"id":4,"name":"Edsger Dijkstra"}]` {
+		t.Errorf("persisted state was not loaded: %s", got)
+	}
+
+	items, err := se.LoadResourceFile(persistFile)
+	if err != nil || len(items) != 3 {
+		t.Errorf("unexpected persisted items: %v (%v)", items, err)
+	}
+}
+
+func TestResourceCollection_UUIDs(t *testing.T) {
+	t.Parallel()
+
+	c, err := newResourceCollection(se.ResourceSettings{IdField: "key", IdType: se.ResourceIdUUID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, second := c.newId(), c.newId()
+	if first == second || len(first.(string)) != 36 {
+		t.Errorf("unexpected uuids: %v, %v", first, second)
+	}
+	if len(c.items) != 0 || c.idField != "key" {
+		t.Errorf("unexpected collection: %+v", c)
+	}
+}
+
+func TestResourceCollections_Commit(t *testing.T) {
+	t.Parallel()
+
+	binding := func(path string, pageSize int) se.ResponseBinding {
+		return se.ResponseBinding{Path: path, ResponseBodyType: se.Resource, Resource: &se.ResourceSettings{PageSize: pageSize}}
+	}
+	var c resourceCollections
+
+	users, err := c.collection("api", binding("/users", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	orders, err := c.collection("api", binding("/orders", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	teams, err := c.collection("admin", binding("/teams", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.collections) != 0 {
+		t.Fatal("collections were kept before the plan was committed")
+	}
+	c.commit(map[string]bool{"api": true, "admin": true}, map[string]bool{"api": true, "admin": true})
+
+	// A rejected plan leaves what's running alone...
+	changed, err := c.collection("api", binding("/users", 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed == users {
+		t.Fatal("changed settings reused the running collection")
+	}
+	c.discard()
+	if got, _ := c.collection("api", binding("/users", 0)); got != users {
+		t.Error("a rejected plan replaced the running collection")
+	}
+
+	// ...and a committed one drops the bindings and listeners that are gone, keeping listeners it didn't rebuild
+	c.commit(map[string]bool{"api": true}, map[string]bool{"api": true, "admin": true})
+	if c.collections[resourceKey("api", "/users")] != users || c.collections[resourceKey("admin", "/teams")] != teams {
+		t.Errorf("collections were not kept: %v", c.collections)
+	}
+	if _, ok := c.collections[resourceKey("api", "/orders")]; ok || orders == nil {
+		t.Error("the removed binding's collection was kept")
+	}
+
+	c.commit(map[string]bool{}, map[string]bool{"api": true})
+	if len(c.collections) != 1 {
+		t.Errorf("the removed listener's collections were kept: %v", c.collections)
+	}
+}
@@ -0,0 +1,460 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"regexp"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+
+	co "github.com/nrexception/mockapi/pkg/common"
+	se "github.com/nrexception/mockapi/pkg/settings"
+)
+
+// The most a socket listener reads as one message, longer delimited messages end the connection.
+const socketMaxMessageBytes = 64 << 10
+
+type socketReply struct {
+	settings se.SocketReply
+	hex      []byte // settings.Match.Hex, decoded
+	regex    *regexp.Regexp
+}
+
+func (r socketReply) matches(message []byte) bool {
+	match := r.settings.Match
+
+	if match.Text != "" && string(message) != match.Text {
+		return false
+	}
+	if match.Hex != "" && !bytes.Equal(message, r.hex) {
+		return false
+	}
+	if match.Contains != "" && !bytes.Contains(message, []byte(match.Contains)) {
+		return false
+	}
+	if r.regex != nil && !r.regex.Match(message) {
+		return false
+	}
+
+	return true
+}
+
+// A running tcp or udp listener. Unlike web listeners there's nothing to swap while it runs, changed settings restart it.
+type socketListener struct {
+	settings   se.UnmarshalledRootSettingSocketListener
+	threaduuid uuid.UUID
+	replies    []socketReply
+	delimiter  []byte
+	ln         net.Listener   // tcp
+	pc         net.PacketConn // udp
+	addr       net.Addr       // Where it's actually listening, set by start
+	ctx        context.Context
+	cancel     context.CancelFunc
+	mu         sync.Mutex
+	conns      map[net.Conn]bool // Open tcp connections, closed when the listener stops
+	wg         sync.WaitGroup
+	serveErr   atomic.Pointer[error] // Set if the listener stopped for any reason other than being stopped
+}
+
+// Compiles the listener's replies, so bad settings are caught before anything is bound.
+func newSocketListener(settings se.UnmarshalledRootSettingSocketListener, threaduuid uuid.UUID) (*socketListener, error) {
+	l := &socketListener{settings: settings, threaduuid: threaduuid, delimiter: []byte(settings.Delimiter), conns: map[net.Conn]bool{}}
+
+	for _, r := range settings.Replies {
+		reply := socketReply{settings: r}
+
+		var err error
+		reply.hex, err = se.DecodeHex(r.Match.Hex)
+		if err != nil {
+			return nil, fmt.Errorf("newSocketListener: %w", err)
+		}
+
+		if r.Match.Regex != "" {
+			reply.regex, err = regexp.Compile(r.Match.Regex)
+			if err != nil {
+				return nil, fmt.Errorf("newSocketListener: %w", err)
+			}
+		}
+
+		l.replies = append(l.replies, reply)
+	}
+
+	return l, nil
+}
+
+// Binds and starts serving.
+func (l *socketListener) start() error {
+	err := l.bind()
+	if err != nil {
+		return fmt.Errorf("socketListener.start: %w", err)
+	}
+
+	l.serve()
+	return nil
+}
+
+// Binds the listener's address: where it last listened if it's been started before, as configured otherwise.
+func (l *socketListener) bind() error {
+	if l.settings.IsUDP() {
+		network := "udp"
+		if ip := net.ParseIP(l.settings.ListenAddress); ip != nil && ip.To4() != nil {
+			network = "udp4"
+		}
+		address := net.JoinHostPort(l.settings.ListenAddress, strconv.Itoa(l.settings.ListenerPort))
+		if l.addr != nil {
+			network, address = l.addr.Network(), l.addr.String()
+		}
+
+		pc, err := net.ListenPacket(network, address)
+		if err != nil {
+			return fmt.Errorf("socketListener.bind: %w", err)
+		}
+		l.pc, l.addr = pc, pc.LocalAddr()
+		return nil
+	}
+
+	var ln net.Listener
+	var err error
+	if l.addr != nil {
+		ln, err = net.Listen(l.addr.Network(), l.addr.String())
+	} else {
+		ln, err = listenTCP(l.settings.ListenAddress, l.settings.ListenerPort)
+	}
+	if err != nil {
+		return fmt.Errorf("socketListener.bind: %w", err)
+	}
+	l.ln, l.addr = ln, ln.Addr()
+	return nil
+}
+
+// Starts serving on the bound address.
+func (l *socketListener) serve() {
+	l.ctx, l.cancel = context.WithCancel(context.Background())
+
+	if l.pc != nil {
+		co.LogNonVerboseOnThread(l.threaduuid, co.MSGTYPE_INFO, "starting udp listener...", co.LOGKEY_LISTENER, l.settings.ListenerName)
+		l.wg.Add(1)
+		go l.serveUDP()
+		return
+	}
+
+	co.LogNonVerboseOnThread(l.threaduuid, co.MSGTYPE_INFO, "starting tcp listener...", co.LOGKEY_LISTENER, l.settings.ListenerName)
+	l.wg.Add(1)
+	go l.serveTCP()
+}
+
+// Closes the listener and every connection it has open, waiting (up to shutdownTimeout) for them to wind up.
+func (l *socketListener) stop() error {
+	l.cancel()
+
+	var err error
+	if l.ln != nil {
+		err = l.ln.Close()
+	} else {
+		err = l.pc.Close()
+	}
+
+	l.mu.Lock()
+	for conn := range l.conns {
+		_ = conn.Close()
+	}
+	l.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		l.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(shutdownTimeout):
+		err = errors.Join(err, errors.New("timed out waiting for connections to close"))
+	}
+
+	if err != nil {
+		return fmt.Errorf("socketListener.stop: %w", err)
+	}
+
+	return nil
+}
+
+func (l *socketListener) serveFailed(err error) {
+	l.serveErr.Store(&err)
+	co.LogNonVerboseOnThread(l.threaduuid, co.MSGTYPE_ERROR, "listener stopped", co.LOGKEY_LISTENER, l.settings.ListenerName, co.LOGKEY_ERROR, err)
+}
+
+func (l *socketListener) serveTCP() {
+	defer l.wg.Done()
+
+	for {
+		conn, err := l.ln.Accept()
+		if err != nil {
+			if l.ctx.Err() == nil {
+				l.serveFailed(err)
+			}
+			return
+		}
+
+		l.mu.Lock()
+		if l.ctx.Err() != nil {
+			l.mu.Unlock()
+			_ = conn.Close()
+			return
+		}
+		l.conns[conn] = true
+		l.wg.Add(1)
+		l.mu.Unlock()
+
+		go l.serveConn(conn)
+	}
+}
+
+// The reply for message, nil if none match.
+func (l *socketListener) reply(message []byte) *socketReply {
+	for i, r := range l.replies {
+		if r.matches(message) {
+			return &l.replies[i]
+		}
+	}
+
+	return nil
+}
+
+// Sends messages in order, false if the listener stopped or a write failed.
+func (l *socketListener) send(messages []se.SocketMessage, write func([]byte) error) bool {
+	for _, m := range messages {
+		if !sleepContext(l.ctx, m.Delay) {
+			return false
+		}
+
+		err := write(m.Bytes())
+		if err != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (l *socketListener) logMessage(message []byte, reply *socketReply, remoteAddr net.Addr) {
+	msg := "message answered"
+	if reply == nil {
+		msg = "message unmatched"
+	}
+
+	co.LogNonVerboseOnThread(l.threaduuid, co.MSGTYPE_INFO, msg,
+		co.LOGKEY_LISTENER, l.settings.ListenerName,
+		co.LOGKEY_BYTES, len(message),
+		co.LOGKEY_REMOTEADDR, remoteAddr.String(),
+	)
+}
+
+// Reads the next message: up to the delimiter (which is dropped) if there is one, otherwise whatever arrives next.
+func (l *socketListener) readMessage(r *bufio.Reader) ([]byte, error) {
+	if len(l.delimiter) == 0 {
+		buf := make([]byte, socketMaxMessageBytes)
+		n, err := r.Read(buf)
+		return buf[:n], err
+	}
+
+	var message []byte
+	for {
+		chunk, err := r.ReadSlice(l.delimiter[len(l.delimiter)-1])
+		message = append(message, chunk...)
+
+		if err == nil && bytes.HasSuffix(message, l.delimiter) {
+			return message[:len(message)-len(l.delimiter)], nil
+		}
+		if len(message) > socketMaxMessageBytes {
+			return nil, fmt.Errorf("readMessage: no delimiter in %d bytes", socketMaxMessageBytes)
+		}
+		if err != nil && !errors.Is(err, bufio.ErrBufferFull) {
+			return message, err // Whatever arrived before the connection closed
+		}
+	}
+}
+
+func (l *socketListener) serveConn(conn net.Conn) {
+	defer l.wg.Done()
+	defer func() {
+		l.mu.Lock()
+		delete(l.conns, conn)
+		l.mu.Unlock()
+		_ = conn.Close()
+	}()
+
+	co.LogVerboseOnThread(l.threaduuid, co.MSGTYPE_INFO, "connection opened", co.LOGKEY_LISTENER, l.settings.ListenerName, co.LOGKEY_REMOTEADDR, conn.RemoteAddr().String())
+
+	write := func(b []byte) error {
+		_, err := conn.Write(b)
+		return err
+	}
+
+	if !l.send(l.settings.Banner, write) {
+		return
+	}
+
+	r := bufio.NewReader(conn)
+	for {
+		if l.settings.IdleTimeout > 0 {
+			_ = conn.SetReadDeadline(time.Now().Add(l.settings.IdleTimeout))
+		}
+
+		message, err := l.readMessage(r)
+		if len(message) > 0 {
+			reply := l.reply(message)
+			l.logMessage(message, reply, conn.RemoteAddr())
+
+			if reply != nil && (!l.send(reply.settings.Messages, write) || reply.settings.Close) {
+				return
+			}
+		}
+
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				co.LogVerboseOnThread(l.threaduuid, co.MSGTYPE_INFO, "closing idle connection", co.LOGKEY_LISTENER, l.settings.ListenerName, co.LOGKEY_REMOTEADDR, conn.RemoteAddr().String())
+			}
+			return
+		}
+	}
+}
+
+func (l *socketListener) serveUDP() {
+	defer l.wg.Done()
+
+	buf := make([]byte, socketMaxMessageBytes)
+	for {
+		n, remoteAddr, err := l.pc.ReadFrom(buf)
+		if err != nil {
+			if l.ctx.Err() == nil {
+				l.serveFailed(err)
+			}
+			return
+		}
+
+		message := bytes.Clone(buf[:n])
+		reply := l.reply(message)
+		l.logMessage(message, reply, remoteAddr)
+		if reply == nil {
+			continue
+		}
+
+		// Replies are sent from their own goroutine, so one with a delay doesn't hold up everyone else's
+		l.wg.Add(1)
+		go func() {
+			defer l.wg.Done()
+			l.send(reply.settings.Messages, func(b []byte) error {
+				_, err := l.pc.WriteTo(b, remoteAddr)
+				return err
+			})
+		}()
+	}
+}
+
+// The socket listeners that need (re)starting for settings, adding their names to configured (which already holds the
+// web listeners'). Nothing running is touched, so bad settings leave everything as it was. Expects m.mu to be held.
+func (m *ListenerManager) planSocketListeners(settings []se.UnmarshalledRootSettingSocketListener, configured map[string]bool) ([]*socketListener, error) {
+	plan := []*socketListener{}
+
+	for _, ls := range settings {
+		if configured[ls.ListenerName] {
+			return nil, fmt.Errorf("planSocketListeners: listener name \"%s\" is used more than once", ls.ListenerName)
+		}
+		configured[ls.ListenerName] = true
+
+		running := m.socketListeners[ls.ListenerName]
+		if running != nil && reflect.DeepEqual(running.settings, ls) {
+			continue // Untouched, leave it be...
+		}
+
+		threaduuid := uuid.New()
+		if running != nil {
+			threaduuid = running.threaduuid
+		}
+
+		l, err := newSocketListener(ls, threaduuid)
+		if err != nil {
+			return nil, fmt.Errorf("planSocketListeners: listener \"%s\": %w", ls.ListenerName, err)
+		}
+		plan = append(plan, l)
+	}
+
+	return plan, nil
+}
+
+// Stops socket listeners that are no longer configured. Expects m.mu to be held.
+func (m *ListenerManager) stopRemovedSocketListeners(configured map[string]bool) []error {
+	errs := []error{}
+
+	for name, l := range m.socketListeners {
+		if configured[name] {
+			continue
+		}
+
+		co.LogVerboseOnThread(l.threaduuid, co.MSGTYPE_WARN, "listener removed from settings, closing...", co.LOGKEY_LISTENER, name)
+		err := l.stop()
+		if err != nil {
+			errs = append(errs, err)
+		}
+		delete(m.socketListeners, name)
+		m.respond(fmt.Sprintf("listener \"%s\" closed", name))
+	}
+
+	return errs
+}
+
+// Starts the planned socket listeners in place of the ones they replace. As with web listeners, a replacement is bound
+// before what it replaces stops unless that holds the address, and if it can't bind the running one is started again.
+// Expects m.mu to be held.
+func (m *ListenerManager) startSocketListeners(plan []*socketListener, startErrs map[string]error) []error {
+	errs := []error{}
+
+	for _, l := range plan {
+		name := l.settings.ListenerName
+		running := m.socketListeners[name]
+
+		err := l.bind()
+		if running != nil {
+			co.LogVerboseOnThread(running.threaduuid, co.MSGTYPE_WARN, "listener settings changed, restarting...", co.LOGKEY_LISTENER, name)
+			stopErr := running.stop()
+			if stopErr != nil {
+				errs = append(errs, stopErr)
+			}
+
+			if err != nil {
+				err = l.bind()
+			}
+			if err != nil {
+				restartErr := running.start()
+				if restartErr != nil {
+					delete(m.socketListeners, name)
+					err = errors.Join(err, restartErr)
+				} else {
+					co.LogNonVerboseOnThread(running.threaduuid, co.MSGTYPE_WARN, "listener could not be restarted, the previous settings are still serving", co.LOGKEY_LISTENER, name, co.LOGKEY_ERROR, err)
+				}
+			}
+		}
+		if err != nil {
+			startErrs[name] = err
+			errs = append(errs, fmt.Errorf("listener \"%s\": %w", name, err))
+			continue
+		}
+
+		l.serve()
+		m.socketListeners[name] = l
+		m.respond(fmt.Sprintf("listener \"%s\" listening on %s/%s", name, l.addr.Network(), l.addr))
+	}
+
+	return errs
+}
@@ -0,0 +1,193 @@
+package server
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	se "github.com/nrexception/mockapi/pkg/settings"
+)
+
+func TestListenerManager_SocketListeners(t *testing.T) {
+	t.Parallel()
+
+	m := NewListenerManager(nil, nil)
+	t.Cleanup(func() { _ = m.Close() })
+
+	err := m.Apply(&se.UnmarshalledRootSettings{SocketListeners: []se.UnmarshalledRootSettingSocketListener{
+		{
+			ListenerName:  "smtp-ish",
+			ListenAddress: "127.0.0.1",
+			Delimiter:     "\r\n",
+			Banner:        []se.SocketMessage{{Text: "220 mock ready\r\n"}},
+			Replies: []se.SocketReply{
+				{Match: se.SocketMatch{Text: "QUIT"}, Messages: []se.SocketMessage{{Text: "221 bye\r\n"}}, Close: true},
+				{Match: se.SocketMatch{Regex: `^HELO \w+$`}, Messages: []se.SocketMessage{{Text: "250 hello\r\n", Delay: 20 * time.Millisecond}}},
+				{Match: se.SocketMatch{Hex: "00 ff"}, Messages: []se.SocketMessage{{Hex: "de ad be ef"}}},
+				{Messages: []se.SocketMessage{{Text: "500 what?\r\n"}}},
+			},
+		},
+		{
+			ListenerName:  "dns-ish",
+			Protocol:      se.UDP,
+			ListenAddress: "127.0.0.1",
+			Replies:       []se.SocketReply{{Match: se.SocketMatch{Contains: "ping"}, Messages: []se.SocketMessage{{Text: "pong"}}}},
+		},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tcpPort, udpPort := listenerPort(t, m, "smtp-ish"), listenerPort(t, m, "dns-ish")
+
+	t.Run("tcp", func(t *testing.T) {
+		conn, err := net.Dial("tcp", "127.0.0.1:"+strconv.Itoa(tcpPort))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+		r := bufio.NewReader(conn)
+
+		expectLine := func(want string) {
+			t.Helper()
+			got, err := r.ReadString('\n')
+			if err != nil || got != want {
+				t.Fatalf("expected %q, got %q (%v)", want, got, err)
+			}
+		}
+
+		expectLine("220 mock ready\r\n")
+
+		start := time.Now()
+		_, _ = io.WriteString(conn, "HELO test\r\n")
+		expectLine("250 hello\r\n")
+		if time.Since(start) < 20*time.Millisecond {
+			t.Error("reply was not delayed")
+		}
+
+		_, _ = io.WriteString(conn, "NOOP\r\n")
+		expectLine("500 what?\r\n")
+
+		_, _ = conn.Write([]byte{0x00, 0xff, '\r', '\n'})
+		b := make([]byte, 4)
+		_, err = io.ReadFull(r, b)
+		if err != nil || string(b) != "\xde\xad\xbe\xef" {
+			t.Fatalf("unexpected hex reply: %x (%v)", b, err)
+		}
+
+		_, _ = io.WriteString(conn, "QUIT\r\n")
+		expectLine("221 bye\r\n")
+		if _, err = r.ReadByte(); err != io.EOF {
+			t.Errorf("expected the connection to be closed, got %v", err)
+		}
+	})
+
+	t.Run("udp", func(t *testing.T) {
+		conn, err := net.Dial("udp", "127.0.0.1:"+strconv.Itoa(udpPort))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+		_, _ = io.WriteString(conn, "ignored")
+		_, _ = io.WriteString(conn, "a ping")
+
+		b := make([]byte, 64)
+		n, err := conn.Read(b)
+		if err != nil || string(b[:n]) != "pong" {
+			t.Fatalf("unexpected reply: %q (%v)", b[:n], err)
+		}
+	})
+
+	t.Run("status", func(t *testing.T) {
+		status := m.Status()
+		if !status.Ready || len(status.Listeners) != 2 {
+			t.Fatalf("unexpected status: %+v", status)
+		}
+		if status.Listeners[1].Port != udpPort || status.Listeners[1].State != LISTENERSTATE_BOUND {
+			t.Errorf("unexpected udp listener status: %+v", status.Listeners[1])
+		}
+	})
+}
+
+func TestListenerManager_SocketListenerReload(t *testing.T) {
+	t.Parallel()
+
+	m := NewListenerManager(nil, nil)
+	t.Cleanup(func() { _ = m.Close() })
+
+	settings := func(banner string) *se.UnmarshalledRootSettings {
+		return &se.UnmarshalledRootSettings{SocketListeners: []se.UnmarshalledRootSettingSocketListener{
+			{ListenerName: "health", ListenAddress: "127.0.0.1", Banner: []se.SocketMessage{{Text: banner}}},
+		}}
+	}
+
+	// Restarts bind a new port, so it's looked up again after each one that succeeds
+	port := 0
+	banner := func() string {
+		t.Helper()
+		conn, err := net.Dial("tcp", "127.0.0.1:"+strconv.Itoa(port))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+		b := make([]byte, 16)
+		n, _ := conn.Read(b)
+		return string(b[:n])
+	}
+
+	if err := m.Apply(settings("one")); err != nil {
+		t.Fatal(err)
+	}
+	port = listenerPort(t, m, "health")
+	if got := banner(); got != "one" {
+		t.Errorf("expected the first banner, got %q", got)
+	}
+
+	if err := m.Apply(settings("two")); err != nil {
+		t.Fatal(err)
+	}
+	port = listenerPort(t, m, "health")
+	if got := banner(); got != "two" {
+		t.Errorf("expected the restarted listener's banner, got %q", got)
+	}
+
+	// A web listener can't take a socket listener's name
+	err := m.Apply(&se.UnmarshalledRootSettings{
+		WebListeners:    []se.UnmarshalledRootSettingWebListener{{ListenerName: "health"}},
+		SocketListeners: settings("three").SocketListeners,
+	})
+	if err == nil {
+		t.Error("expected a duplicate name to be rejected")
+	}
+	if got := banner(); got != "two" {
+		t.Errorf("a rejected config changed the running listener: %q", got)
+	}
+
+	// Moving onto a port that's taken leaves the running listener where it was
+	occupied, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer occupied.Close()
+	moved := settings("three")
+	moved.SocketListeners[0].ListenerPort = occupied.Addr().(*net.TCPAddr).Port
+	if err := m.Apply(moved); err == nil {
+		t.Error("expected the occupied port to fail")
+	}
+	if got := banner(); got != "two" {
+		t.Errorf("expected the previous listener to keep serving, got %q", got)
+	}
+
+	if err := m.Apply(&se.UnmarshalledRootSettings{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := net.Dial("tcp", "127.0.0.1:"+strconv.Itoa(port)); err == nil {
+		t.Error("expected the removed listener to be closed")
+	}
+}
@@ -0,0 +1,203 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	se "github.com/nrexception/mockapi/pkg/settings"
+)
+
+type ListenerState string
+
+const (
+	LISTENERSTATE_BOUND  ListenerState = "bound"
+	LISTENERSTATE_FAILED ListenerState = "failed"
+	LISTENERSTATE_CLOSED ListenerState = "closed"
+)
+
+var errListenerClosed = errors.New("closed by command")
+
+type ListenerStatus struct {
+	Name    string        `json:"name"`
+	Address string        `json:"address"` // Where it's bound, eg. [::]:8080 or a unix socket path
+	Port    int           `json:"port"`    // 0 for unix sockets
+	State   ListenerState `json:"state"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// Point in time view of the applied config and whether everything in it is serving.
+type ManagerStatus struct {
+	Ready      bool             `json:"ready"` // A config is applied and every listener in it (admin included) is bound
+	ConfigId   string           `json:"configid"`
+	ConfigHash string           `json:"confighash"` // sha256 of the applied settings
+	AppliedAt  time.Time        `json:"appliedat"`
+	Listeners  []ListenerStatus `json:"listeners"`
+	Admin      *ListenerStatus  `json:"admin,omitempty"`
+}
+
+type listenerSnapshot struct {
+	name     string
+	address  string
+	port     int
+	serveErr *atomic.Pointer[error] // nil if the listener never started
+	startErr error
+}
+
+func (s listenerSnapshot) status() ListenerStatus {
+	st := ListenerStatus{Name: s.name, Address: s.address, Port: s.port, State: LISTENERSTATE_BOUND}
+
+	err := s.startErr
+	if err == nil && s.serveErr != nil {
+		if p := s.serveErr.Load(); p != nil {
+			err = *p
+		}
+	}
+
+	switch {
+	case errors.Is(err, errListenerClosed):
+		st.State, st.Error = LISTENERSTATE_CLOSED, err.Error()
+	case err != nil:
+		st.State, st.Error = LISTENERSTATE_FAILED, err.Error()
+	}
+
+	return st
+}
+
+// Published by the manager whenever its listeners change, read without taking the manager's lock so health checks
+// never wait on an Apply (which may itself be waiting on the admin listener to drain).
+type statusSnapshot struct {
+	configId   string
+	configHash string
+	appliedAt  time.Time
+	listeners  []listenerSnapshot
+	admin      *listenerSnapshot
+}
+
+// Port a listener actually bound, which differs from its settings when they asked for port 0.
+func addrPort(addr net.Addr, fallback int) int {
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		return a.Port
+	case *net.UDPAddr:
+		return a.Port
+	}
+
+	return fallback
+}
+
+func hashSettings(rootSettings *se.UnmarshalledRootSettings) string {
+	b, _ := json.Marshal(rootSettings)
+	sum := sha256.Sum256(b)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// Publishes the state of rootSettings' listeners, startErrs holding why any of them couldn't be started.
+// Expects m.mu to be held.
+func (m *ListenerManager) publishStatus(rootSettings *se.UnmarshalledRootSettings, startErrs map[string]error, adminErr error) {
+	s := &statusSnapshot{configId: rootSettings.Id, configHash: hashSettings(rootSettings), appliedAt: time.Now()}
+
+	for _, ls := range rootSettings.WebListeners {
+		ss := listenerSnapshot{name: ls.ListenerName, port: ls.ListenerPort, startErr: startErrs[ls.ListenerName]}
+		if l := m.listeners[ls.ListenerName]; l != nil {
+			ss.address, ss.port = l.addr.String(), addrPort(l.addr, ss.port)
+			ss.serveErr = &l.serveErr
+		} else if ss.startErr == nil {
+			ss.startErr = errors.New("not started")
+		}
+		s.listeners = append(s.listeners, ss)
+	}
+
+	for _, ls := range rootSettings.SocketListeners {
+		ss := listenerSnapshot{name: ls.ListenerName, port: ls.ListenerPort, startErr: startErrs[ls.ListenerName]}
+		if l := m.socketListeners[ls.ListenerName]; l != nil {
+			ss.address, ss.port = l.addr.String(), addrPort(l.addr, ss.port)
+			ss.serveErr = &l.serveErr
+		} else if ss.startErr == nil {
+			ss.startErr = errors.New("not started")
+		}
+		s.listeners = append(s.listeners, ss)
+	}
+
+	if rootSettings.Admin != nil {
+		ss := listenerSnapshot{name: "admin", port: rootSettings.Admin.ListenerPort, startErr: adminErr}
+		if m.admin != nil {
+			ss.address, ss.port = m.admin.addr.String(), addrPort(m.admin.addr, ss.port)
+			ss.serveErr = &m.admin.serveErr
+		} else if ss.startErr == nil {
+			ss.startErr = errors.New("not started")
+		}
+		s.admin = &ss
+	}
+
+	m.status.Store(s)
+}
+
+// Marks the named listener as closed in the published status.
+func (m *ListenerManager) publishListenerClosed(name string) {
+	previous := m.status.Load()
+	if previous == nil {
+		return
+	}
+
+	s := *previous
+	s.listeners = append([]listenerSnapshot{}, previous.listeners...)
+	for i := range s.listeners {
+		if s.listeners[i].name == name {
+			s.listeners[i].startErr = errListenerClosed
+		}
+	}
+
+	m.status.Store(&s)
+}
+
+// Reports the applied config and the bind status of each of its listeners.
+func (m *ListenerManager) Status() ManagerStatus {
+	s := m.status.Load()
+	if s == nil {
+		return ManagerStatus{Listeners: []ListenerStatus{}}
+	}
+
+	st := ManagerStatus{Ready: true, ConfigId: s.configId, ConfigHash: s.configHash, AppliedAt: s.appliedAt, Listeners: []ListenerStatus{}}
+	for _, ls := range s.listeners {
+		ls := ls.status()
+		st.Ready = st.Ready && ls.State == LISTENERSTATE_BOUND
+		st.Listeners = append(st.Listeners, ls)
+	}
+
+	if s.admin != nil {
+		admin := s.admin.status()
+		st.Ready = st.Ready && admin.State == LISTENERSTATE_BOUND
+		st.Admin = &admin
+	}
+
+	return st
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// Answers as long as the process is up and serving the admin listener.
+func (m *ListenerManager) serveLiveness(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// 200 once every configured listener is bound, 503 until then, with the status either way.
+func (m *ListenerManager) serveReadiness(w http.ResponseWriter, r *http.Request) {
+	st := m.Status()
+
+	code := http.StatusOK
+	if !st.Ready {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, st)
+}
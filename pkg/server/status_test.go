@@ -0,0 +1,98 @@
+package server
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+
+	se "github.com/nrexception/mockapi/pkg/settings"
+)
+
+func TestListenerManager_Status(t *testing.T) {
+	t.Parallel()
+
+	m := NewListenerManager(nil, nil)
+	defer m.Close()
+
+	if m.Status().Ready {
+		t.Fatal("expected not ready before any config is applied")
+	}
+
+	// Something else is holding b's port...
+	occupied, err := net.Listen("tcp", "0.0.0.0:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	portB := occupied.Addr().(*net.TCPAddr).Port
+
+	settings := &se.UnmarshalledRootSettings{
+		Id:    "status-test",
+		Admin: &se.UnmarshalledRootSettingAdminListener{},
+		WebListeners: []se.UnmarshalledRootSettingWebListener{
+			inlineListener("a", 0, map[string]string{"/": "a"}),
+			inlineListener("b", portB, map[string]string{"/": "b"}),
+		},
+	}
+
+	err = m.Apply(settings)
+	if err == nil {
+		t.Fatal("expected a bind error")
+	}
+	adminPort := listenerPort(t, m, "admin")
+
+	expectReadiness(t, adminPort, http.StatusServiceUnavailable, map[string]ListenerState{"a": LISTENERSTATE_BOUND, "b": LISTENERSTATE_FAILED})
+
+	// ...until it lets go, and the same config is applied again
+	_ = occupied.Close()
+	err = m.Apply(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	st := expectReadiness(t, adminPort, http.StatusOK, map[string]ListenerState{"a": LISTENERSTATE_BOUND, "b": LISTENERSTATE_BOUND})
+	if st.ConfigId != "status-test" || st.ConfigHash != hashSettings(settings) {
+		t.Errorf("unexpected config: %s %s", st.ConfigId, st.ConfigHash)
+	}
+	if st.Admin == nil || st.Admin.State != LISTENERSTATE_BOUND {
+		t.Errorf("unexpected admin status: %+v", st.Admin)
+	}
+
+	res, err := http.Get("http://127.0.0.1:" + strconv.Itoa(adminPort) + defaultLivenessPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("unexpected liveness status: %d", res.StatusCode)
+	}
+}
+
+func expectReadiness(t *testing.T, adminPort int, code int, states map[string]ListenerState) ManagerStatus {
+	t.Helper()
+
+	res, err := http.Get("http://127.0.0.1:" + strconv.Itoa(adminPort) + defaultReadinessPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != code {
+		t.Errorf("unexpected readiness status:\ngot: %d\nwant:%d", res.StatusCode, code)
+	}
+
+	st := ManagerStatus{}
+	err = json.NewDecoder(res.Body).Decode(&st)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ls := range st.Listeners {
+		if ls.State != states[ls.Name] {
+			t.Errorf("unexpected state for %s:\ngot: %s\nwant:%s", ls.Name, ls.State, states[ls.Name])
+		}
+	}
+
+	return st
+}
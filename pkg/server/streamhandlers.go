@@ -0,0 +1,100 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+
+	co "github.com/nrexception/mockapi/pkg/common"
+	se "github.com/nrexception/mockapi/pkg/settings"
+)
+
+// A chunk as an SSE event: its fields, a data line per line of data, then the blank line ending the event.
+func formatSSEChunk(c se.StreamChunk) []byte {
+	var b bytes.Buffer
+
+	if c.Id != "" {
+		b.WriteString("id: " + c.Id + "\n")
+	}
+	if c.Event != "" {
+		b.WriteString("event: " + c.Event + "\n")
+	}
+	if c.Retry > 0 {
+		b.WriteString("retry: " + strconv.FormatInt(c.Retry.Milliseconds(), 10) + "\n")
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(c.Data, "\r\n", "\n"), "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+	b.WriteString("\n")
+
+	return b.Bytes()
+}
+
+// Index of the chunk to start from, the one after lastEventId if a client is resuming an sse stream.
+func streamResumeIndex(stream se.StreamSettings, lastEventId string) int {
+	if stream.Format != se.StreamSSE || lastEventId == "" {
+		return 0
+	}
+
+	for i, c := range stream.Chunks {
+		if c.Id == lastEventId {
+			return i + 1
+		}
+	}
+
+	return 0 // An id we never sent, start over
+}
+
+// Sends the binding's chunks one at a time, flushing each so the client sees it straight away, until they run out (or,
+// looping, the client goes away).
+func serveStream(w http.ResponseWriter, r *http.Request, binding se.ResponseBinding, threaduuid uuid.UUID) {
+	stream := *binding.Stream
+
+	if stream.Format == se.StreamSSE {
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", "text/event-stream")
+		}
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+	w.Header().Set("X-Content-Type-Options", "nosniff") // Or the first chunk is held back to guess the type
+
+	rc := http.NewResponseController(w)
+	w.WriteHeader(binding.ResponseCode)
+	err := rc.Flush()
+	if err != nil {
+		co.LogNonVerboseOnThread(threaduuid, co.MSGTYPE_WARN, "stream can't be flushed, chunks may arrive together", co.LOGKEY_BINDING, binding.Path, co.LOGKEY_ERROR, err)
+	}
+
+	i := streamResumeIndex(stream, r.Header.Get("Last-Event-ID"))
+	for {
+		if i == len(stream.Chunks) {
+			if !stream.Loop {
+				return
+			}
+			i = 0
+		}
+		c := stream.Chunks[i]
+		i++
+
+		if !sleepContext(r.Context(), c.Delay) {
+			return
+		}
+
+		chunk := []byte(c.Data)
+		if stream.Format == se.StreamSSE {
+			chunk = formatSSEChunk(c)
+		}
+
+		_, err = w.Write(chunk)
+		if err == nil {
+			err = rc.Flush()
+		}
+		if err != nil {
+			co.LogVerboseOnThread(threaduuid, co.MSGTYPE_INFO, "stream ended by client", co.LOGKEY_BINDING, binding.Path, co.LOGKEY_ERROR, err)
+			return
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	se "github.com/nrexception/mockapi/pkg/settings"
+)
+
+func TestListenerManager_Stream(t *testing.T) {
+	t.Parallel()
+
+	sse := se.StreamSettings{Format: se.StreamSSE, Chunks: []se.StreamChunk{
+		{Id: "1", Event: "token", Data: "Hel", Retry: 2 * time.Second},
+		{Id: "2", Event: "token", Data: "lo"},
+		{Id: "3", Data: "line one\nline two"},
+	}}
+	raw := se.StreamSettings{Format: se.StreamRaw, Loop: true, Chunks: []se.StreamChunk{{Data: "{\"n\":1}\n", Delay: time.Millisecond}}}
+
+	m := NewListenerManager(nil, nil)
+	t.Cleanup(func() { _ = m.Close() })
+
+	err := m.Apply(&se.UnmarshalledRootSettings{WebListeners: []se.UnmarshalledRootSettingWebListener{{
+		ListenerName: "stream",
+		ContentBindings: []se.ResponseBinding{
+			{Path: "/events", ResponseCode: http.StatusOK, ResponseBodyType: se.Stream, Stream: &sse},
+			{Path: "/lines", ResponseCode: http.StatusOK, ResponseBodyType: se.Stream, Stream: &raw, ResponseHeaders: []se.ResponseHeader{{Key: "Content-Type", Value: "application/x-ndjson"}}},
+		},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listenerPort(t, m, "stream")
+
+	testCases := []struct {
+		name        string
+		lastEventId string
+		expected    string
+	}{
+		{name: "whole stream", expected: "id: 1\nevent: token\nretry: 2000\ndata: Hel\n\nid: 2\nevent: token\ndata: lo\n\nid: 3\ndata: line one\ndata: line two\n\n"},
+		{name: "resume", lastEventId: "2", expected: "id: 3\ndata: line one\ndata: line two\n\n"},
+		{name: "unknown id starts over", lastEventId: "9", expected: "id: 1\nevent: token\nretry: 2000\ndata: Hel\n\nid: 2\nevent: token\ndata: lo\n\nid: 3\ndata: line one\ndata: line two\n\n"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(http.MethodGet, "http://127.0.0.1:"+strconv.Itoa(port)+"/events", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.lastEventId != "" {
+				req.Header.Set("Last-Event-ID", tc.lastEventId)
+			}
+
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+
+			body, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Header.Get("Content-Type") != "text/event-stream" || string(body) != tc.expected {
+				t.Errorf("unexpected response: %s\n%q", res.Header.Get("Content-Type"), body)
+			}
+		})
+	}
+
+	t.Run("loop", func(t *testing.T) {
+		t.Parallel()
+
+		res, err := http.Get("http://127.0.0.1:" + strconv.Itoa(port) + "/lines")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+
+		if res.Header.Get("Content-Type") != "application/x-ndjson" || len(res.TransferEncoding) == 0 || res.TransferEncoding[0] != "chunked" {
+			t.Errorf("unexpected response headers: %v %v", res.Header, res.TransferEncoding)
+		}
+
+		// Keeps going well past the single configured chunk
+		lines := bufio.NewScanner(res.Body)
+		for i := 0; i < 5; i++ {
+			if !lines.Scan() || lines.Text() != `{"n":1}` {
+				t.Fatalf("unexpected line %d: %q (%v)", i, lines.Text(), lines.Err())
+			}
+		}
+	})
+}
@@ -0,0 +1,206 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/nrexception/mockapi/pkg/certs"
+	se "github.com/nrexception/mockapi/pkg/settings"
+)
+
+// Whether a listener's certificate is issued by the local CA, and so has to be reissued if the CA changes.
+func usesLocalCA(ls se.UnmarshalledRootSettingWebListener) bool {
+	return ls.EnableTLS && ls.AutoTLS != nil && !ls.AutoTLS.SelfSigned
+}
+
+// Works out the CA rootSettings call for, returning the running one if that hasn't changed. nil if nothing needs one.
+func (m *ListenerManager) planLocalCA(rootSettings *se.UnmarshalledRootSettings) (ca *certs.CA, dir string, err error) {
+	needed := rootSettings.LocalCA != nil
+	for _, ls := range rootSettings.WebListeners {
+		needed = needed || usesLocalCA(ls)
+	}
+	if !needed {
+		return nil, "", nil
+	}
+
+	if rootSettings.LocalCA != nil {
+		dir = rootSettings.LocalCA.Dir
+	}
+
+	if running := m.ca.Load(); running != nil && dir == m.caDir {
+		return running, dir, nil
+	}
+
+	if dir == "" {
+		ca, err = certs.NewCA()
+	} else {
+		ca, err = certs.LoadOrCreateCA(dir)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("planLocalCA: %w", err)
+	}
+
+	return ca, dir, nil
+}
+
+// Builds the tls config a listener serves with, nil if it doesn't use tls. Certificates from files come with the store
+// serving them, which the listener has to close once it stops.
+func listenerTLSConfig(ls se.UnmarshalledRootSettingWebListener, ca *certs.CA) (*tls.Config, *certificateStore, error) {
+	if !ls.EnableTLS {
+		return nil, nil, nil
+	}
+
+	config := &tls.Config{}
+	err := applyTLSOptions(config, ls)
+	if err != nil {
+		return nil, nil, fmt.Errorf("listenerTLSConfig: %w", err)
+	}
+
+	err = configureClientAuth(config, ls.ClientAuth)
+	if err != nil {
+		return nil, nil, fmt.Errorf("listenerTLSConfig: %w", err)
+	}
+
+	if ls.AutoTLS == nil {
+		store, err := newCertificateStore(ls.ListenerName, *ls.CertDetails)
+		if err != nil {
+			return nil, nil, fmt.Errorf("listenerTLSConfig: %w", err)
+		}
+		config.GetCertificate = store.getCertificate
+
+		return config, store, nil
+	}
+
+	hostnames := ls.AutoTLS.Hostnames
+	if len(hostnames) == 0 {
+		hostnames = se.DefaultAutoTLSHostnames
+	}
+
+	ipStrings := ls.AutoTLS.IPs
+	if len(ipStrings) == 0 {
+		ipStrings = se.DefaultAutoTLSIPs
+	}
+	ips := []net.IP{}
+	for _, ip := range ipStrings {
+		ips = append(ips, net.ParseIP(ip))
+	}
+
+	var cert tls.Certificate
+	if ls.AutoTLS.SelfSigned {
+		cert, err = certs.SelfSigned(hostnames, ips)
+	} else {
+		cert, err = ca.Issue(hostnames, ips)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("listenerTLSConfig: %w", err)
+	}
+	config.Certificates = []tls.Certificate{cert}
+
+	return config, nil, nil
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// Defaults to what http.Server offers when left to itself, minus h2 for listeners with it disabled.
+var (
+	defaultALPN      = []string{"h2", "http/1.1"}
+	defaultHTTP1ALPN = []string{"http/1.1"}
+)
+
+// Ids of the cipher suites tlsoptions can choose.
+func cipherSuiteIds() map[string]uint16 {
+	ids := map[string]uint16{}
+	for _, suite := range se.ConfigurableCipherSuites() {
+		ids[suite.Name] = suite.ID
+	}
+
+	return ids
+}
+
+func applyTLSOptions(config *tls.Config, ls se.UnmarshalledRootSettingWebListener) error {
+	config.NextProtos = defaultALPN
+	if ls.HTTPOptions != nil && ls.HTTPOptions.DisableHTTP2 {
+		config.NextProtos = defaultHTTP1ALPN
+	}
+
+	options := ls.TLSOptions
+	if options == nil {
+		return nil
+	}
+
+	config.MinVersion = tlsVersions[options.MinVersion]
+	config.MaxVersion = tlsVersions[options.MaxVersion]
+
+	if len(options.ALPN) > 0 {
+		config.NextProtos = options.ALPN
+	}
+
+	ids := cipherSuiteIds()
+	for _, name := range options.CipherSuites {
+		id, ok := ids[name]
+		if !ok {
+			return fmt.Errorf("applyTLSOptions: unknown cipher suite \"%s\"", name)
+		}
+		config.CipherSuites = append(config.CipherSuites, id)
+	}
+
+	return nil
+}
+
+var clientAuthTypes = map[se.ClientAuthMode]tls.ClientAuthType{
+	"":                   tls.NoClientCert,
+	se.ClientAuthNone:    tls.NoClientCert,
+	se.ClientAuthRequest: tls.RequestClientCert,
+	se.ClientAuthRequire: tls.RequireAnyClientCert,
+	se.ClientAuthVerify:  tls.RequireAndVerifyClientCert,
+}
+
+func configureClientAuth(config *tls.Config, settings *se.ClientAuthSettings) error {
+	if settings == nil {
+		return nil
+	}
+
+	config.ClientAuth = clientAuthTypes[settings.Mode]
+	if settings.CAFile == "" {
+		return nil
+	}
+
+	b, err := os.ReadFile(settings.CAFile)
+	if err != nil {
+		return fmt.Errorf("configureClientAuth: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return fmt.Errorf("configureClientAuth: no certificates found in %s", settings.CAFile)
+	}
+	config.ClientCAs = pool
+
+	return nil
+}
+
+// The CA signing auto tls certificates, nil if no listener uses one.
+func (m *ListenerManager) LocalCA() *certs.CA {
+	return m.ca.Load()
+}
+
+// Hands out the local CA certificate, so clients can fetch what to trust.
+func (m *ListenerManager) serveLocalCA(w http.ResponseWriter, r *http.Request) {
+	ca := m.ca.Load()
+	if ca == nil {
+		http.Error(w, "no local CA in use", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-pem-file")
+	_, _ = w.Write(ca.CertPEM())
+}
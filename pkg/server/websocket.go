@@ -0,0 +1,275 @@
+package server
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Just enough of RFC 6455 to hold a scripted conversation: no extensions, text and binary messages, pings answered.
+
+const (
+	wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+	wsOpContinuation = 0x0
+	wsOpText         = 0x1
+	wsOpBinary       = 0x2
+	wsOpClose        = 0x8
+	wsOpPing         = 0x9
+	wsOpPong         = 0xA
+
+	wsCloseNormal        = 1000
+	wsCloseGoingAway     = 1001
+	wsCloseProtocolError = 1002
+	wsCloseTooBig        = 1009
+
+	wsMaxMessageBytes = 1 << 20
+	wsCloseWait       = time.Second // How long to wait for the client to answer our close before hanging up
+)
+
+// A closed conversation, code being what the client sent (or 1005 if it didn't say).
+type wsCloseError struct {
+	code   int
+	reason string
+}
+
+func (e *wsCloseError) Error() string {
+	return fmt.Sprintf("websocket closed by client: %d %s", e.code, e.reason)
+}
+
+type wsConn struct {
+	conn      net.Conn
+	reader    *bufio.Reader
+	writeMu   sync.Mutex
+	closeSent atomic.Bool
+}
+
+func headerContainsToken(h http.Header, key string, token string) bool {
+	for _, v := range h.Values(key) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func wsAccept(key string) string {
+	sum := sha1.Sum([]byte(key + wsGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// Takes over the connection behind w, answering the handshake with any headers already set on w and the first of the
+// client's subprotocols we accept. Requests that aren't a websocket handshake get an error response.
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request, subprotocols []string) (*wsConn, error) {
+	if r.Method != http.MethodGet || !headerContainsToken(r.Header, "Connection", "upgrade") || !headerContainsToken(r.Header, "Upgrade", "websocket") {
+		w.Header().Set("Upgrade", "websocket")
+		http.Error(w, "websocket upgrade required", http.StatusUpgradeRequired)
+		return nil, errors.New("upgradeWebSocket: not a websocket handshake")
+	}
+
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported websocket version", http.StatusUpgradeRequired)
+		return nil, fmt.Errorf("upgradeWebSocket: unsupported version \"%s\"", r.Header.Get("Sec-WebSocket-Version"))
+	}
+
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		http.Error(w, "missing Sec-WebSocket-Key", http.StatusBadRequest)
+		return nil, errors.New("upgradeWebSocket: missing Sec-WebSocket-Key")
+	}
+
+	protocol := ""
+	for _, offered := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, p := range strings.Split(offered, ",") {
+			p = strings.TrimSpace(p)
+			if protocol == "" && p != "" && slices.Contains(subprotocols, p) {
+				protocol = p
+			}
+		}
+	}
+
+	conn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		http.Error(w, "websockets need HTTP/1.1", http.StatusHTTPVersionNotSupported)
+		return nil, fmt.Errorf("upgradeWebSocket: %w", err)
+	}
+	_ = conn.SetDeadline(time.Time{}) // Drop any read and write timeouts, conversations last as long as they last
+
+	header := w.Header().Clone()
+	header.Set("Upgrade", "websocket")
+	header.Set("Connection", "Upgrade")
+	header.Set("Sec-WebSocket-Accept", wsAccept(key))
+	if protocol != "" {
+		header.Set("Sec-WebSocket-Protocol", protocol)
+	}
+
+	_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+	_ = header.Write(rw)
+	_, _ = rw.WriteString("\r\n")
+	err = rw.Flush()
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("upgradeWebSocket: %w", err)
+	}
+
+	return &wsConn{conn: conn, reader: rw.Reader}, nil
+}
+
+func (c *wsConn) writeFrame(opcode byte, payload []byte) error {
+	header := []byte{0x80 | opcode} // Always a final frame, we never fragment
+	switch {
+	case len(payload) < 126:
+		header = append(header, byte(len(payload)))
+	case len(payload) <= 0xFFFF:
+		header = append(header, 126)
+		header = binary.BigEndian.AppendUint16(header, uint16(len(payload)))
+	default:
+		header = append(header, 127)
+		header = binary.BigEndian.AppendUint64(header, uint64(len(payload)))
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	_, err := c.conn.Write(append(header, payload...))
+	if err != nil {
+		return fmt.Errorf("wsConn.writeFrame: %w", err)
+	}
+
+	return nil
+}
+
+func (c *wsConn) writeText(text string) error {
+	return c.writeFrame(wsOpText, []byte(text))
+}
+
+// Sends a close frame, only the first one goes out.
+func (c *wsConn) writeClose(code int, reason string) error {
+	if c.closeSent.Swap(true) {
+		return nil
+	}
+
+	payload := binary.BigEndian.AppendUint16(nil, uint16(code))
+	return c.writeFrame(wsOpClose, append(payload, reason...))
+}
+
+func (c *wsConn) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	var head [2]byte
+	_, err = io.ReadFull(c.reader, head[:])
+	if err != nil {
+		return false, 0, nil, err
+	}
+
+	fin, opcode = head[0]&0x80 != 0, head[0]&0x0F
+	masked := head[1]&0x80 != 0
+	length := uint64(head[1] & 0x7F)
+
+	switch length {
+	case 126:
+		var ext [2]byte
+		_, err = io.ReadFull(c.reader, ext[:])
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		_, err = io.ReadFull(c.reader, ext[:])
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if err != nil {
+		return false, 0, nil, err
+	}
+
+	if !masked {
+		_ = c.writeClose(wsCloseProtocolError, "client frames must be masked")
+		return false, 0, nil, errors.New("wsConn.readFrame: unmasked client frame")
+	}
+	if length > wsMaxMessageBytes {
+		_ = c.writeClose(wsCloseTooBig, "message too big")
+		return false, 0, nil, fmt.Errorf("wsConn.readFrame: %d byte frame is too big", length)
+	}
+
+	var mask [4]byte
+	_, err = io.ReadFull(c.reader, mask[:])
+	if err != nil {
+		return false, 0, nil, err
+	}
+
+	payload = make([]byte, length)
+	_, err = io.ReadFull(c.reader, payload)
+	if err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+
+	return fin, opcode, payload, nil
+}
+
+// Reads the next text or binary message, reassembling fragments and answering pings along the way. A close from the
+// client is answered and returned as a *wsCloseError.
+func (c *wsConn) readMessage() (opcode byte, message []byte, err error) {
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch op {
+		case wsOpPing:
+			_ = c.writeFrame(wsOpPong, payload)
+			continue
+		case wsOpPong:
+			continue
+		case wsOpClose:
+			closeErr := &wsCloseError{code: 1005}
+			if len(payload) >= 2 {
+				closeErr.code, closeErr.reason = int(binary.BigEndian.Uint16(payload)), string(payload[2:])
+			}
+			_ = c.writeClose(wsCloseNormal, "")
+			return 0, nil, closeErr
+		case wsOpText, wsOpBinary:
+			if opcode != 0 {
+				_ = c.writeClose(wsCloseProtocolError, "expected a continuation frame")
+				return 0, nil, errors.New("wsConn.readMessage: new message before the last one finished")
+			}
+			opcode = op
+		case wsOpContinuation:
+			if opcode == 0 {
+				_ = c.writeClose(wsCloseProtocolError, "unexpected continuation frame")
+				return 0, nil, errors.New("wsConn.readMessage: continuation frame without a message")
+			}
+		default:
+			_ = c.writeClose(wsCloseProtocolError, "unknown opcode")
+			return 0, nil, fmt.Errorf("wsConn.readMessage: unknown opcode %d", op)
+		}
+
+		message = append(message, payload...)
+		if len(message) > wsMaxMessageBytes {
+			_ = c.writeClose(wsCloseTooBig, "message too big")
+			return 0, nil, fmt.Errorf("wsConn.readMessage: message is over %d bytes", wsMaxMessageBytes)
+		}
+
+		if fin {
+			return opcode, message, nil
+		}
+	}
+}
+
+func (c *wsConn) close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,188 @@
+package server
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	se "github.com/nrexception/mockapi/pkg/settings"
+)
+
+type testWSClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func dialWebSocket(t *testing.T, port int, path string, protocols string) (*testWSClient, *http.Response) {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", "127.0.0.1:"+strconv.Itoa(port))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	req, err := http.NewRequest(http.MethodGet, "http://127.0.0.1"+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	if protocols != "" {
+		req.Header.Set("Sec-WebSocket-Protocol", protocols)
+	}
+	err = req.Write(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader := bufio.NewReader(conn)
+	res, err := http.ReadResponse(reader, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testWSClient{conn: conn, reader: reader}, res
+}
+
+// Clients have to mask what they send, the mask itself doesn't matter.
+func (c *testWSClient) send(t *testing.T, opcode byte, payload []byte) {
+	t.Helper()
+
+	mask := []byte{1, 2, 3, 4}
+	frame := []byte{0x80 | opcode, 0x80 | byte(len(payload))}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+
+	_, err := c.conn.Write(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (c *testWSClient) receive(t *testing.T) (byte, string) {
+	t.Helper()
+
+	head := make([]byte, 2)
+	_, err := io.ReadFull(c.reader, head)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := make([]byte, head[1]&0x7F) // Tests stick to short messages
+	_, err = io.ReadFull(c.reader, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return head[0] & 0x0F, string(payload)
+}
+
+func TestListenerManager_WebSocket(t *testing.T) {
+	t.Parallel()
+
+	m := NewListenerManager(nil, nil)
+	t.Cleanup(func() { _ = m.Close() })
+
+	err := m.Apply(&se.UnmarshalledRootSettings{WebListeners: []se.UnmarshalledRootSettingWebListener{{
+		ListenerName: "ws",
+		ContentBindings: []se.ResponseBinding{{
+			Path:             "/ws",
+			ResponseBodyType: se.WebSocket,
+			ResponseHeaders:  []se.ResponseHeader{{Key: "X-Mock", Value: "yes"}},
+			WebSocket: &se.WebSocketSettings{
+				Subprotocols: []string{"chat.v2"},
+				OnConnect:    []se.WebSocketMessage{{Text: "welcome"}},
+				Replies: []se.WebSocketReply{
+					{Match: se.WebSocketMatch{JSON: map[string]any{"type": "subscribe", "channel": map[string]any{"id": 1}}}, Messages: []se.WebSocketMessage{{Text: `{"type":"subscribed"}`}}},
+					{Match: se.WebSocketMatch{Regex: `^ping \d+$`}, Messages: []se.WebSocketMessage{{Text: "pong"}}},
+					{Match: se.WebSocketMatch{Text: "bye"}, Close: &se.WebSocketClose{Code: 4001, Reason: "see you"}},
+				},
+				Push: []se.WebSocketPush{{Text: "tick", Interval: 10 * time.Millisecond, Count: 1}},
+			},
+		}},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listenerPort(t, m, "ws")
+
+	c, res := dialWebSocket(t, port, "/ws", "chat.v1, chat.v2")
+	if res.StatusCode != http.StatusSwitchingProtocols || res.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" || res.Header.Get("Sec-WebSocket-Protocol") != "chat.v2" || res.Header.Get("X-Mock") != "yes" {
+		t.Fatalf("unexpected handshake: %d %v", res.StatusCode, res.Header)
+	}
+
+	expect := func(opcode byte, text string) {
+		t.Helper()
+		gotOpcode, gotText := c.receive(t)
+		if gotOpcode != opcode || gotText != text {
+			t.Fatalf("expected %d %q, got %d %q", opcode, text, gotOpcode, gotText)
+		}
+	}
+
+	expect(wsOpText, "welcome")
+	expect(wsOpText, "tick")
+
+	c.send(t, wsOpText, []byte(`{"type":"subscribe","channel":{"id":1,"name":"news"}}`))
+	expect(wsOpText, `{"type":"subscribed"}`)
+
+	c.send(t, wsOpText, []byte("unmatched"))
+	c.send(t, wsOpPing, []byte("hi"))
+	expect(wsOpPong, "hi")
+
+	c.send(t, wsOpText, []byte("ping 42"))
+	expect(wsOpText, "pong")
+
+	c.send(t, wsOpText, []byte("bye"))
+	expect(wsOpClose, string(binary.BigEndian.AppendUint16(nil, 4001))+"see you")
+
+	// Plain requests are told to upgrade
+	plain, err := http.Get("http://127.0.0.1:" + strconv.Itoa(port) + "/ws")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain.Body.Close()
+	if plain.StatusCode != http.StatusUpgradeRequired {
+		t.Errorf("unexpected status for a plain request: %d", plain.StatusCode)
+	}
+}
+
+func TestListenerManager_WebSocketShutdown(t *testing.T) {
+	t.Parallel()
+
+	m := NewListenerManager(nil, nil)
+
+	err := m.Apply(&se.UnmarshalledRootSettings{WebListeners: []se.UnmarshalledRootSettingWebListener{{
+		ListenerName:    "ws",
+		ContentBindings: []se.ResponseBinding{{Path: "/ws", ResponseBodyType: se.WebSocket, WebSocket: &se.WebSocketSettings{}}},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listenerPort(t, m, "ws")
+
+	c, res := dialWebSocket(t, port, "/ws", "")
+	if res.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("unexpected status: %d", res.StatusCode)
+	}
+
+	err = m.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opcode, payload := c.receive(t)
+	if opcode != wsOpClose || binary.BigEndian.Uint16([]byte(payload)) != wsCloseGoingAway {
+		t.Errorf("expected a going away close, got %d %q", opcode, payload)
+	}
+}
@@ -0,0 +1,253 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	co "github.com/nrexception/mockapi/pkg/common"
+	se "github.com/nrexception/mockapi/pkg/settings"
+)
+
+type webSocketReply struct {
+	settings se.WebSocketReply
+	regex    *regexp.Regexp
+	json     any // settings.Match.JSON as decoded json would hold it (float64 numbers and so on)
+}
+
+// The conversation held by a websocket binding, with its matchers compiled once rather than per message.
+type webSocketScript struct {
+	settings se.WebSocketSettings
+	replies  []webSocketReply
+}
+
+func newWebSocketScript(settings se.WebSocketSettings, threaduuid uuid.UUID) *webSocketScript {
+	s := &webSocketScript{settings: settings}
+
+	for _, r := range settings.Replies {
+		reply := webSocketReply{settings: r}
+
+		if r.Match.Regex != "" {
+			re, err := regexp.Compile(r.Match.Regex)
+			if err != nil {
+				co.LogNonVerboseOnThread(threaduuid, co.MSGTYPE_WARN, "skipping websocket reply", co.LOGKEY_ERROR, err)
+				continue
+			}
+			reply.regex = re
+		}
+
+		if r.Match.JSON != nil {
+			b, err := json.Marshal(r.Match.JSON)
+			if err == nil {
+				err = json.Unmarshal(b, &reply.json)
+			}
+			if err != nil {
+				co.LogNonVerboseOnThread(threaduuid, co.MSGTYPE_WARN, "skipping websocket reply", co.LOGKEY_ERROR, err)
+				continue
+			}
+		}
+
+		s.replies = append(s.replies, reply)
+	}
+
+	return s
+}
+
+// Whether got holds everything in want, objects may have more fields than asked for.
+func jsonContains(want any, got any) bool {
+	wantObject, ok := want.(map[string]any)
+	if !ok {
+		return reflect.DeepEqual(want, got)
+	}
+
+	gotObject, ok := got.(map[string]any)
+	if !ok {
+		return false
+	}
+
+	for k, v := range wantObject {
+		if !jsonContains(v, gotObject[k]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (r webSocketReply) matches(message []byte) bool {
+	match, text := r.settings.Match, string(message)
+
+	if match.Text != "" && text != match.Text {
+		return false
+	}
+	if match.Contains != "" && !strings.Contains(text, match.Contains) {
+		return false
+	}
+	if r.regex != nil && !r.regex.Match(message) {
+		return false
+	}
+
+	if r.json != nil {
+		var got any
+		if json.Unmarshal(message, &got) != nil || !jsonContains(r.json, got) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Waits for d, false if ctx ended first.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func sendWebSocketMessages(ctx context.Context, conn *wsConn, messages []se.WebSocketMessage) bool {
+	for _, m := range messages {
+		if !sleepContext(ctx, m.Delay) || conn.writeText(m.Text) != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+func pushWebSocketMessages(ctx context.Context, conn *wsConn, push se.WebSocketPush) {
+	ticker := time.NewTicker(push.Interval)
+	defer ticker.Stop()
+
+	for sent := 0; push.Count == 0 || sent < push.Count; sent++ {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		if conn.writeText(push.Text) != nil {
+			return
+		}
+	}
+}
+
+// Sends a scripted close, then gives the client a moment to answer it before hanging up.
+func closeWebSocket(ctx context.Context, conn *wsConn, readErr chan error, scripted se.WebSocketClose) {
+	if !sleepContext(ctx, scripted.After) {
+		_ = conn.writeClose(wsCloseGoingAway, "server shutting down")
+		return
+	}
+	_ = conn.writeClose(scripted.Code, scripted.Reason)
+
+	select {
+	case <-readErr:
+	case <-time.After(wsCloseWait):
+	}
+}
+
+// Upgrades the request and holds the conversation until either side closes it, or the listener stops.
+func (s *webSocketScript) serve(w http.ResponseWriter, r *http.Request, threaduuid uuid.UUID) {
+	conn, err := upgradeWebSocket(w, r, s.settings.Subprotocols)
+	if err != nil {
+		co.LogVerboseOnThread(threaduuid, co.MSGTYPE_WARN, "websocket handshake refused", co.LOGKEY_PATH, r.URL.Path, co.LOGKEY_ERROR, err)
+		return
+	}
+	defer conn.close()
+
+	ctx, cancel := context.WithCancel(r.Context()) // Ends with the listener too
+	var pushes sync.WaitGroup
+	defer func() {
+		cancel()
+		pushes.Wait()
+	}()
+
+	// Read on the side so pushes, scripted closes and shutdowns don't wait on a quiet client
+	incoming := make(chan []byte)
+	readErr := make(chan error, 1)
+	go func() {
+		for {
+			_, message, err := conn.readMessage()
+			if err != nil {
+				readErr <- err
+				return
+			}
+
+			select {
+			case incoming <- message:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	if !sendWebSocketMessages(ctx, conn, s.settings.OnConnect) {
+		return
+	}
+
+	for _, p := range s.settings.Push {
+		pushes.Add(1)
+		go func() {
+			defer pushes.Done()
+			pushWebSocketMessages(ctx, conn, p)
+		}()
+	}
+
+	var closeTimer <-chan time.Time
+	if s.settings.Close != nil {
+		t := time.NewTimer(s.settings.Close.After)
+		defer t.Stop()
+		closeTimer = t.C
+	}
+
+	for {
+		select {
+		case message := <-incoming:
+			for _, reply := range s.replies {
+				if !reply.matches(message) {
+					continue
+				}
+
+				if !sendWebSocketMessages(ctx, conn, reply.settings.Messages) {
+					return
+				}
+				if reply.settings.Close != nil {
+					closeWebSocket(ctx, conn, readErr, *reply.settings.Close)
+					return
+				}
+				break
+			}
+		case err := <-readErr:
+			var closeErr *wsCloseError
+			if !errors.As(err, &closeErr) {
+				co.LogVerboseOnThread(threaduuid, co.MSGTYPE_WARN, "websocket connection lost", co.LOGKEY_PATH, r.URL.Path, co.LOGKEY_ERROR, err)
+			}
+			return
+		case <-closeTimer:
+			scripted := *s.settings.Close
+			scripted.After = 0 // Already waited for
+			closeWebSocket(ctx, conn, readErr, scripted)
+			return
+		case <-ctx.Done():
+			_ = conn.writeClose(wsCloseGoingAway, "server shutting down")
+			return
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package settings
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+const (
+	AccessLogCommon   AccessLogFormat = "common"
+	AccessLogCombined AccessLogFormat = "combined"
+	AccessLogJSON     AccessLogFormat = "json"
+)
+
+type AccessLogFormat string
+
+func (format AccessLogFormat) String() string { return string(format) }
+
+// Headers redacted from captured requests and responses when redactheaders isn't set.
+var DefaultRedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+const DefaultAccessLogMaxBodyBytes = 4096
+
+type AccessLogSettings struct {
+	Format         AccessLogFormat `yaml:"format"`                   // common, combined or json
+	File           string          `yaml:"file,omitempty"`           // File to append to, defaults to the process log output
+	CaptureHeaders bool            `yaml:"captureheaders,omitempty"` // Include request and response headers, json only
+	CaptureBodies  bool            `yaml:"capturebodies,omitempty"`  // Include request and response bodies, json only
+	MaxBodyBytes   int             `yaml:"maxbodybytes,omitempty"`   // Bodies are truncated to this many bytes, defaults to 4096
+	RedactHeaders  []string        `yaml:"redactheaders,omitempty"`  // Header values replaced with "[REDACTED]", defaults to DefaultRedactedHeaders
+}
+
+func (s *AccessLogSettings) Validate() error {
+	allowedFormats := []AccessLogFormat{AccessLogCommon, AccessLogCombined, AccessLogJSON}
+
+	if !slices.Contains(allowedFormats, s.Format) {
+		return fmt.Errorf("AccessLogSettings.Validate(): invalid access log format: \"%s\"", s.Format)
+	}
+
+	if (s.CaptureHeaders || s.CaptureBodies) && s.Format != AccessLogJSON {
+		return errors.New("AccessLogSettings.Validate(): headers and bodies can only be captured with the json format")
+	}
+
+	if s.MaxBodyBytes < 0 {
+		return fmt.Errorf("AccessLogSettings.Validate(): maxbodybytes must not be negative: %d", s.MaxBodyBytes)
+	}
+
+	return nil
+}
@@ -0,0 +1,202 @@
+package settings
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nrexception/mockapi/pkg/jwt"
+)
+
+// Credentials a request needs before a binding answers it, set on a listener (covering all its bindings) or a binding
+// (replacing its listener's). Any one of the schemes given lets a request through. Missing or invalid credentials get
+// unauthorized (401), valid ones that don't carry the claims required get forbidden (403).
+type AuthSettings struct {
+	Disabled     bool            `yaml:"disabled,omitempty"` // Lets a binding skip its listener's auth, eg. for a health check
+	Realm        string          `yaml:"realm,omitempty"`    // For WWW-Authenticate, defaults to mockapi
+	Basic        []BasicAuthUser `yaml:"basic,omitempty"`
+	APIKeys      *APIKeyAuth     `yaml:"apikeys,omitempty"`
+	Bearer       []BearerToken   `yaml:"bearer,omitempty"` // Static tokens, checked before jwt
+	JWT          *JWTAuth        `yaml:"jwt,omitempty"`
+	Unauthorized *AuthResponse   `yaml:"unauthorized,omitempty"` // Replaces the default 401
+	Forbidden    *AuthResponse   `yaml:"forbidden,omitempty"`    // Replaces the default 403
+}
+
+func (s *AuthSettings) Validate() error {
+	if s.Disabled {
+		return nil
+	}
+
+	if len(s.Basic) == 0 && s.APIKeys == nil && len(s.Bearer) == 0 && s.JWT == nil {
+		return fmt.Errorf("AuthSettings.Validate(): at least one of basic, apikeys, bearer or jwt is required, or disabled")
+	}
+
+	for _, u := range s.Basic {
+		if u.Username == "" {
+			return fmt.Errorf("AuthSettings.Validate(): basic users need a username")
+		}
+	}
+
+	if s.APIKeys != nil {
+		err := s.APIKeys.Validate()
+		if err != nil {
+			return fmt.Errorf("AuthSettings.Validate(): %w", err)
+		}
+	}
+
+	for _, t := range s.Bearer {
+		if t.Token == "" {
+			return fmt.Errorf("AuthSettings.Validate(): bearer tokens can't be empty")
+		}
+	}
+
+	if s.JWT != nil {
+		err := s.JWT.Validate()
+		if err != nil {
+			return fmt.Errorf("AuthSettings.Validate(): %w", err)
+		}
+	}
+
+	for _, r := range []*AuthResponse{s.Unauthorized, s.Forbidden} {
+		if r == nil {
+			continue
+		}
+		err := r.Validate()
+		if err != nil {
+			return fmt.Errorf("AuthSettings.Validate(): %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (s *AuthSettings) RealmName() string {
+	if s.Realm == "" {
+		return "mockapi"
+	}
+
+	return s.Realm
+}
+
+// Claims are what matchers and templates see of the request's credentials, as they would a token's.
+type BasicAuthUser struct {
+	Username string         `yaml:"username"`
+	Password string         `yaml:"password,omitempty"`
+	Claims   map[string]any `yaml:"claims,omitempty"` // sub defaults to the username
+}
+
+// Keys looked for in a header, a query parameter or both.
+type APIKeyAuth struct {
+	Header string   `yaml:"header,omitempty"` // Defaults to X-API-Key unless query is set
+	Query  string   `yaml:"query,omitempty"`
+	Keys   []APIKey `yaml:"keys"`
+}
+
+func (a *APIKeyAuth) Validate() error {
+	if len(a.Keys) == 0 {
+		return fmt.Errorf("APIKeyAuth.Validate(): at least one key is required")
+	}
+
+	for _, k := range a.Keys {
+		if k.Key == "" {
+			return fmt.Errorf("APIKeyAuth.Validate(): keys can't be empty")
+		}
+	}
+
+	return nil
+}
+
+func (a *APIKeyAuth) HeaderName() string {
+	if a.Header == "" && a.Query == "" {
+		return "X-API-Key"
+	}
+
+	return a.Header
+}
+
+type APIKey struct {
+	Key    string         `yaml:"key"`
+	Claims map[string]any `yaml:"claims,omitempty"`
+}
+
+type BearerToken struct {
+	Token  string         `yaml:"token"`
+	Claims map[string]any `yaml:"claims,omitempty"`
+}
+
+// Validates bearer tokens as JWTs signed with secret (HS256, HS384, HS512) or the RSA keys (RS256, RS384, RS512) in
+// publickeyfile or jwksfile. exp and nbf are always checked when present.
+type JWTAuth struct {
+	Secret        string         `yaml:"secret,omitempty"`
+	PublicKeyFile string         `yaml:"publickeyfile,omitempty"` // PEM public key or certificate
+	JWKSFile      string         `yaml:"jwksfile,omitempty"`      // Keys are picked by the token's kid, RSA and oct keys are used
+	Issuer        string         `yaml:"issuer,omitempty"`        // iss has to be this
+	Audience      string         `yaml:"audience,omitempty"`      // aud has to be or contain this
+	Claims        map[string]any `yaml:"claims,omitempty"`        // Required claims, matched like a binding match's claims, a 403 otherwise
+	Leeway        time.Duration  `yaml:"leeway,omitempty"`        // Allowed clock skew for exp and nbf
+}
+
+func (j *JWTAuth) Validate() error {
+	if j.Leeway < 0 {
+		return fmt.Errorf("JWTAuth.Validate(): leeway can't be negative")
+	}
+
+	_, err := j.Keys()
+	if err != nil {
+		return fmt.Errorf("JWTAuth.Validate(): %w", err)
+	}
+
+	return nil
+}
+
+// Every key tokens may be signed with.
+func (j *JWTAuth) Keys() ([]jwt.Key, error) {
+	keys := []jwt.Key{}
+
+	if j.Secret != "" {
+		keys = append(keys, jwt.Key{Secret: []byte(j.Secret)})
+	}
+
+	if j.PublicKeyFile != "" {
+		k, err := jwt.LoadPublicKey(j.PublicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("JWTAuth.Keys: %w", err)
+		}
+		keys = append(keys, jwt.Key{RSA: k})
+	}
+
+	if j.JWKSFile != "" {
+		jwks, err := jwt.LoadJWKS(j.JWKSFile)
+		if err != nil {
+			return nil, fmt.Errorf("JWTAuth.Keys: %w", err)
+		}
+		keys = append(keys, jwks...)
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("JWTAuth.Keys: one of secret, publickeyfile or jwksfile is required")
+	}
+
+	return keys, nil
+}
+
+// What's sent back when auth fails, in place of the default json error.
+type AuthResponse struct {
+	ResponseCode    int              `yaml:"responsecode,omitempty"` // Defaults to 401 or 403
+	ResponseHeaders []ResponseHeader `yaml:"responseheaders,omitempty"`
+	ResponseBody    string           `yaml:"responsebody,omitempty"`
+}
+
+func (r *AuthResponse) Validate() error {
+	if r.ResponseCode != 0 && (r.ResponseCode < 200 || r.ResponseCode > 599) {
+		return fmt.Errorf("AuthResponse.Validate(): invalid response code: %d", r.ResponseCode)
+	}
+
+	for _, h := range r.ResponseHeaders {
+		err := h.Validate()
+		if err != nil {
+			return fmt.Errorf("AuthResponse.Validate(): %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package settings
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/nrexception/mockapi/pkg/fake"
+)
+
+// A request sent once a binding has responded, eg. the webhook a payment provider fires after answering 202. The url,
+// header values and body are templates over the original request ({{.Method}}, {{.Path}}, {{.Query.Get "id"}},
+// {{.Header.Get "X-Id"}}, {{.Body}} and {{.JSON.field}} for json bodies), with the fake data functions too.
+type Callback struct {
+	URL     string           `yaml:"url"`
+	Method  string           `yaml:"method,omitempty"` // Defaults to POST
+	Headers []ResponseHeader `yaml:"headers,omitempty"`
+	Body    string           `yaml:"body,omitempty"`
+	Delay   time.Duration    `yaml:"delay,omitempty"`   // After the response, before the first attempt
+	Retries int              `yaml:"retries,omitempty"` // Further attempts after one that doesn't get a 2xx
+	Backoff time.Duration    `yaml:"backoff,omitempty"` // Before the first retry, doubling for each one after. Defaults to 1s
+	Timeout time.Duration    `yaml:"timeout,omitempty"` // Per attempt, defaults to 10s
+}
+
+func (c *Callback) Validate() error {
+	if c.URL == "" {
+		return fmt.Errorf("Callback.Validate(): a url is required")
+	}
+
+	// Only urls without templates can be checked up front
+	if !strings.Contains(c.URL, "{{") {
+		u, err := url.Parse(c.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("Callback.Validate(): url must be an absolute http or https url: \"%s\"", c.URL)
+		}
+	}
+
+	if c.Method != "" && strings.ToUpper(c.Method) != c.Method || strings.ContainsAny(c.Method, " \t/") {
+		return fmt.Errorf("Callback.Validate(): invalid method, expected an upper case method such as POST: \"%s\"", c.Method)
+	}
+
+	if c.Delay < 0 || c.Backoff < 0 || c.Timeout < 0 {
+		return fmt.Errorf("Callback.Validate(): delay, backoff and timeout can't be negative")
+	}
+
+	if c.Retries < 0 {
+		return fmt.Errorf("Callback.Validate(): retries can't be negative")
+	}
+
+	templates := []string{c.URL, c.Body}
+	for _, h := range c.Headers {
+		err := h.Validate()
+		if err != nil {
+			return fmt.Errorf("Callback.Validate(): %w", err)
+		}
+		templates = append(templates, h.Value)
+	}
+
+	g := fake.New(nil)
+	for _, t := range templates {
+		_, err := g.Template("callback", t)
+		if err != nil {
+			return fmt.Errorf("Callback.Validate(): %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (c *Callback) RequestMethod() string {
+	if c.Method == "" {
+		return http.MethodPost
+	}
+
+	return c.Method
+}
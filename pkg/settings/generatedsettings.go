@@ -0,0 +1,85 @@
+package settings
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/nrexception/mockapi/pkg/fake"
+)
+
+// What bindings with a responsebodytype of "generated" make up their bodies from, one of a template, a JSON Schema or
+// an example.
+type GeneratedSettings struct {
+	Template   string         `yaml:"template,omitempty"`   // A text/template using the fake data functions, eg. {{name}}
+	Schema     map[string]any `yaml:"schema,omitempty"`     // A JSON Schema, inline
+	SchemaFile string         `yaml:"schemafile,omitempty"` // A JSON Schema, in a json file
+	Example    any            `yaml:"example,omitempty"`    // A value to make up more like
+	Count      int            `yaml:"count,omitempty"`      // Wraps that many schema or example values in an array, 0 sends one as is
+	Seed       *int64         `yaml:"seed,omitempty"`       // Makes the same bodies, in the same order, every run
+}
+
+func (s *GeneratedSettings) Validate() error {
+	sources := 0
+	for _, given := range []bool{s.Template != "", s.Schema != nil, s.SchemaFile != "", s.Example != nil} {
+		if given {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return fmt.Errorf("GeneratedSettings.Validate(): exactly one of template, schema, schemafile or example is required")
+	}
+
+	if s.Count < 0 {
+		return fmt.Errorf("GeneratedSettings.Validate(): count can't be negative")
+	}
+
+	if s.Count > 0 && s.Template != "" {
+		return fmt.Errorf("GeneratedSettings.Validate(): count only applies to schemas and examples, templates can {{range seq n}}")
+	}
+
+	// Seeded, so whether a schema validates doesn't depend on what happened to be drawn
+	seed := int64(0)
+	g := fake.New(&seed)
+
+	if s.Template != "" {
+		_, err := g.Template("template", s.Template)
+		if err != nil {
+			return fmt.Errorf("GeneratedSettings.Validate(): %w", err)
+		}
+	}
+
+	schema, err := s.LoadSchema()
+	if err != nil {
+		return fmt.Errorf("GeneratedSettings.Validate(): %w", err)
+	}
+	if schema != nil {
+		// Anything the schema can't make is found by trying
+		_, err = g.FromSchema(schema)
+		if err != nil {
+			return fmt.Errorf("GeneratedSettings.Validate(): %w", err)
+		}
+	}
+
+	return nil
+}
+
+// The inline schema, or the one in schemafile. nil if neither was given.
+func (s *GeneratedSettings) LoadSchema() (map[string]any, error) {
+	if s.SchemaFile == "" {
+		return s.Schema, nil
+	}
+
+	b, err := os.ReadFile(s.SchemaFile)
+	if err != nil {
+		return nil, fmt.Errorf("LoadSchema: %w", err)
+	}
+
+	var schema map[string]any
+	err = json.Unmarshal(b, &schema)
+	if err != nil {
+		return nil, fmt.Errorf("LoadSchema: %s: %w", s.SchemaFile, err)
+	}
+
+	return schema, nil
+}
@@ -0,0 +1,91 @@
+package settings
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nrexception/mockapi/pkg/graphql"
+)
+
+// What bindings with a responsebodytype of "graphql" answer queries with. Fields nothing here covers get made up
+// values of the right type.
+type GraphQLSettings struct {
+	SchemaFile string             `yaml:"schemafile"`           // The schema, in SDL
+	Operations []GraphQLOperation `yaml:"operations,omitempty"` // Whole results for named operations, the first match wins
+	Fields     []GraphQLField     `yaml:"fields,omitempty"`     // Values for fields, whatever operation asks for them
+}
+
+func (s *GraphQLSettings) Validate() error {
+	if s.SchemaFile == "" {
+		return fmt.Errorf("GraphQLSettings.Validate(): a schemafile is required")
+	}
+
+	schema, err := graphql.LoadSchema(s.SchemaFile)
+	if err != nil {
+		return fmt.Errorf("GraphQLSettings.Validate(): %w", err)
+	}
+
+	for _, o := range s.Operations {
+		err = o.Validate()
+		if err != nil {
+			return fmt.Errorf("GraphQLSettings.Validate(): %w", err)
+		}
+	}
+
+	for _, f := range s.Fields {
+		err = f.Validate(schema)
+		if err != nil {
+			return fmt.Errorf("GraphQLSettings.Validate(): %w", err)
+		}
+	}
+
+	return nil
+}
+
+// A canned result for an operation, matched by its name and (if given) the variables it was sent with.
+type GraphQLOperation struct {
+	Name      string         `yaml:"name"`
+	Variables map[string]any `yaml:"variables,omitempty"` // Only match when the request's variables hold these
+	Data      map[string]any `yaml:"data,omitempty"`      // Keyed by the query's response keys, the rest is made up
+	Errors    []GraphQLError `yaml:"errors,omitempty"`    // Sent as the result's errors, data is null unless given too
+}
+
+func (o *GraphQLOperation) Validate() error {
+	if o.Name == "" {
+		return fmt.Errorf("GraphQLOperation.Validate(): operations are matched by name, one is required")
+	}
+
+	for _, e := range o.Errors {
+		if e.Message == "" {
+			return fmt.Errorf("GraphQLOperation.Validate(): errors for operation \"%s\" need a message", o.Name)
+		}
+	}
+
+	return nil
+}
+
+type GraphQLError struct {
+	Message    string         `yaml:"message"`
+	Path       []any          `yaml:"path,omitempty"`
+	Extensions map[string]any `yaml:"extensions,omitempty"`
+}
+
+// A canned value for a field, as Type.field.
+type GraphQLField struct {
+	Field string `yaml:"field"`
+	Value any    `yaml:"value"`
+}
+
+func (f *GraphQLField) Validate(schema *graphql.Schema) error {
+	typeName, fieldName, ok := strings.Cut(f.Field, ".")
+	if !ok {
+		return fmt.Errorf("GraphQLField.Validate(): expected a field as Type.field: \"%s\"", f.Field)
+	}
+
+	t := schema.Types[typeName]
+	if t == nil || t.Field(fieldName) == nil {
+		return fmt.Errorf("GraphQLField.Validate(): the schema has no field \"%s\"", f.Field)
+	}
+
+	return nil
+}
@@ -0,0 +1,133 @@
+package settings
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nrexception/mockapi/pkg/protobuf"
+)
+
+// gRPC status codes by name, as google.golang.org/grpc/codes has them.
+var GRPCCodes = map[string]int{
+	"OK":                  0,
+	"CANCELLED":           1,
+	"UNKNOWN":             2,
+	"INVALID_ARGUMENT":    3,
+	"DEADLINE_EXCEEDED":   4,
+	"NOT_FOUND":           5,
+	"ALREADY_EXISTS":      6,
+	"PERMISSION_DENIED":   7,
+	"RESOURCE_EXHAUSTED":  8,
+	"FAILED_PRECONDITION": 9,
+	"ABORTED":             10,
+	"OUT_OF_RANGE":        11,
+	"UNIMPLEMENTED":       12,
+	"INTERNAL":            13,
+	"UNAVAILABLE":         14,
+	"DATA_LOSS":           15,
+	"UNAUTHENTICATED":     16,
+}
+
+// Turns a listener into a gRPC server for the services in a descriptor set (protoc --include_imports
+// --descriptor_set_out), alongside any content bindings.
+type GRPCSettings struct {
+	DescriptorSet string       `yaml:"descriptorset"`
+	Reflection    bool         `yaml:"reflection,omitempty"` // Serve grpc.reflection, for grpcurl and the like
+	Methods       []GRPCMethod `yaml:"methods,omitempty"`
+}
+
+func (s *GRPCSettings) Validate() error {
+	if s.DescriptorSet == "" {
+		return fmt.Errorf("GRPCSettings.Validate(): a descriptorset is required")
+	}
+
+	registry, err := protobuf.LoadDescriptorSet(s.DescriptorSet)
+	if err != nil {
+		return fmt.Errorf("GRPCSettings.Validate(): %w", err)
+	}
+
+	configured := map[string]bool{}
+	for _, m := range s.Methods {
+		if configured[m.Method] {
+			return fmt.Errorf("GRPCSettings.Validate(): method \"%s\" is configured more than once", m.Method)
+		}
+		configured[m.Method] = true
+
+		err = m.Validate(registry)
+		if err != nil {
+			return fmt.Errorf("GRPCSettings.Validate(): %w", err)
+		}
+	}
+
+	return nil
+}
+
+// The canned responses for one method, given as package.Service/Method.
+type GRPCMethod struct {
+	Method    string         `yaml:"method"`
+	Responses []GRPCResponse `yaml:"responses"` // The first whose match the request meets is sent
+}
+
+func (m *GRPCMethod) Validate(registry *protobuf.Registry) error {
+	method := registry.MethodByPath("/" + m.Method)
+	if method == nil {
+		return fmt.Errorf("GRPCMethod.Validate(): the descriptor set has no method \"%s\", expected package.Service/Method", m.Method)
+	}
+
+	if method.ClientStreaming {
+		return fmt.Errorf("GRPCMethod.Validate(): \"%s\" is client streaming, only unary and server streaming methods can be mocked", m.Method)
+	}
+
+	if len(m.Responses) == 0 {
+		return fmt.Errorf("GRPCMethod.Validate(): \"%s\" needs at least one response", m.Method)
+	}
+
+	for _, r := range m.Responses {
+		err := r.Validate(registry, method)
+		if err != nil {
+			return fmt.Errorf("GRPCMethod.Validate(): \"%s\": %w", m.Method, err)
+		}
+	}
+
+	return nil
+}
+
+// Messages are written as the proto3 JSON mapping has them (in yaml or json). Metadata keys ending in -bin take base64
+// values.
+type GRPCResponse struct {
+	Match    map[string]any    `yaml:"match,omitempty"`    // Fields the request must hold, in its JSON form (64 bit integers are strings)
+	Headers  map[string]string `yaml:"headers,omitempty"`  // Metadata sent before the messages
+	Messages []map[string]any  `yaml:"messages,omitempty"` // One for unary methods, any number for server streaming
+	Interval time.Duration     `yaml:"interval,omitempty"` // Between streamed messages
+	Status   string            `yaml:"status,omitempty"`   // A code name such as NOT_FOUND, defaults to OK
+	Message  string            `yaml:"message,omitempty"`  // Sent as grpc-message with a status other than OK
+	Trailers map[string]string `yaml:"trailers,omitempty"` // Metadata sent with the status
+}
+
+func (r *GRPCResponse) Validate(registry *protobuf.Registry, method *protobuf.Method) error {
+	code, ok := GRPCCodes[r.Status]
+	if r.Status != "" && !ok {
+		return fmt.Errorf("GRPCResponse.Validate(): unknown status \"%s\", expected a code name such as NOT_FOUND", r.Status)
+	}
+
+	if !method.ServerStreaming && code == 0 && len(r.Messages) != 1 {
+		return fmt.Errorf("GRPCResponse.Validate(): unary methods answer OK with exactly one message, not %d", len(r.Messages))
+	}
+
+	if !method.ServerStreaming && code != 0 && len(r.Messages) > 0 {
+		return fmt.Errorf("GRPCResponse.Validate(): unary methods can't send messages with a status other than OK")
+	}
+
+	if r.Interval < 0 {
+		return fmt.Errorf("GRPCResponse.Validate(): interval can't be negative")
+	}
+
+	for _, m := range r.Messages {
+		_, err := registry.Marshal(method.Output, m)
+		if err != nil {
+			return fmt.Errorf("GRPCResponse.Validate(): %w", err)
+		}
+	}
+
+	return nil
+}
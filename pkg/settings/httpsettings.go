@@ -0,0 +1,32 @@
+package settings
+
+import (
+	"fmt"
+	"time"
+)
+
+// How a listener speaks http, anything left empty keeps Go's defaults. Durations are written as eg. 30s or 1m.
+type HTTPOptions struct {
+	DisableHTTP2   bool          `yaml:"disablehttp2,omitempty"`   // HTTP/1.1 only, even when a tls client offers h2
+	H2C            bool          `yaml:"h2c,omitempty"`            // Also accept HTTP/2 without tls, from clients with prior knowledge. Listeners without tls only
+	IdleTimeout    time.Duration `yaml:"idletimeout,omitempty"`    // How long a kept-alive connection waits for its next request
+	ReadTimeout    time.Duration `yaml:"readtimeout,omitempty"`    // For reading a whole request, body included
+	WriteTimeout   time.Duration `yaml:"writetimeout,omitempty"`   // For writing a response, from the end of reading the request headers
+	MaxHeaderBytes int           `yaml:"maxheaderbytes,omitempty"` // Largest request header accepted, defaults to 1MB
+}
+
+func (o *HTTPOptions) Validate() error {
+	if o.DisableHTTP2 && o.H2C {
+		return fmt.Errorf("HTTPOptions.Validate(): h2c can't be used with disablehttp2")
+	}
+
+	if o.IdleTimeout < 0 || o.ReadTimeout < 0 || o.WriteTimeout < 0 {
+		return fmt.Errorf("HTTPOptions.Validate(): timeouts can't be negative")
+	}
+
+	if o.MaxHeaderBytes < 0 {
+		return fmt.Errorf("HTTPOptions.Validate(): maxheaderbytes can't be negative: %d", o.MaxHeaderBytes)
+	}
+
+	return nil
+}
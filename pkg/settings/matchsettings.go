@@ -0,0 +1,45 @@
+package settings
+
+import "fmt"
+
+// Conditions a request has to meet for a binding to answer it. Bindings sharing a path and method are tried in order,
+// so a binding without a match after the ones with one answers everything else.
+//
+// Claims come from the binding's (or its listener's) auth and every one given has to match. Nested claims are named
+// with dots (realm_access.roles), a list of values matches any one of them, and a claim that's an array matches if it
+// holds the value. scope is taken as the space separated list it is.
+type BindingMatch struct {
+	ClientCert *ClientCertMatch `yaml:"clientcert,omitempty"`
+	Claims     map[string]any   `yaml:"claims,omitempty"`
+}
+
+func (m *BindingMatch) Validate() error {
+	if m.ClientCert != nil {
+		err := m.ClientCert.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	for k := range m.Claims {
+		if k == "" {
+			return fmt.Errorf("claim match names can't be empty")
+		}
+	}
+
+	return nil
+}
+
+// Matches the tls client certificate presented with the request, every field set has to match.
+type ClientCertMatch struct {
+	CommonName string   `yaml:"commonname,omitempty"` // Subject common name
+	SANs       []string `yaml:"sans,omitempty"`       // Any one of these among the DNS, email, IP or URI SANs
+}
+
+func (m *ClientCertMatch) Validate() error {
+	if m.CommonName == "" && len(m.SANs) == 0 {
+		return fmt.Errorf("client certificate match needs a commonname or sans")
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package settings
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+type ResourceIdType string
+
+const (
+	ResourceIdInt  ResourceIdType = "int"  // Counting up from the highest id in the collection
+	ResourceIdUUID ResourceIdType = "uuid" // Random (version 4) uuids
+)
+
+// An in-memory collection of json objects, served with REST style CRUD by bindings with a responsebodytype of
+// "resource".
+type ResourceSettings struct {
+	SeedFile    string         `yaml:"seedfile,omitempty"`    // A json array of objects to start with, empty otherwise
+	IdField     string         `yaml:"idfield,omitempty"`     // Defaults to id
+	IdType      ResourceIdType `yaml:"idtype,omitempty"`      // How ids are made up for objects posted without one, defaults to int
+	PageSize    int            `yaml:"pagesize,omitempty"`    // Objects per page when a list doesn't ask for a _limit, 0 lists them all
+	PersistFile string         `yaml:"persistfile,omitempty"` // Saved to after every change, and loaded instead of seedfile when it exists
+}
+
+func (s *ResourceSettings) Validate() error {
+	if s.IdType != "" && s.IdType != ResourceIdInt && s.IdType != ResourceIdUUID {
+		return fmt.Errorf("ResourceSettings.Validate(): idtype must be int or uuid: \"%s\"", s.IdType)
+	}
+
+	if s.PageSize < 0 {
+		return fmt.Errorf("ResourceSettings.Validate(): pagesize can't be negative")
+	}
+
+	if s.SeedFile != "" {
+		items, err := LoadResourceFile(s.SeedFile)
+		if err != nil {
+			return fmt.Errorf("ResourceSettings.Validate(): %w", err)
+		}
+
+		seen := map[string]bool{}
+		for _, item := range items {
+			id, ok := item[s.Id()]
+			if !ok {
+				continue // Given one when loaded
+			}
+			if seen[fmt.Sprint(id)] {
+				return fmt.Errorf("ResourceSettings.Validate(): %s: %s %v is used more than once", s.SeedFile, s.Id(), id)
+			}
+			seen[fmt.Sprint(id)] = true
+		}
+	}
+
+	if s.PersistFile != "" {
+		stat, err := os.Stat(filepath.Dir(s.PersistFile))
+		if err != nil || !stat.IsDir() {
+			return fmt.Errorf("ResourceSettings.Validate(): persistfile's directory does not exist: %s", s.PersistFile)
+		}
+
+		// One left by a previous run has to be loadable, or it'd be overwritten by the first change
+		_, err = LoadResourceFile(s.PersistFile)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("ResourceSettings.Validate(): %w", err)
+		}
+	}
+
+	return nil
+}
+
+// The id field, as defaulted.
+func (s *ResourceSettings) Id() string {
+	if s.IdField == "" {
+		return "id"
+	}
+
+	return s.IdField
+}
+
+// Reads a json array of objects. Numbers are kept as json.Number, so big ids survive being saved back.
+func LoadResourceFile(fileName string) ([]map[string]any, error) {
+	b, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("LoadResourceFile: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+
+	var items []map[string]any
+	err = decoder.Decode(&items)
+	if err != nil {
+		return nil, fmt.Errorf("LoadResourceFile: %s: expected a json array of objects: %w", fileName, err)
+	}
+
+	for i, item := range items {
+		if item == nil {
+			return nil, fmt.Errorf("LoadResourceFile: %s: item %d is null", fileName, i)
+		}
+	}
+
+	return items, nil
+}
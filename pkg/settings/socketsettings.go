@@ -0,0 +1,170 @@
+package settings
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	co "github.com/nrexception/mockapi/pkg/common"
+)
+
+type SocketProtocol string
+
+const (
+	TCP SocketProtocol = "tcp"
+	UDP SocketProtocol = "udp"
+)
+
+// A raw tcp or udp listener, for protocols that aren't HTTP. What it sends is scripted: a banner when tcp clients
+// connect, then replies to the messages that match. Durations are written as eg. 500ms or 5s.
+type UnmarshalledRootSettingSocketListener struct {
+	ListenerName  string          `yaml:"listenername"`
+	Protocol      SocketProtocol  `yaml:"protocol,omitempty"` // tcp or udp, defaults to tcp
+	ListenerPort  int             `yaml:"listenerport"`
+	ListenAddress string          `yaml:"listenaddress,omitempty"` // IPv4 or IPv6 address to bind, defaults to every interface
+	Delimiter     string          `yaml:"delimiter,omitempty"`     // Splits what tcp clients send into messages, eg. "\n", without it every read is a message
+	IdleTimeout   time.Duration   `yaml:"idletimeout,omitempty"`   // Closes tcp connections that have sent nothing for this long
+	Banner        []SocketMessage `yaml:"banner,omitempty"`        // Sent as soon as a tcp client connects
+	Replies       []SocketReply   `yaml:"replies,omitempty"`       // Tried in order against every message received, the first match answers
+}
+
+func (s *UnmarshalledRootSettingSocketListener) IsUDP() bool {
+	return s.Protocol == UDP
+}
+
+func (s *UnmarshalledRootSettingSocketListener) Validate() error {
+	if len(s.ListenerName) == 0 {
+		return errors.New("UnmarshalledRootSettingSocketListener.Validate(): ListenerName in settings file must be present!")
+	}
+	co.LogVerbose(fmt.Sprintf("UnmarshalledRootSettingSocketListener.Validate() Evaluating \"%s\"...", s.ListenerName), co.MSGTYPE_INFO)
+
+	if s.Protocol != "" && s.Protocol != TCP && s.Protocol != UDP {
+		return fmt.Errorf("UnmarshalledRootSettingSocketListener.Validate(): protocol must be tcp or udp: \"%s\"", s.Protocol)
+	}
+
+	// 0 picks a free port when the listener starts
+	if s.ListenerPort < 0 || s.ListenerPort > 65535 {
+		return fmt.Errorf("UnmarshalledRootSettingSocketListener.Validate(): ListenerPort in settings file must be between 0 and 65535: %d", s.ListenerPort)
+	}
+
+	err := validateListenAddress(s.ListenAddress)
+	if err != nil {
+		return fmt.Errorf("UnmarshalledRootSettingSocketListener.Validate(): %w", err)
+	}
+
+	if s.IdleTimeout < 0 {
+		return fmt.Errorf("UnmarshalledRootSettingSocketListener.Validate(): idletimeout can't be negative")
+	}
+
+	// Datagrams are messages already, and there's no connection to greet, time out or close
+	if s.IsUDP() && (s.Delimiter != "" || s.IdleTimeout != 0 || len(s.Banner) > 0) {
+		return fmt.Errorf("UnmarshalledRootSettingSocketListener.Validate(): delimiter, idletimeout and banner are for tcp listeners")
+	}
+
+	for _, m := range s.Banner {
+		err = m.Validate()
+		if err != nil {
+			return fmt.Errorf("UnmarshalledRootSettingSocketListener.Validate(): %w", err)
+		}
+	}
+
+	for _, r := range s.Replies {
+		err = r.Validate()
+		if err != nil {
+			return fmt.Errorf("UnmarshalledRootSettingSocketListener.Validate(): %w", err)
+		}
+
+		if r.Close && s.IsUDP() {
+			return fmt.Errorf("UnmarshalledRootSettingSocketListener.Validate(): udp listeners have no connection to close")
+		}
+	}
+
+	return nil
+}
+
+// Hex that may be spaced out, as messages and matches are written.
+func DecodeHex(s string) ([]byte, error) {
+	return hex.DecodeString(strings.Join(strings.Fields(s), ""))
+}
+
+// Bytes to send, written as text or as hex (eg. "0d0a" or "0D 0A").
+type SocketMessage struct {
+	Text  string        `yaml:"text,omitempty"`
+	Hex   string        `yaml:"hex,omitempty"`
+	Delay time.Duration `yaml:"delay,omitempty"` // Wait this long before sending
+}
+
+func (m *SocketMessage) Bytes() []byte {
+	if m.Hex != "" {
+		b, _ := DecodeHex(m.Hex)
+		return b
+	}
+
+	return []byte(m.Text)
+}
+
+func (m *SocketMessage) Validate() error {
+	if m.Text != "" && m.Hex != "" {
+		return fmt.Errorf("SocketMessage.Validate(): text and hex can't both be set")
+	}
+
+	_, err := DecodeHex(m.Hex)
+	if err != nil {
+		return fmt.Errorf("SocketMessage.Validate(): hex: %w", err)
+	}
+
+	if m.Delay < 0 {
+		return fmt.Errorf("SocketMessage.Validate(): delay can't be negative")
+	}
+
+	return nil
+}
+
+type SocketReply struct {
+	Match    SocketMatch     `yaml:"match,omitempty"`    // Empty matches any message
+	Messages []SocketMessage `yaml:"messages,omitempty"` // Sent in order
+	Close    bool            `yaml:"close,omitempty"`    // Closes the connection once the messages are sent
+}
+
+func (r *SocketReply) Validate() error {
+	err := r.Match.Validate()
+	if err != nil {
+		return fmt.Errorf("SocketReply.Validate(): %w", err)
+	}
+
+	for _, m := range r.Messages {
+		err = m.Validate()
+		if err != nil {
+			return fmt.Errorf("SocketReply.Validate(): %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Conditions on an incoming message, all of those set have to hold.
+type SocketMatch struct {
+	Text     string `yaml:"text,omitempty"`     // The whole message
+	Hex      string `yaml:"hex,omitempty"`      // The whole message, as hex
+	Contains string `yaml:"contains,omitempty"` // Part of the message
+	Regex    string `yaml:"regex,omitempty"`    // RE2 syntax, see https://golang.org/s/re2syntax
+}
+
+func (m *SocketMatch) Validate() error {
+	_, err := DecodeHex(m.Hex)
+	if err != nil {
+		return fmt.Errorf("SocketMatch.Validate(): hex: %w", err)
+	}
+
+	if m.Regex != "" {
+		_, err := regexp.Compile(m.Regex)
+		if err != nil {
+			return fmt.Errorf("SocketMatch.Validate(): %w", err)
+		}
+	}
+
+	return nil
+}
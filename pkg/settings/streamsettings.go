@@ -0,0 +1,71 @@
+package settings
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+type StreamFormat string
+
+const (
+	StreamSSE StreamFormat = "sse" // Server-Sent Events, served as text/event-stream
+	StreamRaw StreamFormat = "raw" // Chunks written as they are, eg. json lines or plain text tokens
+)
+
+// What bindings with a responsebodytype of "stream" send, flushing after every chunk. Durations are written as eg.
+// 200ms or 1s.
+type StreamSettings struct {
+	Format StreamFormat  `yaml:"format"`
+	Chunks []StreamChunk `yaml:"chunks"`
+	Loop   bool          `yaml:"loop,omitempty"` // Start over after the last chunk, until the client goes away
+}
+
+func (s *StreamSettings) Validate() error {
+	if !slices.Contains([]StreamFormat{StreamSSE, StreamRaw}, s.Format) {
+		return fmt.Errorf("StreamSettings.Validate(): invalid stream format \"%s\", expected sse or raw", s.Format)
+	}
+
+	if len(s.Chunks) == 0 {
+		return fmt.Errorf("StreamSettings.Validate(): a stream needs at least one chunk")
+	}
+
+	// Otherwise a looping stream would write as fast as the client can read, forever
+	if s.Loop && !slices.ContainsFunc(s.Chunks, func(c StreamChunk) bool { return c.Delay > 0 }) {
+		return fmt.Errorf("StreamSettings.Validate(): looping streams need a delay on at least one chunk")
+	}
+
+	for _, c := range s.Chunks {
+		err := c.Validate(s.Format)
+		if err != nil {
+			return fmt.Errorf("StreamSettings.Validate(): %w", err)
+		}
+	}
+
+	return nil
+}
+
+type StreamChunk struct {
+	Data  string        `yaml:"data"`
+	Delay time.Duration `yaml:"delay,omitempty"` // Wait this long before sending
+	Event string        `yaml:"event,omitempty"` // SSE only, the event's type
+	Id    string        `yaml:"id,omitempty"`    // SSE only, clients reconnecting with it in Last-Event-ID resume after this chunk
+	Retry time.Duration `yaml:"retry,omitempty"` // SSE only, how long clients should wait before reconnecting
+}
+
+func (c *StreamChunk) Validate(format StreamFormat) error {
+	if c.Delay < 0 || c.Retry < 0 {
+		return fmt.Errorf("StreamChunk.Validate(): delay and retry can't be negative")
+	}
+
+	if format != StreamSSE && (c.Event != "" || c.Id != "" || c.Retry != 0) {
+		return fmt.Errorf("StreamChunk.Validate(): event, id and retry are only for sse streams")
+	}
+
+	if strings.ContainsAny(c.Event+c.Id, "\r\n") {
+		return fmt.Errorf("StreamChunk.Validate(): event and id can't span lines")
+	}
+
+	return nil
+}
@@ -0,0 +1,166 @@
+package settings
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+)
+
+// Names and addresses auto tls certificates are valid for when none are configured.
+var (
+	DefaultAutoTLSHostnames = []string{"localhost"}
+	DefaultAutoTLSIPs       = []string{"127.0.0.1", "::1"}
+)
+
+// Generates a listener's certificate when it starts, instead of loading certdetails.
+type AutoTLSSettings struct {
+	Hostnames  []string `yaml:"hostnames,omitempty"`  // Defaults to DefaultAutoTLSHostnames
+	IPs        []string `yaml:"ips,omitempty"`        // Defaults to DefaultAutoTLSIPs
+	SelfSigned bool     `yaml:"selfsigned,omitempty"` // Sign the certificate with itself rather than the local CA
+}
+
+func (s *AutoTLSSettings) Validate() error {
+	for _, h := range s.Hostnames {
+		if h == "" {
+			return fmt.Errorf("AutoTLSSettings.Validate(): hostnames can't be empty")
+		}
+	}
+
+	for _, ip := range s.IPs {
+		if net.ParseIP(ip) == nil {
+			return fmt.Errorf("AutoTLSSettings.Validate(): invalid ip address: \"%s\"", ip)
+		}
+	}
+
+	return nil
+}
+
+// Where the CA signing auto tls certificates is kept. Without it a new CA is created every run and only lives in memory.
+type LocalCASettings struct {
+	Dir string `yaml:"dir"` // Loaded from, or created in, this directory as ca.pem and ca-key.pem
+}
+
+func (s *LocalCASettings) Validate() error {
+	if s.Dir == "" {
+		return fmt.Errorf("LocalCASettings.Validate(): dir must be set")
+	}
+
+	return nil
+}
+
+type ClientAuthMode string
+
+const (
+	ClientAuthNone    ClientAuthMode = "none"    // Don't ask for a client certificate
+	ClientAuthRequest ClientAuthMode = "request" // Ask for one, but carry on without it
+	ClientAuthRequire ClientAuthMode = "require" // Insist on one, without verifying it
+	ClientAuthVerify  ClientAuthMode = "verify"  // Insist on one signed by the client CA bundle
+)
+
+// Client certificates a tls listener asks for, however its own certificate is served.
+type ClientAuthSettings struct {
+	Mode   ClientAuthMode `yaml:"mode,omitempty"`   // Defaults to none
+	CAFile string         `yaml:"cafile,omitempty"` // PEM bundle of CAs client certificates are verified against
+}
+
+func (s *ClientAuthSettings) Validate() error {
+	allowedModes := []ClientAuthMode{"", ClientAuthNone, ClientAuthRequest, ClientAuthRequire, ClientAuthVerify}
+	if !slices.Contains(allowedModes, s.Mode) {
+		return fmt.Errorf("ClientAuthSettings.Validate(): invalid client auth mode: \"%s\"", s.Mode)
+	}
+
+	if s.Mode == ClientAuthVerify && s.CAFile == "" {
+		return fmt.Errorf("ClientAuthSettings.Validate(): client auth mode verify needs a cafile")
+	}
+
+	if s.CAFile != "" {
+		_, err := os.Stat(s.CAFile)
+		if err != nil {
+			return fmt.Errorf("ClientAuthSettings.Validate(): CA file does not exist or is not readable: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Versions accepted by minversion and maxversion.
+var TLSVersions = []string{"1.0", "1.1", "1.2", "1.3"}
+
+// Protocol level tls settings for a listener, anything left empty keeps Go's defaults.
+type TLSOptions struct {
+	MinVersion   string   `yaml:"minversion,omitempty"`   // One of TLSVersions
+	MaxVersion   string   `yaml:"maxversion,omitempty"`   // One of TLSVersions
+	CipherSuites []string `yaml:"ciphersuites,omitempty"` // Go names, eg. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256. TLS 1.3 suites can't be chosen
+	ALPN         []string `yaml:"alpn,omitempty"`         // Protocols offered during the handshake, in order of preference. Defaults to h2 and http/1.1
+}
+
+func (o *TLSOptions) Validate() error {
+	for _, v := range []string{o.MinVersion, o.MaxVersion} {
+		if v != "" && !slices.Contains(TLSVersions, v) {
+			return fmt.Errorf("TLSOptions.Validate(): invalid tls version \"%s\", expected one of %v", v, TLSVersions)
+		}
+	}
+
+	// The versions sort the same as they compare...
+	if o.MinVersion != "" && o.MaxVersion != "" && o.MinVersion > o.MaxVersion {
+		return fmt.Errorf("TLSOptions.Validate(): minversion %s is above maxversion %s", o.MinVersion, o.MaxVersion)
+	}
+
+	for _, name := range o.CipherSuites {
+		if isTLS13CipherSuite(name) {
+			return fmt.Errorf("TLSOptions.Validate(): cipher suite \"%s\" is TLS 1.3 only, Go always enables those so they can't be chosen", name)
+		}
+		if !slices.ContainsFunc(ConfigurableCipherSuites(), func(s *tls.CipherSuite) bool { return s.Name == name }) {
+			return fmt.Errorf("TLSOptions.Validate(): unknown cipher suite \"%s\"", name)
+		}
+	}
+
+	for _, p := range o.ALPN {
+		if p == "" {
+			return fmt.Errorf("TLSOptions.Validate(): alpn protocols can't be empty")
+		}
+	}
+
+	return nil
+}
+
+// Every cipher suite Go implements for TLS 1.2 and older, insecure ones included so legacy clients can be mimicked.
+func ConfigurableCipherSuites() []*tls.CipherSuite {
+	return slices.DeleteFunc(append(tls.CipherSuites(), tls.InsecureCipherSuites()...), func(s *tls.CipherSuite) bool {
+		return slices.Equal(s.SupportedVersions, []uint16{tls.VersionTLS13})
+	})
+}
+
+func isTLS13CipherSuite(name string) bool {
+	return slices.ContainsFunc(tls.CipherSuites(), func(s *tls.CipherSuite) bool {
+		return s.Name == name && slices.Equal(s.SupportedVersions, []uint16{tls.VersionTLS13})
+	})
+}
+
+// An extra certificate, served to clients asking for one of its hostnames through SNI.
+type SNICertificate struct {
+	Hostnames []string `yaml:"hostnames"` // Exact names, or wildcards such as *.example.com
+	CertFile  string   `yaml:"certfile"`
+	KeyFile   string   `yaml:"keyfile"`
+}
+
+func (c *SNICertificate) Validate() error {
+	if len(c.Hostnames) == 0 {
+		return fmt.Errorf("SNICertificate.Validate(): hostnames must be set")
+	}
+
+	for _, f := range []string{c.CertFile, c.KeyFile} {
+		if f == "" {
+			return fmt.Errorf("SNICertificate.Validate(): certfile and keyfile must be set")
+		}
+
+		_, err := os.Stat(f)
+		if err != nil {
+			return fmt.Errorf("SNICertificate.Validate(): %w", err)
+		}
+	}
+
+	return nil
+}
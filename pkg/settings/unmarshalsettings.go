@@ -1,8 +1,11 @@
 package settings
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
 	"slices"
 	"strings"
@@ -30,31 +33,82 @@ func (header *ResponseHeader) Validate() error {
 }
 
 const (
-	File   BodyType = "file"
-	Inline BodyType = "inline"
-	Proxy  BodyType = "proxy"
+	File      BodyType = "file"
+	Inline    BodyType = "inline"
+	Proxy     BodyType = "proxy"
+	Directory BodyType = "directory"
+	WebSocket BodyType = "websocket"
+	Stream    BodyType = "stream"
+	GraphQL   BodyType = "graphql"
+	Resource  BodyType = "resource"
+	Generated BodyType = "generated"
 )
 
 type BodyType string
 
 func (bodyType BodyType) String() string { return string(bodyType) }
 
+// Options for bindings with a responsebodytype of "directory", where responsebody is the folder to serve from.
+type DirectoryOptions struct {
+	IndexFiles    []string `yaml:"indexfiles,omitempty"`    // Files to serve when a directory is requested, tried in order. Defaults to index.html
+	EnableListing bool     `yaml:"enablelisting,omitempty"` // Render a listing for directories without an index file, otherwise 404
+}
+
+func (options *DirectoryOptions) Validate() error {
+	for _, i := range options.IndexFiles {
+		if i == "" || strings.ContainsAny(i, `/\`) {
+			return fmt.Errorf("invalid directory index file: \"%s\"", i)
+		}
+	}
+
+	return nil
+}
+
+// Response returned by "file" bindings when their file is missing or empty.
+type FileErrorResponse struct {
+	ResponseCode int    `yaml:"responsecode,omitempty"`
+	ResponseBody string `yaml:"responsebody,omitempty"`
+}
+
+func (response *FileErrorResponse) Validate() error {
+	if response.ResponseCode != 0 && response.ResponseCode <= 100 {
+		return fmt.Errorf("invalid file error response code: %d", response.ResponseCode)
+	}
+
+	return nil
+}
+
 type ResponseBinding struct {
-	Path             string           `yaml:"bindingpath"`
-	ResponseHeaders  []ResponseHeader `yaml:"responseheaders"`
-	ResponseCode     int              `yaml:"responsecode"`
-	ResponseBody     string           `yaml:"responsebody"`
-	ResponseBodyType BodyType         `yaml:"responsebodytype"`
+	Path              string             `yaml:"bindingpath"`
+	Method            string             `yaml:"method,omitempty"` // Only answer requests with this method, empty answers any
+	ResponseHeaders   []ResponseHeader   `yaml:"responseheaders,omitempty"`
+	ResponseCode      int                `yaml:"responsecode,omitempty"`
+	ResponseBody      string             `yaml:"responsebody,omitempty"`
+	ResponseBodyType  BodyType           `yaml:"responsebodytype"`
+	DirectoryOptions  *DirectoryOptions  `yaml:"directoryoptions,omitempty"`
+	FileErrorResponse *FileErrorResponse `yaml:"fileerrorresponse,omitempty"`
+	Match             *BindingMatch      `yaml:"match,omitempty"`     // Only answer requests meeting these conditions
+	WebSocket         *WebSocketSettings `yaml:"websocket,omitempty"` // The conversation held by "websocket" bindings
+	Stream            *StreamSettings    `yaml:"stream,omitempty"`    // The chunks sent by "stream" bindings
+	GraphQL           *GraphQLSettings   `yaml:"graphql,omitempty"`   // The schema and canned results of "graphql" bindings
+	Resource          *ResourceSettings  `yaml:"resource,omitempty"`  // The collection served by "resource" bindings
+	Generated         *GeneratedSettings `yaml:"generated,omitempty"` // What "generated" bindings make up their bodies from
+	Callbacks         []Callback         `yaml:"callbacks,omitempty"` // Requests sent once the binding has responded
+	Auth              *AuthSettings      `yaml:"auth,omitempty"`      // Replaces the listener's auth for this binding
 }
 
 func (binding *ResponseBinding) Validate() error {
-	allowedResponseBodyTypes := []BodyType{File, Inline, Proxy}
+	allowedResponseBodyTypes := []BodyType{File, Inline, Proxy, Directory, WebSocket, Stream, GraphQL, Resource, Generated}
 	allowedFileTypes := []string{".json", ".txt", ".csv", ".html", ".xml"}
 
 	if binding.Path == "" {
 		return fmt.Errorf("binding path must be defined")
 	}
 
+	if binding.Method != "" && strings.ToUpper(binding.Method) != binding.Method || strings.ContainsAny(binding.Method, " \t/") {
+		return fmt.Errorf("invalid binding method, expected an upper case method such as GET: \"%s\"", binding.Method)
+	}
+
 	// We might not want any headers...
 	if len(binding.ResponseHeaders) > 0 {
 		for _, i := range binding.ResponseHeaders {
@@ -65,10 +119,45 @@ func (binding *ResponseBinding) Validate() error {
 		}
 	}
 
-	if binding.ResponseCode <= 100 {
+	for _, c := range binding.Callbacks {
+		err := c.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if binding.Auth != nil {
+		err := binding.Auth.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	// Directory bindings derive their response code from the file being served, websockets always switch protocols...
+	if binding.ResponseCode <= 100 && binding.ResponseBodyType != Directory && binding.ResponseBodyType != WebSocket && binding.ResponseBodyType != GraphQL && binding.ResponseBodyType != Resource {
 		return fmt.Errorf("invalid response code: %d", binding.ResponseCode)
 	}
 
+	if binding.ResponseBodyType == WebSocket {
+		return binding.validateWebSocket()
+	}
+
+	if binding.ResponseBodyType == Stream {
+		return binding.validateStream()
+	}
+
+	if binding.ResponseBodyType == GraphQL {
+		return binding.validateGraphQL()
+	}
+
+	if binding.ResponseBodyType == Resource {
+		return binding.validateResource()
+	}
+
+	if binding.ResponseBodyType == Generated {
+		return binding.validateGenerated()
+	}
+
 	// TODO: response body could be empty
 	if binding.ResponseBody == "" {
 		return fmt.Errorf("invalid response body: %s", binding.ResponseBody)
@@ -78,6 +167,17 @@ func (binding *ResponseBinding) Validate() error {
 		return fmt.Errorf("invalid response body type: %s", binding.ResponseBodyType)
 	}
 
+	if binding.Match != nil {
+		err := binding.Match.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if binding.ResponseBodyType == Directory {
+		return binding.validateDirectory()
+	}
+
 	if binding.ResponseBodyType != File {
 		return nil
 	}
@@ -94,12 +194,130 @@ func (binding *ResponseBinding) Validate() error {
 		return fmt.Errorf("invalid response body file type: %s", binding.ResponseBody)
 	}
 
+	if binding.FileErrorResponse != nil {
+		return binding.FileErrorResponse.Validate()
+	}
+
+	return nil
+}
+
+func (binding *ResponseBinding) validateWebSocket() error {
+	if binding.WebSocket == nil {
+		return fmt.Errorf("websocket bindings need a websocket conversation")
+	}
+
+	if binding.Method != "" && binding.Method != http.MethodGet {
+		return fmt.Errorf("websocket bindings can only be opened with GET: \"%s\"", binding.Method)
+	}
+
+	if binding.Match != nil {
+		err := binding.Match.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	return binding.WebSocket.Validate()
+}
+
+func (binding *ResponseBinding) validateStream() error {
+	if binding.Stream == nil {
+		return fmt.Errorf("stream bindings need stream chunks")
+	}
+
+	if binding.Match != nil {
+		err := binding.Match.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	return binding.Stream.Validate()
+}
+
+// GraphQL bindings answer 200 unless told otherwise, errors and all, as GraphQL servers do.
+func (binding *ResponseBinding) validateGraphQL() error {
+	if binding.GraphQL == nil {
+		return fmt.Errorf("graphql bindings need graphql settings")
+	}
+
+	if binding.ResponseCode != 0 && binding.ResponseCode <= 100 {
+		return fmt.Errorf("invalid response code: %d", binding.ResponseCode)
+	}
+
+	if binding.Method != "" && binding.Method != http.MethodGet && binding.Method != http.MethodPost {
+		return fmt.Errorf("graphql bindings answer GET or POST: \"%s\"", binding.Method)
+	}
+
+	if binding.Match != nil {
+		err := binding.Match.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	return binding.GraphQL.Validate()
+}
+
+// Resource bindings pick their status from what was asked of them (200, 201, 204, 404...) and answer every method.
+func (binding *ResponseBinding) validateResource() error {
+	if binding.Resource == nil {
+		return fmt.Errorf("resource bindings need resource settings")
+	}
+
+	if binding.Method != "" {
+		return fmt.Errorf("resource bindings answer every method, they can't be limited to \"%s\"", binding.Method)
+	}
+
+	if binding.Match != nil {
+		err := binding.Match.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	return binding.Resource.Validate()
+}
+
+func (binding *ResponseBinding) validateGenerated() error {
+	if binding.Generated == nil {
+		return fmt.Errorf("generated bindings need generated settings")
+	}
+
+	if binding.Match != nil {
+		err := binding.Match.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	return binding.Generated.Validate()
+}
+
+func (binding *ResponseBinding) validateDirectory() error {
+	stat, err := os.Stat(binding.ResponseBody)
+	if err != nil {
+		return fmt.Errorf("response body directory does not exist or is not readable: %w", err)
+	}
+
+	if !stat.IsDir() {
+		return fmt.Errorf("response body is not a directory: %s", binding.ResponseBody)
+	}
+
+	if binding.DirectoryOptions != nil {
+		err = binding.DirectoryOptions.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
 type UnmarshalledRootSettingWebListenerHTTPSCertFiles struct {
-	CertFile string
-	KeyFile  string
+	CertFile string           `yaml:"certfile"`
+	KeyFile  string           `yaml:"keyfile"`
+	SNICerts []SNICertificate `yaml:"snicerts,omitempty"` // Served instead of CertFile to clients asking for their hostnames
 }
 
 func (s *UnmarshalledRootSettingWebListenerHTTPSCertFiles) Validate() error {
@@ -121,16 +339,41 @@ func (s *UnmarshalledRootSettingWebListenerHTTPSCertFiles) Validate() error {
 		return fmt.Errorf("UnmarshalledRootSettingWebListenerHTTPSCertFiles.Validate(): Key File does not exist or is not readable: %w", err)
 	}
 
+	for _, c := range s.SNICerts {
+		err = c.Validate()
+		if err != nil {
+			return fmt.Errorf("UnmarshalledRootSettingWebListenerHTTPSCertFiles.Validate(): %w", err)
+		}
+	}
+
+	return nil
+}
+
+func validateListenAddress(address string) error {
+	if address != "" && net.ParseIP(address) == nil {
+		return fmt.Errorf("validateListenAddress: listenaddress must be an IPv4 or IPv6 address: \"%s\"", address)
+	}
+
 	return nil
 }
 
 type UnmarshalledRootSettingWebListener struct {
-	ListenerName       string
-	ListenerPort       int
-	OnConnectKeepAlive bool
-	EnableTLS          bool
-	CertDetails        *UnmarshalledRootSettingWebListenerHTTPSCertFiles
-	ContentBindings    []ResponseBinding
+	ListenerName       string                                            `yaml:"listenername"`
+	ListenerPort       int                                               `yaml:"listenerport"`
+	ListenAddress      string                                            `yaml:"listenaddress,omitempty"`      // IPv4 or IPv6 address to bind, defaults to every interface
+	UnixSocket         string                                            `yaml:"unixsocket,omitempty"`         // Listen on this socket path instead of a tcp port
+	OnConnectKeepAlive *bool                                             `yaml:"onconnectkeepalive,omitempty"` // Keep connections open between requests, defaults to true
+	HTTPOptions        *HTTPOptions                                      `yaml:"httpoptions,omitempty"`
+	EnableTLS          bool                                              `yaml:"enabletls,omitempty"`
+	CertDetails        *UnmarshalledRootSettingWebListenerHTTPSCertFiles `yaml:"certdetails,omitempty"`
+	AutoTLS            *AutoTLSSettings                                  `yaml:"autotls,omitempty"`
+	TLSOptions         *TLSOptions                                       `yaml:"tlsoptions,omitempty"`
+	ClientAuth         *ClientAuthSettings                               `yaml:"clientauth,omitempty"` // Client certificates asked for, whether certdetails or autotls serves the listener's own
+	AccessLog          *AccessLogSettings                                `yaml:"accesslog,omitempty"`
+	MetricsPath        string                                            `yaml:"metricspath,omitempty"` // Serve Prometheus metrics on this path of the listener, alongside its bindings
+	GRPC               *GRPCSettings                                     `yaml:"grpc,omitempty"`        // Also serve gRPC, over h2c without tls
+	Auth               *AuthSettings                                     `yaml:"auth,omitempty"`        // Required by every binding that doesn't set its own
+	ContentBindings    []ResponseBinding                                 `yaml:"contentbindings"`
 }
 
 func (s *UnmarshalledRootSettingWebListener) Validate() error {
@@ -139,8 +382,18 @@ func (s *UnmarshalledRootSettingWebListener) Validate() error {
 	}
 	co.LogVerbose(fmt.Sprintf("UnmarshalledRootSettingWebListener.Validate() Evaluating \"%s\"...", s.ListenerName), co.MSGTYPE_INFO)
 
-	if s.ListenerPort <= 0 {
-		return errors.New(fmt.Sprintf("UnmarshalledRootSettingWebListener.Validate(): ListenerPort in settings file must be greater than 0"))
+	// 0 picks a free port when the listener starts
+	if s.ListenerPort < 0 || s.ListenerPort > 65535 {
+		return fmt.Errorf("UnmarshalledRootSettingWebListener.Validate(): ListenerPort in settings file must be between 0 and 65535: %d", s.ListenerPort)
+	}
+
+	err := validateListenAddress(s.ListenAddress)
+	if err != nil {
+		return fmt.Errorf("UnmarshalledRootSettingWebListener.Validate(): %w", err)
+	}
+
+	if s.UnixSocket != "" && (s.ListenAddress != "" || s.ListenerPort != 0) {
+		return fmt.Errorf("UnmarshalledRootSettingWebListener.Validate(): unixsocket can't be combined with listenaddress or listenerport")
 	}
 
 	// Object is "nillable" as it's a ptr reference...
@@ -151,6 +404,75 @@ func (s *UnmarshalledRootSettingWebListener) Validate() error {
 		}
 	}
 
+	if s.AutoTLS != nil {
+		err := s.AutoTLS.Validate()
+		if err != nil {
+			return fmt.Errorf("UnmarshalledRootSettingWebListener.Validate(): %w", err)
+		}
+	}
+
+	if s.TLSOptions != nil {
+		err := s.TLSOptions.Validate()
+		if err != nil {
+			return fmt.Errorf("UnmarshalledRootSettingWebListener.Validate(): %w", err)
+		}
+	}
+
+	if s.ClientAuth != nil {
+		err := s.ClientAuth.Validate()
+		if err != nil {
+			return fmt.Errorf("UnmarshalledRootSettingWebListener.Validate(): %w", err)
+		}
+
+		if !s.EnableTLS {
+			return errors.New("UnmarshalledRootSettingWebListener.Validate(): clientauth needs EnableTLS")
+		}
+	}
+
+	if s.HTTPOptions != nil {
+		err := s.HTTPOptions.Validate()
+		if err != nil {
+			return fmt.Errorf("UnmarshalledRootSettingWebListener.Validate(): %w", err)
+		}
+
+		if s.HTTPOptions.H2C && s.EnableTLS {
+			return fmt.Errorf("UnmarshalledRootSettingWebListener.Validate(): h2c is for listeners without tls, tls listeners negotiate HTTP/2 through alpn")
+		}
+	}
+
+	if s.GRPC != nil {
+		err := s.GRPC.Validate()
+		if err != nil {
+			return fmt.Errorf("UnmarshalledRootSettingWebListener.Validate(): %w", err)
+		}
+
+		if s.HTTPOptions != nil && s.HTTPOptions.DisableHTTP2 {
+			return fmt.Errorf("UnmarshalledRootSettingWebListener.Validate(): gRPC needs HTTP/2, it can't be disabled on grpc listeners")
+		}
+	}
+
+	if s.Auth != nil {
+		err := s.Auth.Validate()
+		if err != nil {
+			return fmt.Errorf("UnmarshalledRootSettingWebListener.Validate(): %w", err)
+		}
+	}
+
+	if s.EnableTLS && (s.CertDetails == nil) == (s.AutoTLS == nil) {
+		return errors.New("UnmarshalledRootSettingWebListener.Validate(): EnableTLS needs exactly one of CertDetails or AutoTLS")
+	}
+
+	if s.MetricsPath != "" && !strings.HasPrefix(s.MetricsPath, "/") {
+		return fmt.Errorf("UnmarshalledRootSettingWebListener.Validate(): MetricsPath must start with /: \"%s\"", s.MetricsPath)
+	}
+
+	if s.AccessLog != nil {
+		err := s.AccessLog.Validate()
+		if err != nil {
+			return fmt.Errorf("UnmarshalledRootSettingWebListener.Validate(): %w", err)
+		}
+	}
+
 	for _, i := range s.ContentBindings {
 		err := i.Validate()
 		if err != nil {
@@ -161,11 +483,42 @@ func (s *UnmarshalledRootSettingWebListener) Validate() error {
 	return nil
 }
 
+// Built-in listener for operating MockAPI itself (metrics...), kept apart from the mocked endpoints.
+type UnmarshalledRootSettingAdminListener struct {
+	ListenerPort  int    `yaml:"listenerport"`
+	ListenAddress string `yaml:"listenaddress,omitempty"` // Defaults to every interface
+	MetricsPath   string `yaml:"metricspath,omitempty"`   // Defaults to /metrics
+	LivenessPath  string `yaml:"livenesspath,omitempty"`  // Defaults to /healthz
+	ReadinessPath string `yaml:"readinesspath,omitempty"` // Defaults to /readyz
+}
+
+func (s *UnmarshalledRootSettingAdminListener) Validate() error {
+	if s.ListenerPort < 0 || s.ListenerPort > 65535 {
+		return fmt.Errorf("UnmarshalledRootSettingAdminListener.Validate(): ListenerPort in settings file must be between 0 and 65535: %d", s.ListenerPort)
+	}
+
+	err := validateListenAddress(s.ListenAddress)
+	if err != nil {
+		return fmt.Errorf("UnmarshalledRootSettingAdminListener.Validate(): %w", err)
+	}
+
+	for name, path := range map[string]string{"MetricsPath": s.MetricsPath, "LivenessPath": s.LivenessPath, "ReadinessPath": s.ReadinessPath} {
+		if path != "" && !strings.HasPrefix(path, "/") {
+			return fmt.Errorf("UnmarshalledRootSettingAdminListener.Validate(): %s must start with /: \"%s\"", name, path)
+		}
+	}
+
+	return nil
+}
+
 type UnmarshalledRootSettings struct {
-	Id           string
-	Schema       string
-	Description  string
-	WebListeners []UnmarshalledRootSettingWebListener
+	Id              string                                  `yaml:"id"`
+	Schema          string                                  `yaml:"schema"`
+	Description     string                                  `yaml:"description"`
+	Admin           *UnmarshalledRootSettingAdminListener   `yaml:"admin,omitempty"`
+	LocalCA         *LocalCASettings                        `yaml:"localca,omitempty"`
+	WebListeners    []UnmarshalledRootSettingWebListener    `yaml:"weblisteners"`
+	SocketListeners []UnmarshalledRootSettingSocketListener `yaml:"socketlisteners,omitempty"` // Raw tcp and udp
 }
 
 func (s *UnmarshalledRootSettings) Validate() error {
@@ -180,8 +533,22 @@ func (s *UnmarshalledRootSettings) Validate() error {
 	if len(s.Description) == 0 {
 		return errors.New("UnmarshalledRootSettings.Validate(): Schema field in settings file must be present!")
 	}
-	if len(s.WebListeners) < 1 {
-		return errors.New("UnmarshalledRootSettings.Validate(): WebListeners definition must be present and must have at least one valid entry!")
+	if len(s.WebListeners)+len(s.SocketListeners) < 1 {
+		return errors.New("UnmarshalledRootSettings.Validate(): WebListeners (or SocketListeners) definition must be present and must have at least one valid entry!")
+	}
+
+	if s.Admin != nil {
+		err := s.Admin.Validate()
+		if err != nil {
+			return fmt.Errorf("UnmarshalledRootSettings.Validate(): %w", err)
+		}
+	}
+
+	if s.LocalCA != nil {
+		err := s.LocalCA.Validate()
+		if err != nil {
+			return fmt.Errorf("UnmarshalledRootSettings.Validate(): %w", err)
+		}
 	}
 
 	co.LogVerbose("UnmarshalSettingsFile() Validating web listeners...", co.MSGTYPE_INFO)
@@ -192,6 +559,14 @@ func (s *UnmarshalledRootSettings) Validate() error {
 		}
 	}
 
+	co.LogVerbose("UnmarshalSettingsFile() Validating socket listeners...", co.MSGTYPE_INFO)
+	for _, i := range s.SocketListeners {
+		err := i.Validate()
+		if err != nil {
+			return fmt.Errorf("UnmarshalledRootSettings.Validate(): %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -199,8 +574,6 @@ func (s *UnmarshalledRootSettings) Validate() error {
 func UnmarshalSettingsFile(path string) (umrs *UnmarshalledRootSettings, err error) {
 	co.LogVerbose(fmt.Sprintf("UnmarshalSettingsFile() Unmarshalling settings file \"%s\"", path), co.MSGTYPE_INFO)
 
-	var decodedSettings UnmarshalledRootSettings
-
 	// Read file and validate
 	co.LogVerbose("UnmarshalSettingsFile() Reading file data...", co.MSGTYPE_INFO)
 	b, err := os.ReadFile(path)
@@ -213,22 +586,49 @@ func UnmarshalSettingsFile(path string) (umrs *UnmarshalledRootSettings, err err
 
 	co.LogVerbose(fmt.Sprintf("UnmarshalSettingsFile() file is %d bytes", len(b)), co.MSGTYPE_INFO)
 
-	// Unmarshal and validate
+	return UnmarshalSettings(b)
+}
+
+// Validates settings and renders them as yaml, in the same format UnmarshalSettings reads.
+func MarshalSettings(s *UnmarshalledRootSettings) ([]byte, error) {
+	err := s.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("MarshalSettings: %w", err)
+	}
+
+	// Two space indents, the same as the example settings files
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	err = enc.Encode(s)
+	if err == nil {
+		err = enc.Close()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("MarshalSettings: %w", err)
+	}
+
+	return b.Bytes(), nil
+}
+
+// Unmarshals and validates settings from yaml already in memory.
+func UnmarshalSettings(b []byte) (*UnmarshalledRootSettings, error) {
+	var decodedSettings UnmarshalledRootSettings
 
-	co.LogVerbose("UnmarshalSettingsFile() Unmarshalling bytes...", co.MSGTYPE_INFO)
-	err = yaml.Unmarshal(b, &decodedSettings)
+	co.LogVerbose("UnmarshalSettings() Unmarshalling bytes...", co.MSGTYPE_INFO)
+	err := yaml.Unmarshal(b, &decodedSettings)
 	if err != nil {
 		return nil, fmt.Errorf("error unmarshaling file contents: %w", err)
 	}
 
 	// Validate struct critical datatypes...
-	co.LogVerbose("UnmarshalSettingsFile() Validating data structures...", co.MSGTYPE_INFO)
+	co.LogVerbose("UnmarshalSettings() Validating data structures...", co.MSGTYPE_INFO)
 	err = decodedSettings.Validate()
 	if err != nil {
 		return nil, fmt.Errorf("error validating yaml file: %w", err)
 	}
 
-	co.LogVerbose("UnmarshalSettingsFile() All data structures valid!", co.MSGTYPE_INFO)
+	co.LogVerbose("UnmarshalSettings() All data structures valid!", co.MSGTYPE_INFO)
 
 	return &decodedSettings, nil
 }
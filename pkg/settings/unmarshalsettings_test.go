@@ -2,7 +2,10 @@ package settings_test
 
 import (
 	"net/http"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/nrexception/mockapi/pkg/settings"
 )
@@ -80,6 +83,10 @@ func TestBodyType_String(t *testing.T) {
 			name:     "proxy",
 			bodyType: settings.Proxy,
 		},
+		{
+			name:     "directory",
+			bodyType: settings.Directory,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -101,14 +108,37 @@ func TestBodyType_String(t *testing.T) {
 func TestResponseBinding_Validate(t *testing.T) {
 	t.Parallel()
 
+	schemaFile := filepath.Join(t.TempDir(), "schema.graphql")
+	err := os.WriteFile(schemaFile, []byte("type Query { user: User } type User { name: String }"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seedFile := filepath.Join(t.TempDir(), "users.json")
+	err = os.WriteFile(seedFile, []byte(`[{"id": 1, "name": "Ada"}, {"id": 2, "name": "Grace"}]`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	duplicateSeedFile := filepath.Join(t.TempDir(), "duplicates.json")
+	err = os.WriteFile(duplicateSeedFile, []byte(`[{"id": 1}, {"id": 1}]`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	// TODO: Add more test cases
 	testCases := []struct {
 		name             string
 		path             string
+		method           string
 		responseHeaders  []settings.ResponseHeader
 		responseCode     int
 		responseBody     string
 		responseBodyType settings.BodyType
+		webSocket        *settings.WebSocketSettings
+		graphQL          *settings.GraphQLSettings
+		resource         *settings.ResourceSettings
+		generated        *settings.GeneratedSettings
 		expectedError    bool
 	}{
 		{
@@ -120,6 +150,198 @@ func TestResponseBinding_Validate(t *testing.T) {
 			responseBodyType: settings.File,
 			expectedError:    false,
 		},
+		{
+			name:             "directory",
+			path:             "/static",
+			responseBody:     ".",
+			responseBodyType: settings.Directory,
+			expectedError:    false,
+		},
+		{
+			name:             "directory does not exist",
+			path:             "/static",
+			responseBody:     "does-not-exist",
+			responseBodyType: settings.Directory,
+			expectedError:    true,
+		},
+		{
+			name:             "directory is a file",
+			path:             "/static",
+			responseBody:     "unmarshalsettings.go",
+			responseBodyType: settings.Directory,
+			expectedError:    true,
+		},
+		{
+			name:             "method",
+			path:             "/",
+			method:           http.MethodPost,
+			responseCode:     http.StatusCreated,
+			responseBody:     "created",
+			responseBodyType: settings.Inline,
+			expectedError:    false,
+		},
+		{
+			name:             "websocket",
+			path:             "/ws",
+			responseBodyType: settings.WebSocket,
+			webSocket:        &settings.WebSocketSettings{Close: &settings.WebSocketClose{Code: 4000}},
+			expectedError:    false,
+		},
+		{
+			name:             "websocket without a conversation",
+			path:             "/ws",
+			responseBodyType: settings.WebSocket,
+			expectedError:    true,
+		},
+		{
+			name:             "websocket with a reserved close code",
+			path:             "/ws",
+			responseBodyType: settings.WebSocket,
+			webSocket:        &settings.WebSocketSettings{Close: &settings.WebSocketClose{Code: 1006}},
+			expectedError:    true,
+		},
+		{
+			name:             "graphql",
+			path:             "/graphql",
+			responseBodyType: settings.GraphQL,
+			graphQL:          &settings.GraphQLSettings{SchemaFile: schemaFile, Fields: []settings.GraphQLField{{Field: "User.name", Value: "Ada"}}},
+			expectedError:    false,
+		},
+		{
+			name:             "graphql schema does not exist",
+			path:             "/graphql",
+			responseBodyType: settings.GraphQL,
+			graphQL:          &settings.GraphQLSettings{SchemaFile: "does-not-exist.graphql"},
+			expectedError:    true,
+		},
+		{
+			name:             "graphql field not in the schema",
+			path:             "/graphql",
+			responseBodyType: settings.GraphQL,
+			graphQL:          &settings.GraphQLSettings{SchemaFile: schemaFile, Fields: []settings.GraphQLField{{Field: "User.email", Value: "a@b.c"}}},
+			expectedError:    true,
+		},
+		{
+			name:             "resource",
+			path:             "/users",
+			responseBodyType: settings.Resource,
+			resource:         &settings.ResourceSettings{SeedFile: seedFile, PageSize: 10, PersistFile: filepath.Join(t.TempDir(), "users.json")},
+			expectedError:    false,
+		},
+		{
+			name:             "resource without settings",
+			path:             "/users",
+			responseBodyType: settings.Resource,
+			expectedError:    true,
+		},
+		{
+			name:             "resource limited to a method",
+			path:             "/users",
+			method:           http.MethodGet,
+			responseBodyType: settings.Resource,
+			resource:         &settings.ResourceSettings{},
+			expectedError:    true,
+		},
+		{
+			name:             "resource with an unknown id type",
+			path:             "/users",
+			responseBodyType: settings.Resource,
+			resource:         &settings.ResourceSettings{IdType: "serial"},
+			expectedError:    true,
+		},
+		{
+			name:             "resource seed with duplicate ids",
+			path:             "/users",
+			responseBodyType: settings.Resource,
+			resource:         &settings.ResourceSettings{SeedFile: duplicateSeedFile},
+			expectedError:    true,
+		},
+		{
+			name:             "resource persisted to a missing directory",
+			path:             "/users",
+			responseBodyType: settings.Resource,
+			resource:         &settings.ResourceSettings{PersistFile: "does-not-exist/users.json"},
+			expectedError:    true,
+		},
+		{
+			name:             "generated from a template",
+			path:             "/greeting",
+			responseCode:     http.StatusOK,
+			responseBodyType: settings.Generated,
+			generated:        &settings.GeneratedSettings{Template: "Hello {{name}}"},
+			expectedError:    false,
+		},
+		{
+			name:             "generated from a schema",
+			path:             "/users",
+			responseCode:     http.StatusOK,
+			responseBodyType: settings.Generated,
+			generated:        &settings.GeneratedSettings{Schema: map[string]any{"type": "array", "items": map[string]any{"type": "string"}}, Count: 2},
+			expectedError:    false,
+		},
+		{
+			name:             "generated without settings",
+			path:             "/users",
+			responseCode:     http.StatusOK,
+			responseBodyType: settings.Generated,
+			expectedError:    true,
+		},
+		{
+			name:             "generated from a template and an example",
+			path:             "/users",
+			responseCode:     http.StatusOK,
+			responseBodyType: settings.Generated,
+			generated:        &settings.GeneratedSettings{Template: "{{name}}", Example: "Ada"},
+			expectedError:    true,
+		},
+		{
+			name:             "generated template with an unknown function",
+			path:             "/users",
+			responseCode:     http.StatusOK,
+			responseBodyType: settings.Generated,
+			generated:        &settings.GeneratedSettings{Template: "{{shoeSize}}"},
+			expectedError:    true,
+		},
+		{
+			name:             "generated schema with an unknown type",
+			path:             "/users",
+			responseCode:     http.StatusOK,
+			responseBodyType: settings.Generated,
+			generated:        &settings.GeneratedSettings{Schema: map[string]any{"type": "tuple"}},
+			expectedError:    true,
+		},
+		{
+			name:             "generated schema with negative maxItems",
+			path:             "/users",
+			responseCode:     http.StatusOK,
+			responseBodyType: settings.Generated,
+			generated:        &settings.GeneratedSettings{Schema: map[string]any{"type": "array", "maxItems": -1}},
+			expectedError:    true,
+		},
+		{
+			name:             "generated schema with an integer range wider than int64",
+			path:             "/users",
+			responseCode:     http.StatusOK,
+			responseBodyType: settings.Generated,
+			generated:        &settings.GeneratedSettings{Schema: map[string]any{"type": "integer", "minimum": -9.2e18, "maximum": 9.2e18}},
+			expectedError:    false,
+		},
+		{
+			name:             "generated without a response code",
+			path:             "/users",
+			responseBodyType: settings.Generated,
+			generated:        &settings.GeneratedSettings{Example: "Ada"},
+			expectedError:    true,
+		},
+		{
+			name:             "lower case method",
+			path:             "/",
+			method:           "post",
+			responseCode:     http.StatusCreated,
+			responseBody:     "created",
+			responseBodyType: settings.Inline,
+			expectedError:    true,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -130,10 +352,15 @@ func TestResponseBinding_Validate(t *testing.T) {
 
 			binding := &settings.ResponseBinding{
 				Path:             tc.path,
+				Method:           tc.method,
 				ResponseHeaders:  tc.responseHeaders,
 				ResponseCode:     tc.responseCode,
 				ResponseBody:     tc.responseBody,
 				ResponseBodyType: tc.responseBodyType,
+				WebSocket:        tc.webSocket,
+				GraphQL:          tc.graphQL,
+				Resource:         tc.resource,
+				Generated:        tc.generated,
 			}
 
 			err := binding.Validate()
@@ -143,3 +370,380 @@ func TestResponseBinding_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestTLSOptions_Validate(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name          string
+		options       settings.TLSOptions
+		expectedError bool
+	}{
+		{
+			name:          "empty",
+			options:       settings.TLSOptions{},
+			expectedError: false,
+		},
+		{
+			name:          "version range",
+			options:       settings.TLSOptions{MinVersion: "1.0", MaxVersion: "1.2"},
+			expectedError: false,
+		},
+		{
+			name:          "unknown version",
+			options:       settings.TLSOptions{MinVersion: "1.4"},
+			expectedError: true,
+		},
+		{
+			name:          "min above max",
+			options:       settings.TLSOptions{MinVersion: "1.3", MaxVersion: "1.2"},
+			expectedError: true,
+		},
+		{
+			name:          "insecure cipher suite",
+			options:       settings.TLSOptions{CipherSuites: []string{"TLS_RSA_WITH_3DES_EDE_CBC_SHA"}},
+			expectedError: false,
+		},
+		{
+			name:          "tls 1.3 cipher suite",
+			options:       settings.TLSOptions{CipherSuites: []string{"TLS_AES_128_GCM_SHA256"}},
+			expectedError: true,
+		},
+		{
+			name:          "unknown cipher suite",
+			options:       settings.TLSOptions{CipherSuites: []string{"TLS_MADE_UP"}},
+			expectedError: true,
+		},
+		{
+			name:          "empty alpn protocol",
+			options:       settings.TLSOptions{ALPN: []string{"h2", ""}},
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.options.Validate()
+			if (err != nil) != tc.expectedError {
+				t.Errorf("unexpected error response: %v", err)
+			}
+		})
+	}
+}
+
+func TestGRPCSettings_Validate(t *testing.T) {
+	t.Parallel()
+
+	const set = "../protobuf/testdata/greet.pb"
+	reply := []map[string]any{{"message": "hi"}}
+
+	testCases := []struct {
+		name          string
+		grpc          settings.GRPCSettings
+		expectedError bool
+	}{
+		{
+			name:          "no methods",
+			grpc:          settings.GRPCSettings{DescriptorSet: set, Reflection: true},
+			expectedError: false,
+		},
+		{
+			name: "unary and streaming",
+			grpc: settings.GRPCSettings{DescriptorSet: set, Methods: []settings.GRPCMethod{
+				{Method: "greet.v1.Greeter/SayHello", Responses: []settings.GRPCResponse{{Match: map[string]any{"name": "x"}, Status: "NOT_FOUND"}, {Messages: reply}}},
+				{Method: "greet.v1.Greeter/SayHellos", Responses: []settings.GRPCResponse{{Messages: append(reply, reply...), Status: "ABORTED"}}},
+			}},
+			expectedError: false,
+		},
+		{
+			name:          "missing descriptor set",
+			grpc:          settings.GRPCSettings{DescriptorSet: "nope.pb"},
+			expectedError: true,
+		},
+		{
+			name:          "unknown method",
+			grpc:          settings.GRPCSettings{DescriptorSet: set, Methods: []settings.GRPCMethod{{Method: "greet.v1.Greeter/Missing", Responses: []settings.GRPCResponse{{Messages: reply}}}}},
+			expectedError: true,
+		},
+		{
+			name:          "client streaming",
+			grpc:          settings.GRPCSettings{DescriptorSet: set, Methods: []settings.GRPCMethod{{Method: "greet.v1.Greeter/Chat", Responses: []settings.GRPCResponse{{Messages: reply}}}}},
+			expectedError: true,
+		},
+		{
+			name:          "unary without a message",
+			grpc:          settings.GRPCSettings{DescriptorSet: set, Methods: []settings.GRPCMethod{{Method: "greet.v1.Greeter/SayHello", Responses: []settings.GRPCResponse{{}}}}},
+			expectedError: true,
+		},
+		{
+			name:          "unary error with a message",
+			grpc:          settings.GRPCSettings{DescriptorSet: set, Methods: []settings.GRPCMethod{{Method: "greet.v1.Greeter/SayHello", Responses: []settings.GRPCResponse{{Messages: reply, Status: "INTERNAL"}}}}},
+			expectedError: true,
+		},
+		{
+			name:          "unknown status",
+			grpc:          settings.GRPCSettings{DescriptorSet: set, Methods: []settings.GRPCMethod{{Method: "greet.v1.Greeter/SayHellos", Responses: []settings.GRPCResponse{{Status: "BROKEN"}}}}},
+			expectedError: true,
+		},
+		{
+			name:          "message not matching the descriptor",
+			grpc:          settings.GRPCSettings{DescriptorSet: set, Methods: []settings.GRPCMethod{{Method: "greet.v1.Greeter/SayHello", Responses: []settings.GRPCResponse{{Messages: []map[string]any{{"nope": 1}}}}}}},
+			expectedError: true,
+		},
+		{
+			name: "duplicate method",
+			grpc: settings.GRPCSettings{DescriptorSet: set, Methods: []settings.GRPCMethod{
+				{Method: "greet.v1.Greeter/SayHello", Responses: []settings.GRPCResponse{{Messages: reply}}},
+				{Method: "greet.v1.Greeter/SayHello", Responses: []settings.GRPCResponse{{Messages: reply}}},
+			}},
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.grpc.Validate()
+			if (err != nil) != tc.expectedError {
+				t.Errorf("unexpected error response: %v", err)
+			}
+		})
+	}
+}
+
+func TestUnmarshalledRootSettingSocketListener_Validate(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name          string
+		listener      settings.UnmarshalledRootSettingSocketListener
+		expectedError bool
+	}{
+		{
+			name: "tcp script",
+			listener: settings.UnmarshalledRootSettingSocketListener{
+				ListenerName: "tcp", Delimiter: "\n", IdleTimeout: time.Second,
+				Banner:  []settings.SocketMessage{{Text: "hello\n"}},
+				Replies: []settings.SocketReply{{Match: settings.SocketMatch{Hex: "0D 0a", Regex: "^a"}, Messages: []settings.SocketMessage{{Hex: "ff"}}, Close: true}},
+			},
+			expectedError: false,
+		},
+		{
+			name:          "udp",
+			listener:      settings.UnmarshalledRootSettingSocketListener{ListenerName: "udp", Protocol: settings.UDP, Replies: []settings.SocketReply{{Messages: []settings.SocketMessage{{Text: "pong"}}}}},
+			expectedError: false,
+		},
+		{
+			name:          "no name",
+			listener:      settings.UnmarshalledRootSettingSocketListener{},
+			expectedError: true,
+		},
+		{
+			name:          "unknown protocol",
+			listener:      settings.UnmarshalledRootSettingSocketListener{ListenerName: "x", Protocol: "sctp"},
+			expectedError: true,
+		},
+		{
+			name:          "port out of range",
+			listener:      settings.UnmarshalledRootSettingSocketListener{ListenerName: "x", ListenerPort: 70000},
+			expectedError: true,
+		},
+		{
+			name:          "bad hex",
+			listener:      settings.UnmarshalledRootSettingSocketListener{ListenerName: "x", Banner: []settings.SocketMessage{{Hex: "zz"}}},
+			expectedError: true,
+		},
+		{
+			name:          "text and hex",
+			listener:      settings.UnmarshalledRootSettingSocketListener{ListenerName: "x", Banner: []settings.SocketMessage{{Text: "a", Hex: "61"}}},
+			expectedError: true,
+		},
+		{
+			name:          "bad regex",
+			listener:      settings.UnmarshalledRootSettingSocketListener{ListenerName: "x", Replies: []settings.SocketReply{{Match: settings.SocketMatch{Regex: "("}}}},
+			expectedError: true,
+		},
+		{
+			name:          "udp banner",
+			listener:      settings.UnmarshalledRootSettingSocketListener{ListenerName: "x", Protocol: settings.UDP, Banner: []settings.SocketMessage{{Text: "hi"}}},
+			expectedError: true,
+		},
+		{
+			name:          "udp close",
+			listener:      settings.UnmarshalledRootSettingSocketListener{ListenerName: "x", Protocol: settings.UDP, Replies: []settings.SocketReply{{Close: true}}},
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.listener.Validate()
+			if (err != nil) != tc.expectedError {
+				t.Errorf("unexpected error response: %v", err)
+			}
+		})
+	}
+}
+
+func TestCallback_Validate(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name          string
+		callback      settings.Callback
+		expectedError bool
+	}{
+		{
+			name: "templated",
+			callback: settings.Callback{
+				URL:     "{{.Query.Get \"callback\"}}",
+				Method:  http.MethodPut,
+				Headers: []settings.ResponseHeader{{Key: "X-Id", Value: "{{uuid}}"}},
+				Body:    `{"id": {{.JSON.id | json}}}`,
+				Delay:   time.Second,
+				Retries: 3,
+			},
+			expectedError: false,
+		},
+		{
+			name:          "no url",
+			callback:      settings.Callback{},
+			expectedError: true,
+		},
+		{
+			name:          "relative url",
+			callback:      settings.Callback{URL: "/webhooks"},
+			expectedError: true,
+		},
+		{
+			name:          "lower case method",
+			callback:      settings.Callback{URL: "http://localhost/hook", Method: "post"},
+			expectedError: true,
+		},
+		{
+			name:          "negative retries",
+			callback:      settings.Callback{URL: "http://localhost/hook", Retries: -1},
+			expectedError: true,
+		},
+		{
+			name:          "negative delay",
+			callback:      settings.Callback{URL: "http://localhost/hook", Delay: -time.Second},
+			expectedError: true,
+		},
+		{
+			name:          "bad body template",
+			callback:      settings.Callback{URL: "http://localhost/hook", Body: "{{.JSON.id"},
+			expectedError: true,
+		},
+		{
+			name:          "header without a value",
+			callback:      settings.Callback{URL: "http://localhost/hook", Headers: []settings.ResponseHeader{{Key: "X-Id"}}},
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.callback.Validate()
+			if (err != nil) != tc.expectedError {
+				t.Errorf("unexpected error response: %v", err)
+			}
+		})
+	}
+}
+
+func TestAuthSettings_Validate(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name          string
+		auth          settings.AuthSettings
+		expectedError bool
+	}{
+		{
+			name: "every scheme",
+			auth: settings.AuthSettings{
+				Basic:        []settings.BasicAuthUser{{Username: "alice", Password: "wonderland"}},
+				APIKeys:      &settings.APIKeyAuth{Query: "api_key", Keys: []settings.APIKey{{Key: "k-123"}}},
+				Bearer:       []settings.BearerToken{{Token: "static-token"}},
+				JWT:          &settings.JWTAuth{Secret: "s3cret", Issuer: "https://issuer.example", Leeway: time.Minute},
+				Unauthorized: &settings.AuthResponse{ResponseBody: "who are you?"},
+				Forbidden:    &settings.AuthResponse{ResponseCode: http.StatusNotFound},
+			},
+			expectedError: false,
+		},
+		{
+			name:          "disabled",
+			auth:          settings.AuthSettings{Disabled: true},
+			expectedError: false,
+		},
+		{
+			name:          "no schemes",
+			auth:          settings.AuthSettings{Realm: "api"},
+			expectedError: true,
+		},
+		{
+			name:          "user without a name",
+			auth:          settings.AuthSettings{Basic: []settings.BasicAuthUser{{Password: "secret"}}},
+			expectedError: true,
+		},
+		{
+			name:          "api keys without keys",
+			auth:          settings.AuthSettings{APIKeys: &settings.APIKeyAuth{Header: "X-API-Key"}},
+			expectedError: true,
+		},
+		{
+			name:          "empty bearer token",
+			auth:          settings.AuthSettings{Bearer: []settings.BearerToken{{}}},
+			expectedError: true,
+		},
+		{
+			name:          "jwt without keys",
+			auth:          settings.AuthSettings{JWT: &settings.JWTAuth{Issuer: "https://issuer.example"}},
+			expectedError: true,
+		},
+		{
+			name:          "missing jwks file",
+			auth:          settings.AuthSettings{JWT: &settings.JWTAuth{JWKSFile: "does-not-exist.json"}},
+			expectedError: true,
+		},
+		{
+			name:          "negative leeway",
+			auth:          settings.AuthSettings{JWT: &settings.JWTAuth{Secret: "s3cret", Leeway: -time.Second}},
+			expectedError: true,
+		},
+		{
+			name:          "invalid failure code",
+			auth:          settings.AuthSettings{Bearer: []settings.BearerToken{{Token: "t"}}, Unauthorized: &settings.AuthResponse{ResponseCode: 42}},
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.auth.Validate()
+			if (err != nil) != tc.expectedError {
+				t.Errorf("unexpected error response: %v", err)
+			}
+		})
+	}
+}
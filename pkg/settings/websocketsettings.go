@@ -0,0 +1,160 @@
+package settings
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// A scripted conversation for bindings with a responsebodytype of "websocket". Durations are written as eg. 500ms or 5s.
+type WebSocketSettings struct {
+	Subprotocols []string           `yaml:"subprotocols,omitempty"` // Accepted when the client offers them, the first one it offers wins
+	OnConnect    []WebSocketMessage `yaml:"onconnect,omitempty"`    // Sent as soon as the connection is upgraded
+	Replies      []WebSocketReply   `yaml:"replies,omitempty"`      // Tried in order against every message received, the first match answers
+	Push         []WebSocketPush    `yaml:"push,omitempty"`         // Sent on a timer for as long as the connection is open
+	Close        *WebSocketClose    `yaml:"close,omitempty"`        // Closes the connection from the server side, counting from the upgrade
+}
+
+func (s *WebSocketSettings) Validate() error {
+	for _, m := range s.OnConnect {
+		err := m.Validate()
+		if err != nil {
+			return fmt.Errorf("WebSocketSettings.Validate(): %w", err)
+		}
+	}
+
+	for _, r := range s.Replies {
+		err := r.Validate()
+		if err != nil {
+			return fmt.Errorf("WebSocketSettings.Validate(): %w", err)
+		}
+	}
+
+	for _, p := range s.Push {
+		err := p.Validate()
+		if err != nil {
+			return fmt.Errorf("WebSocketSettings.Validate(): %w", err)
+		}
+	}
+
+	if s.Close != nil {
+		err := s.Close.Validate()
+		if err != nil {
+			return fmt.Errorf("WebSocketSettings.Validate(): %w", err)
+		}
+	}
+
+	return nil
+}
+
+// A text message, json included.
+type WebSocketMessage struct {
+	Text  string        `yaml:"text"`
+	Delay time.Duration `yaml:"delay,omitempty"` // Wait this long before sending
+}
+
+func (m *WebSocketMessage) Validate() error {
+	if m.Delay < 0 {
+		return fmt.Errorf("WebSocketMessage.Validate(): delay can't be negative")
+	}
+
+	return nil
+}
+
+type WebSocketReply struct {
+	Match    WebSocketMatch     `yaml:"match,omitempty"`    // Empty matches any message
+	Messages []WebSocketMessage `yaml:"messages,omitempty"` // Sent in order
+	Close    *WebSocketClose    `yaml:"close,omitempty"`    // Closes the connection once the messages are sent
+}
+
+func (r *WebSocketReply) Validate() error {
+	err := r.Match.Validate()
+	if err != nil {
+		return fmt.Errorf("WebSocketReply.Validate(): %w", err)
+	}
+
+	for _, m := range r.Messages {
+		err = m.Validate()
+		if err != nil {
+			return fmt.Errorf("WebSocketReply.Validate(): %w", err)
+		}
+	}
+
+	if r.Close != nil {
+		err = r.Close.Validate()
+		if err != nil {
+			return fmt.Errorf("WebSocketReply.Validate(): %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Conditions on an incoming message, all of those set have to hold.
+type WebSocketMatch struct {
+	Text     string         `yaml:"text,omitempty"`     // The whole message
+	Contains string         `yaml:"contains,omitempty"` // Part of the message
+	Regex    string         `yaml:"regex,omitempty"`    // RE2 syntax, see https://golang.org/s/re2syntax
+	JSON     map[string]any `yaml:"json,omitempty"`     // The message is a json object holding at least these fields, nested objects are compared the same way
+}
+
+func (m *WebSocketMatch) Validate() error {
+	if m.Regex != "" {
+		_, err := regexp.Compile(m.Regex)
+		if err != nil {
+			return fmt.Errorf("WebSocketMatch.Validate(): %w", err)
+		}
+	}
+
+	if m.JSON != nil {
+		_, err := json.Marshal(m.JSON)
+		if err != nil {
+			return fmt.Errorf("WebSocketMatch.Validate(): json can't be represented as json: %w", err)
+		}
+	}
+
+	return nil
+}
+
+type WebSocketPush struct {
+	Text     string        `yaml:"text"`
+	Interval time.Duration `yaml:"interval"`
+	Count    int           `yaml:"count,omitempty"` // Stop after this many, 0 keeps going
+}
+
+func (p *WebSocketPush) Validate() error {
+	if p.Interval <= 0 {
+		return fmt.Errorf("WebSocketPush.Validate(): interval must be above 0")
+	}
+
+	if p.Count < 0 {
+		return fmt.Errorf("WebSocketPush.Validate(): count can't be negative")
+	}
+
+	return nil
+}
+
+// A close frame, eg. code 1000 for a normal close or 1011 for a server error.
+type WebSocketClose struct {
+	Code   int           `yaml:"code"`
+	Reason string        `yaml:"reason,omitempty"` // At most 123 bytes
+	After  time.Duration `yaml:"after,omitempty"`  // Wait this long before closing
+}
+
+func (c *WebSocketClose) Validate() error {
+	// 1005, 1006 and 1015 are reserved for reporting what happened locally and can't be sent
+	if c.Code < 1000 || c.Code > 4999 || c.Code == 1005 || c.Code == 1006 || c.Code == 1015 {
+		return fmt.Errorf("WebSocketClose.Validate(): invalid close code: %d", c.Code)
+	}
+
+	if len(c.Reason) > 123 {
+		return fmt.Errorf("WebSocketClose.Validate(): reason is longer than 123 bytes")
+	}
+
+	if c.After < 0 {
+		return fmt.Errorf("WebSocketClose.Validate(): after can't be negative")
+	}
+
+	return nil
+}